@@ -0,0 +1,125 @@
+package datadog
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAdaptiveInterval - interval the throttling is checked at
+	defaultAdaptiveInterval = 10 * time.Second
+	// defaultAdaptiveMinRate - lowest rate low-severity entries are sampled at
+	defaultAdaptiveMinRate = 0.01
+	// defaultAdaptiveThreshold - share of throttled attempts lowering the rate
+	defaultAdaptiveThreshold = 0.1
+)
+
+// adaptive - sampling of the entries less severe than warnings, halved every
+// interval the intake throttles and doubled back every interval it does not
+type adaptive struct {
+	hook      *Hook
+	interval  time.Duration
+	minRate   float64
+	threshold float64
+
+	rate uint64 // float64 bits
+
+	m                   sync.Mutex
+	attempts, throttled int64
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newAdaptive(h *Hook, options Options) *adaptive {
+	a := &adaptive{
+		hook:      h,
+		interval:  options.AdaptiveInterval,
+		minRate:   options.AdaptiveMinRate,
+		threshold: options.AdaptiveThreshold,
+		rate:      math.Float64bits(1),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	if a.interval <= 0 {
+		a.interval = defaultAdaptiveInterval
+	}
+	if a.minRate <= 0 || a.minRate > 1 {
+		a.minRate = defaultAdaptiveMinRate
+	}
+	if a.threshold <= 0 {
+		a.threshold = defaultAdaptiveThreshold
+	}
+	go a.run()
+	return a
+}
+
+func (a *adaptive) run() {
+	defer close(a.stopped)
+	for {
+		select {
+		case <-a.hook.after(a.interval):
+			a.adjust()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// adjust - follow the throttling of the attempts made since the last call
+func (a *adaptive) adjust() {
+	stats := a.hook.client.Stats()
+	a.m.Lock()
+	attempts, throttled := stats.Attempts-a.attempts, stats.Throttled-a.throttled
+	a.attempts, a.throttled = stats.Attempts, stats.Throttled
+	a.m.Unlock()
+
+	rate := a.current()
+	switch {
+	case attempts > 0 && float64(throttled)/float64(attempts) >= a.threshold:
+		rate = math.Max(rate/2, a.minRate)
+	case throttled == 0:
+		rate = math.Min(rate*2, 1)
+	}
+	atomic.StoreUint64(&a.rate, math.Float64bits(rate))
+}
+
+// current - the active rate of the low-severity entries
+func (a *adaptive) current() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&a.rate))
+}
+
+// sampled - whether an entry is kept, warnings and worse always are
+func (a *adaptive) sampled(level logrus.Level) bool {
+	if a == nil || level <= logrus.WarnLevel {
+		return true
+	}
+	h := a.hook
+	rate := a.current()
+	if rate >= 1 {
+		return true
+	}
+	h.samplerLock.Lock()
+	defer h.samplerLock.Unlock()
+	return h.sampler.Float64() < rate
+}
+
+// stop - stop adjusting the rate
+func (a *adaptive) stop() {
+	a.once.Do(func() { close(a.done) })
+	<-a.stopped
+}
+
+// AdaptiveRate - share of the entries less severe than warnings shipped, 1
+// unless Options.AdaptiveSampling lowered it while the intake throttles
+func (h *Hook) AdaptiveRate() float64 {
+	if h.adaptive == nil {
+		return 1
+	}
+	return h.adaptive.current()
+}
@@ -0,0 +1,82 @@
+package datadog
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdaptiveSampling(t *testing.T) {
+	var throttle int32 = 1
+	hook := NewHook(DatadogUSHost, "key", 10*time.Millisecond, 0, logrus.TraceLevel, &logrus.JSONFormatter{}, Options{
+		AdaptiveSampling: true,
+		AdaptiveInterval: time.Hour,
+		AdaptiveMinRate:  0.2,
+		FlushPolicy:      intake.MaxEntries(1),
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error {
+			if atomic.LoadInt32(&throttle) == 1 {
+				return &intake.StatusError{Code: http.StatusTooManyRequests, Status: "429 Too Many Requests"}
+			}
+			return nil
+		}),
+	})
+	defer hook.Close(context.Background())
+	equals(t, 1.0, hook.Stats().AdaptiveRate)
+
+	// attempts made since the last adjustment drive the next one
+	attempt := func() {
+		attempts := hook.Stats().Client.Attempts
+		ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.ErrorLevel}))
+		for hook.Stats().Client.Attempts == attempts {
+			time.Sleep(time.Millisecond)
+		}
+		hook.adaptive.adjust()
+	}
+	attempt()
+	equals(t, 0.5, hook.Stats().AdaptiveRate)
+	attempt()
+	equals(t, 0.25, hook.Stats().AdaptiveRate)
+	attempt()
+	equals(t, 0.2, hook.Stats().AdaptiveRate)
+	equals(t, int64(3), hook.Stats().Client.Throttled)
+
+	// warnings and worse are never sampled out
+	skipped := hook.Stats().Skipped
+	for i := 0; i < 100; i++ {
+		ok(t, hook.Fire(&logrus.Entry{Message: "failed", Level: logrus.WarnLevel}))
+	}
+	equals(t, skipped, hook.Stats().Skipped)
+	for i := 0; i < 100; i++ {
+		ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel}))
+	}
+	assert(t, hook.Stats().Skipped > skipped+50, "info entries are sampled, skipped %d", hook.Stats().Skipped-skipped)
+
+	for hook.Stats().Client.Attempts < hook.Stats().Client.Pushed {
+		time.Sleep(time.Millisecond)
+	}
+	hook.adaptive.adjust()
+	equals(t, 0.2, hook.Stats().AdaptiveRate)
+
+	// the rate is restored once the intake recovers
+	atomic.StoreInt32(&throttle, 0)
+	attempt()
+	equals(t, 0.4, hook.Stats().AdaptiveRate)
+	hook.adaptive.adjust()
+	hook.adaptive.adjust()
+	equals(t, 1.0, hook.Stats().AdaptiveRate)
+}
+
+func TestAdaptiveDisabled(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", time.Second, 0, logrus.TraceLevel, &logrus.JSONFormatter{}, Options{
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	equals(t, 1.0, hook.AdaptiveRate())
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel}))
+	equals(t, int64(0), hook.Stats().Skipped)
+}
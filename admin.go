@@ -0,0 +1,126 @@
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxUpdateSize - largest Update body the admin handler reads
+const maxUpdateSize = 64 << 10
+
+// Status is the state of a hook reported by its admin handler
+type Status struct {
+	Level     string  `json:"level"`
+	Sampling  float64 `json:"sampling"`
+	Debug     bool    `json:"debug"`
+	Incidents int64   `json:"incidents"`
+	Config    string  `json:"config"`
+	// Muted - fingerprints muted and when they are unmuted
+	Muted map[string]time.Time `json:"muted,omitempty"`
+}
+
+// Update is a runtime configuration change accepted by the admin handler,
+// fields left out are not changed. A Sampling of 0 is refused rather than
+// taken as the unset default shipping every entry, send 1 for that.
+type Update struct {
+	Level    *string  `json:"level,omitempty"`
+	Sampling *float64 `json:"sampling,omitempty"`
+	Debug    *bool    `json:"debug,omitempty"`
+	MaxRetry *int     `json:"maxRetry,omitempty"`
+	Compress *bool    `json:"compress,omitempty"`
+	// Mute - durations to mute fingerprints for, such as "30m", "0" unmutes
+	Mute map[string]string `json:"mute,omitempty"`
+}
+
+// Status - the current state of the hook
+func (h *Hook) Status() Status {
+	return Status{
+		Level:     h.Level().String(),
+		Sampling:  h.Sampling(),
+		Debug:     h.client.Debug(),
+		Incidents: h.Incidents(),
+		Config:    h.String(),
+		Muted:     h.Muted(),
+	}
+}
+
+// Apply - apply a runtime configuration change, nothing is changed if the
+// update is invalid
+func (h *Hook) Apply(u Update) error {
+	var level logrus.Level
+	if u.Level != nil {
+		l, err := logrus.ParseLevel(*u.Level)
+		if err != nil {
+			return err
+		}
+		level = l
+	}
+	if u.Sampling != nil && (*u.Sampling <= 0 || *u.Sampling > 1) {
+		return fmt.Errorf("datadog: sampling %v is not above 0 and at most 1", *u.Sampling)
+	}
+	mute := make(map[string]time.Duration, len(u.Mute))
+	for fingerprint, d := range u.Mute {
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return fmt.Errorf("datadog: mute %s: %v", fingerprint, err)
+		}
+		mute[fingerprint] = duration
+	}
+	if u.Level != nil {
+		h.SetLevel(level)
+	}
+	if u.Sampling != nil {
+		h.SetSampling(*u.Sampling)
+	}
+	if u.Debug != nil {
+		h.SetDebug(*u.Debug)
+	}
+	if u.MaxRetry != nil || u.Compress != nil {
+		h.UpdateSettings(func(s *Settings) {
+			if u.MaxRetry != nil {
+				s.MaxRetry = *u.MaxRetry
+			}
+			if u.Compress != nil {
+				s.Compress = *u.Compress
+			}
+		})
+	}
+	for fingerprint, d := range mute {
+		h.Mute(fingerprint, d)
+	}
+	return nil
+}
+
+// Handler - an http.Handler reporting the Status of the hook as JSON on GET
+// and applying an Update sent as JSON on POST, PUT or PATCH, so operators
+// can adjust shipping of an instance without a redeploy. Bodies over 64kB
+// are refused. It has no access control of its own, mount it behind the
+// service's admin authentication.
+func (h *Hook) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			var u Update
+			body := http.MaxBytesReader(w, r.Body, maxUpdateSize)
+			if err := json.NewDecoder(body).Decode(&u); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := h.Apply(u); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT, PATCH")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Status())
+	})
+}
@@ -0,0 +1,99 @@
+package datadog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHandler(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "secret-key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+	handler := hook.Handler()
+
+	serve := func(method, body string) (int, Status) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		var s Status
+		if w.Code == http.StatusOK {
+			ok(t, json.NewDecoder(w.Body).Decode(&s))
+		}
+		return w.Code, s
+	}
+
+	code, s := serve("GET", "")
+	equals(t, http.StatusOK, code)
+	equals(t, "info", s.Level)
+	equals(t, 1.0, s.Sampling)
+	equals(t, false, s.Debug)
+	assert(t, !strings.Contains(s.Config, "secret-key"), "API key leaked in %q", s.Config)
+
+	code, s = serve("PATCH", `{"level":"debug","sampling":0.25,"debug":true}`)
+	equals(t, http.StatusOK, code)
+	equals(t, "debug", s.Level)
+	equals(t, 0.25, s.Sampling)
+	equals(t, true, s.Debug)
+	equals(t, logrus.DebugLevel, hook.Level())
+
+	code, _ = serve("POST", `{"level":"loud","sampling":0.5}`)
+	equals(t, http.StatusBadRequest, code)
+	code, _ = serve("POST", `{"sampling":2}`)
+	equals(t, http.StatusBadRequest, code)
+	// 0 is not taken as the default keeping everything
+	code, _ = serve("POST", `{"sampling":0}`)
+	equals(t, http.StatusBadRequest, code)
+	code, _ = serve("POST", `{"debug":false`+strings.Repeat(" ", maxUpdateSize)+`}`)
+	equals(t, http.StatusBadRequest, code)
+	equals(t, true, hook.client.Debug())
+	code, _ = serve("POST", `not json`)
+	equals(t, http.StatusBadRequest, code)
+	code, _ = serve("DELETE", "")
+	equals(t, http.StatusMethodNotAllowed, code)
+	// invalid updates change nothing
+	equals(t, 0.25, hook.Sampling())
+	equals(t, logrus.DebugLevel, hook.Level())
+	hook.SetDebug(false)
+}
+
+func TestLevelAndSampling(t *testing.T) {
+	var tee strings.Builder
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.TextFormatter{DisableTimestamp: true}, Options{
+		Tee:  &tee,
+		Seed: 1,
+	})
+	equals(t, logrus.AllLevels, hook.Levels())
+
+	ok(t, hook.Fire(&logrus.Entry{Message: "skipped", Level: logrus.DebugLevel}))
+	equals(t, "", tee.String())
+	hook.SetLevel(logrus.DebugLevel)
+	ok(t, hook.Fire(&logrus.Entry{Message: "shipped", Level: logrus.DebugLevel}))
+	assert(t, strings.Contains(tee.String(), "shipped"), "entry missing in %q", tee.String())
+
+	hook.SetSampling(0.5)
+	shipped := 0
+	for i := 0; i < 1000; i++ {
+		if hook.sampled(&logrus.Entry{}) {
+			shipped++
+		}
+	}
+	assert(t, shipped > 400 && shipped < 600, "%d entries of 1000 sampled", shipped)
+	hook.SetSampling(0)
+	equals(t, 1.0, hook.Sampling())
+}
+
+func TestApplySettings(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 3, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+	retries, compress := 0, true
+	ok(t, hook.Apply(Update{MaxRetry: &retries, Compress: &compress}))
+	equals(t, 0, hook.Settings().MaxRetry)
+	equals(t, true, hook.Settings().Compress)
+	assert(t, strings.Contains(hook.String(), "maxRetry=0 json=true compress=true"), "settings not reported in %q", hook.String())
+
+	hook.UpdateSettings(func(s *Settings) { s.MaxRetry = 2 })
+	equals(t, 2, hook.Settings().MaxRetry)
+	equals(t, true, hook.Settings().Compress)
+}
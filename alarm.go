@@ -0,0 +1,112 @@
+package datadog
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultErrorRateThreshold - share of failed attempts raising the alarm
+	defaultErrorRateThreshold = 0.5
+	// defaultErrorRateWindow - window the failure rate is measured over
+	defaultErrorRateWindow = time.Minute
+	// alarmSlots - steps the window slides by
+	alarmSlots = 10
+)
+
+// Alarm tells log shipping broke or was restored, see Options.ErrorRateAlarm
+type Alarm struct {
+	// Broken - the failure rate reached the threshold, false once it went
+	// back below
+	Broken bool
+	// Rate - share of the delivery attempts which failed over the window
+	Rate float64
+	// Attempts - delivery attempts made over the window
+	Attempts int64
+	// Time - when the rate was measured
+	Time time.Time
+}
+
+// alarmSample - the counters of the client at the end of a slot
+type alarmSample struct {
+	attempts, failed int64
+}
+
+// alarm - the failure rate of the delivery attempts over a sliding window,
+// reported once when it reaches the threshold and once when it recovers
+type alarm struct {
+	hook      *Hook
+	threshold float64
+	window    time.Duration
+	fn        func(Alarm)
+
+	m       sync.Mutex
+	samples [alarmSlots]alarmSample // the counters of hooks start at zero
+	next    int
+	broken  bool
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newAlarm(h *Hook, options Options) *alarm {
+	a := &alarm{
+		hook:      h,
+		threshold: options.ErrorRateThreshold,
+		window:    options.ErrorRateWindow,
+		fn:        options.ErrorRateAlarm,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	if a.threshold <= 0 {
+		a.threshold = defaultErrorRateThreshold
+	}
+	if a.window <= 0 {
+		a.window = defaultErrorRateWindow
+	}
+	go a.run()
+	return a
+}
+
+func (a *alarm) run() {
+	defer close(a.stopped)
+	for {
+		select {
+		case <-a.hook.after(a.window / alarmSlots):
+			a.check()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// check - slide the window by a slot, calling back if the rate crossed the
+// threshold. A window without attempts leaves the state as is.
+func (a *alarm) check() {
+	stats := a.hook.client.Stats()
+	cur := alarmSample{attempts: stats.Attempts, failed: stats.AttemptsFailed}
+	a.m.Lock()
+	old := a.samples[a.next]
+	a.samples[a.next] = cur
+	a.next = (a.next + 1) % alarmSlots
+	attempts := cur.attempts - old.attempts
+	if attempts <= 0 {
+		a.m.Unlock()
+		return
+	}
+	rate := float64(cur.failed-old.failed) / float64(attempts)
+	broken := rate >= a.threshold
+	changed := broken != a.broken
+	a.broken = broken
+	a.m.Unlock()
+	if changed {
+		a.fn(Alarm{Broken: broken, Rate: rate, Attempts: attempts, Time: a.hook.now()})
+	}
+}
+
+// stop - stop measuring the rate
+func (a *alarm) stop() {
+	a.once.Do(func() { close(a.done) })
+	<-a.stopped
+}
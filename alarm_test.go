@@ -0,0 +1,57 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestErrorRateAlarm(t *testing.T) {
+	var down int32 = 1
+	var alarms []Alarm
+	hook := New("key",
+		WithMaxRetry(1),
+		WithOption(func(o *Options) { o.FlushPolicy = intake.MaxEntries(1) }),
+		WithErrorRateAlarm(0.5, time.Hour, func(a Alarm) { alarms = append(alarms, a) }),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error {
+			if atomic.LoadInt32(&down) == 1 {
+				return errors.New("down")
+			}
+			return nil
+		})),
+	)
+	defer hook.Close(context.Background())
+
+	// the window slides once per check
+	attempt := func() {
+		attempts := hook.Stats().Client.Attempts
+		ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel}))
+		for hook.Stats().Client.Attempts == attempts {
+			time.Sleep(time.Millisecond)
+		}
+		hook.alarm.check()
+	}
+	hook.alarm.check()
+	equals(t, 0, len(alarms))
+	attempt()
+	attempt()
+	// raised once
+	equals(t, 1, len(alarms))
+	equals(t, true, alarms[0].Broken)
+	equals(t, 1.0, alarms[0].Rate)
+
+	atomic.StoreInt32(&down, 0)
+	for i := 0; i < alarmSlots; i++ {
+		attempt()
+	}
+	// cleared once, as soon as the rate went below
+	equals(t, 2, len(alarms))
+	equals(t, false, alarms[1].Broken)
+	equals(t, 0.4, alarms[1].Rate)
+	equals(t, int64(5), alarms[1].Attempts)
+}
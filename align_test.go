@@ -0,0 +1,18 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+func TestAlignBatches(t *testing.T) {
+	equals(t, nil, Options{}.flushPolicy())
+	now := time.Date(2020, 1, 2, 3, 4, 7, 0, time.UTC)
+	p := Options{AlignBatches: 10 * time.Second}.flushPolicy()
+	equals(t, false, p.OnTick(intake.BatchInfo{Window: now.Truncate(10 * time.Second)}, now))
+	p = Options{AlignBatches: 10 * time.Second, FlushPolicy: intake.MaxEntries(2)}.flushPolicy()
+	equals(t, true, p.OnAdd(intake.BatchInfo{Entries: 2}))
+	equals(t, true, p.OnTick(intake.BatchInfo{}, now))
+}
@@ -0,0 +1,41 @@
+package datadog
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// BuildInfoTags - tags identifying the build of the running binary: module
+// version, VCS revision and time, and Go version, from debug.ReadBuildInfo.
+// Tags whose value is unknown, e.g. built outside of a repository, are left out.
+func BuildInfoTags() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	return buildInfoTags(info)
+}
+
+func buildInfoTags(info *debug.BuildInfo) []string {
+	var tags []string
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		tags = append(tags, "version:"+v)
+	}
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if rev := settings["vcs.revision"]; rev != "" {
+		tags = append(tags, "git.commit.sha:"+rev)
+		if settings["vcs.modified"] == "true" {
+			tags = append(tags, "git.modified:true")
+		}
+	}
+	if t := settings["vcs.time"]; t != "" {
+		tags = append(tags, "build.time:"+t)
+	}
+	if info.GoVersion != "" {
+		tags = append(tags, "go.version:"+strings.TrimPrefix(info.GoVersion, "go"))
+	}
+	return tags
+}
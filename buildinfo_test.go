@@ -0,0 +1,42 @@
+package datadog
+
+import (
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBuildInfoTags(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.22.1",
+		Main:      debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2024-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	equals(t, []string{
+		"version:v1.2.3",
+		"git.commit.sha:0123abcd",
+		"git.modified:true",
+		"build.time:2024-01-02T03:04:05Z",
+		"go.version:1.22.1",
+	}, buildInfoTags(info))
+
+	info = &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}
+	equals(t, []string(nil), buildInfoTags(info))
+}
+
+func TestBuildInfoOption(t *testing.T) {
+	tags := []string{"env:prod"}
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{Tags: tags, BuildInfo: true})
+	equals(t, append([]string{"env:prod"}, BuildInfoTags()...), hook.options.Tags)
+	// the caller's tags are left as they are
+	equals(t, []string{"env:prod"}, tags)
+
+	hook = New("key", WithTags("env:prod"), WithBuildInfo())
+	equals(t, append([]string{"env:prod"}, BuildInfoTags()...), hook.options.Tags)
+}
@@ -0,0 +1,88 @@
+// Command ddhook holds operator tools of the hook.
+//
+//	ddhook replay [flags] files...
+//
+// replay re-sends the lines of dead-letter files written by
+// Options.Fallback, rate limited, to recover from an extended outage. The
+// API key is read from the DATADOG_APIKEY environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "replay":
+		replay(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ddhook replay [flags] files...")
+	os.Exit(2)
+}
+
+func replay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	host := fs.String("host", intake.DatadogUSHost, "host of the intake")
+	json := fs.Bool("json", true, "lines are JSON objects")
+	rate := fs.Float64("rate", 1000, "lines per second, unlimited if 0")
+	retry := fs.Int("retry", 5, "max retry of a batch")
+	source := fs.String("source", "", "source of the lines")
+	service := fs.String("service", "", "service of the lines")
+	hostname := fs.String("hostname", "", "hostname of the lines")
+	tags := fs.String("tags", "", "comma separated tags of the lines")
+	deadLetter := fs.String("dead-letter", "", "file the lines dropped again are appended to")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usage()
+	}
+
+	config := intake.Config{
+		Host:         *host,
+		APIKey:       os.Getenv("DATADOG_APIKEY"),
+		BatchTimeout: 5 * time.Second,
+		MaxRetry:     *retry,
+		JSON:         *json,
+		Stream:       intake.Stream{Source: *source, Service: *service, Hostname: *hostname},
+	}
+	if *tags != "" {
+		config.Stream.Tags = strings.Split(*tags, ",")
+	}
+	if *deadLetter != "" {
+		f, err := os.OpenFile(*deadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		config.Fallback = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := intake.ReplayFiles(ctx, fs.Args(), intake.ReplayOptions{
+		Config: config,
+		Rate:   *rate,
+		OnFile: func(path string, lines int) { log.Printf("%s: %d lines", path, lines) },
+	})
+	log.Printf("files=%d lines=%d delivered=%d dropped=%d", result.Files, result.Lines, result.Delivered, result.Dropped)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
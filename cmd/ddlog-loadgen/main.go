@@ -0,0 +1,159 @@
+// Command ddlog-loadgen fires log entries of a configurable shape and rate
+// through a hook tuned by its flags, against the mock intake or a real one,
+// and prints delivery, drop and latency statistics for capacity planning.
+// Without -host it starts the mock in process with the given profile. With
+// -real, -host is a Datadog intake and the API key is read from
+// DATADOG_APIKEY.
+//
+//	ddlog-loadgen -rate 5000 -duration 1m -size 100 -max-size 2000 -fields 10 -levels info,warn,error -error-rate 0.05 -compress
+//	DATADOG_APIKEY=... ddlog-loadgen -real -host http-intake.logs.datadoghq.eu -rate 100 -duration 10s
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+var protocols = map[string]datadog.Protocol{
+	"v1": datadog.ProtocolV1HTTP,
+	"v2": datadog.ProtocolV2HTTP,
+}
+
+func main() {
+	host := flag.String("host", "", "host of the intake, an in-process mock if empty")
+	real := flag.Bool("real", false, "the host is a Datadog intake, it has no stats and its certificate is verified")
+	service := flag.String("service", "ddlog-loadgen", "service of the entries")
+
+	batch := flag.Duration("batch", datadog.DefaultBatchTimeout, "batch timeout of the hook")
+	retry := flag.Int("retry", datadog.DefaultMaxRetry, "max retry of the hook")
+	maxEntries := flag.Int("max-entries", 0, "also send a batch once it holds this many entries, never if 0")
+	align := flag.Duration("align", 0, "cut batches on wall-clock boundaries of this duration")
+	compress := flag.Bool("compress", false, "compress payloads")
+	threshold := flag.Int("compression-threshold", 0, "payloads smaller than this many bytes are not compressed")
+	protocol := flag.String("protocol", "v2", "intake API, v1 or v2")
+	timeout := flag.Duration("request-timeout", 0, "timeout of an attempt, 30s if 0")
+	sampling := flag.Float64("sampling", 0, "share of the entries shipped, all if 0")
+	format := flag.String("format", "json", "formatter of the entries, json or text")
+
+	var load datadogtest.Load
+	flag.IntVar(&load.Rate, "rate", 1000, "entries per second, unthrottled if 0")
+	flag.DurationVar(&load.Duration, "duration", 10*time.Second, "time spent firing")
+	flag.IntVar(&load.Workers, "workers", 4, "goroutines firing")
+	flag.IntVar(&load.Size, "size", 100, "bytes of padding in every message")
+	flag.IntVar(&load.MaxSize, "max-size", 0, "when above -size, the padding is drawn between -size and -max-size bytes")
+	flag.IntVar(&load.Fields, "fields", 0, "extra fields of every entry")
+	levels := flag.String("levels", "info", "comma separated levels entries are fired at in turn")
+	flag.Float64Var(&load.ErrorRate, "error-rate", 0, "share of the entries carrying an error field")
+	flag.Int64Var(&load.Seed, "seed", 1, "seed of the sizes and errors drawn")
+
+	var profile datadogtest.Profile
+	flag.DurationVar(&profile.Latency, "latency", 0, "latency of the in-process mock")
+	flag.DurationVar(&profile.Jitter, "jitter", 0, "jitter of the in-process mock")
+	flag.Float64Var(&profile.FailureRate, "failure", 0, "failure rate of the in-process mock")
+	flag.Parse()
+
+	minLevel := logrus.PanicLevel
+	for _, name := range strings.Split(*levels, ",") {
+		level, err := logrus.ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		load.Levels = append(load.Levels, level)
+		if level > minLevel {
+			minLevel = level
+		}
+	}
+	p, ok := protocols[*protocol]
+	if !ok {
+		log.Fatalf("unknown protocol %q", *protocol)
+	}
+	var formatter logrus.Formatter = &logrus.JSONFormatter{}
+	if *format == "text" {
+		formatter = &logrus.TextFormatter{DisableColors: true}
+	}
+
+	apiKey := "mock"
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var srv *datadogtest.Server
+	switch {
+	case *real:
+		if *host == "" {
+			log.Fatal("-real requires -host")
+		}
+		if apiKey = os.Getenv("DATADOG_APIKEY"); apiKey == "" {
+			log.Fatal("DATADOG_APIKEY is not set")
+		}
+		client = nil
+	case *host == "":
+		srv = datadogtest.NewServer(profile)
+		defer srv.Close()
+		*host, client = srv.Host(), srv.Client()
+	}
+
+	var policy intake.FlushPolicy
+	if *maxEntries > 0 {
+		policy = intake.Any(intake.Interval(*batch), intake.MaxEntries(*maxEntries))
+	}
+	hook := datadog.New(apiKey,
+		datadog.WithHost(*host),
+		datadog.WithBatchTimeout(*batch),
+		datadog.WithMaxRetry(*retry),
+		datadog.WithLevel(minLevel),
+		datadog.WithFormatter(formatter),
+		datadog.WithService(*service),
+		datadog.WithSource("go"),
+		datadog.WithProtocol(p),
+		datadog.WithHTTPClient(client),
+		datadog.WithRequestTimeout(*timeout),
+		datadog.WithOption(func(o *datadog.Options) {
+			o.Compress, o.CompressionThreshold = *compress, *threshold
+			o.Sampling = *sampling
+			o.FlushPolicy = policy
+			o.AlignBatches = *align
+		}),
+	)
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(minLevel)
+	logger.AddHook(hook)
+
+	result := datadogtest.Run(logger, load)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		log.Printf("close: %v", err)
+	}
+
+	report := datadogtest.Report{Result: result, Client: hook.Stats().Client}
+	switch {
+	case srv != nil:
+		report.Server = srv.Stats()
+	case !*real:
+		resp, err := client.Get("https://" + *host + datadogtest.StatsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&report.Server); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Fprintln(os.Stdout, report)
+	if q := report.Client.Quota; q != nil {
+		fmt.Fprintf(os.Stdout, "quota limit=%d remaining=%d reset=%s\n", q.Limit, q.Remaining, q.Reset.Format(time.RFC3339))
+	}
+}
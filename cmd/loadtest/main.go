@@ -0,0 +1,81 @@
+// Command loadtest runs a hook at a configurable rate against the mock
+// intake and prints delivery, drop and latency statistics. Without -host it
+// starts the mock in process with the given profile.
+//
+//	loadtest -rate 2000 -duration 30s -latency 100ms -failure 0.1 -batch 1s -retry 3
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	host := flag.String("host", "", "host of a running mock intake, whose stats add up across runs, an in-process one if empty")
+	apiKey := flag.String("api-key", "mock", "API key sent")
+	batch := flag.Duration("batch", 5*time.Second, "batch timeout of the hook")
+	retry := flag.Int("retry", 3, "max retry of the hook")
+	compress := flag.Bool("compress", false, "compress payloads")
+	var load datadogtest.Load
+	flag.IntVar(&load.Rate, "rate", 1000, "entries per second, unthrottled if 0")
+	flag.DurationVar(&load.Duration, "duration", 10*time.Second, "time spent firing")
+	flag.IntVar(&load.Workers, "workers", 4, "goroutines firing")
+	flag.IntVar(&load.Size, "size", 100, "bytes of padding in every message")
+	var profile datadogtest.Profile
+	flag.DurationVar(&profile.Latency, "latency", 0, "latency of the in-process mock")
+	flag.DurationVar(&profile.Jitter, "jitter", 0, "jitter of the in-process mock")
+	flag.Float64Var(&profile.FailureRate, "failure", 0, "failure rate of the in-process mock")
+	flag.Parse()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var srv *datadogtest.Server
+	if *host == "" {
+		srv = datadogtest.NewServer(profile)
+		defer srv.Close()
+		*host, client = srv.Host(), srv.Client()
+	}
+
+	hook := datadog.NewHook(*host, *apiKey, *batch, *retry, logrus.InfoLevel, &logrus.JSONFormatter{}, datadog.Options{
+		Service:    "loadtest",
+		Source:     "go",
+		Compress:   *compress,
+		HTTPClient: client,
+	})
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(hook)
+
+	result := datadogtest.Run(logger, load)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		log.Printf("close: %v", err)
+	}
+
+	report := datadogtest.Report{Result: result, Client: hook.Stats().Client}
+	if srv != nil {
+		report.Server = srv.Stats()
+	} else {
+		resp, err := client.Get("https://" + *host + datadogtest.StatsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&report.Server); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Fprintln(os.Stdout, report)
+}
@@ -0,0 +1,63 @@
+// Command mockintake serves the mock Datadog intake of the datadogtest
+// package over TLS with a self-signed certificate, printing what it
+// received every interval.
+//
+//	mockintake -addr :8443 -latency 50ms -failure 0.05
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+)
+
+func main() {
+	addr := flag.String("addr", ":8443", "address to listen at")
+	plain := flag.Bool("plain", false, "serve HTTP instead of HTTPS")
+	interval := flag.Duration("interval", 10*time.Second, "interval between two stats")
+	var profile datadogtest.Profile
+	flag.DurationVar(&profile.Latency, "latency", 0, "latency of every request")
+	flag.DurationVar(&profile.Jitter, "jitter", 0, "random latency added to every request")
+	flag.Float64Var(&profile.FailureRate, "failure", 0, "share of requests failed")
+	flag.IntVar(&profile.Status, "status", http.StatusServiceUnavailable, "status of failed requests")
+	flag.Int64Var(&profile.Seed, "seed", 1, "seed of failures and jitter")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := datadogtest.NewHandler(profile)
+	srv := httptest.NewUnstartedServer(h)
+	srv.Listener.Close()
+	srv.Listener = l
+	if *plain {
+		srv.Start()
+	} else {
+		srv.StartTLS()
+	}
+	log.Printf("mock intake at %s", srv.URL)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log.Printf("%+v", h.Stats())
+		case <-signals:
+			srv.Close()
+			log.Printf("%+v", h.Stats())
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package collector exposes the counters of a hook as a prometheus.Collector.
+// It lives in its own package so only programs using it depend on the
+// Prometheus client.
+//
+//	prometheus.MustRegister(collector.New(hook, nil))
+package collector
+
+import (
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "datadog_hook"
+
+// Collector is the prometheus.Collector of a hook
+type Collector struct {
+	hook *datadog.Hook
+
+	fired, skipped, summarized, failed      *prometheus.Desc
+	pushed, delivered, dropped              *prometheus.Desc
+	batches, batchesDropped, bytes, retries *prometheus.Desc
+	attemptsFailed, queued, latency         *prometheus.Desc
+	incidents, sampling                     *prometheus.Desc
+	quotaLimit, quotaRemaining, lastError   *prometheus.Desc
+	serviceEntries, serviceBytes            *prometheus.Desc
+	sourceEntries, sourceBytes              *prometheus.Desc
+}
+
+// New - create collector of the hook, labels are added to every metric to
+// tell several hooks of a program apart
+func New(hook *datadog.Hook, labels prometheus.Labels) *Collector {
+	desc := func(name, help string, variable ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, variable, labels)
+	}
+	return &Collector{
+		hook:           hook,
+		fired:          desc("entries_fired_total", "Entries given to the hook."),
+		skipped:        desc("entries_skipped_total", "Entries not shipped because of the level, sampling or rules."),
+		summarized:     desc("entries_summarized_total", "Debug entries counted in summaries instead of shipped."),
+		failed:         desc("entries_failed_total", "Entries the hook failed to queue."),
+		pushed:         desc("entries_queued_total", "Entries queued for delivery."),
+		delivered:      desc("entries_delivered_total", "Entries of the batches delivered."),
+		dropped:        desc("entries_dropped_total", "Entries of the batches dropped."),
+		batches:        desc("batches_delivered_total", "Batches delivered."),
+		batchesDropped: desc("batches_dropped_total", "Batches dropped after retries."),
+		bytes:          desc("delivered_bytes_total", "Payload bytes delivered, before compression."),
+		retries:        desc("retries_total", "Delivery attempts made after a failed one."),
+		attemptsFailed: desc("attempts_failed_total", "Delivery attempts which failed, retried or not."),
+		queued:         desc("queue_entries", "Entries queued not yet delivered nor dropped."),
+		latency:        desc("delivery_latency_seconds", "Time batches took to be delivered, retries included."),
+		incidents:      desc("incidents_total", "Panics recovered while batching and sending."),
+		sampling:       desc("sampling_ratio", "Share of the entries shipped."),
+		quotaLimit:     desc("quota_limit", "Requests allowed in a period, as last reported by the intake."),
+		quotaRemaining: desc("quota_remaining", "Requests left in the current period, as last reported by the intake."),
+		lastError:      desc("last_error_timestamp_seconds", "When the hook last reported an error, as a Unix time."),
+		serviceEntries: desc("service_delivered_entries_total", "Entries delivered by service.", "service"),
+		serviceBytes:   desc("service_delivered_bytes_total", "Payload bytes delivered by service.", "service"),
+		sourceEntries:  desc("source_delivered_entries_total", "Entries delivered by source.", "source"),
+		sourceBytes:    desc("source_delivered_bytes_total", "Payload bytes delivered by source.", "source"),
+	}
+}
+
+// Describe - implement prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.fired, c.skipped, c.summarized, c.failed,
+		c.pushed, c.delivered, c.dropped,
+		c.batches, c.batchesDropped, c.bytes, c.retries,
+		c.attemptsFailed, c.queued, c.latency,
+		c.incidents, c.sampling,
+		c.quotaLimit, c.quotaRemaining, c.lastError,
+		c.serviceEntries, c.serviceBytes, c.sourceEntries, c.sourceBytes,
+	} {
+		ch <- d
+	}
+}
+
+// Collect - implement prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.hook.Stats()
+	counter := func(d *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v), labels...)
+	}
+	counter(c.fired, s.Fired)
+	counter(c.skipped, s.Skipped)
+	counter(c.summarized, s.Summarized)
+	counter(c.failed, s.Failed)
+	counter(c.pushed, s.Client.Pushed)
+	counter(c.delivered, s.Client.Delivered)
+	counter(c.dropped, s.Client.Dropped)
+	counter(c.batches, s.Client.Batches)
+	counter(c.batchesDropped, s.Client.BatchesDropped)
+	counter(c.bytes, s.Client.Bytes)
+	counter(c.retries, s.Client.Retries)
+	counter(c.attemptsFailed, s.Client.AttemptsFailed)
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(s.Client.Queued))
+	l := s.Client.Latency
+	ch <- prometheus.MustNewConstHistogram(c.latency, l.Count, l.Sum.Seconds(), l.Buckets)
+	counter(c.incidents, c.hook.Incidents())
+	ch <- prometheus.MustNewConstMetric(c.sampling, prometheus.GaugeValue, c.hook.Sampling())
+	if q := s.Client.Quota; q != nil {
+		ch <- prometheus.MustNewConstMetric(c.quotaLimit, prometheus.GaugeValue, float64(q.Limit))
+		ch <- prometheus.MustNewConstMetric(c.quotaRemaining, prometheus.GaugeValue, float64(q.Remaining))
+	}
+	if t := s.Client.LastError; !t.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.lastError, prometheus.GaugeValue, float64(t.UnixNano())/1e9)
+	}
+
+	b := c.hook.Breakdown()
+	for service, u := range b.Services {
+		counter(c.serviceEntries, u.Entries, service)
+		counter(c.serviceBytes, u.Bytes, service)
+	}
+	for source, u := range b.Sources {
+		counter(c.sourceEntries, u.Entries, source)
+		counter(c.sourceBytes, u.Bytes, source)
+	}
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func TestCollector(t *testing.T) {
+	hook := datadog.NewHook(datadog.DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, datadog.Options{
+		Service:  "api",
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	equals(t, nil, hook.Fire(&logrus.Entry{Message: "one", Level: logrus.InfoLevel}))
+	equals(t, nil, hook.Fire(&logrus.Entry{Message: "two", Level: logrus.DebugLevel}))
+	equals(t, nil, hook.Close(context.Background()))
+
+	c := New(hook, prometheus.Labels{"hook": "main"})
+	equals(t, nil, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP datadog_hook_entries_fired_total Entries given to the hook.
+# TYPE datadog_hook_entries_fired_total counter
+datadog_hook_entries_fired_total{hook="main"} 2
+# HELP datadog_hook_entries_skipped_total Entries not shipped because of the level, sampling or rules.
+# TYPE datadog_hook_entries_skipped_total counter
+datadog_hook_entries_skipped_total{hook="main"} 1
+# HELP datadog_hook_service_delivered_entries_total Entries delivered by service.
+# TYPE datadog_hook_service_delivered_entries_total counter
+datadog_hook_service_delivered_entries_total{hook="main",service="api"} 1
+`), "datadog_hook_entries_fired_total", "datadog_hook_entries_skipped_total", "datadog_hook_service_delivered_entries_total"))
+
+	problems, err := testutil.CollectAndLint(c)
+	equals(t, nil, err)
+	equals(t, 0, len(problems))
+}
+
+// frozenClock is a Clock stopped at a time, whose timers never fire
+type frozenClock struct{ now time.Time }
+
+func (c frozenClock) Now() time.Time                       { return c.now }
+func (c frozenClock) After(time.Duration) <-chan time.Time { return nil }
+
+func TestCollectorDelivery(t *testing.T) {
+	attempts := 0
+	hook := datadog.NewHook(datadog.DatadogUSHost, "key", 5*time.Second, 2, logrus.InfoLevel, &logrus.JSONFormatter{}, datadog.Options{
+		Clock: frozenClock{time.Now()},
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error {
+			if attempts++; attempts == 1 {
+				return errors.New("down")
+			}
+			return nil
+		}),
+	})
+	equals(t, nil, hook.Fire(&logrus.Entry{Message: "one", Level: logrus.InfoLevel}))
+	equals(t, nil, hook.Close(context.Background()))
+
+	c := New(hook, nil)
+	equals(t, nil, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP datadog_hook_attempts_failed_total Delivery attempts which failed, retried or not.
+# TYPE datadog_hook_attempts_failed_total counter
+datadog_hook_attempts_failed_total 1
+# HELP datadog_hook_queue_entries Entries queued not yet delivered nor dropped.
+# TYPE datadog_hook_queue_entries gauge
+datadog_hook_queue_entries 0
+# HELP datadog_hook_delivery_latency_seconds Time batches took to be delivered, retries included.
+# TYPE datadog_hook_delivery_latency_seconds histogram
+datadog_hook_delivery_latency_seconds_bucket{le="0.05"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="0.1"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="0.25"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="0.5"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="1"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="2.5"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="5"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="10"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="30"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="60"} 1
+datadog_hook_delivery_latency_seconds_bucket{le="+Inf"} 1
+datadog_hook_delivery_latency_seconds_sum 0
+datadog_hook_delivery_latency_seconds_count 1
+`), "datadog_hook_attempts_failed_total", "datadog_hook_queue_entries", "datadog_hook_delivery_latency_seconds"))
+}
@@ -0,0 +1,33 @@
+package datadog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestOnErrorContext(t *testing.T) {
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs []error
+	var ctxs []context.Context
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Exporter:       intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+		FileAttributes: map[string]string{"machine_id": filepath.Join(t.TempDir(), "missing")},
+		BaseContext:    base,
+		OnErrorContext: func(ctx context.Context, err error) {
+			ctxs = append(ctxs, ctx)
+			errs = append(errs, err)
+		},
+	})
+	equals(t, 1, len(errs))
+	equals(t, true, os.IsNotExist(errs[0]))
+	equals(t, hook.Context(), ctxs[0])
+	ok(t, hook.Close(context.Background()))
+	equals(t, context.Canceled, hook.Context().Err())
+}
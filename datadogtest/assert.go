@@ -0,0 +1,197 @@
+package datadogtest
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// DefaultWait - how long the assertions wait for the entries they expect,
+// twice the shortest interval between the batches of a hook, to set in
+// TestMain
+var DefaultWait = 10 * time.Second
+
+// pollInterval - how often the assertions look at the entries received
+const pollInterval = 10 * time.Millisecond
+
+// Entry is a line accepted by the mock with the stream it was sent with,
+// read from the query string of v1 requests and the reserved attributes of
+// the objects of v2 requests
+type Entry struct {
+	// Line - the line as received
+	Line string
+	// Message - the message of JSON lines, in "message" or else "msg", the
+	// line itself for plain text
+	Message  string
+	Service  string
+	Source   string
+	Hostname string
+	Tags     []string
+}
+
+// HasTag - whether the entry carries tag, such as "env:prod"
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// request - query of an accepted request and how many lines it held
+type request struct {
+	query url.Values
+	lines int
+}
+
+// Entries - entries accepted so far, in the order received
+func (h *Handler) Entries() []Entry {
+	h.m.Lock()
+	lines := append([]string(nil), h.lines...)
+	requests := append([]request(nil), h.requests...)
+	h.m.Unlock()
+	entries := make([]Entry, 0, len(lines))
+	for _, r := range requests {
+		for _, line := range lines[:r.lines] {
+			entries = append(entries, parseEntry(r.query, line))
+		}
+		lines = lines[r.lines:]
+	}
+	return entries
+}
+
+// WaitFor - the first entry matching, waiting up to timeout for it
+func (h *Handler) WaitFor(timeout time.Duration, match func(Entry) bool) (Entry, bool) {
+	var found Entry
+	ok := h.poll(timeout, func(entries []Entry) bool {
+		for _, e := range entries {
+			if match(e) {
+				found = e
+				return true
+			}
+		}
+		return false
+	})
+	return found, ok
+}
+
+// AssertContainsMessage - fail tb unless an entry whose message contains msg
+// arrives within DefaultWait
+func (h *Handler) AssertContainsMessage(tb testing.TB, msg string) Entry {
+	tb.Helper()
+	e, ok := h.WaitFor(DefaultWait, func(e Entry) bool { return strings.Contains(e.Message, msg) })
+	if !ok {
+		tb.Fatalf("datadogtest: no entry with a message containing %q among %d", msg, len(h.Entries()))
+	}
+	return e
+}
+
+// AssertTag - fail tb unless an entry tagged tag, such as "env:prod",
+// arrives within DefaultWait
+func (h *Handler) AssertTag(tb testing.TB, tag string) Entry {
+	tb.Helper()
+	e, ok := h.WaitFor(DefaultWait, func(e Entry) bool { return e.HasTag(tag) })
+	if !ok {
+		tb.Fatalf("datadogtest: no entry tagged %q among %d", tag, len(h.Entries()))
+	}
+	return e
+}
+
+// AssertServiceEquals - fail tb unless entries arrive within DefaultWait and
+// all of them are of service
+func (h *Handler) AssertServiceEquals(tb testing.TB, service string) {
+	tb.Helper()
+	if !h.poll(DefaultWait, func(entries []Entry) bool { return len(entries) > 0 }) {
+		tb.Fatalf("datadogtest: no entry of service %q received", service)
+	}
+	for _, e := range h.Entries() {
+		if e.Service != service {
+			tb.Fatalf("datadogtest: entry %q of service %q, not %q", e.Line, e.Service, service)
+		}
+	}
+}
+
+// AssertCount - fail tb unless exactly n entries were received once n of
+// them arrived or timeout passed, the entries arriving later than n are not
+// waited for
+func (h *Handler) AssertCount(tb testing.TB, n int, timeout time.Duration) {
+	tb.Helper()
+	h.poll(timeout, func(entries []Entry) bool { return len(entries) >= n })
+	if got := len(h.Entries()); got != n {
+		tb.Fatalf("datadogtest: %d entries received, not %d", got, n)
+	}
+}
+
+// AssertCountAtLeast - fail tb unless n entries or more arrive within
+// timeout
+func (h *Handler) AssertCountAtLeast(tb testing.TB, n int, timeout time.Duration) {
+	tb.Helper()
+	if !h.poll(timeout, func(entries []Entry) bool { return len(entries) >= n }) {
+		tb.Fatalf("datadogtest: %d entries received within %s, not at least %d", len(h.Entries()), timeout, n)
+	}
+}
+
+// poll - whether done holds for the entries received within timeout
+func (h *Handler) poll(timeout time.Duration, done func([]Entry) bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if done(h.Entries()) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// parseEntry - the entry of a line of a request with query
+func parseEntry(query url.Values, line string) Entry {
+	e := Entry{
+		Line:     line,
+		Message:  line,
+		Service:  query.Get("service"),
+		Source:   query.Get("ddsource"),
+		Hostname: query.Get("hostname"),
+		Tags:     splitTags(query.Get("ddtags")),
+	}
+	var fields map[string]interface{}
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &fields) != nil {
+		return e
+	}
+	str := func(key string) (string, bool) {
+		s, ok := fields[key].(string)
+		return s, ok
+	}
+	if msg, ok := str("message"); ok {
+		e.Message = msg
+	} else if msg, ok := str("msg"); ok {
+		e.Message = msg
+	} else {
+		e.Message = ""
+	}
+	if s, ok := str("service"); ok {
+		e.Service = s
+	}
+	if s, ok := str("ddsource"); ok {
+		e.Source = s
+	}
+	if s, ok := str("hostname"); ok {
+		e.Hostname = s
+	}
+	if s, ok := str("ddtags"); ok {
+		e.Tags = append(e.Tags, splitTags(s)...)
+	}
+	return e
+}
+
+// splitTags - the tags of a ddtags value
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
@@ -0,0 +1,112 @@
+package datadogtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeTB - a testing.TB recording its failures instead of failing
+type fakeTB struct {
+	testing.TB
+	failures []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.failures = append(tb.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	for _, protocol := range []datadog.Protocol{datadog.ProtocolV2HTTP, datadog.ProtocolV1HTTP} {
+		t.Run(protocol.String(), func(t *testing.T) {
+			s := NewServer(Profile{})
+			defer s.Close()
+			hook := datadog.New("key",
+				datadog.WithHost(s.Host()),
+				datadog.WithHTTPClient(s.Client()),
+				datadog.WithProtocol(protocol),
+				datadog.WithService("checkout"),
+				datadog.WithTags("env:prod"),
+				// sent as both entries are fired
+				datadog.WithOption(func(o *datadog.Options) { o.FlushPolicy = intake.MaxEntries(2) }),
+			)
+			defer hook.Close(context.Background())
+			for _, msg := range []string{"payment accepted", "payment failed"} {
+				equals(t, nil, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: msg, Data: logrus.Fields{}}))
+			}
+
+			// waited for as the batch is sent after the test asserts
+			e := s.AssertContainsMessage(t, "failed")
+			equals(t, "payment failed", e.Message)
+			equals(t, "checkout", e.Service)
+			equals(t, true, e.HasTag("env:prod"))
+			s.AssertTag(t, "env:prod")
+			s.AssertServiceEquals(t, "checkout")
+			s.AssertCount(t, 2, time.Second)
+			s.AssertCountAtLeast(t, 1, time.Second)
+		})
+	}
+}
+
+func TestAssertionFailures(t *testing.T) {
+	wait := DefaultWait
+	DefaultWait = 50 * time.Millisecond
+	defer func() { DefaultWait = wait }()
+	s := NewServer(Profile{})
+	defer s.Close()
+
+	tb := &fakeTB{}
+	s.AssertServiceEquals(tb, "checkout")
+	equals(t, []string{`datadogtest: no entry of service "checkout" received`}, tb.failures)
+
+	post(t, s, "application/json", []byte(`[{"msg":"one","service":"api","ddtags":"env:dev"},{"message":"two"}]`), false)
+	tb = &fakeTB{}
+	s.AssertContainsMessage(tb, "three")
+	s.AssertTag(tb, "env:prod")
+	s.AssertServiceEquals(tb, "api")
+	s.AssertCount(tb, 3, 50*time.Millisecond)
+	s.AssertCount(tb, 1, time.Second)
+	s.AssertCountAtLeast(tb, 3, 50*time.Millisecond)
+	equals(t, []string{
+		`datadogtest: no entry with a message containing "three" among 2`,
+		`datadogtest: no entry tagged "env:prod" among 2`,
+		`datadogtest: entry "{\"message\":\"two\"}" of service "", not "api"`,
+		"datadogtest: 2 entries received, not 3",
+		"datadogtest: 2 entries received, not 1",
+		"datadogtest: 2 entries received within 50ms, not at least 3",
+	}, tb.failures)
+}
+
+func TestEntries(t *testing.T) {
+	s := NewServer(Profile{})
+	defer s.Close()
+	req := `[{"msg":"one","service":"api","ddsource":"go","hostname":"web-1","ddtags":"team:core"},{"count":1}]`
+	post(t, s, "application/json", []byte(req), false)
+	post(t, s, "text/plain", []byte("two\n"), false)
+	equals(t, []Entry{
+		{Line: `{"msg":"one","service":"api","ddsource":"go","hostname":"web-1","ddtags":"team:core"}`, Message: "one", Service: "api", Source: "go", Hostname: "web-1", Tags: []string{"team:core"}},
+		{Line: `{"count":1}`},
+		{Line: "two", Message: "two"},
+	}, s.Entries())
+
+	e, found := s.WaitFor(time.Millisecond, func(e Entry) bool { return e.Hostname == "web-1" })
+	equals(t, true, found)
+	equals(t, "one", e.Message)
+	_, found = s.WaitFor(time.Millisecond, func(e Entry) bool { return e.Hostname == "web-2" })
+	equals(t, false, found)
+}
+
+func TestParseEntry(t *testing.T) {
+	// the stream of v1 requests is in the query string
+	query := map[string][]string{"service": {"api"}, "ddsource": {"go"}, "hostname": {"web-1"}, "ddtags": {"env:prod,team:core"}}
+	equals(t, Entry{Line: `{"msg":"one","ddtags":"v:1"}`, Message: "one", Service: "api", Source: "go", Hostname: "web-1", Tags: []string{"env:prod", "team:core", "v:1"}},
+		parseEntry(query, `{"msg":"one","ddtags":"v:1"}`))
+	equals(t, Entry{Line: "{not json", Message: "{not json"}, parseEntry(nil, "{not json"))
+}
@@ -0,0 +1,260 @@
+// Package datadogtest provides a mock Datadog HTTP intake with latency and
+// failure profiles, and a load runner reporting how a hook keeps up with it,
+// to validate tuning before rolling it out. Assertions on the entries the
+// mock received, waiting for them to arrive, keep tests of logging short.
+//
+//	srv := datadogtest.NewServer(datadogtest.Profile{Latency: 50 * time.Millisecond, FailureRate: 0.1})
+//	defer srv.Close()
+//	hook := datadog.NewHook(srv.Host(), "key", time.Second, 3, logrus.InfoLevel, &logrus.JSONFormatter{},
+//		datadog.Options{HTTPClient: srv.Client()})
+package datadogtest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SentField - field of JSON lines holding the UnixNano time the entry was
+// fired at, the mock measures the delivery latency of lines carrying it
+const SentField = "sent"
+
+// Profile shape how the mock intake answers
+type Profile struct {
+	// Latency - time taken to answer every request
+	Latency time.Duration
+	// Jitter - random latency added on top of Latency, up to Jitter
+	Jitter time.Duration
+	// FailureRate - share of requests failed, between 0 and 1
+	FailureRate float64
+	// Status - status of failed requests, 503 if 0
+	Status int
+	// Seed - seed of the failures and jitter, 1 if 0
+	Seed int64
+}
+
+// Stats count what the mock intake received
+type Stats struct {
+	// Requests - requests received
+	Requests int64
+	// Failed - requests answered with an error
+	Failed int64
+	// Lines - lines of the requests accepted
+	Lines int64
+	// Bytes - bytes of the requests accepted, after decompression
+	Bytes int64
+	// Latency - delivery latencies of the accepted lines carrying SentField
+	Latency Latency
+}
+
+// Latency summarize delivery latencies
+type Latency struct {
+	P50, P99, Max time.Duration
+}
+
+// StatsPath - path the mock answers its Stats at, as JSON
+const StatsPath = "/stats"
+
+// Handler is a mock intake accepting the v1 and v2 HTTP intake requests.
+// It is usable on its own to serve the mock outside of tests.
+type Handler struct {
+	profile Profile
+
+	m         sync.Mutex
+	rand      *rand.Rand
+	stats     Stats
+	lines     []string
+	requests  []request
+	latencies []time.Duration
+}
+
+// NewHandler - create mock intake answering with profile
+func NewHandler(profile Profile) *Handler {
+	if profile.Status == 0 {
+		profile.Status = http.StatusServiceUnavailable
+	}
+	if profile.Seed == 0 {
+		profile.Seed = 1
+	}
+	return &Handler{profile: profile, rand: rand.New(rand.NewSource(profile.Seed))}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == StatsPath {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Stats())
+		return
+	}
+	h.m.Lock()
+	h.stats.Requests++
+	fail := h.rand.Float64() < h.profile.FailureRate
+	delay := h.profile.Latency
+	if h.profile.Jitter > 0 {
+		delay += time.Duration(h.rand.Int63n(int64(h.profile.Jitter)))
+	}
+	h.m.Unlock()
+	time.Sleep(delay)
+
+	status := http.StatusAccepted
+	var lines []string
+	var size int
+	switch {
+	case r.Method != http.MethodPost:
+		status = http.StatusMethodNotAllowed
+	case r.Header.Get("DD-API-KEY") == "":
+		status = http.StatusForbidden
+	case fail:
+		status = h.profile.Status
+	default:
+		var err error
+		lines, size, err = readLines(r)
+		if err != nil {
+			status = http.StatusBadRequest
+		}
+	}
+
+	received := time.Now()
+	h.m.Lock()
+	if status >= 400 {
+		h.stats.Failed++
+	} else {
+		h.stats.Lines += int64(len(lines))
+		h.stats.Bytes += int64(size)
+		h.lines = append(h.lines, lines...)
+		h.requests = append(h.requests, request{query: r.URL.Query(), lines: len(lines)})
+		for _, line := range lines {
+			if sent, ok := sentAt(line); ok {
+				h.latencies = append(h.latencies, received.Sub(sent))
+			}
+		}
+	}
+	h.m.Unlock()
+	w.WriteHeader(status)
+}
+
+// Stats - snapshot of what was received so far
+func (h *Handler) Stats() Stats {
+	h.m.Lock()
+	defer h.m.Unlock()
+	stats := h.stats
+	stats.Latency = summarize(h.latencies)
+	return stats
+}
+
+// Lines - lines accepted so far, in the order received
+func (h *Handler) Lines() []string {
+	h.m.Lock()
+	defer h.m.Unlock()
+	return append([]string(nil), h.lines...)
+}
+
+// Server is a mock intake listening over TLS on a local port
+type Server struct {
+	*Handler
+	srv *httptest.Server
+}
+
+// NewServer - start mock intake answering with profile
+func NewServer(profile Profile) *Server {
+	h := NewHandler(profile)
+	return &Server{Handler: h, srv: httptest.NewTLSServer(h)}
+}
+
+// Host - host to give to the hook
+func (s *Server) Host() string {
+	return s.srv.Listener.Addr().String()
+}
+
+// Client - HTTP client trusting the certificate of the server
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Close - stop the server, waiting for the requests in flight
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// readLines - lines of a JSON array or a plain text body and its size
+func readLines(r *http.Request) ([]string, int, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		z, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer z.Close()
+		body = z
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, 0, err
+		}
+		lines := make([]string, len(raw))
+		for i, line := range raw {
+			lines[i] = string(line)
+		}
+		return lines, len(b), nil
+	}
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines, len(b), s.Err()
+}
+
+// sentAt - time the line was fired at, read from SentField
+func sentAt(line string) (time.Time, bool) {
+	if !strings.Contains(line, `"`+SentField+`"`) {
+		return time.Time{}, false
+	}
+	var fields map[string]interface{}
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+	if d.Decode(&fields) != nil {
+		return time.Time{}, false
+	}
+	var value string
+	switch v := fields[SentField].(type) {
+	case json.Number:
+		value = v.String()
+	case string:
+		value = v
+	}
+	ns, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// summarize - percentiles of the latencies
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Latency{P50: at(0.5), P99: at(0.99), Max: sorted[len(sorted)-1]}
+}
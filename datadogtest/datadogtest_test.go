@@ -0,0 +1,150 @@
+package datadogtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/sirupsen/logrus"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func post(t *testing.T, s *Server, contentType string, body []byte, gzipped bool) int {
+	req, _ := http.NewRequest("POST", "https://"+s.Host()+"/v1/input", bytes.NewReader(body))
+	req.Header.Set("DD-API-KEY", "key")
+	req.Header.Set("Content-Type", contentType)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(Profile{})
+	defer s.Close()
+
+	sent := strconv.FormatInt(time.Now().UnixNano(), 10)
+	equals(t, http.StatusAccepted, post(t, s, "application/json", []byte(`[{"msg":"one"},{"sent":`+sent+`}]`), false))
+	equals(t, http.StatusAccepted, post(t, s, "text/plain", []byte("two\nthree\n"), false))
+	var z bytes.Buffer
+	w := gzip.NewWriter(&z)
+	w.Write([]byte(`[{"msg":"four"}]`))
+	w.Close()
+	equals(t, http.StatusAccepted, post(t, s, "application/json", z.Bytes(), true))
+	equals(t, http.StatusBadRequest, post(t, s, "application/json", []byte(`[`), false))
+
+	equals(t, []string{`{"msg":"one"}`, `{"sent":` + sent + `}`, "two", "three", `{"msg":"four"}`}, s.Lines())
+	stats := s.Stats()
+	equals(t, int64(4), stats.Requests)
+	equals(t, int64(1), stats.Failed)
+	equals(t, int64(5), stats.Lines)
+	equals(t, true, stats.Latency.Max > 0)
+
+	resp, err := s.Client().Get("https://" + s.Host() + StatsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var served Stats
+	json.NewDecoder(resp.Body).Decode(&served)
+	equals(t, stats, served)
+}
+
+func TestProfile(t *testing.T) {
+	s := NewServer(Profile{FailureRate: 1, Status: http.StatusTooManyRequests})
+	defer s.Close()
+	equals(t, http.StatusTooManyRequests, post(t, s, "text/plain", []byte("one\n"), false))
+
+	s = NewServer(Profile{Latency: 50 * time.Millisecond})
+	defer s.Close()
+	start := time.Now()
+	post(t, s, "text/plain", []byte("one\n"), false)
+	equals(t, true, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestRun(t *testing.T) {
+	s := NewServer(Profile{})
+	defer s.Close()
+	hook := datadog.NewHook(s.Host(), "key", 50*time.Millisecond, 0, logrus.InfoLevel, &logrus.JSONFormatter{},
+		datadog.Options{HTTPClient: s.Client()})
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(hook)
+
+	result := Run(logger, Load{Rate: 200, Duration: 200 * time.Millisecond, Workers: 2, Size: 10})
+	equals(t, nil, hook.Close(context.Background()))
+	equals(t, true, result.Fired > 0 && result.Fired <= 50)
+	report := Report{Result: result, Client: hook.Stats().Client, Server: s.Stats()}
+	equals(t, result.Fired, report.Server.Lines)
+	equals(t, true, report.Server.Latency.P50 > 0)
+	equals(t, true, strings.Contains(report.String(), "dropped=0 pending=0"))
+}
+
+// recorder - hook keeping the entries fired
+type recorder struct {
+	m       sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (r *recorder) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (r *recorder) Fire(e *logrus.Entry) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func TestRunShapes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	rec := &recorder{}
+	logger.AddHook(rec)
+	result := Run(logger, Load{
+		Rate:      100,
+		Duration:  100 * time.Millisecond,
+		Size:      5,
+		MaxSize:   20,
+		Fields:    3,
+		Levels:    []logrus.Level{logrus.WarnLevel, logrus.DebugLevel},
+		ErrorRate: 1,
+	})
+	rec.m.Lock()
+	defer rec.m.Unlock()
+	equals(t, result.Fired, int64(len(rec.entries)))
+	equals(t, true, len(rec.entries) >= 2)
+	for i, e := range rec.entries {
+		equals(t, []logrus.Level{logrus.WarnLevel, logrus.DebugLevel}[i%2], e.Level)
+		equals(t, 2, e.Data["field_2"])
+		equals(t, errLoad, e.Data[logrus.ErrorKey])
+		n := len(e.Message) - len("load ")
+		equals(t, true, n >= 5 && n <= 20)
+	}
+}
@@ -0,0 +1,131 @@
+package datadogtest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// errLoad - error of the entries fired with an error field
+var errLoad = errors.New("load error")
+
+// Load describe the entries fired by Run
+type Load struct {
+	// Rate - entries fired per second, as fast as possible if 0
+	Rate int
+	// Duration - time spent firing
+	Duration time.Duration
+	// Workers - goroutines firing, 1 if 0
+	Workers int
+	// Size - bytes of padding in every message
+	Size int
+	// MaxSize - when above Size, the padding of a message is drawn between
+	// Size and MaxSize bytes
+	MaxSize int
+	// Fields - extra fields of every entry, field_0 to field_N-1
+	Fields int
+	// Levels - levels entries are fired at in turn, info if empty. Fatal and
+	// panic entries are logged without exiting nor panicking.
+	Levels []logrus.Level
+	// ErrorRate - share of the entries carrying an error field
+	ErrorRate float64
+	// Seed - seed of the sizes and errors drawn, 1 if 0
+	Seed int64
+}
+
+// Result tell what Run did
+type Result struct {
+	// Fired - entries logged
+	Fired int64
+	// Elapsed - time spent firing
+	Elapsed time.Duration
+}
+
+// Run - log entries with logger at the rate of load, each carrying
+// SentField for the mock to measure the delivery latency
+func Run(logger *logrus.Logger, load Load) Result {
+	workers := load.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	var interval time.Duration
+	if load.Rate > 0 {
+		interval = time.Duration(int64(time.Second) * int64(workers) / int64(load.Rate))
+	}
+	size := load.Size
+	if load.MaxSize > size {
+		size = load.MaxSize
+	}
+	padding := make([]byte, size)
+	for i := range padding {
+		padding[i] = 'x'
+	}
+	levels := load.Levels
+	if len(levels) == 0 {
+		levels = []logrus.Level{logrus.InfoLevel}
+	}
+	seed := load.Seed
+	if seed == 0 {
+		seed = 1
+	}
+
+	var fired int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(load.Duration)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed + int64(w)))
+			next := time.Now()
+			for i := 0; time.Now().Before(deadline); i++ {
+				fields := make(logrus.Fields, load.Fields+4)
+				for f := 0; f < load.Fields; f++ {
+					fields["field_"+strconv.Itoa(f)] = f
+				}
+				if load.ErrorRate > 0 && r.Float64() < load.ErrorRate {
+					fields[logrus.ErrorKey] = errLoad
+				}
+				fields[SentField], fields["worker"], fields["seq"] = time.Now().UnixNano(), w, i
+				n := load.Size
+				if load.MaxSize > load.Size {
+					n += r.Intn(load.MaxSize - load.Size + 1)
+				}
+				logger.WithFields(fields).Log(levels[i%len(levels)], "load ", string(padding[:n]))
+				atomic.AddInt64(&fired, 1)
+				if interval > 0 {
+					next = next.Add(interval)
+					time.Sleep(time.Until(next))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return Result{Fired: fired, Elapsed: time.Since(start)}
+}
+
+// Report put together what was fired, what the client did and what the mock
+// intake received
+type Report struct {
+	Result
+	Client intake.Stats
+	Server Stats
+}
+
+func (r Report) String() string {
+	rate := float64(r.Fired) / r.Elapsed.Seconds()
+	return fmt.Sprintf(
+		"fired=%d (%.0f/s) delivered=%d dropped=%d pending=%d batches=%d retries=%d requests=%d failed=%d received=%d latency p50=%s p99=%s max=%s",
+		r.Fired, rate, r.Client.Delivered, r.Client.Dropped, r.Fired-r.Client.Delivered-r.Client.Dropped,
+		r.Client.Batches, r.Client.Retries, r.Server.Requests, r.Server.Failed, r.Server.Lines,
+		r.Server.Latency.P50, r.Server.Latency.P99, r.Server.Latency.Max,
+	)
+}
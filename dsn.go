@@ -0,0 +1,184 @@
+package datadog
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// DSNScheme - scheme of the connection strings of ParseDSN
+const DSNScheme = "datadog"
+
+// ErrDSNCredentials - the DSN carries credentials, the API key must come from
+// DATADOG_APIKEY or the argument of New
+var ErrDSNCredentials = errors.New("datadog: DSN must not embed credentials, set DATADOG_APIKEY or pass the API key to New")
+
+// ParseDSN - the API key and options of a connection string such as
+// datadog://us5?service=checkout&env=prod&batch=10s&compress=gzip, for tools
+// passing the configuration of their sink as a single flag or variable.
+//
+// The host is a Datadog site, "us", "us3", "us5", "eu", "ap1", "ap2" or
+// "gov", or the address of an intake, DatadogUSHost if empty. The API key is
+// the DATADOG_APIKEY environment variable, New takes an explicit one with the
+// options: a DSN with a user, such as datadog://KEY@eu, is refused with
+// ErrDSNCredentials, as API keys are never accepted in URLs.
+// The query sets service, source, hostname, env, version, tags (comma
+// separated), level, batch (interval, raised to 5s if shorter), retry,
+// compress (gzip or none), timeout (of a request), protocol (v2-http,
+// v1-http, tcp or agent), sampling and sample_by. Unknown parameters are errors so typos don't go
+// unnoticed.
+func ParseDSN(dsn string) (string, []Option, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		// the error quotes the DSN, API key included
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return "", nil, fmt.Errorf("datadog: invalid DSN: %w", err)
+	}
+	if u.Scheme != DSNScheme {
+		return "", nil, fmt.Errorf("datadog: DSN scheme %q is not %q", u.Scheme, DSNScheme)
+	}
+	if u.User != nil {
+		return "", nil, ErrDSNCredentials
+	}
+	apiKey := os.Getenv("DATADOG_APIKEY")
+
+	var opts []Option
+	protocol := intake.ProtocolV2HTTP
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	// tags in a stable order
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := query[key]
+		value := values[len(values)-1]
+		switch key {
+		case "service":
+			opts = append(opts, WithService(value))
+		case "source":
+			opts = append(opts, WithSource(value))
+		case "hostname":
+			opts = append(opts, WithHostname(value))
+		case "env", "version":
+			opts = append(opts, WithTags(key+":"+value))
+		case "tags":
+			for _, v := range values {
+				opts = append(opts, WithTags(strings.Split(v, ",")...))
+			}
+		case "level":
+			level, err := logrus.ParseLevel(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("datadog: DSN level: %w", err)
+			}
+			opts = append(opts, WithLevel(level))
+		case "batch", "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return "", nil, fmt.Errorf("datadog: DSN %s %q is not a positive duration", key, value)
+			}
+			if key == "batch" {
+				opts = append(opts, WithBatchTimeout(d))
+			} else {
+				opts = append(opts, WithRequestTimeout(d))
+			}
+		case "retry":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return "", nil, fmt.Errorf("datadog: DSN retry %q is not a count", value)
+			}
+			opts = append(opts, WithMaxRetry(n))
+		case "compress":
+			switch value {
+			case "gzip", "true":
+				opts = append(opts, WithCompression())
+			case "none", "false":
+				opts = append(opts, WithOption(func(o *Options) { o.Compress = false }))
+			default:
+				return "", nil, fmt.Errorf("datadog: DSN compress %q is not gzip or none", value)
+			}
+		case "protocol":
+			p, ok := parseProtocol(value)
+			if !ok {
+				return "", nil, fmt.Errorf("datadog: DSN protocol %q is not v2-http, v1-http, tcp or agent", value)
+			}
+			protocol = p
+		case "sampling":
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return "", nil, fmt.Errorf("datadog: DSN sampling %q is not between 0 and 1", value)
+			}
+			opts = append(opts, WithOption(func(o *Options) { o.Sampling = rate }))
+		case "sample_by":
+			opts = append(opts, WithOption(func(o *Options) { o.SampleBy = value }))
+		default:
+			return "", nil, fmt.Errorf("datadog: unknown DSN parameter %q", key)
+		}
+	}
+
+	host, err := dsnHost(u.Host, protocol)
+	if err != nil {
+		return "", nil, err
+	}
+	if protocol == intake.ProtocolAgent {
+		opts = append(opts, WithOption(func(o *Options) { o.AgentAddr = host }))
+	} else if host != "" {
+		opts = append(opts, WithHost(host))
+	}
+	return apiKey, append(opts, WithProtocol(protocol)), nil
+}
+
+// NewFromDSN - create hook configured by the connection string dsn as told
+// by ParseDSN, then by opts
+func NewFromDSN(dsn string, opts ...Option) (*Hook, error) {
+	apiKey, dsnOpts, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return New(apiKey, append(dsnOpts, opts...)...), nil
+}
+
+// dsnHost - the intake of the host of a DSN, a site or an address
+func dsnHost(host string, protocol Protocol) (string, error) {
+	if host == "" && protocol == intake.ProtocolTCP {
+		host = "us"
+	}
+	if host == "" || host == "localhost" || strings.ContainsAny(host, ".:") || protocol == intake.ProtocolAgent {
+		return host, nil
+	}
+	if protocol == intake.ProtocolTCP {
+		switch strings.ToLower(host) {
+		case "us":
+			return DatadogUSTCPHost, nil
+		case "eu":
+			return DatadogEUTCPHost, nil
+		}
+		return "", fmt.Errorf("datadog: no TCP intake known for the site %q of the DSN", host)
+	}
+	if h, ok := intake.SiteHost(host); ok {
+		return h, nil
+	}
+	return "", fmt.Errorf("datadog: DSN host %q is not a Datadog site", host)
+}
+
+// parseProtocol - the Protocol named as by its String
+func parseProtocol(name string) (Protocol, bool) {
+	for _, p := range []Protocol{intake.ProtocolV2HTTP, intake.ProtocolV1HTTP, intake.ProtocolTCP, intake.ProtocolAgent} {
+		if p.String() == name {
+			return p, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,96 @@
+package datadog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// parseDSN - the config New creates the hook of dsn with
+func parseDSN(t *testing.T, dsn string) (string, config) {
+	apiKey, opts, err := ParseDSN(dsn)
+	ok(t, err)
+	c := config{host: DatadogUSHost, batchTimeout: DefaultBatchTimeout, maxRetry: DefaultMaxRetry, minLevel: logrus.InfoLevel}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return apiKey, c
+}
+
+func TestParseDSN(t *testing.T) {
+	t.Setenv("DATADOG_APIKEY", "env-key")
+	apiKey, c := parseDSN(t, "datadog://us5?service=checkout&env=prod&version=1.2&tags=team:payments,tier:1&batch=2s&compress=gzip&level=debug&retry=5&timeout=10s&sampling=0.1&sample_by=request_id")
+	equals(t, "env-key", apiKey)
+	equals(t, "http-intake.logs.us5.datadoghq.com", c.host)
+	equals(t, "checkout", c.options.Service)
+	equals(t, []string{"env:prod", "team:payments", "tier:1", "version:1.2"}, c.options.Tags)
+	equals(t, 2*time.Second, c.batchTimeout)
+	equals(t, true, c.options.Compress)
+	equals(t, logrus.DebugLevel, c.minLevel)
+	equals(t, 5, c.maxRetry)
+	equals(t, 10*time.Second, c.options.RequestTimeout)
+	equals(t, 0.1, c.options.Sampling)
+	equals(t, "request_id", c.options.SampleBy)
+	equals(t, intake.ProtocolV2HTTP, c.options.Protocol)
+
+	apiKey, c = parseDSN(t, "datadog://relay.internal:8080?protocol=v1-http&compress=none&source=go&hostname=web-1")
+	equals(t, "env-key", apiKey)
+	equals(t, "relay.internal:8080", c.host)
+	equals(t, intake.ProtocolV1HTTP, c.options.Protocol)
+	equals(t, false, c.options.Compress)
+	equals(t, "go", c.options.Source)
+	equals(t, "web-1", c.options.Hostname)
+
+	_, c = parseDSN(t, "datadog://")
+	equals(t, DatadogUSHost, c.host)
+	_, c = parseDSN(t, "datadog://eu")
+	equals(t, DatadogEUHost, c.host)
+	_, c = parseDSN(t, "datadog://?protocol=tcp")
+	equals(t, DatadogUSTCPHost, c.host)
+	_, c = parseDSN(t, "datadog://eu?protocol=tcp")
+	equals(t, DatadogEUTCPHost, c.host)
+	_, c = parseDSN(t, "datadog://localhost:10518?protocol=agent")
+	equals(t, DatadogUSHost, c.host)
+	equals(t, "localhost:10518", c.options.AgentAddr)
+	equals(t, intake.ProtocolAgent, c.options.Protocol)
+}
+
+func TestParseDSNErrors(t *testing.T) {
+	for _, dsn := range []string{
+		"http://us5",
+		"datadog://mars",
+		"datadog://us5?protocol=tcp",
+		"datadog://us?batch=soon",
+		"datadog://us?batch=-1s",
+		"datadog://us?retry=-1",
+		"datadog://us?level=loud",
+		"datadog://us?compress=zstd",
+		"datadog://us?protocol=udp",
+		"datadog://us?sampling=2",
+		"datadog://us?servce=checkout",
+	} {
+		_, _, err := ParseDSN(dsn)
+		assert(t, err != nil, "%s should be refused", dsn)
+	}
+	// the API key is not in the error
+	_, _, err := ParseDSN("datadog://secret-key@us:port")
+	assert(t, err != nil && !strings.Contains(err.Error(), "secret-key"), "unexpected error %v", err)
+	// nor ever taken from the URL
+	for _, dsn := range []string{"datadog://secret-key@eu", "datadog://:secret-key@eu", "datadog://@eu"} {
+		_, _, err = ParseDSN(dsn)
+		equals(t, ErrDSNCredentials, err)
+	}
+}
+
+func TestNewFromDSN(t *testing.T) {
+	hook, err := NewFromDSN("datadog://localhost:1?service=checkout&batch=1h", WithService("override"))
+	ok(t, err)
+	defer hook.Close(context.Background())
+	equals(t, "override", hook.options.Service)
+	_, err = NewFromDSN("datadog://mars")
+	assert(t, err != nil, "unknown site should be refused")
+}
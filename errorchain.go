@@ -0,0 +1,92 @@
+package datadog
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxErrorCauses - most causes of an error listed, deeper ones are left out
+const maxErrorCauses = 16
+
+// ErrorCause - an error wrapped by the error of an entry, see
+// Options.ErrorChains
+type ErrorCause struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// errorChain - copy of the entry with the error of logrus.ErrorKey as the
+// error attributes of Datadog: the message, the kind of the root cause so
+// Error Tracking groups errors by what failed rather than by their
+// wrappers, and the causes the error wraps. The entry itself without error.
+func errorChain(entry *logrus.Entry) *logrus.Entry {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok || err == nil {
+		return entry
+	}
+	attributes := map[string]interface{}{
+		"message": err.Error(),
+		"kind":    errorKind(rootCause(err)),
+	}
+	if causes := errorCauses(err); len(causes) > 0 {
+		attributes["causes"] = causes
+	}
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[logrus.ErrorKey] = attributes
+	return &e
+}
+
+// unwrap - the errors err wraps, with errors.Join and fmt.Errorf with
+// several %w as well
+func unwrap(err error) []error {
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := u.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	case interface{ Unwrap() []error }:
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// rootCause - the innermost error of the first chain of err
+func rootCause(err error) error {
+	for i := 0; i < maxErrorCauses; i++ {
+		causes := unwrap(err)
+		if len(causes) == 0 || causes[0] == nil {
+			break
+		}
+		err = causes[0]
+	}
+	return err
+}
+
+// errorCauses - the errors err wraps, depth first
+func errorCauses(err error) []ErrorCause {
+	var causes []ErrorCause
+	stack := unwrap(err)
+	for len(stack) > 0 && len(causes) < maxErrorCauses {
+		cause := stack[0]
+		stack = stack[1:]
+		if cause == nil {
+			continue
+		}
+		causes = append(causes, ErrorCause{Kind: errorKind(cause), Message: cause.Error()})
+		stack = append(unwrap(cause), stack...)
+	}
+	return causes
+}
+
+// errorKind - the type of err, as Datadog expects in error.kind
+func errorKind(err error) string {
+	if scrubbed, ok := err.(scrubbedError); ok {
+		err = scrubbed.err
+	}
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,108 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// loopError wraps itself forever
+type loopError struct{}
+
+func (loopError) Error() string { return "loop" }
+
+func (e loopError) Unwrap() error { return e }
+
+func TestErrorChain(t *testing.T) {
+	_, errOpen := os.Open("/does/not/exist")
+	err := fmt.Errorf("load config: %w", fmt.Errorf("read: %w", errOpen))
+	entry := &logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: err, "user": 42}}
+	e := errorChain(entry)
+	equals(t, map[string]interface{}{
+		"message": err.Error(),
+		"kind":    "syscall.Errno",
+		"causes": []ErrorCause{
+			{Kind: "*fmt.wrapError", Message: "read: " + errOpen.Error()},
+			{Kind: "*fs.PathError", Message: errOpen.Error()},
+			{Kind: "syscall.Errno", Message: "no such file or directory"},
+		},
+	}, e.Data[logrus.ErrorKey])
+	equals(t, 42, e.Data["user"])
+	// the entry fired is left alone
+	equals(t, err, entry.Data[logrus.ErrorKey])
+
+	var pathErr *fs.PathError
+	equals(t, true, errors.As(err, &pathErr))
+	equals(t, "*fs.PathError", errorKind(pathErr))
+}
+
+func TestErrorChainJoined(t *testing.T) {
+	errA, errB := errors.New("a"), fmt.Errorf("b: %w", context.Canceled)
+	err := fmt.Errorf("both: %w", errors.Join(errA, errB))
+	attributes := errorChain(&logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: err}}).Data[logrus.ErrorKey].(map[string]interface{})
+	equals(t, "*errors.errorString", attributes["kind"])
+	equals(t, []ErrorCause{
+		{Kind: "*errors.joinError", Message: "a\nb: context canceled"},
+		{Kind: "*errors.errorString", Message: "a"},
+		{Kind: "*fmt.wrapError", Message: "b: context canceled"},
+		{Kind: "*errors.errorString", Message: "context canceled"},
+	}, attributes["causes"])
+}
+
+func TestErrorChainEdges(t *testing.T) {
+	// no error, or not an error
+	for _, data := range []logrus.Fields{{}, {logrus.ErrorKey: "failed"}, {logrus.ErrorKey: nil}} {
+		entry := &logrus.Entry{Data: data}
+		equals(t, entry, errorChain(entry))
+	}
+	// errors without causes have none
+	attributes := errorChain(&logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: errors.New("plain")}}).Data[logrus.ErrorKey]
+	equals(t, map[string]interface{}{"message": "plain", "kind": "*errors.errorString"}, attributes)
+	// endless chains are cut
+	attributes = errorChain(&logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: loopError{}}}).Data[logrus.ErrorKey]
+	equals(t, maxErrorCauses, len(attributes.(map[string]interface{})["causes"].([]ErrorCause)))
+}
+
+func TestErrorChains(t *testing.T) {
+	var mu sync.Mutex
+	var lines [][]byte
+	hook := New("key", WithBatchTimeout(time.Hour), WithErrorChains(), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, b.Lines...)
+		return nil
+	})))
+	err := fmt.Errorf("charge: %w", context.DeadlineExceeded)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: "payment failed", Data: logrus.Fields{logrus.ErrorKey: err}}))
+	ok(t, hook.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	equals(t, 1, len(lines))
+	var entry struct {
+		Error struct {
+			Message string       `json:"message"`
+			Kind    string       `json:"kind"`
+			Causes  []ErrorCause `json:"causes"`
+		} `json:"error"`
+	}
+	ok(t, json.Unmarshal(lines[0], &entry))
+	equals(t, "charge: context deadline exceeded", entry.Error.Message)
+	equals(t, "context.deadlineExceededError", entry.Error.Kind)
+	equals(t, []ErrorCause{{Kind: "context.deadlineExceededError", Message: "context deadline exceeded"}}, entry.Error.Causes)
+}
+
+func TestWithErrorChains(t *testing.T) {
+	c := config{}
+	WithErrorChains()(&c)
+	equals(t, true, c.options.ErrorChains)
+}
@@ -0,0 +1,45 @@
+// Command basic ships a few entries to an in-process mock intake with the
+// functional options of datadog.New and prints what the intake received.
+// Point WithHost at a Datadog intake and pass a real API key to ship them.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	srv := datadogtest.NewServer(datadogtest.Profile{})
+	defer srv.Close()
+
+	hook := datadog.New("api-key",
+		datadog.WithHost(srv.Host()),
+		datadog.WithHTTPClient(srv.Client()),
+		datadog.WithBatchTimeout(time.Second),
+		datadog.WithService("checkout"),
+		datadog.WithSource("go"),
+		datadog.WithTags("env:example"),
+	)
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddHook(hook)
+
+	l.WithField("order", 42).Info("order placed")
+	l.WithField("order", 42).Warn("payment retried")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		log.Fatal(err)
+	}
+	for _, line := range srv.Lines() {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,58 @@
+// Command shutdown shows how a program makes sure its last entries are
+// delivered: Flush before a checkpoint, then Shutdown with a deadline, telling
+// from its error and report whether entries were lost. The in-process mock
+// intake fails half of the requests.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	srv := datadogtest.NewServer(datadogtest.Profile{FailureRate: 0.5})
+	defer srv.Close()
+
+	hook := datadog.New("api-key",
+		datadog.WithHost(srv.Host()),
+		datadog.WithHTTPClient(srv.Client()),
+		datadog.WithMaxRetry(1),
+		datadog.WithService("worker"),
+		datadog.WithOption(func(o *datadog.Options) {
+			// entries of the batches dropped are kept locally
+			o.Fallback = os.Stderr
+			o.OnDrainProgress = func(p datadog.DrainProgress) {
+				log.Printf("draining: %.0f%%", p.Percent())
+			}
+		}),
+	)
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddHook(hook)
+
+	for i := 0; i < 10; i++ {
+		l.WithField("job", i).Info("job done")
+	}
+	if err := hook.Flush(); errors.Is(err, intake.ErrNotDelivered) {
+		log.Printf("checkpoint: %v", err)
+	}
+
+	l.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	report, err := hook.Shutdown(ctx)
+	if err != nil {
+		log.Printf("close: %v", err)
+	}
+	fmt.Println(report)
+}
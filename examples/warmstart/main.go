@@ -0,0 +1,50 @@
+// Command warmstart logs while its configuration loads, before the hook
+// can be created, and replays those entries into the hook once it exists.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/sirupsen/logrus"
+)
+
+// loadSecrets - stands for reading the API key from a secret store
+func loadSecrets(l *logrus.Logger) string {
+	l.Info("loading secrets")
+	l.WithField("store", "vault").Info("secrets loaded")
+	return "api-key"
+}
+
+func main() {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	warm := datadog.NewWarmStart(100)
+	l.AddHook(warm)
+
+	apiKey := loadSecrets(l)
+
+	srv := datadogtest.NewServer(datadogtest.Profile{})
+	defer srv.Close()
+	hook := datadog.New(apiKey, datadog.WithHost(srv.Host()), datadog.WithHTTPClient(srv.Client()))
+	replayed, err := warm.Attach(hook)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l.Info("started")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("replayed %d entries, the intake received:\n", replayed)
+	for _, line := range srv.Lines() {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,98 @@
+// Package elasticsearch delivers batches to the Elasticsearch bulk API, so the
+// same hook and batching can feed Elasticsearch, e.g. while migrating from or
+// to Datadog.
+//
+//	hook := datadog.NewHook("", "", 5*time.Second, 3, logrus.InfoLevel, &logrus.JSONFormatter{}, datadog.Options{
+//		Exporter: elasticsearch.New("http://localhost:9200", "logs"),
+//	})
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+// Exporter is the intake.Exporter indexing every line as a document
+type Exporter struct {
+	// URL - base URL of the cluster
+	URL string
+	// Index - index the documents land in
+	Index string
+	// Header - added to every request, e.g. Authorization
+	Header http.Header
+	// Client - http.DefaultClient if nil
+	Client *http.Client
+}
+
+// ErrBulk - some documents of the batch were rejected
+var ErrBulk = errors.New("elasticsearch: bulk request has errors")
+
+// New - create exporter indexing into index of the cluster at url
+func New(url, index string) *Exporter {
+	return &Exporter{URL: url, Index: index}
+}
+
+// Export - index the lines of the batch with a single bulk request, plain
+// text lines are indexed as the message of a document
+func (e *Exporter) Export(b *intake.Batch) error {
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": e.Index}})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, line := range b.Lines {
+		buf.Write(action)
+		buf.WriteByte('\n')
+		if b.JSON {
+			buf.Write(line)
+		} else {
+			doc, err := json.Marshal(map[string]string{"message": string(line)})
+			if err != nil {
+				return err
+			}
+			buf.Write(doc)
+		}
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(e.URL, "/")+"/_bulk", &buf)
+	if err != nil {
+		return err
+	}
+	for k, v := range e.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("elasticsearch: %s", resp.Status)
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	if result.Errors {
+		return ErrBulk
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package elasticsearch
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func TestExport(t *testing.T) {
+	var body, path, contentType, auth string
+	response := `{"errors":false}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, path = string(b), r.URL.Path
+		contentType, auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	e := New(srv.URL+"/", "logs")
+	e.Header = http.Header{"Authorization": {"ApiKey secret"}}
+	equals(t, nil, e.Export(&intake.Batch{JSON: true, Lines: [][]byte{[]byte(`{"msg":"one"}`), []byte(`{"msg":"two"}`)}}))
+	equals(t, "/_bulk", path)
+	equals(t, "application/x-ndjson", contentType)
+	equals(t, "ApiKey secret", auth)
+	equals(t, "{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"one\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"two\"}\n", body)
+
+	equals(t, nil, e.Export(&intake.Batch{Lines: [][]byte{[]byte(`say "hi"`)}}))
+	equals(t, "{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"say \\\"hi\\\"\"}\n", body)
+
+	response = `{"errors":true}`
+	equals(t, ErrBulk, e.Export(&intake.Batch{Lines: [][]byte{[]byte("one")}}))
+}
+
+func TestExportStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := New(srv.URL, "logs").Export(&intake.Batch{Lines: [][]byte{[]byte("one")}})
+	equals(t, "elasticsearch: 401 Unauthorized", err.Error())
+}
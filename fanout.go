@@ -0,0 +1,125 @@
+package datadog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+// Destination is a backend entries are fanned out to besides the one of the
+// hook, such as an internal relay. Every destination has a client of its
+// own: its queue, batching, compression, retries and circuit breaker, so a
+// slow one doesn't delay the others.
+type Destination struct {
+	// Name - key of the destination in Stats().Destinations
+	Name string
+	// Host, Protocol - where batches are delivered, as for the hook
+	Host     string
+	Protocol Protocol
+	// APIKey - the API key of the hook if empty and Host is a Datadog site,
+	// so a relay never gets the key of the hook
+	APIKey string
+	// Exporter - delivers the batches instead of Host, as Options.Exporter
+	Exporter Exporter
+	// MaxRetry - attempts at a batch, the first one included, as for the
+	// hook: the hook's if 0, a single one if below 0
+	MaxRetry int
+	// CircuitFailures, CircuitCooldown - circuit breaker of the
+	// destination, see Options.CircuitFailures
+	CircuitFailures int
+	CircuitCooldown time.Duration
+	// QueueSize, QueueBytes - capacity of the queue of the destination
+	QueueSize  int
+	QueueBytes int64
+	// Overflow - what to do once the queue is full, OverflowDropNewest
+	// unless OverflowDropOldest: a destination never blocks Fire
+	Overflow Overflow
+}
+
+// destination - the client entries are fanned out to for a Destination
+type destination struct {
+	name   string
+	client *intake.Client
+}
+
+// newDestination - client of d, created with the config of the hook's
+// client but for where and how its batches are delivered. The spool,
+// sequence and fallback of the hook stay the hook's own, while its dry run
+// and allowed sites hold for d too: a destination outside of the allowed
+// sites is refused.
+func newDestination(base intake.Config, d Destination) (*destination, error) {
+	config := base
+	config.Host, config.Protocol, config.Exporter = d.Host, d.Protocol, d.Exporter
+	config.APIKey = d.APIKey
+	if config.APIKey == "" && d.Exporter == nil && intake.Site(d.Host) != "" {
+		config.APIKey = base.APIKey
+	}
+	if d.MaxRetry != 0 {
+		config.MaxRetry = d.MaxRetry
+	}
+	config.CircuitFailures, config.CircuitCooldown = d.CircuitFailures, d.CircuitCooldown
+	config.QueueSize, config.QueueBytes = d.QueueSize, d.QueueBytes
+	config.Overflow = d.Overflow
+	if config.Overflow != intake.OverflowDropOldest {
+		config.Overflow = intake.OverflowDropNewest
+	}
+	config.SpoolDir, config.Sequence, config.SequenceFile = "", false, ""
+	config.Fallback, config.ErrorHandler = nil, nil
+	config.OnDrainProgress, config.Trainer, config.Dictionary = nil, nil, nil
+	config.DetectAgent = false
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("destination %s: %w", d.Name, err)
+	}
+	return &destination{name: d.Name, client: intake.New(config)}, nil
+}
+
+// fanOut - queue the entry to every destination, never waiting
+func (h *Hook) fanOut(e intake.Entry) {
+	for _, d := range h.destinations {
+		if err := d.client.PushEntry(e); err != nil {
+			h.client.Debugf("Unable to queue entry to %s, %v", d.name, err)
+		}
+	}
+}
+
+// eachClient - call fn with the client of the hook and the ones of the
+// destinations at once, the error of the hook's or else of the first
+// destination failing
+func (h *Hook) eachClient(fn func(*intake.Client) error) error {
+	errs := make([]error, len(h.destinations))
+	var wg sync.WaitGroup
+	for i, d := range h.destinations {
+		wg.Add(1)
+		go func(i int, d *destination) {
+			defer wg.Done()
+			if err := fn(d.client); err != nil {
+				errs[i] = fmt.Errorf("destination %s: %w", d.name, err)
+			}
+		}(i, d)
+	}
+	err := fn(h.client)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destinationStats - the counters of every destination by name
+func (h *Hook) destinationStats() map[string]intake.Stats {
+	if len(h.destinations) == 0 {
+		return nil
+	}
+	stats := make(map[string]intake.Stats, len(h.destinations))
+	for _, d := range h.destinations {
+		stats[d.name] = d.client.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,168 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// countingExporter counts the lines delivered
+type countingExporter struct {
+	lines int64
+}
+
+func (e *countingExporter) Export(b *intake.Batch) error {
+	atomic.AddInt64(&e.lines, int64(len(b.Lines)))
+	return nil
+}
+
+// wait - wait up to a second for n lines to be delivered
+func (e *countingExporter) wait(n int64) int64 {
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&e.lines) < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return atomic.LoadInt64(&e.lines)
+}
+
+func TestFanOutStalledDestination(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	stalled := intake.ExporterFunc(func(*intake.Batch) error {
+		<-release
+		return nil
+	})
+	primary := &countingExporter{}
+	hook := New("key",
+		WithExporter(primary),
+		WithOption(func(o *Options) {
+			o.FlushPolicy = intake.MaxEntries(1)
+			o.Destinations = []Destination{{Name: "relay", Exporter: stalled, QueueSize: 1}}
+		}),
+	)
+	defer once.Do(func() { close(release) })
+
+	// Fire never waits for the stalled relay, and Datadog gets every entry
+	for i := 0; i < 20; i++ {
+		ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	}
+	equals(t, int64(20), primary.wait(20))
+	relay := hook.Stats().Destinations["relay"]
+	assert(t, relay.Overflowed > 0, "the relay queue should overflow, got %+v", relay)
+	equals(t, int64(0), relay.Delivered)
+
+	once.Do(func() { close(release) })
+	ok(t, hook.Close(context.Background()))
+	relay = hook.Stats().Destinations["relay"]
+	equals(t, int64(20), relay.Delivered+relay.Overflowed)
+}
+
+func TestFanOutFailingDestination(t *testing.T) {
+	primary, relay := &countingExporter{}, int64(0)
+	hook := New("key",
+		WithExporter(primary),
+		WithMaxRetry(1),
+		WithOption(func(o *Options) {
+			o.Destinations = []Destination{{
+				Name:            "relay",
+				MaxRetry:        5,
+				CircuitFailures: 2,
+				CircuitCooldown: time.Hour,
+				Exporter: intake.ExporterFunc(func(*intake.Batch) error {
+					atomic.AddInt64(&relay, 1)
+					return errors.New("down")
+				}),
+			}}
+		}),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the circuit of the relay holds its batch back until Close gives up
+	err := hook.Close(ctx)
+	equals(t, true, errors.Is(err, context.DeadlineExceeded))
+	equals(t, int64(1), primary.wait(1))
+	stats := hook.Stats()
+	equals(t, int64(1), stats.Client.Delivered)
+	equals(t, int64(0), stats.Client.CircuitOpens)
+	assert(t, atomic.LoadInt64(&relay) >= 2, "the relay should be attempted")
+	assert(t, stats.Destinations["relay"].CircuitOpens > 0, "the relay circuit should open, got %+v", stats.Destinations["relay"])
+}
+
+func TestFanOutDestinationRetries(t *testing.T) {
+	relay := int64(0)
+	hook := New("key",
+		WithExporter(&countingExporter{}),
+		WithMaxRetry(3),
+		WithOption(func(o *Options) {
+			// attempted as many times as the hook's batches
+			o.Destinations = []Destination{{
+				Name: "relay",
+				Exporter: intake.ExporterFunc(func(*intake.Batch) error {
+					atomic.AddInt64(&relay, 1)
+					return errors.New("down")
+				}),
+			}}
+		}),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	assert(t, hook.Close(context.Background()) != nil, "the relay batch should be dropped")
+	equals(t, int64(3), atomic.LoadInt64(&relay))
+}
+
+func TestFanOutDryRun(t *testing.T) {
+	var mu sync.Mutex
+	keys := map[string]string{}
+	hook := New("secretkey",
+		WithOption(func(o *Options) {
+			o.FlushPolicy = intake.MaxEntries(1)
+			o.DryRun = func(p Preview) {
+				mu.Lock()
+				defer mu.Unlock()
+				u, err := url.Parse(p.URL)
+				ok(t, err)
+				keys[u.Host] = p.Header.Get("DD-API-KEY")
+			}
+			o.Destinations = []Destination{
+				{Name: "eu", Host: "http-intake.logs.datadoghq.eu"},
+				{Name: "relay", Host: "relay.internal:8080"},
+			}
+		}),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	// every destination is previewed rather than sent, and only the Datadog
+	// site gets the key of the hook
+	equals(t, 3, len(keys))
+	equals(t, "*****tkey", keys["http-intake.logs.datadoghq.eu"])
+	equals(t, "", keys["relay.internal:8080"])
+}
+
+func TestFanOutAllowedSites(t *testing.T) {
+	primary := &countingExporter{}
+	hook := New("key",
+		WithExporter(primary),
+		WithOption(func(o *Options) {
+			o.AllowedSites = []string{"eu"}
+			o.Destinations = []Destination{
+				{Name: "eu", Exporter: &countingExporter{}},
+				{Name: "us", Host: "http-intake.logs.datadoghq.com"},
+				{Name: "relay", Host: "relay.internal:8080"},
+			}
+		}),
+	)
+	defer hook.Close(context.Background())
+	stats := hook.Stats().Destinations
+	equals(t, 1, len(stats))
+	_, found := stats["eu"]
+	equals(t, true, found)
+}
@@ -0,0 +1,122 @@
+package datadog
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fileAttributes - attributes read from files, added to every entry not
+// setting them, and read again every interval when watched
+type fileAttributes struct {
+	hook     *Hook
+	paths    map[string]string
+	interval time.Duration
+	values   atomic.Value // logrus.Fields
+	failing  map[string]bool
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newFileAttributes(h *Hook, paths map[string]string, interval time.Duration) *fileAttributes {
+	f := &fileAttributes{
+		hook:     h,
+		paths:    paths,
+		interval: interval,
+		failing:  map[string]bool{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	f.read()
+	if interval > 0 {
+		go f.run()
+	} else {
+		close(f.stopped)
+	}
+	return f
+}
+
+func (f *fileAttributes) run() {
+	defer close(f.stopped)
+	for {
+		select {
+		case <-f.hook.after(f.interval):
+			f.read()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// read - read every file, an attribute whose file cannot be read keeps the
+// value last read and the error goes to OnError, once until it is readable
+func (f *fileAttributes) read() {
+	prev, _ := f.values.Load().(logrus.Fields)
+	values := make(logrus.Fields, len(f.paths))
+	for name, path := range f.paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			if v, ok := prev[name]; ok {
+				values[name] = v
+			}
+			if !f.failing[name] {
+				f.hook.notifyError(err)
+			}
+			f.failing[name] = true
+			continue
+		}
+		delete(f.failing, name)
+		values[name] = fileValue(b)
+	}
+	f.values.Store(values)
+}
+
+// apply - entry with the attributes it does not set
+func (f *fileAttributes) apply(entry *logrus.Entry) *logrus.Entry {
+	values, _ := f.values.Load().(logrus.Fields)
+	if len(values) == 0 {
+		return entry
+	}
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+len(values))
+	for k, v := range values {
+		e.Data[k] = v
+	}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	return &e
+}
+
+// stop - stop watching the files
+func (f *fileAttributes) stop() {
+	f.once.Do(func() { close(f.done) })
+	<-f.stopped
+}
+
+// fileValue - the trimmed content of a file, or the map of its lines in the
+// key="value" format of Downward API files such as pod labels
+func fileValue(b []byte) interface{} {
+	content := strings.TrimSpace(string(b))
+	lines := strings.Split(content, "\n")
+	labels := make(map[string]string, len(lines))
+	for _, line := range lines {
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return content
+		}
+		value, err := strconv.Unquote(line[i+1:])
+		if err != nil {
+			return content
+		}
+		labels[line[:i]] = value
+	}
+	return labels
+}
@@ -0,0 +1,105 @@
+package datadog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFileAttributes(t *testing.T) {
+	dir := t.TempDir()
+	machineID := filepath.Join(dir, "machine-id")
+	labels := filepath.Join(dir, "labels")
+	ok(t, ioutil.WriteFile(machineID, []byte("0123abcd\n"), 0644))
+	ok(t, ioutil.WriteFile(labels, []byte("app=\"api\"\npod-template-hash=\"5d8f\"\n"), 0644))
+
+	var m sync.Mutex
+	var errs []error
+	var tee lockedBuffer
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Tee: &tee,
+		FileAttributes: map[string]string{
+			"machine_id": machineID,
+			"labels":     labels,
+			"missing":    filepath.Join(dir, "missing"),
+		},
+		FileAttributesInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			errs = append(errs, err)
+		},
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel, Data: logrus.Fields{"machine_id": "own"}}))
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel, Data: logrus.Fields{}}))
+	lines := strings.Split(strings.TrimSpace(tee.String()), "\n")
+	equals(t, 2, len(lines))
+	// fields set by the entry win
+	assert(t, strings.Contains(lines[0], `"machine_id":"own"`), "line %s", lines[0])
+	assert(t, strings.Contains(lines[1], `"machine_id":"0123abcd"`), "line %s", lines[1])
+	assert(t, strings.Contains(lines[1], `"labels":{"app":"api","pod-template-hash":"5d8f"}`), "line %s", lines[1])
+	assert(t, !strings.Contains(lines[1], "missing"), "line %s", lines[1])
+	m.Lock()
+	equals(t, 1, len(errs))
+	assert(t, os.IsNotExist(errs[0]), "error %v", errs[0])
+	m.Unlock()
+
+	// watched files are read again, keeping the last value while unreadable
+	ok(t, ioutil.WriteFile(labels, []byte("app=\"web\"\n"), 0644))
+	ok(t, os.Remove(machineID))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		e := hook.files.apply(&logrus.Entry{})
+		if labels, _ := e.Data["labels"].(map[string]string); labels["app"] == "web" {
+			equals(t, "0123abcd", e.Data["machine_id"])
+			m.Lock()
+			assert(t, len(errs) <= 2, "errors %v", errs)
+			m.Unlock()
+			break
+		}
+		assert(t, time.Now().Before(deadline), "labels not read again")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithFileAttributes(t *testing.T) {
+	machineID := filepath.Join(t.TempDir(), "machine-id")
+	ok(t, ioutil.WriteFile(machineID, []byte("0123abcd\n"), 0644))
+	files := map[string]string{"machine_id": machineID}
+	var tee lockedBuffer
+	hook := New("key",
+		WithOption(func(o *Options) { o.Tee = &tee }),
+		WithFileAttributes(files),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })),
+	)
+	defer hook.Close(context.Background())
+
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel, Data: logrus.Fields{}}))
+	assert(t, strings.Contains(tee.String(), `"machine_id":"0123abcd"`), "line %s", tee.String())
+
+	// later calls add to the attributes, the map given is not changed
+	var c config
+	WithFileAttributes(files)(&c)
+	WithFileAttributes(map[string]string{"labels": "/labels"})(&c)
+	equals(t, map[string]string{"machine_id": machineID, "labels": "/labels"}, c.options.FileAttributes)
+	equals(t, map[string]string{"machine_id": machineID}, files)
+}
+
+func TestFileValue(t *testing.T) {
+	equals(t, "0123abcd", fileValue([]byte(" 0123abcd\n")))
+	equals(t, map[string]string{"a": "1", "b": "x=y"}, fileValue([]byte("a=\"1\"\nb=\"x=y\"\n")))
+	// not every line is key="value"
+	equals(t, "a=\"1\"\nplain", fileValue([]byte("a=\"1\"\nplain")))
+	equals(t, "a=1", fileValue([]byte("a=1")))
+}
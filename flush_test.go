@@ -0,0 +1,32 @@
+package datadog
+
+import (
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFlush(t *testing.T) {
+	var delivered int64
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Exporter: intake.ExporterFunc(func(b *intake.Batch) error {
+			atomic.AddInt64(&delivered, int64(len(b.Lines)))
+			return nil
+		}),
+	})
+	l := logrus.New()
+	l.Hooks.Add(hook)
+	l.Out = ioutil.Discard
+	l.Info("one")
+	l.Info("two")
+	ok(t, hook.Flush())
+	equals(t, int64(2), atomic.LoadInt64(&delivered))
+
+	ok(t, hook.Close(context.Background()))
+	equals(t, intake.ErrClosed, hook.FlushWithContext(context.Background()))
+}
@@ -0,0 +1,133 @@
+package datadog
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// formatCache - the last formatted line, reused while the same entry is
+// fired over and over. The hash only rules out most entries cheaply, a hit
+// compares the entry in full, so a collision never ships a wrong line.
+type formatCache struct {
+	precision time.Duration
+
+	m     sync.Mutex
+	hash  uint64
+	entry logrus.Entry // copy of the entry formatted, with a copy of its data
+	line  []byte       // never changed in place, callers may keep it
+}
+
+func newFormatCache(options Options) *formatCache {
+	return &formatCache{precision: options.FormatCachePrecision}
+}
+
+// key - hash of the entry, false if a field value is not of a basic type
+// the entries can be compared with
+func (c *formatCache) key(entry *logrus.Entry) (uint64, bool) {
+	h := fnv.New64a()
+	var buf [64]byte
+	h.Write(strconv.AppendUint(buf[:0], uint64(entry.Level), 10))
+	h.Write(strconv.AppendInt(buf[:0], c.time(entry.Time).UnixNano(), 10))
+	h.Write([]byte(entry.Message))
+	sum := h.Sum64()
+	// fields in any order hash the same
+	for k, v := range entry.Data {
+		b, ok := appendBasic(buf[:0], v)
+		if !ok {
+			return 0, false
+		}
+		h.Reset()
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(b)
+		sum ^= h.Sum64()
+	}
+	return sum, true
+}
+
+// appendBasic - a basic field value appended to b, false for other types
+func appendBasic(b []byte, v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return append(b, v...), true
+	case bool:
+		return strconv.AppendBool(b, v), true
+	case int:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int64:
+		return strconv.AppendInt(b, v, 10), true
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case uint64:
+		return strconv.AppendUint(b, v, 10), true
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case float64:
+		return strconv.AppendUint(b, math.Float64bits(v), 16), true
+	case float32:
+		return strconv.AppendUint(b, uint64(math.Float32bits(v)), 16), true
+	case nil:
+		return b, true
+	}
+	return b, false
+}
+
+// time - t at the precision entries are compared at
+func (c *formatCache) time(t time.Time) time.Time {
+	if c.precision > 0 {
+		return t.Truncate(c.precision)
+	}
+	return t
+}
+
+// get - the line last formatted if the entry of the hash is the same
+func (c *formatCache) get(entry *logrus.Entry, hash uint64) ([]byte, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.line == nil || hash != c.hash || !c.same(entry) {
+		return nil, false
+	}
+	return c.line, true
+}
+
+// same - whether the entry formats like the cached one, only called locked
+func (c *formatCache) same(entry *logrus.Entry) bool {
+	e := &c.entry
+	if entry.Level != e.Level || entry.Message != e.Message || !c.time(entry.Time).Equal(c.time(e.Time)) || len(entry.Data) != len(e.Data) {
+		return false
+	}
+	if (entry.Caller == nil) != (e.Caller == nil) || (entry.Caller != nil && (entry.Caller.File != e.Caller.File ||
+		entry.Caller.Line != e.Caller.Line || entry.Caller.Function != e.Caller.Function)) {
+		return false
+	}
+	for k, v := range entry.Data {
+		cached, ok := e.Data[k]
+		if !ok || cached != v {
+			return false
+		}
+	}
+	return true
+}
+
+// put - remember the line of the entry of the hash, copied
+func (c *formatCache) put(entry *logrus.Entry, hash uint64, line []byte) {
+	e := logrus.Entry{Level: entry.Level, Time: entry.Time, Message: entry.Message, Data: make(logrus.Fields, len(entry.Data))}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	if entry.Caller != nil {
+		caller := *entry.Caller
+		e.Caller = &caller
+	}
+	line = append([]byte(nil), line...)
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.hash, c.entry, c.line = hash, e, line
+}
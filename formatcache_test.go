@@ -0,0 +1,64 @@
+package datadog
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// countingFormatter - JSON formatter counting the entries it formatted
+type countingFormatter struct {
+	logrus.JSONFormatter
+	formatted int64
+}
+
+func (f *countingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	atomic.AddInt64(&f.formatted, 1)
+	return f.JSONFormatter.Format(entry)
+}
+
+func TestFormatCache(t *testing.T) {
+	f := &countingFormatter{}
+	hook := New("key",
+		WithFormatter(f),
+		WithOption(func(o *Options) { o.FormatCache, o.FormatCachePrecision = true, time.Second }),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })),
+	)
+	defer hook.Close(context.Background())
+	// the formatter is probed once when the hook is created
+	probed := atomic.LoadInt64(&f.formatted)
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fire := func(at time.Time, data logrus.Fields) {
+		ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Time: at, Message: "spin", Data: data}))
+	}
+	fire(at, logrus.Fields{"n": 1, "ok": true})
+	fire(at.Add(time.Millisecond), logrus.Fields{"ok": true, "n": 1})
+	fire(at, logrus.Fields{"n": 1, "ok": true})
+	equals(t, int64(1), atomic.LoadInt64(&f.formatted)-probed)
+	equals(t, int64(2), hook.Stats().FormatCached)
+
+	// another value, another second, or a value which cannot be compared
+	fire(at, logrus.Fields{"n": 2, "ok": true})
+	fire(at.Add(time.Second), logrus.Fields{"n": 2, "ok": true})
+	fire(at, logrus.Fields{"n": []int{2}})
+	fire(at, logrus.Fields{"n": []int{2}})
+	equals(t, int64(5), atomic.LoadInt64(&f.formatted)-probed)
+	equals(t, int64(2), hook.Stats().FormatCached)
+}
+
+func TestFormatCacheCollision(t *testing.T) {
+	c := newFormatCache(Options{})
+	c.put(&logrus.Entry{Message: "one", Data: logrus.Fields{}}, 42, []byte("one"))
+	line, hit := c.get(&logrus.Entry{Message: "one", Data: logrus.Fields{}}, 42)
+	equals(t, true, hit)
+	equals(t, "one", string(line))
+	// the same hash for another entry is not a hit
+	_, hit = c.get(&logrus.Entry{Message: "two", Data: logrus.Fields{}}, 42)
+	equals(t, false, hit)
+	_, hit = c.get(&logrus.Entry{Message: "one", Data: logrus.Fields{"k": "v"}}, 42)
+	equals(t, false, hit)
+}
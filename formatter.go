@@ -0,0 +1,327 @@
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FastFormatter is a JSON formatter writing the same output as
+// logrus.JSONFormatter, appending common field types without reflection and
+// without building an intermediate map. Other types go through
+// encoding/json like they do with logrus.JSONFormatter.
+type FastFormatter struct {
+	// TimestampFormat - layout of the time, time.RFC3339 if empty
+	TimestampFormat string
+	// DisableTimestamp - leave the time out
+	DisableTimestamp bool
+	// DataKey - nest the fields of the entry under this key
+	DataKey string
+	// FieldMap - rename the time, level, msg, func and file keys
+	FieldMap logrus.FieldMap
+	// CallerPrettyfier - render the caller when it is reported, empty values
+	// leave the key out
+	CallerPrettyfier func(*runtime.Frame) (function string, file string)
+}
+
+// member - key and value of the formatted object
+type member struct {
+	key   string
+	value interface{}
+}
+
+// members - pooled members and scratch space of a Format call
+type members struct {
+	list []member
+	buf  []byte
+}
+
+var memberPool = sync.Pool{New: func() interface{} { return &members{} }}
+
+// fastFormatter - FastFormatter equivalent to f, nil if it has no equivalent
+func fastFormatter(f *logrus.JSONFormatter) *FastFormatter {
+	if f.PrettyPrint {
+		return nil
+	}
+	return &FastFormatter{
+		TimestampFormat:  f.TimestampFormat,
+		DisableTimestamp: f.DisableTimestamp,
+		DataKey:          f.DataKey,
+		FieldMap:         f.FieldMap,
+		CallerPrettyfier: f.CallerPrettyfier,
+	}
+}
+
+// resolve - key of a default field, renamed by the field map
+func resolve[K ~string](fieldMap map[K]string, key K) string {
+	if k, ok := fieldMap[key]; ok {
+		return k
+	}
+	return string(key)
+}
+
+// Format - implement logrus.Formatter, writing to entry.Buffer when set
+func (f *FastFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	m := memberPool.Get().(*members)
+	defer func() {
+		for i := range m.list {
+			m.list[i] = member{}
+		}
+		m.list, m.buf = m.list[:0], m.buf[:0]
+		memberPool.Put(m)
+	}()
+
+	timeKey, msgKey, levelKey := resolve(f.FieldMap, logrus.FieldKeyTime), resolve(f.FieldMap, logrus.FieldKeyMsg), resolve(f.FieldMap, logrus.FieldKeyLevel)
+	errKey := resolve(f.FieldMap, logrus.FieldKeyLogrusError)
+	hasCaller := entry.HasCaller()
+	var funcKey, fileKey string
+	if hasCaller {
+		funcKey, fileKey = resolve(f.FieldMap, logrus.FieldKeyFunc), resolve(f.FieldMap, logrus.FieldKeyFile)
+	}
+	if f.DataKey != "" {
+		m.list = append(m.list, member{key: f.DataKey, value: entry.Data})
+	} else {
+		for k, v := range entry.Data {
+			// the same renames as logrus.JSONFormatter on clashes
+			switch k {
+			case timeKey, msgKey, levelKey, errKey:
+				k = "fields." + k
+			case funcKey, fileKey:
+				if hasCaller {
+					m.list = append(m.list, member{key: "fields." + k, value: v})
+				}
+			}
+			m.list = append(m.list, member{key: k, value: v})
+		}
+	}
+	if !f.DisableTimestamp {
+		format := f.TimestampFormat
+		if format == "" {
+			format = time.RFC3339
+		}
+		m.list = append(m.list, member{key: timeKey, value: entry.Time.Format(format)})
+	}
+	m.list = append(m.list, member{key: msgKey, value: entry.Message}, member{key: levelKey, value: entry.Level.String()})
+	if hasCaller {
+		funcVal := entry.Caller.Function
+		fileVal := entry.Caller.File + ":" + strconv.Itoa(entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			funcVal, fileVal = f.CallerPrettyfier(entry.Caller)
+		}
+		if funcVal != "" {
+			m.list = append(m.list, member{key: funcKey, value: funcVal})
+		}
+		if fileVal != "" {
+			m.list = append(m.list, member{key: fileKey, value: fileVal})
+		}
+	}
+	m.list = dedupe(m.list)
+
+	buf := append(m.buf, '{')
+	var err error
+	for i, kv := range m.list {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendString(buf, kv.key)
+		buf = append(buf, ':')
+		if kv.key == f.DataKey && f.DataKey != "" {
+			buf, err = appendFields(buf, entry.Data)
+		} else {
+			buf, err = appendValue(buf, kv.value, true)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+		}
+	}
+	buf = append(buf, '}', '\n')
+	m.buf = buf
+
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+	b.Write(buf)
+	return b.Bytes(), nil
+}
+
+// dedupe - members sorted by key, the last one of a key kept like in a map
+func dedupe(list []member) []member {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].key < list[j].key })
+	out := list[:0]
+	for i, kv := range list {
+		if i+1 < len(list) && list[i+1].key == kv.key {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// appendFields - append fields as a JSON object with sorted keys
+func appendFields(dst []byte, fields logrus.Fields) ([]byte, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	dst = append(dst, '{')
+	var err error
+	for i, k := range keys {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendString(dst, k)
+		dst = append(dst, ':')
+		if dst, err = appendValue(dst, fields[k], true); err != nil {
+			return nil, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// appendValue - append v as encoding/json would, errors rendered as their
+// message at the top level like logrus.JSONFormatter does
+func appendValue(dst []byte, v interface{}, top bool) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(dst, "null"...), nil
+	case string:
+		return appendString(dst, v), nil
+	case bool:
+		return strconv.AppendBool(dst, v), nil
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(dst, v, 10), nil
+	case uint:
+		return strconv.AppendUint(dst, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(dst, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(dst, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(dst, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(dst, v, 10), nil
+	case float64:
+		return appendFloat(dst, v, 64)
+	case float32:
+		return appendFloat(dst, float64(v), 32)
+	case time.Duration:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case time.Time:
+		b, err := v.MarshalJSON()
+		return append(dst, b...), err
+	case []string:
+		if v == nil {
+			return append(dst, "null"...), nil
+		}
+		dst = append(dst, '[')
+		for i, s := range v {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendString(dst, s)
+		}
+		return append(dst, ']'), nil
+	case error:
+		if top {
+			return appendString(dst, v.Error()), nil
+		}
+	}
+	b, err := json.Marshal(v)
+	return append(dst, b...), err
+}
+
+// appendFloat - append f as encoding/json would
+func appendFloat(dst []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, &json.UnsupportedValueError{Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst, nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString - append s as a JSON string escaped as encoding/json does,
+// HTML characters included
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,109 @@
+package datadog
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type point struct {
+	X, Y int
+}
+
+// formatterEntries - entries covering the field types and clashes
+func formatterEntries() []*logrus.Entry {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	logger := logrus.New()
+	logger.ReportCaller = true
+	return []*logrus.Entry{
+		{Time: now, Level: logrus.InfoLevel, Message: "served", Data: logrus.Fields{}},
+		{Time: now, Level: logrus.ErrorLevel, Message: "quotes \" \\ <b>&</b> \n\t\r\b\f \x01 \u2028 \xff é 日本", Data: logrus.Fields{
+			"string": "value", "bool": true, "int": -42, "int8": int8(-8), "int16": int16(16), "int32": int32(32), "int64": int64(math.MinInt64),
+			"uint": uint(42), "uint8": uint8(8), "uint16": uint16(16), "uint32": uint32(32), "uint64": uint64(math.MaxUint64),
+			"float64": 3.14, "float32": float32(0.1), "small": 1e-7, "big": 1e21, "zero": 0.0, "negative": -2.5e-10,
+			"duration": 1500 * time.Millisecond, "time": now, "strings": []string{"a", "<b>"}, "nilstrings": []string(nil),
+			"error": errors.New("boom"), "nil": nil, "struct": point{1, 2}, "map": map[string]interface{}{"k": []int{1}},
+			"errors": []interface{}{errors.New("nested")}, "bytes": []byte("raw"),
+		}},
+		{Time: now, Level: logrus.WarnLevel, Message: "clash", Data: logrus.Fields{"msg": "own", "level": 1, "func": "f", "file": "x"}},
+		{Time: now, Level: logrus.DebugLevel, Message: "caller", Logger: logger, Data: logrus.Fields{"func": "own", "file": "x"},
+			Caller: &runtime.Frame{Function: "main.run", File: "/src/main.go", Line: 12}},
+	}
+}
+
+func TestFastFormatter(t *testing.T) {
+	for _, f := range []*logrus.JSONFormatter{
+		{},
+		{TimestampFormat: time.RFC3339Nano},
+		{DisableTimestamp: true},
+		{DataKey: "fields"},
+		{FieldMap: logrus.FieldMap{logrus.FieldKeyMsg: "message", logrus.FieldKeyLevel: "status", logrus.FieldKeyFunc: "caller"}},
+		{CallerPrettyfier: func(f *runtime.Frame) (string, string) { return "", f.File }},
+	} {
+		fast := fastFormatter(f)
+		for _, entry := range formatterEntries() {
+			exp, err := f.Format(entry)
+			ok(t, err)
+			got, err := fast.Format(entry)
+			ok(t, err)
+			equals(t, string(exp), string(got))
+		}
+	}
+	equals(t, (*FastFormatter)(nil), fastFormatter(&logrus.JSONFormatter{PrettyPrint: true}))
+}
+
+func TestFastFormatterBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("kept ")
+	entry := &logrus.Entry{Message: "served", Data: logrus.Fields{}, Buffer: &buf}
+	line, err := (&FastFormatter{DisableTimestamp: true}).Format(entry)
+	ok(t, err)
+	equals(t, "kept {\"level\":\"panic\",\"msg\":\"served\"}\n", string(line))
+
+	_, err = (&FastFormatter{}).Format(&logrus.Entry{Data: logrus.Fields{"nan": math.NaN()}})
+	assert(t, err != nil, "NaN formatted")
+}
+
+func TestFastFormatOption(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{FastFormat: true})
+	_, fast := hook.formatter.(*FastFormatter)
+	equals(t, true, fast)
+	equals(t, true, hook.json)
+	hook = NewHook(DatadogUSHost, "key", time.Second, 0, logrus.InfoLevel, &logrus.TextFormatter{}, Options{FastFormat: true})
+	_, fast = hook.formatter.(*FastFormatter)
+	equals(t, false, fast)
+}
+
+func benchmarkFormatter(b *testing.B, f logrus.Formatter) {
+	entry := &logrus.Entry{
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: "request served",
+		Data: logrus.Fields{
+			"method": "GET", "path": "/api/v1/users", "status": 200, "duration": 12 * time.Millisecond,
+			"bytes": int64(5120), "ratio": 0.75, "cached": false, "user_id": "u-12345",
+		},
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var buf bytes.Buffer
+		e := *entry
+		e.Buffer = &buf
+		for pb.Next() {
+			buf.Reset()
+			if _, err := f.Format(&e); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFormatter(b *testing.B) {
+	b.Run("logrus.JSONFormatter", func(b *testing.B) { benchmarkFormatter(b, &logrus.JSONFormatter{}) })
+	b.Run("FastFormatter", func(b *testing.B) { benchmarkFormatter(b, &FastFormatter{}) })
+}
@@ -1,8 +1,14 @@
 module github.com/bin3377/logrus-datadog-hook
 
-go 1.13
+go 1.25.0
 
 require (
 	github.com/sirupsen/logrus v1.4.2
+	golang.org/x/net v0.57.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
+
+require (
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+)
@@ -0,0 +1,87 @@
+package datadog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HeartbeatMessage - message of the heartbeat entries
+	HeartbeatMessage = "log shipping heartbeat"
+	// HeartbeatField - field of the heartbeat holding the stats of the hook
+	HeartbeatField = "heartbeat"
+)
+
+// heartbeat - a synthetic entry carrying the stats of the hook shipped
+// periodically, so its absence in Datadog tells shipping from a host broke
+type heartbeat struct {
+	hook     *Hook
+	interval time.Duration
+	started  time.Time
+	beats    int64
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newHeartbeat(h *Hook, interval time.Duration) *heartbeat {
+	b := &heartbeat{
+		hook:     h,
+		interval: interval,
+		started:  h.now(),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *heartbeat) run() {
+	defer close(b.stopped)
+	// the first beat tells shipping works as soon as the hook is created
+	b.beat()
+	for {
+		select {
+		case <-b.hook.after(b.interval):
+			b.beat()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// beat - ship a heartbeat with the stats of the hook so far
+func (b *heartbeat) beat() {
+	b.beats++
+	s := b.hook.Stats()
+	now := b.hook.now()
+	entry := &logrus.Entry{
+		Time:    now,
+		Level:   logrus.InfoLevel,
+		Message: HeartbeatMessage,
+		Data: logrus.Fields{HeartbeatField: map[string]interface{}{
+			"beat":      b.beats,
+			"uptime":    int64(now.Sub(b.started) / time.Second),
+			"fired":     s.Fired,
+			"skipped":   s.Skipped,
+			"failed":    s.Failed,
+			"pushed":    s.Client.Pushed,
+			"delivered": s.Client.Delivered,
+			"dropped":   s.Client.Dropped,
+			"retries":   s.Client.Retries,
+			"throttled": s.Client.Throttled,
+		}},
+	}
+	if err := b.hook.ship(entry); err != nil {
+		b.hook.client.Debugf("Unable to ship heartbeat, %v", err)
+	}
+}
+
+// stop - stop beating
+func (b *heartbeat) stop() {
+	b.once.Do(func() { close(b.done) })
+	<-b.stopped
+}
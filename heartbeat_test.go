@@ -0,0 +1,65 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// tickClock - a fake clock whose timers fire when the test sends on ticks
+type tickClock struct {
+	fakeClock
+	ticks chan time.Time
+}
+
+func (c *tickClock) After(time.Duration) <-chan time.Time {
+	return c.ticks
+}
+
+func TestHeartbeat(t *testing.T) {
+	var tee lockedBuffer
+	clock := &tickClock{fakeClock: fakeClock{now: time.Unix(1000, 0)}, ticks: make(chan time.Time)}
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.WarnLevel, &logrus.JSONFormatter{}, Options{
+		Tee:       &tee,
+		Clock:     clock,
+		Heartbeat: time.Minute,
+		Exporter:  intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	// the first beat is shipped right away
+	for !strings.Contains(tee.String(), HeartbeatMessage) {
+		time.Sleep(time.Millisecond)
+	}
+	ok(t, hook.Fire(&logrus.Entry{Message: "disk full", Level: logrus.ErrorLevel}))
+	ok(t, hook.Fire(&logrus.Entry{Message: "chatty", Level: logrus.InfoLevel}))
+	clock.advance(90 * time.Second)
+	clock.ticks <- clock.Now()
+	ok(t, hook.Close(context.Background()))
+
+	type beat struct {
+		Msg       string           `json:"msg"`
+		Level     string           `json:"level"`
+		Heartbeat map[string]int64 `json:"heartbeat"`
+	}
+	var beats []beat
+	for _, line := range strings.Split(strings.TrimSpace(tee.String()), "\n") {
+		var b beat
+		ok(t, json.Unmarshal([]byte(line), &b))
+		if b.Msg == HeartbeatMessage {
+			beats = append(beats, b)
+		}
+	}
+	equals(t, 2, len(beats))
+	// shipped whatever the minimum level
+	equals(t, "info", beats[0].Level)
+	equals(t, int64(1), beats[0].Heartbeat["beat"])
+	equals(t, int64(0), beats[0].Heartbeat["uptime"])
+	equals(t, int64(2), beats[1].Heartbeat["beat"])
+	equals(t, int64(90), beats[1].Heartbeat["uptime"])
+	equals(t, int64(2), beats[1].Heartbeat["fired"])
+	equals(t, int64(1), beats[1].Heartbeat["skipped"])
+}
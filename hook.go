@@ -2,71 +2,100 @@ package datadog
 
 import (
 	"bytes"
-	"log"
-	"net/http"
-	"net/url"
+	"context"
+	"fmt"
+	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bin3377/logrus-datadog-hook/intake"
 	"github.com/sirupsen/logrus"
 )
 
-// Options define the options for Datadog log stream
-type Options struct {
-	Source   string
-	Service  string
-	Hostname string
-	Tags     []string
-}
-
-// Hook is the struct holding connect information to Datadog backend
+// Hook is the struct adapting logrus entries to the Datadog intake client.
+// Every method is safe for concurrent use once NewHook returned: options are
+// read-only, settings changed at runtime and counters are atomic, and shared
+// buffers are locked. An entry given to Fire must not be modified until Fire
+// returns, as logrus guarantees.
 type Hook struct {
-	host      string
-	apiKey    string
-	maxRetry  int
 	formatter logrus.Formatter
-	minLevel  logrus.Level
+	level     uint32
 	options   Options
+	client    *intake.Client
+	rules     atomic.Value
+	teeLock   sync.Mutex
+
+	sampling    uint64
+	sampler     *rand.Rand
+	samplerLock sync.Mutex
 
-	ch     chan []byte
-	buffer [][]byte
-	m      sync.Mutex
-	err    error
+	summary   *summary
+	quotas    *levelQuotas
+	adaptive  *adaptive
+	alarm     *alarm
+	files     *fileAttributes
+	heartbeat *heartbeat
+	self      *selfReport
+	mutes     mutes
+	normalize *normalizer
+	cache     *formatCache
+	stats     stats
+	json      bool
+	anomalies anomalies
+	// locality - the tags of the entries with the locality ones, once the
+	// metadata service answered
+	locality atomic.Value
+
+	destinations []*destination
 }
 
 const (
 	// DatadogUSHost - Host For Datadog US
-	DatadogUSHost = "http-intake.logs.datadoghq.com"
+	DatadogUSHost = intake.DatadogUSHost
 	// DatadogEUHost - Host For Datadog EU
-	DatadogEUHost = "http-intake.logs.datadoghq.eu"
-
-	basePath       = "/v1/input"
-	apiKeyHeader   = "DD-API-KEY"
-	defaultTimeout = time.Second * 30
-
-	// ContentTypePlain - content is plain text
-	contentTypePlain = "text/plain"
-
-	// ContentTypeJSON - content is JSON
-	contentTypeJSON = "application/json"
-
-	// Maximum content size per payload: 5MB
-	maxContentByteSize = 5*1024*1024 - 2
+	DatadogEUHost = intake.DatadogEUHost
+	// DatadogUSTCPHost - TCP intake For Datadog US
+	DatadogUSTCPHost = intake.DatadogUSTCPHost
+	// DatadogEUTCPHost - TCP intake For Datadog EU
+	DatadogEUTCPHost = intake.DatadogEUTCPHost
+)
 
-	// Maximum size for a single log: 256kB
-	maxEntryByteSize = 256 * 1024
+const (
+	// ProtocolV2HTTP - the v2 HTTP intake at /api/v2/logs, the default
+	ProtocolV2HTTP = intake.ProtocolV2HTTP
+	// ProtocolV1HTTP - the deprecated v1 HTTP intake at /v1/input
+	ProtocolV1HTTP = intake.ProtocolV1HTTP
+	// ProtocolTCP - the TCP intake over TLS, host is the address of the intake
+	ProtocolTCP = intake.ProtocolTCP
+	// ProtocolAgent - the log listener of a local Agent at AgentAddr
+	ProtocolAgent = intake.ProtocolAgent
+)
 
-	// Maximum array size if sending multiple logs in an array: 500 entries
-	maxArraySize = 500
+const (
+	// LaneLow - the lane of trace and debug entries
+	LaneLow = intake.LaneLow
+	// LaneNormal - the lane of info entries
+	LaneNormal = intake.LaneNormal
+	// LaneHigh - the lane of warnings and worse
+	LaneHigh = intake.LaneHigh
 )
 
-var (
-	// Debug - print out debug log if true
-	Debug = false
+const (
+	// OverflowBlock - wait for room in the queue
+	OverflowBlock = intake.OverflowBlock
+	// OverflowDropNewest - drop the entry fired when the queue is full
+	OverflowDropNewest = intake.OverflowDropNewest
+	// OverflowDropOldest - drop the oldest entry queued to make room
+	OverflowDropOldest = intake.OverflowDropOldest
 )
 
-// NewHook - create hook with input
+// AttemptsField - attribute of the JSON lines written to the fallback with
+// the delivery attempts of their batch
+const AttemptsField = intake.AttemptsField
+
+// NewHook - create hook with input, see New for the functional options
 func NewHook(
 	host string,
 	apiKey string,
@@ -76,171 +105,473 @@ func NewHook(
 	formatter logrus.Formatter,
 	options Options,
 ) *Hook {
+	return New(apiKey,
+		WithHost(host),
+		WithBatchTimeout(batchTimeout),
+		WithMaxRetry(maxRetry),
+		WithLevel(minLevel),
+		WithFormatter(formatter),
+		WithOptions(options),
+	)
+}
 
+// newHook - create hook with the config of New
+func newHook(apiKey string, c config) *Hook {
+	host, batchTimeout, maxRetry := c.host, c.batchTimeout, c.maxRetry
+	minLevel, formatter := c.minLevel, c.formatter
 	h := &Hook{
-		host:      host,
-		apiKey:    apiKey,
-		maxRetry:  maxRetry,
-		minLevel:  minLevel,
+		level:     uint32(minLevel),
 		formatter: formatter,
-		options:   options,
+		options:   c.options.withBuildInfo(),
 	}
-
-	if batchTimeout < 5*time.Second {
-		batchTimeout = 5 * time.Second
+	h.resolveLocality()
+	options := h.options
+	h.SetSampling(options.Sampling)
+	seed := options.Seed
+	if seed == 0 {
+		seed = h.now().UnixNano()
+	}
+	h.sampler = rand.New(rand.NewSource(seed))
+	if f, ok := formatter.(*logrus.JSONFormatter); ok && options.FastFormat {
+		if fast := fastFormatter(f); fast != nil {
+			h.formatter = fast
+		}
+	}
+	h.json = h.isJSON()
+	if options.SelfReport > 0 {
+		h.self = newSelfReport(h, host, apiKey, options)
+	}
+	onError := options.OnError
+	if h.self != nil {
+		onError = h.clientError
+	}
+	config := intake.Config{
+		Host:                 host,
+		APIKey:               apiKey,
+		BatchTimeout:         batchTimeout,
+		MaxRetry:             maxRetry,
+		JSON:                 h.json,
+		Stream:               options.Stream(),
+		BatchJitter:          options.BatchJitter,
+		OnDrainProgress:      options.OnDrainProgress,
+		HMACKey:              options.HMACKey,
+		HMACHeader:           options.HMACHeader,
+		OnAudit:              options.OnAudit,
+		FlushPolicy:          options.flushPolicy(),
+		Fallback:             options.Fallback,
+		SpoolDir:             options.SpoolDir,
+		SpoolMaxBytes:        options.SpoolMaxBytes,
+		SpoolMaxFiles:        options.SpoolMaxFiles,
+		SpoolMaxAge:          options.SpoolMaxAge,
+		ErrorHandler:         options.ErrorHandler,
+		Compress:             options.Compress,
+		CompressionThreshold: options.CompressionThreshold,
+		Dictionary:           options.Dictionary,
+		Trainer:              options.Trainer,
+		PayloadStages:        options.Pipeline.Batch,
+		Clock:                options.Clock,
+		Seed:                 options.Seed,
+		OnError:              onError,
+		OnErrorContext:       options.OnErrorContext,
+		OnAuditContext:       options.OnAuditContext,
+		BaseContext:          options.BaseContext,
+		BreakdownTags:        options.BreakdownTags,
+		BreakdownLimit:       options.BreakdownLimit,
+		DNSFallback:          options.DNSFallback,
+		DryRun:               options.DryRun,
+		Sequence:             options.Sequence,
+		SequenceFile:         options.SequenceFile,
+		QueueSize:            options.QueueSize,
+		QueueBytes:           options.QueueBytes,
+		Overflow:             options.Overflow,
+		Shards:               options.Shards,
+		RetryPolicy:          options.RetryPolicy,
+		RetryAfter:           options.RetryAfter,
+		CircuitFailures:      options.CircuitFailures,
+		CircuitCooldown:      options.CircuitCooldown,
+		MaxRequestsPerSecond: options.MaxRequestsPerSecond,
+		MaxBytesPerSecond:    options.MaxBytesPerSecond,
+		LaneWeights:          options.LaneWeights,
+		AllowedSites:         options.AllowedSites,
+		HTTPClient:           options.HTTPClient,
+		RequestTimeout:       options.RequestTimeout,
+		Proxy:                options.Proxy,
+		TLSConfig:            options.TLSConfig,
+		MaxEntryBytes:        options.MaxEntryBytes,
+		Protocol:             options.Protocol,
+		DetectAgent:          options.DetectAgent,
+		AgentAddr:            options.AgentAddr,
+		Exporter:             options.Exporter,
+	}
+	if options.SystemSnapshot {
+		config.Companion = h.snapshot
+	}
+	h.client = intake.New(config)
+	for _, d := range options.Destinations {
+		dest, err := newDestination(config, d)
+		if err != nil {
+			h.client.Debugf("Unable to create %v", err)
+			continue
+		}
+		h.destinations = append(h.destinations, dest)
+	}
+	if options.SummarizeDebug > 0 {
+		h.summary = newSummary(h, options.SummarizeDebug)
+	}
+	if len(options.LevelQuotas) > 0 {
+		h.quotas = newLevelQuotas(h, options)
+	}
+	if options.AdaptiveSampling {
+		h.adaptive = newAdaptive(h, options)
+	}
+	if options.ErrorRateAlarm != nil {
+		h.alarm = newAlarm(h, options)
+	}
+	if options.FormatCache {
+		h.cache = newFormatCache(options)
+	}
+	if options.NormalizeValues {
+		h.normalize = newNormalizer(options)
+	}
+	if len(options.FileAttributes) > 0 {
+		h.files = newFileAttributes(h, options.FileAttributes, options.FileAttributesInterval)
+	}
+	if options.Heartbeat > 0 {
+		h.heartbeat = newHeartbeat(h, options.Heartbeat)
 	}
-	h.ch = make(chan []byte, 1)
-	go h.pile(time.Tick(batchTimeout))
 	return h
 }
 
-// Levels - implement Hook interface supporting all levels
+// SetDebug - print out debug log of this hook if true, safe to call at any time
+func (h *Hook) SetDebug(debug bool) {
+	h.client.SetDebug(debug)
+}
+
+// Settings - the delivery settings batches are currently sent with
+func (h *Hook) Settings() Settings {
+	return h.client.Settings()
+}
+
+// UpdateSettings - change the delivery settings at runtime, batches being
+// sent keep the settings they started with, safe to call at any time
+func (h *Hook) UpdateSettings(update func(*Settings)) {
+	h.client.UpdateSettings(update)
+}
+
+// String - summary of the hook config safe for startup logs, the API key is redacted
+func (h *Hook) String() string {
+	return fmt.Sprintf("datadog.Hook{minLevel=%s %s}", h.Level(), h.client.String())
+}
+
+// Flush - send the buffered entries and wait for their delivery, see
+// FlushWithContext
+func (h *Hook) Flush() error {
+	return h.FlushWithContext(context.Background())
+}
+
+// FlushWithContext - send the buffered entries without waiting for the
+// flush policy, e.g. before a checkpoint, and wait for every entry fired so
+// far to be delivered or dropped, or for ctx to be done.
+// intake.ErrNotDelivered if entries were dropped.
+func (h *Hook) FlushWithContext(ctx context.Context) error {
+	return h.eachClient(func(c *intake.Client) error { return c.Flush(ctx) })
+}
+
+// Close - stop shipping entries, flushing what is buffered before ctx is done,
+// intake.ErrNotDelivered if batches were dropped meanwhile
+func (h *Hook) Close(ctx context.Context) error {
+	_, err := h.Shutdown(ctx)
+	return err
+}
+
+// Shutdown - Close, also reporting what happened to the entries left
+func (h *Hook) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	start, before := h.now(), h.client.Stats()
+	if h.heartbeat != nil {
+		h.heartbeat.stop()
+	}
+	if h.adaptive != nil {
+		h.adaptive.stop()
+	}
+	if h.alarm != nil {
+		h.alarm.stop()
+	}
+	if h.files != nil {
+		h.files.stop()
+	}
+	if h.summary != nil {
+		h.summary.stop()
+	}
+	if h.quotas != nil {
+		h.quotas.stop()
+	}
+	err := h.eachClient(func(c *intake.Client) error { return c.Close(ctx) })
+	if h.self != nil {
+		// the errors of the drain included
+		h.self.stop(ctx)
+	}
+	return newShutdownReport(before, h.client.Stats(), h.now().Sub(start)), err
+}
+
+// Context - context given to the context callbacks, derived from
+// Options.BaseContext and canceled once Close returns
+func (h *Hook) Context() context.Context {
+	return h.client.Context()
+}
+
+// notifyError - report an error Fire cannot return to OnError and OnErrorContext
+func (h *Hook) notifyError(err error) {
+	h.clientError(err)
+	if h.options.OnErrorContext != nil {
+		h.options.OnErrorContext(h.Context(), err)
+	}
+}
+
+// clientError - report an error to OnError and the self report
+func (h *Hook) clientError(err error) {
+	if h.self != nil {
+		h.self.add(err)
+	}
+	if h.options.OnError != nil {
+		h.options.OnError(err)
+	}
+}
+
+// Incidents - panics recovered while batching and sending, the hook keeps
+// shipping after them
+func (h *Hook) Incidents() int64 {
+	return h.client.Incidents()
+}
+
+// Breakdown - entries and bytes delivered by service, source and tag, to
+// attribute the cost of logs to the teams owning them
+func (h *Hook) Breakdown() Breakdown {
+	return h.client.Breakdown()
+}
+
+// Levels - implement Hook interface supporting all levels, entries above
+// the level of the hook are skipped by Fire so it can change at any time
 func (h *Hook) Levels() []logrus.Level {
-	return logrus.AllLevels[:h.minLevel+1]
+	return logrus.AllLevels
 }
 
 // Fire - implement Hook interface fire the entry
 func (h *Hook) Fire(entry *logrus.Entry) error {
-	line, err := h.formatter.Format(entry)
+	atomic.AddInt64(&h.stats.fired, 1)
+	if entry.Level > h.Level() || !h.sampled(entry) || !h.adaptive.sampled(entry.Level) {
+		atomic.AddInt64(&h.stats.skipped, 1)
+		return nil
+	}
+	if h.muted(entry) {
+		atomic.AddInt64(&h.stats.muted, 1)
+		return nil
+	}
+	if h.summary != nil && entry.Level >= logrus.DebugLevel {
+		atomic.AddInt64(&h.stats.summarized, 1)
+		h.summary.add(entry)
+		return nil
+	}
+	start := h.now()
+	err := h.ship(entry)
+	h.timeFire(start)
 	if err != nil {
-		dbg("Unable to read entry, %v", err)
-		return err
+		atomic.AddInt64(&h.stats.failed, 1)
 	}
-	h.ch <- line
-	return h.err
+	return err
 }
 
-func (h *Hook) pile(ticker <-chan time.Time) {
-	var pile [][]byte
-	size := 0
-	for {
-		select {
-		case p := <-h.ch:
-			str := string(p)
-			if str == "" {
-				continue
-			}
-			if h.isJSON() {
-				str = strings.TrimRight(str, "\n")
-				str += ","
-			} else if !strings.HasSuffix(str, "\n") {
-				str += "\n"
-			}
-			bytes := []byte(str)
-			messageSize := len(bytes)
-			if size+messageSize >= maxContentByteSize || len(pile) == maxArraySize {
-				go h.send(pile)
-				pile = make([][]byte, 0, maxArraySize)
-				size = 0
-			}
-			pile = append(pile, bytes)
-			size += messageSize
-		case <-ticker:
-			go h.send(pile)
-			pile = make([][]byte, 0, maxArraySize)
-			size = 0
-		}
-	}
+// ship - format and queue the entry
+func (h *Hook) ship(entry *logrus.Entry) error {
+	return h.shipEntry(entry, true)
 }
 
-func (h *Hook) isJSON() bool {
-	if _, ok := h.formatter.(*logrus.JSONFormatter); ok {
-		return true
-	} else if _, ok := h.formatter.(*logrus.TextFormatter); ok {
-		return false
+// shipEntry - ship, holding the entry to the quota of its level if quota
+func (h *Hook) shipEntry(entry *logrus.Entry, quota bool) error {
+	quota = quota && h.quotas != nil
+	if quota && h.quotas.full(entry.Level) {
+		atomic.AddInt64(&h.stats.overQuota, 1)
+		return nil
 	}
-	b, err := h.formatter.Format(&logrus.Entry{})
+	stream := h.localityStream(h.options.resolve(entry))
+	if r, ok := h.rules.Load().(*Rules); ok {
+		if entry, stream = r.apply(entry, stream); entry == nil {
+			atomic.AddInt64(&h.stats.skipped, 1)
+			return nil
+		}
+	}
+	entry, stream = entryTags(entry, stream, h.options.tagsField())
+	if h.files != nil {
+		entry = h.files.apply(entry)
+	}
+	if h.options.ErrorChains {
+		entry = errorChain(entry)
+	}
+	if h.normalize != nil {
+		entry = h.normalize.apply(entry)
+	}
+	if h.options.SpanFromContext != nil {
+		entry = correlate(entry, h.options.SpanFromContext)
+	}
+	if h.options.Fingerprint {
+		entry = fingerprint(entry)
+	}
+	entry, stream, err := h.options.Pipeline.stageEntry(entry, stream)
+	if entry == nil {
+		if err == nil {
+			atomic.AddInt64(&h.stats.skipped, 1)
+		}
+		return err
+	}
+	buf := formatBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer putFormatBuffer(buf)
+	line, err := h.format(entry, buf)
 	if err != nil {
-		return false
+		h.client.Debugf("Unable to read entry, %v", err)
+		return err
 	}
-	str := strings.TrimSpace(string(b))
-	return strings.HasPrefix(str, "{") && strings.HasSuffix(str, "}")
+	if max := h.client.MaxEntryBytes(); entrySize(line) > max {
+		switch h.options.Oversize {
+		case OversizeDrop:
+			return h.oversized(entry, entrySize(line), nil)
+		case OversizeSplit:
+			parts, lines, err := h.split(entry, line, max)
+			if err != nil {
+				return err
+			}
+			if parts != nil {
+				atomic.AddInt64(&h.stats.split, 1)
+				for i, part := range parts {
+					if err := h.queue(part, stream, lines[i], quota); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+		size := entrySize(line)
+		if line, err = h.shorten(entry, line, max); err != nil {
+			return err
+		}
+		// the client cuts text lines, JSON ones cannot be
+		if entrySize(line) > max && h.json {
+			return h.oversized(entry, size, intake.ErrEntryTooLarge)
+		}
+	}
+	return h.queue(entry, stream, line, quota)
 }
 
-func (h *Hook) send(pile [][]byte) {
-	h.m.Lock()
-	defer h.m.Unlock()
-	if len(pile) == 0 {
-		return
+// queue - push the formatted line of an entry to the client, through the
+// Format stages
+func (h *Hook) queue(entry *logrus.Entry, stream intake.Stream, line []byte, quota bool) error {
+	if len(h.options.Pipeline.Format) > 0 {
+		size := len(line)
+		var err error
+		if line, err = h.options.Pipeline.stageLine(line, entry); line == nil {
+			if err == nil {
+				atomic.AddInt64(&h.stats.skipped, 1)
+			}
+			return err
+		}
+		if len(line) > size && entrySize(line) > h.client.MaxEntryBytes() && h.json {
+			return h.oversized(entry, entrySize(line), intake.ErrEntryTooLarge)
+		}
 	}
-
-	buf := make([]byte, 0)
-	for _, line := range pile {
-		buf = append(buf, line...)
+	if (h.options.VerifyFormat || h.options.QuickVerify) && !h.verify(line) {
+		atomic.AddInt64(&h.stats.skipped, 1)
+		return nil
 	}
-	if len(buf) == 0 {
-		return
+	if quota && !h.quotas.admit(entry.Level, len(line)) {
+		atomic.AddInt64(&h.stats.overQuota, 1)
+		return nil
 	}
-	if h.isJSON() {
-		if buf[len(buf)-1] == ',' {
-			buf = buf[:len(buf)-1]
+	local := h.local(entry, line)
+	// the client copies the line, so the buffer can be reused right away
+	e := intake.Entry{Stream: stream, Line: line, Local: local, Severity: Severity(entry.Level), Time: entry.Time}
+	h.fanOut(e)
+	if h.options.Strict {
+		ctx := entry.Context
+		if ctx == nil {
+			ctx = context.Background()
 		}
-		buf = append(buf, ']')
-		buf = append([]byte{'['}, buf...)
+		return h.client.PushWait(ctx, e)
 	}
+	return h.client.PushEntry(e)
+}
 
-	dbg(string(buf))
-
-	req, err := http.NewRequest("POST", h.datadogURL(), bytes.NewBuffer(buf))
-	if err != nil {
-		dbg(err.Error())
-		return
-	}
-	header := http.Header{}
-	header.Add(apiKeyHeader, h.apiKey)
-	if h.isJSON() {
-		header.Add("Content-Type", contentTypeJSON)
-	} else {
-		header.Add("Content-Type", contentTypePlain)
-	}
-	header.Add("charset", "UTF-8")
-	req.Header = header
-
-	i := 0
-	for {
-		resp, err := http.DefaultClient.Do(req)
-		if err == nil && resp.StatusCode < 400 {
-			dbg("Success - %d", resp.StatusCode)
-			return
-		}
-		dbg("err  = %v", err)
-		dbg("resp = %v", resp)
-		i++
-		if h.maxRetry < 0 || i >= h.maxRetry {
-			dbg("Still failed after %d retries", i)
-			return
+// format - the line of the entry, formatted into buf or the one of the same
+// entry fired before with Options.FormatCache
+func (h *Hook) format(entry *logrus.Entry, buf *bytes.Buffer) ([]byte, error) {
+	var hash uint64
+	cacheable := false
+	if h.cache != nil {
+		if hash, cacheable = h.cache.key(entry); cacheable {
+			if line, ok := h.cache.get(entry, hash); ok {
+				atomic.AddInt64(&h.stats.formatCached, 1)
+				return line, nil
+			}
 		}
 	}
+	// formatters write into entry.Buffer when set, saving an allocation per entry
+	prev := entry.Buffer
+	entry.Buffer = buf
+	line, err := h.formatter.Format(entry)
+	entry.Buffer = prev
+	if err == nil && cacheable {
+		h.cache.put(entry, hash, line)
+	}
+	return line, err
 }
 
-func (h *Hook) datadogURL() string {
-	u, err := url.Parse("https://" + h.host)
-	if err != nil {
-		dbg(err.Error())
-		return ""
-	}
-	u.Path += basePath
-	parameters := url.Values{}
-	o := h.options
-	if o.Source != "" {
-		parameters.Add("ddsource", o.Source)
+// now - the time of the hook's clock
+func (h *Hook) now() time.Time {
+	if h.options.Clock != nil {
+		return h.options.Clock.Now()
 	}
-	if o.Service != "" {
-		parameters.Add("service", o.Service)
+	return time.Now()
+}
+
+// after - wait d on the hook's clock
+func (h *Hook) after(d time.Duration) <-chan time.Time {
+	if h.options.Clock != nil {
+		return h.options.Clock.After(d)
 	}
-	if o.Hostname != "" {
-		parameters.Add("hostname", o.Hostname)
+	return time.After(d)
+}
+
+// maxPooledFormatBuffer - buffers grown over Datadog's entry limit are left to the GC
+const maxPooledFormatBuffer = 256 * 1024
+
+var formatBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func putFormatBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledFormatBuffer {
+		formatBuffers.Put(buf)
 	}
-	if o.Tags != nil {
-		tags := strings.Join(o.Tags, ",")
-		parameters.Add("ddtags", tags)
+}
+
+// Severity - the intake severity of a logrus level
+func Severity(level logrus.Level) intake.Severity {
+	if level > logrus.TraceLevel {
+		return intake.SeverityUnknown
 	}
-	u.RawQuery = parameters.Encode()
-	return u.String()
+	return intake.SeverityPanic - intake.Severity(level)
 }
 
-func dbg(format string, a ...interface{}) {
-	if Debug {
-		log.Printf(format+"\n", a...)
+func (h *Hook) isJSON() bool {
+	if _, ok := h.formatter.(*logrus.JSONFormatter); ok {
+		return true
+	} else if _, ok := h.formatter.(*FastFormatter); ok {
+		return true
+	} else if _, ok := h.formatter.(*logrus.TextFormatter); ok {
+		return false
+	}
+	b, err := h.formatter.Format(&logrus.Entry{})
+	if err != nil {
+		return false
 	}
+	str := strings.TrimSpace(string(b))
+	return strings.HasPrefix(str, "{") && strings.HasSuffix(str, "}")
 }
@@ -7,10 +7,12 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/bin3377/logrus-datadog-hook/intake"
 	"github.com/sirupsen/logrus"
 )
 
@@ -44,7 +46,6 @@ func equals(tb testing.TB, exp, act interface{}) {
 func getLogger(t *testing.T, formatter logrus.Formatter) (*Hook, *logrus.Logger) {
 	host := os.Getenv("DATADOG_HOST")
 	apiKey := os.Getenv("DATADOG_APIKEY")
-	Debug = true
 
 	if host == "" {
 		host = DatadogUSHost
@@ -55,6 +56,7 @@ func getLogger(t *testing.T, formatter logrus.Formatter) (*Hook, *logrus.Logger)
 
 	hostName, _ := os.Hostname()
 	hook := NewHook(host, apiKey, 1*time.Second, 3, logrus.TraceLevel, formatter, Options{Hostname: hostName})
+	hook.SetDebug(true)
 	l := logrus.New()
 	l.Level = logrus.TraceLevel
 	l.Hooks.Add(hook)
@@ -69,6 +71,44 @@ func TestHook(t *testing.T) {
 		}
 	}
 }
+func TestSetDebug(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+	other := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hook.SetDebug(i%2 == 0)
+			hook.client.Debugf("TestSetDebug - %d", i)
+		}(i)
+	}
+	wg.Wait()
+
+	hook.SetDebug(true)
+	equals(t, true, hook.client.Debug())
+	equals(t, false, other.client.Debug())
+	hook.SetDebug(false)
+	equals(t, false, hook.client.Debug())
+}
+
+func TestString(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "0123456789abcdef", 5*time.Second, 3, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{Service: "svc"})
+	str := hook.String()
+	assert(t, !strings.Contains(str, "0123456789abcdef"), "API key leaked in %q", str)
+	assert(t, strings.Contains(str, "minLevel=info"), "level missing in %q", str)
+	assert(t, strings.Contains(str, `service="svc"`), "service missing in %q", str)
+}
+
+func TestSeverity(t *testing.T) {
+	equals(t, intake.SeverityPanic, Severity(logrus.PanicLevel))
+	equals(t, intake.SeverityError, Severity(logrus.ErrorLevel))
+	equals(t, intake.SeverityInfo, Severity(logrus.InfoLevel))
+	equals(t, intake.SeverityTrace, Severity(logrus.TraceLevel))
+	equals(t, intake.SeverityUnknown, Severity(logrus.Level(42)))
+}
+
 func TestSending(t *testing.T) {
 	_, l := getLogger(t, &logrus.JSONFormatter{TimestampFormat: time.RFC3339})
 
@@ -0,0 +1,158 @@
+package datadog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// DatadogHook is what applications use of a hook, implemented by Hook,
+// NoopHook and MemoryHook so the implementation can be picked by environment
+type DatadogHook interface {
+	logrus.Hook
+	// Level, SetLevel - the least severe level fired entries are kept from
+	Level() logrus.Level
+	SetLevel(level logrus.Level)
+	// Flush - wait for the entries fired to be handled
+	Flush() error
+	FlushWithContext(ctx context.Context) error
+	// Close - stop taking entries, handling the ones fired before ctx is done
+	Close(ctx context.Context) error
+}
+
+var (
+	_ DatadogHook = (*Hook)(nil)
+	_ DatadogHook = (*NoopHook)(nil)
+	_ DatadogHook = (*MemoryHook)(nil)
+)
+
+// NoopHook discards every entry, e.g. for local runs without an API key
+type NoopHook struct {
+	level uint32
+}
+
+// NewNoopHook - create hook discarding entries
+func NewNoopHook() *NoopHook {
+	return &NoopHook{level: uint32(logrus.InfoLevel)}
+}
+
+// Levels - implement Hook interface supporting all levels
+func (h *NoopHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - implement Hook interface discarding the entry
+func (h *NoopHook) Fire(*logrus.Entry) error {
+	return nil
+}
+
+// Level - the least severe level of the entries kept
+func (h *NoopHook) Level() logrus.Level {
+	return logrus.Level(atomic.LoadUint32(&h.level))
+}
+
+// SetLevel - change the least severe level of the entries kept
+func (h *NoopHook) SetLevel(level logrus.Level) {
+	atomic.StoreUint32(&h.level, uint32(level))
+}
+
+// Flush - nothing to do
+func (h *NoopHook) Flush() error {
+	return nil
+}
+
+// FlushWithContext - nothing to do
+func (h *NoopHook) FlushWithContext(context.Context) error {
+	return nil
+}
+
+// Close - nothing to do
+func (h *NoopHook) Close(context.Context) error {
+	return nil
+}
+
+// MemoryHook keeps the entries fired in memory, for tests asserting on what
+// would have been shipped
+type MemoryHook struct {
+	level  uint32
+	m      sync.Mutex
+	list   []*logrus.Entry
+	closed bool
+}
+
+// NewMemoryHook - create hook keeping the entries down to level
+func NewMemoryHook(level logrus.Level) *MemoryHook {
+	return &MemoryHook{level: uint32(level)}
+}
+
+// Levels - implement Hook interface supporting all levels, entries above
+// the level of the hook are skipped by Fire so it can change at any time
+func (h *MemoryHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - implement Hook interface keeping a copy of the entry, or
+// intake.ErrClosed once closed
+func (h *MemoryHook) Fire(entry *logrus.Entry) error {
+	if entry.Level > h.Level() {
+		return nil
+	}
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Buffer = nil
+	h.m.Lock()
+	defer h.m.Unlock()
+	if h.closed {
+		return intake.ErrClosed
+	}
+	h.list = append(h.list, &e)
+	return nil
+}
+
+// Entries - the entries kept so far, in the order they were fired
+func (h *MemoryHook) Entries() []*logrus.Entry {
+	h.m.Lock()
+	defer h.m.Unlock()
+	return append([]*logrus.Entry(nil), h.list...)
+}
+
+// Reset - forget the entries kept so far
+func (h *MemoryHook) Reset() {
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.list = nil
+}
+
+// Level - the least severe level of the entries kept
+func (h *MemoryHook) Level() logrus.Level {
+	return logrus.Level(atomic.LoadUint32(&h.level))
+}
+
+// SetLevel - change the least severe level of the entries kept
+func (h *MemoryHook) SetLevel(level logrus.Level) {
+	atomic.StoreUint32(&h.level, uint32(level))
+}
+
+// Flush - nothing to do, entries are kept as they are fired
+func (h *MemoryHook) Flush() error {
+	return nil
+}
+
+// FlushWithContext - nothing to do, entries are kept as they are fired
+func (h *MemoryHook) FlushWithContext(context.Context) error {
+	return nil
+}
+
+// Close - stop keeping entries, the ones kept are still available
+func (h *MemoryHook) Close(context.Context) error {
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.closed = true
+	return nil
+}
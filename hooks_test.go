@@ -0,0 +1,51 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMemoryHook(t *testing.T) {
+	var hook DatadogHook = NewMemoryHook(logrus.InfoLevel)
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.SetLevel(logrus.DebugLevel)
+	l.AddHook(hook)
+
+	fields := logrus.Fields{"user": "bob"}
+	l.WithFields(fields).Info("one")
+	l.Debug("skipped")
+	hook.SetLevel(logrus.DebugLevel)
+	l.Debug("two")
+	// the entries kept are copies
+	fields["user"] = "alice"
+
+	memory := hook.(*MemoryHook)
+	entries := memory.Entries()
+	equals(t, 2, len(entries))
+	equals(t, "one", entries[0].Message)
+	equals(t, logrus.Fields{"user": "bob"}, entries[0].Data)
+	equals(t, logrus.DebugLevel, entries[1].Level)
+
+	memory.Reset()
+	equals(t, 0, len(memory.Entries()))
+	ok(t, hook.Flush())
+	ok(t, hook.Close(context.Background()))
+	equals(t, true, errors.Is(hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Data: logrus.Fields{}}), intake.ErrClosed))
+	equals(t, 0, len(memory.Entries()))
+}
+
+func TestNoopHook(t *testing.T) {
+	var hook DatadogHook = NewNoopHook()
+	equals(t, logrus.InfoLevel, hook.Level())
+	hook.SetLevel(logrus.WarnLevel)
+	equals(t, logrus.WarnLevel, hook.Level())
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Data: logrus.Fields{}}))
+	ok(t, hook.FlushWithContext(context.Background()))
+	ok(t, hook.Close(context.Background()))
+}
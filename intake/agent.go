@@ -0,0 +1,42 @@
+package intake
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAgentAddr - address of the TCP log listener of a local Datadog
+	// Agent, as set up with logs_config in its configuration
+	DefaultAgentAddr = "localhost:10518"
+
+	// PathDirect - batches are posted to the Datadog HTTP intake
+	PathDirect = "direct"
+	// PathAgent - lines are forwarded to a local Datadog Agent
+	PathAgent = "agent"
+	// PathExporter - batches are delivered by Config.Exporter
+	PathExporter = "exporter"
+
+	agentDialTimeout = 200 * time.Millisecond
+)
+
+// agentNetwork - network and address of an agent address, "unix:" prefixed
+// addresses are unix sockets
+func agentNetwork(addr string) (string, string) {
+	if strings.HasPrefix(addr, "unix:") {
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	}
+	return "tcp", addr
+}
+
+// detectAgent - whether an Agent listens at addr
+func detectAgent(addr string) bool {
+	network, address := agentNetwork(addr)
+	conn, err := net.DialTimeout(network, address, agentDialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
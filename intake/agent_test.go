@@ -0,0 +1,55 @@
+package intake
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+)
+
+func TestDetectAgent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				s := bufio.NewScanner(conn)
+				for s.Scan() {
+					lines <- s.Text()
+				}
+			}()
+		}
+	}()
+
+	c := New(Config{DetectAgent: true, AgentAddr: l.Addr().String(), JSON: true})
+	equals(t, PathAgent, c.Stats().Path)
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	ok(t, c.Close(context.Background()))
+	equals(t, `{"msg":"one"}`, <-lines)
+	equals(t, `{"msg":"two"}`, <-lines)
+
+	// nothing listens anymore, so batches go to the intake
+	l.Close()
+	c = New(Config{DetectAgent: true, AgentAddr: l.Addr().String()})
+	equals(t, PathDirect, c.Stats().Path)
+	ok(t, c.Close(context.Background()))
+	c = New(Config{})
+	equals(t, PathDirect, c.Stats().Path)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestAgentNetwork(t *testing.T) {
+	network, addr := agentNetwork("unix:/var/run/datadog/logs.sock")
+	equals(t, "unix", network)
+	equals(t, "/var/run/datadog/logs.sock", addr)
+	network, addr = agentNetwork(DefaultAgentAddr)
+	equals(t, "tcp", network)
+	equals(t, DefaultAgentAddr, addr)
+}
@@ -0,0 +1,108 @@
+package intake
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// AttemptsField - attribute of the JSON lines written to the fallback with
+// the delivery attempts of their batch, read back by ReplayFiles so the
+// history survives restarts and shows up in Datadog
+const AttemptsField = "delivery_attempts"
+
+// attemptsKey - AttemptsField as it appears in a JSON line
+var attemptsKey = []byte(`"` + AttemptsField + `"`)
+
+// Attempts - delivery history of a batch, carried across retries, and
+// across restarts through the fallback and ReplayFiles
+type Attempts struct {
+	// Count - attempts made
+	Count int `json:"count"`
+	// First - when the first attempt was made
+	First time.Time `json:"first"`
+	// LastError - error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+}
+
+// RetryPolicy - whether a batch which failed with err after the attempts a
+// is tried again. a includes the attempts made before a restart.
+type RetryPolicy func(a Attempts, err error) bool
+
+// merge - the history of lines batched together: the most attempts and the
+// earliest first one
+func (a Attempts) merge(o Attempts) Attempts {
+	if o.Count > a.Count {
+		a.Count, a.LastError = o.Count, o.LastError
+	}
+	if !o.First.IsZero() && (a.First.IsZero() || o.First.Before(a.First)) {
+		a.First = o.First
+	}
+	return a
+}
+
+// attempt - a with one more attempt started at now
+func (a Attempts) attempt(now time.Time) Attempts {
+	a.Count++
+	if a.First.IsZero() {
+		a.First = now
+	}
+	return a
+}
+
+// retry - whether the batch is tried again after failing with err, the
+// i-th attempt of this client, by the RetryPolicy or MaxRetry
+func (c *Client) retry(s *Settings, i int, a Attempts, err error) bool {
+	if p := c.config.RetryPolicy; p != nil {
+		return p(a, err)
+	}
+	return s.MaxRetry >= 0 && i < s.MaxRetry
+}
+
+// withAttempts - the JSON line carrying a in AttemptsField, in place of the
+// one it may already have, the line as is if it is not an object
+func withAttempts(line []byte, a Attempts) []byte {
+	attr, err := json.Marshal(a)
+	if err != nil {
+		return line
+	}
+	if bytes.Contains(line, attemptsKey) {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(line, &fields) != nil {
+			return line
+		}
+		fields[AttemptsField] = attr
+		if out, err := json.Marshal(fields); err == nil {
+			return out
+		}
+		return line
+	}
+	line = bytes.TrimSpace(line)
+	if len(line) < 2 || line[0] != '{' || line[len(line)-1] != '}' {
+		return line
+	}
+	out := make([]byte, 0, len(line)+len(attemptsKey)+len(attr)+2)
+	out = append(out, line[:len(line)-1]...)
+	if len(bytes.TrimSpace(line[1:len(line)-1])) > 0 {
+		out = append(out, ',')
+	}
+	out = append(out, attemptsKey...)
+	out = append(out, ':')
+	out = append(out, attr...)
+	return append(out, '}')
+}
+
+// lineAttempts - the attempts a JSON line carries in AttemptsField, zero if
+// none
+func lineAttempts(line []byte) Attempts {
+	if !bytes.Contains(line, attemptsKey) {
+		return Attempts{}
+	}
+	var fields struct {
+		Attempts Attempts `json:"delivery_attempts"`
+	}
+	if json.Unmarshal(line, &fields) != nil {
+		return Attempts{}
+	}
+	return fields.Attempts
+}
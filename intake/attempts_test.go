@@ -0,0 +1,97 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithAttempts(t *testing.T) {
+	a := Attempts{Count: 3, First: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), LastError: "down"}
+	attr := `"delivery_attempts":{"count":3,"first":"2020-01-02T03:04:05Z","last_error":"down"}`
+	for _, tc := range []struct {
+		line, exp string
+	}{
+		{`{"msg":"one"}`, `{"msg":"one",` + attr + `}`},
+		{`{}`, `{` + attr + `}`},
+		// replaced, keys are sorted then
+		{`{"msg":"one","delivery_attempts":{"count":1}}`, `{` + attr + `,"msg":"one"}`},
+		{`not json`, `not json`},
+	} {
+		line := withAttempts([]byte(tc.line), a)
+		equals(t, tc.exp, string(line))
+		if tc.line != "not json" {
+			equals(t, a, lineAttempts(line))
+		}
+	}
+	equals(t, Attempts{}, lineAttempts([]byte(`{"msg":"one"}`)))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var seen []Attempts
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	c := New(Config{
+		Clock:    clock,
+		MaxRetry: 10,
+		RetryPolicy: func(a Attempts, err error) bool {
+			seen = append(seen, a)
+			return a.Count < 3
+		},
+		Exporter: ExporterFunc(func(b *Batch) error {
+			return errors.New("down")
+		}),
+	})
+	ok(t, c.Push([]byte("one")))
+	equals(t, true, errors.Is(c.Close(context.Background()), ErrNotDelivered))
+	// the policy takes precedence over MaxRetry
+	equals(t, int64(3), c.Stats().Attempts)
+	equals(t, []Attempts{
+		{Count: 1, First: clock.now, LastError: "down"},
+		{Count: 2, First: clock.now, LastError: "down"},
+		{Count: 3, First: clock.now, LastError: "down"},
+	}, seen)
+}
+
+func TestAttemptsAcrossReplay(t *testing.T) {
+	first := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "dead-letter.log")
+	f, err := os.Create(path)
+	ok(t, err)
+	c := New(Config{
+		JSON:     true,
+		MaxRetry: 2,
+		Fallback: f,
+		Clock:    &fakeClock{now: first},
+		Exporter: ExporterFunc(func(b *Batch) error { return errors.New("down") }),
+	})
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	equals(t, true, errors.Is(c.Close(context.Background()), ErrNotDelivered))
+	ok(t, f.Close())
+
+	var m sync.Mutex
+	var attempts []Attempts
+	result, err := ReplayFiles(context.Background(), []string{path}, ReplayOptions{Config: Config{
+		JSON: true,
+		Exporter: ExporterFunc(func(b *Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			attempts = append(attempts, b.Attempts)
+			return nil
+		}),
+	}})
+	ok(t, err)
+	equals(t, int64(1), result.Delivered)
+	m.Lock()
+	defer m.Unlock()
+	// the third attempt, since the first one before the restart
+	equals(t, []Attempts{{Count: 3, First: first, LastError: "down"}}, attempts)
+
+	b, err := ioutil.ReadFile(path)
+	ok(t, err)
+	equals(t, Attempts{Count: 2, First: first, LastError: "down"}, lineAttempts(b))
+}
@@ -0,0 +1,406 @@
+package intake
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a line queued with the stream it belongs to
+type Entry struct {
+	Stream Stream
+	Line   []byte
+	// Local - rendering of the entry for local sinks such as the fallback,
+	// which get Line when empty
+	Local    []byte
+	Severity Severity
+	// Time - when the entry happened, placing it in a window of an aligned
+	// policy, the time it is batched when zero
+	Time time.Time
+
+	ack      chan<- error
+	attempts Attempts
+	// replay - pushed by the replay of the spool, which waits for room
+	// whatever Config.Overflow says rather than losing spooled entries
+	replay bool
+}
+
+// release - give the buffers of a framed entry back to the pool
+func (e Entry) release() {
+	putBuffer(e.Line)
+	if e.Local != nil {
+		putBuffer(e.Local)
+	}
+}
+
+// batch - lines of a stream waiting to be sent
+type batch struct {
+	stream   Stream
+	lines    [][]byte
+	local    [][]byte
+	size     int
+	created  time.Time
+	severity Severity
+	window   time.Time
+	overhead int // bytes the protocol adds to every line of the stream
+	acks     []chan<- error
+	attempts Attempts // history of the lines replayed
+
+	// done - closed once the batch was delivered or dropped with err
+	done chan struct{}
+	err  error
+}
+
+// release - give the buffers of the batch back to the pool
+func (b *batch) release() {
+	for _, line := range b.lines {
+		putBuffer(line)
+	}
+	for _, line := range b.local {
+		if line != nil {
+			putBuffer(line)
+		}
+	}
+	b.lines, b.local = nil, nil
+}
+
+func (c *Client) newBatch(stream Stream) *batch {
+	return &batch{stream: stream, lines: make([][]byte, 0, maxArraySize), created: c.clock().Now(), done: make(chan struct{})}
+}
+
+func (b *batch) info() BatchInfo {
+	return BatchInfo{
+		Stream:      b.stream,
+		Entries:     len(b.lines),
+		Bytes:       b.size,
+		Created:     b.created,
+		MaxSeverity: b.severity,
+		Window:      b.window,
+	}
+}
+
+// Push - queue a line to be sent in the next batch of the default stream
+func (c *Client) Push(line []byte) error {
+	return c.PushEntry(Entry{Stream: c.config.Stream, Line: line})
+}
+
+// PushStream - queue a line to be sent in the next batch of the given stream
+func (c *Client) PushStream(stream Stream, line []byte) error {
+	return c.PushEntry(Entry{Stream: stream, Line: line})
+}
+
+// PushEntry - queue an entry to be sent in the next batch of its stream. The
+// line is copied, callers may reuse it as soon as PushEntry returns.
+func (c *Client) PushEntry(e Entry) error {
+	return c.push(context.Background(), e, nil)
+}
+
+// push - queue an entry until ctx is done, ack receives the delivery error
+// of its batch when set
+func (c *Client) push(ctx context.Context, e Entry, ack chan<- error) error {
+	if c.err != nil {
+		return c.err
+	}
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrClosed
+	}
+	line, err := c.truncate(e.Line)
+	if err != nil {
+		return err
+	}
+	if e.Line = c.frame(line); e.Line == nil {
+		if ack != nil {
+			ack <- nil
+		}
+		return nil
+	}
+	if err := c.checkLineSize(e.Stream, e.Line); err != nil {
+		return err
+	}
+	if len(e.Local) > 0 && c.config.Fallback != nil {
+		e.Local = frameLocal(e.Local)
+	} else {
+		e.Local = nil
+	}
+	e.ack = ack
+	n := len(e.Line)
+	if err := c.reserve(ctx, n, e.replay); err == errQueueFull {
+		// nothing to give back, the room was not taken
+		atomic.AddInt64(&c.stats.overflowed, 1)
+		if ack != nil {
+			ack <- ErrOverflow
+		}
+		e.release()
+		return nil
+	} else if err != nil {
+		e.release()
+		return err
+	}
+	if c.shards != nil {
+		pushed, err := c.shards.push(e)
+		if err != nil {
+			c.unreserve(n)
+			e.release()
+			return err
+		}
+		if pushed {
+			atomic.AddInt64(&c.stats.pushed, 1)
+			return nil
+		}
+		// the shard is full, the entry waits in the lanes
+	}
+	if c.enqueue(e) {
+		return nil
+	}
+	select {
+	case c.lanes[laneOf(e.Severity)] <- e:
+		atomic.AddInt64(&c.stats.pushed, 1)
+		return nil
+	case <-c.done:
+		c.unreserve(n)
+		e.release()
+		return ErrClosed
+	case <-ctx.Done():
+		c.unreserve(n)
+		e.release()
+		return ctx.Err()
+	}
+}
+
+// frameLocal - a pooled copy of a local rendering ending with a newline
+func frameLocal(line []byte) []byte {
+	line = bytes.TrimRight(line, "\n")
+	framed := getBuffer(len(line) + 1)
+	framed = append(framed, line...)
+	return append(framed, '\n')
+}
+
+// frame - a pooled copy of line ending with the separator of the batch
+// format, nil if the line is empty
+func (c *Client) frame(line []byte) []byte {
+	if c.config.JSON {
+		line = bytes.TrimRight(line, "\n")
+	} else {
+		line = bytes.TrimSuffix(line, []byte("\n"))
+	}
+	if len(line) == 0 {
+		return nil
+	}
+	framed := getBuffer(len(line) + 1)
+	framed = append(framed, line...)
+	if c.config.JSON {
+		return append(framed, ',')
+	}
+	return append(framed, '\n')
+}
+
+// pile - batch the queued entries until the client is closed, restarting
+// the batching loop whenever it panics, e.g. in a user-provided callback
+func (c *Client) pile(next func() <-chan time.Time) {
+	defer close(c.stopped)
+	// batches survive restarts, entries already piled up are not lost
+	piles := map[string]*batch{}
+	for !c.batching(next, piles) {
+	}
+}
+
+// batching - the batching loop, false if it stopped on a recovered panic
+func (c *Client) batching(next func() <-chan time.Time, piles map[string]*batch) (done bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.recovered(v)
+		}
+	}()
+	policy := c.policy()
+	var keyBuf []byte
+	add := func(e Entry) {
+		c.unreserve(len(e.Line))
+		proto := c.protocol()
+		keyBuf = e.Stream.appendKey(keyBuf[:0])
+		window, aligned := c.window(policy, e)
+		if aligned {
+			// entries of different windows never share a batch
+			keyBuf = binary.BigEndian.AppendUint64(keyBuf, uint64(window.UnixNano()))
+		}
+		fresh := func() *batch {
+			b := c.newBatch(e.Stream)
+			b.window = window
+			b.overhead = proto.overhead(c, e.Stream)
+			piles[string(keyBuf)] = b
+			return b
+		}
+		b, ok := piles[string(keyBuf)]
+		if !ok {
+			b = fresh()
+		}
+		size, format := proto.size(c, e.Line)+b.overhead, proto.contentType(c)
+		// the payload with the line would go over the limits of the protocol,
+		// a line alone always fits as push refuses the others
+		if (len(b.lines) > 0 && !b.fits(size, format, c.payloadLimit())) || len(b.lines) == proto.maxEntries() {
+			c.dispatch(b)
+			b = fresh()
+		}
+		b.lines = append(b.lines, e.Line)
+		if e.Local != nil || b.local != nil {
+			if b.local == nil {
+				b.local = make([][]byte, len(b.lines)-1, cap(b.lines))
+			}
+			b.local = append(b.local, e.Local)
+		}
+		if e.ack != nil {
+			b.acks = append(b.acks, e.ack)
+		}
+		b.size += size
+		b.attempts = b.attempts.merge(e.attempts)
+		if e.Severity > b.severity {
+			b.severity = e.Severity
+		}
+		if policy.OnAdd(b.info()) {
+			c.dispatch(b)
+			delete(piles, string(keyBuf))
+		}
+	}
+	tick := func(now time.Time) {
+		for key, b := range piles {
+			if policy.OnTick(b.info(), now) {
+				c.dispatch(b)
+				delete(piles, key)
+			}
+		}
+	}
+	flush := func() {
+		for key, b := range piles {
+			c.dispatch(b)
+			delete(piles, key)
+		}
+	}
+	queue := newLanes(c.lanes, c.config.LaneWeights)
+	// flushing - send the queued entries and the piles, replying the batches
+	// in flight once they are dispatched
+	sweep := c.sweeper()
+	flushing := func(reply chan<- []*batch) {
+		if c.shards != nil {
+			c.shards.sweep(add, false)
+		}
+		for {
+			e, ok := queue.poll()
+			if !ok {
+				break
+			}
+			add(e)
+		}
+		flush()
+		reply <- c.flying()
+	}
+	drain := func() {
+		if c.shards != nil {
+			// pushes to the shards fail with ErrClosed from now on
+			c.shards.sweep(add, true)
+		}
+		for {
+			e, ok := queue.poll()
+			if !ok {
+				flush()
+				return
+			}
+			add(e)
+		}
+	}
+	ticker := next()
+	for {
+		e, ok := queue.poll()
+		if !ok {
+			// no lane holds entries, wait for any
+			select {
+			case e = <-c.lanes[LaneHigh]:
+			case e = <-c.lanes[LaneNormal]:
+			case e = <-c.lanes[LaneLow]:
+			case now := <-ticker:
+				tick(now)
+				ticker = next()
+				continue
+			case <-sweep.wake:
+				c.armSweep(sweep)
+				continue
+			case <-sweep.timer:
+				sweep.timer = nil
+				c.shards.sweep(add, false)
+				continue
+			case reply := <-c.flushes:
+				flushing(reply)
+				continue
+			case <-c.done:
+				drain()
+				return true
+			}
+		}
+		add(e)
+		// entries keep coming under load, ticks and Close are not held back
+		select {
+		case now := <-ticker:
+			tick(now)
+			ticker = next()
+		case <-sweep.wake:
+			c.armSweep(sweep)
+		case <-sweep.timer:
+			sweep.timer = nil
+			c.shards.sweep(add, false)
+		case reply := <-c.flushes:
+			flushing(reply)
+		case <-c.done:
+			drain()
+			return true
+		default:
+		}
+	}
+}
+
+// window - start of the window of the entry under an aligned policy
+func (c *Client) window(policy FlushPolicy, e Entry) (time.Time, bool) {
+	w, ok := policy.(windowed)
+	if !ok {
+		return time.Time{}, false
+	}
+	t := e.Time
+	if t.IsZero() {
+		t = c.clock().Now()
+	}
+	return w.window(t)
+}
+
+// dispatch - hand a batch over to a send goroutine, keeping track of it for Close
+func (c *Client) dispatch(b *batch) {
+	if len(b.lines) == 0 {
+		return
+	}
+	c.accompany(b)
+	c.inflight.Add(1)
+	atomic.AddInt64(&c.pending, int64(len(b.lines)))
+	c.fly(b)
+	go func() {
+		defer c.inflight.Done()
+		entries, acks := len(b.lines), b.acks
+		err := errDropped
+		defer func() {
+			for _, ack := range acks {
+				ack <- err
+			}
+			if err == errSpooled {
+				// counted as Spooled, the replay delivers the entries
+				err = nil
+			} else {
+				c.count(entries, err)
+			}
+			c.finish(entries, err)
+			c.land(b, err)
+		}()
+		defer func() {
+			if v := recover(); v != nil {
+				c.recovered(v)
+			}
+		}()
+		err = c.send(b)
+	}()
+}
@@ -0,0 +1,101 @@
+package intake
+
+import "strings"
+
+const (
+	// BreakdownOther - the value entries are counted under once a dimension
+	// of the breakdown reached its cardinality limit
+	BreakdownOther = "_other"
+
+	defaultBreakdownLimit = 100
+)
+
+// Usage count what was delivered for a value of the breakdown
+type Usage struct {
+	Entries int64
+	Bytes   int64
+}
+
+// Breakdown is the usage delivered by service, source and tag, to attribute
+// the cost of logs to the teams owning them
+type Breakdown struct {
+	Services map[string]Usage
+	Sources  map[string]Usage
+	// Tags - usage by value of each tag key of Config.BreakdownTags
+	Tags map[string]map[string]Usage
+}
+
+// Breakdown - copy of the usage delivered since the client was created
+func (c *Client) Breakdown() Breakdown {
+	c.usageLock.Lock()
+	defer c.usageLock.Unlock()
+	b := Breakdown{
+		Services: copyUsage(c.usage.Services),
+		Sources:  copyUsage(c.usage.Sources),
+		Tags:     make(map[string]map[string]Usage, len(c.usage.Tags)),
+	}
+	for k, v := range c.usage.Tags {
+		b.Tags[k] = copyUsage(v)
+	}
+	return b
+}
+
+// account - add a delivered batch to the breakdown
+func (c *Client) account(stream Stream, entries, bytes int) {
+	u := Usage{Entries: int64(entries), Bytes: int64(bytes)}
+	limit := c.config.BreakdownLimit
+	if limit <= 0 {
+		limit = defaultBreakdownLimit
+	}
+	c.usageLock.Lock()
+	defer c.usageLock.Unlock()
+	if c.usage.Services == nil {
+		c.usage = Breakdown{Services: map[string]Usage{}, Sources: map[string]Usage{}, Tags: map[string]map[string]Usage{}}
+	}
+	addUsage(c.usage.Services, stream.Service, u, limit)
+	addUsage(c.usage.Sources, stream.Source, u, limit)
+	c.accountZone(stream, u, limit)
+	for _, key := range c.config.BreakdownTags {
+		value, ok := tagValue(stream.Tags, key)
+		if !ok {
+			continue
+		}
+		values := c.usage.Tags[key]
+		if values == nil {
+			values = map[string]Usage{}
+			c.usage.Tags[key] = values
+		}
+		addUsage(values, value, u, limit)
+	}
+}
+
+// addUsage - add u to the usage of value, or of BreakdownOther if m already
+// holds limit values
+func addUsage(m map[string]Usage, value string, u Usage, limit int) {
+	if _, ok := m[value]; !ok && len(m) >= limit {
+		value = BreakdownOther
+	}
+	total := m[value]
+	total.Entries += u.Entries
+	total.Bytes += u.Bytes
+	m[value] = total
+}
+
+func copyUsage(m map[string]Usage) map[string]Usage {
+	c := make(map[string]Usage, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// tagValue - the value of the last tag with key, as the last one wins in Datadog
+func tagValue(tags []string, key string) (string, bool) {
+	value, found := "", false
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, key+":") {
+			value, found = tag[len(key)+1:], true
+		}
+	}
+	return value, found
+}
@@ -0,0 +1,69 @@
+package intake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBreakdown(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{BreakdownTags: []string{"team"}, BreakdownLimit: 2})
+	equals(t, Breakdown{Services: map[string]Usage{}, Sources: map[string]Usage{}, Tags: map[string]map[string]Usage{}}, c.Breakdown())
+
+	push := func(service, team string) {
+		ok(t, c.PushStream(Stream{Source: "go", Service: service, Tags: []string{"env:prod", "team:" + team}}, []byte("line")))
+	}
+	// batches are sent one by one so values are counted in order
+	for _, s := range [][2]string{{"api", "core"}, {"api", "core"}, {"worker", "data"}, {"cron", "ops"}} {
+		push(s[0], s[1])
+		tick <- time.Now()
+		<-reqs
+	}
+	ok(t, c.Close(context.Background()))
+
+	b := c.Breakdown()
+	equals(t, map[string]Usage{
+		"api":          {Entries: 2, Bytes: 10},
+		"worker":       {Entries: 1, Bytes: 5},
+		BreakdownOther: {Entries: 1, Bytes: 5},
+	}, b.Services)
+	equals(t, map[string]Usage{"go": {Entries: 4, Bytes: 20}}, b.Sources)
+	equals(t, map[string]Usage{
+		"core":         {Entries: 2, Bytes: 10},
+		"data":         {Entries: 1, Bytes: 5},
+		BreakdownOther: {Entries: 1, Bytes: 5},
+	}, b.Tags["team"])
+	_, found := b.Tags["env"]
+	equals(t, false, found)
+}
+
+func TestBreakdownZones(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{})
+	equals(t, map[string]Usage(nil), c.Stats().Zones)
+
+	for _, zone := range []string{"us-east-1a", "us-east-1a", "us-east-1b", ""} {
+		stream := Stream{Source: "go", Tags: []string{"env:prod"}}
+		if zone != "" {
+			stream.Tags = append(stream.Tags, ZoneTag+":"+zone)
+		}
+		ok(t, c.PushStream(stream, []byte("line")))
+		tick <- time.Now()
+		<-reqs
+	}
+	ok(t, c.Close(context.Background()))
+
+	equals(t, map[string]Usage{
+		"us-east-1a": {Entries: 2, Bytes: 10},
+		"us-east-1b": {Entries: 1, Bytes: 5},
+	}, c.Stats().Zones)
+}
+
+func TestTagValue(t *testing.T) {
+	v, found := tagValue([]string{"team:a", "teams:b", "team:c"}, "team")
+	equals(t, "c", v)
+	equals(t, true, found)
+	_, found = tagValue([]string{"env:prod"}, "team")
+	equals(t, false, found)
+}
@@ -0,0 +1,72 @@
+package intake
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCircuitCooldown - how long the circuit stays open by default
+const DefaultCircuitCooldown = 30 * time.Second
+
+// ErrCircuitOpen - the circuit is open after Config.CircuitFailures
+// consecutive failures, batches are spooled without being attempted
+var ErrCircuitOpen = errors.New("intake: circuit open")
+
+// circuit - consecutive failed attempts, and until when the circuit is open.
+// Only send updates them, with the client locked.
+type circuit struct {
+	failures int
+	// open - unix nanoseconds the circuit is open until, still set once
+	// over while the next attempt probes whether the intake is back
+	open int64
+}
+
+// circuitWait - how long the circuit stays open, zero when attempts may go
+func (c *Client) circuitWait() time.Duration {
+	if c.config.CircuitFailures <= 0 {
+		return 0
+	}
+	open := atomic.LoadInt64(&c.circuit.open)
+	if open == 0 {
+		return 0
+	}
+	if d := time.Duration(open - c.clock().Now().UnixNano()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// circuitResult - account an attempt, opening the circuit after
+// CircuitFailures consecutive failures or a failed probe, closing it once an
+// attempt succeeds
+func (c *Client) circuitResult(err error) {
+	if c.config.CircuitFailures <= 0 {
+		return
+	}
+	cb := &c.circuit
+	if err == nil {
+		if atomic.SwapInt64(&cb.open, 0) != 0 {
+			c.Debugf("Circuit closed, the intake is back")
+		}
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures < c.config.CircuitFailures && atomic.LoadInt64(&cb.open) == 0 {
+		return
+	}
+	cooldown := c.config.CircuitCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitCooldown
+	}
+	atomic.StoreInt64(&cb.open, c.clock().Now().Add(cooldown).UnixNano())
+	atomic.AddInt64(&c.stats.circuitOpens, 1)
+	cb.failures = 0
+	c.Debugf("Circuit open for %v after %v", cooldown, err)
+}
+
+// CircuitOpen - whether attempts are held back by the circuit breaker
+func (c *Client) CircuitOpen() bool {
+	return c.circuitWait() > 0
+}
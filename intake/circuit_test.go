@@ -0,0 +1,78 @@
+package intake
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitSpooled(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	e := &flakyExporter{down: 1}
+	c := New(Config{
+		SpoolDir:        t.TempDir(),
+		MaxRetry:        5,
+		CircuitFailures: 2,
+		CircuitCooldown: time.Minute,
+		FlushPolicy:     MaxEntries(1),
+		Clock:           clock,
+		Exporter:        e,
+	})
+	// two failed attempts open the circuit, the batch and the next one are
+	// spooled without being attempted
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Flush(context.Background()))
+	ok(t, c.Push([]byte("two")))
+	ok(t, c.Flush(context.Background()))
+	equals(t, true, c.CircuitOpen())
+	stats := c.Stats()
+	equals(t, int64(2), stats.Attempts)
+	equals(t, int64(2), stats.Spooled)
+	equals(t, int64(0), stats.Dropped)
+	equals(t, int64(1), stats.CircuitOpens)
+
+	// once the cooldown is over, a probe finds the intake back
+	clock.m.Lock()
+	clock.now = clock.now.Add(time.Minute)
+	clock.m.Unlock()
+	equals(t, false, c.CircuitOpen())
+	atomic.StoreInt32(&e.down, 0)
+	ok(t, c.Push([]byte("three")))
+	ok(t, c.Flush(context.Background()))
+	equals(t, 3, len(e.delivered(3)))
+	equals(t, false, c.CircuitOpen())
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(1), c.Stats().CircuitOpens)
+}
+
+func TestCircuitHeld(t *testing.T) {
+	clock := &instantClock{fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	c := New(Config{
+		MaxRetry:        2,
+		CircuitFailures: 1,
+		Clock:           clock,
+		Exporter:        &flakyExporter{down: 1},
+	})
+	ok(t, c.Push([]byte("one")))
+	closeDropping(t, c)
+	// held for the cooldown, then the probe failing opens the circuit again
+	cooldowns := 0
+	for _, d := range clock.Waits() {
+		if d == DefaultCircuitCooldown {
+			cooldowns++
+		}
+	}
+	equals(t, 1, cooldowns)
+	equals(t, int64(2), c.Stats().Attempts)
+	equals(t, int64(2), c.Stats().CircuitOpens)
+}
+
+func TestCircuitDisabled(t *testing.T) {
+	c := New(Config{MaxRetry: 3, Exporter: &flakyExporter{down: 1}})
+	ok(t, c.Push([]byte("one")))
+	closeDropping(t, c)
+	equals(t, int64(3), c.Stats().Attempts)
+	equals(t, int64(0), c.Stats().CircuitOpens)
+	equals(t, false, c.CircuitOpen())
+}
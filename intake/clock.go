@@ -0,0 +1,32 @@
+package intake
+
+import "time"
+
+// Clock is the source of time of a Client, replaced in simulations and load
+// tests to run batching on a virtual time
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock - the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clock - the configured clock, or the wall clock
+func (c *Client) clock() Clock {
+	if c.config.Clock != nil {
+		return c.config.Clock
+	}
+	return systemClock{}
+}
+
+// seed - the seed of the client's random source, from the clock if not configured
+func (c *Client) seed() int64 {
+	if c.config.Seed != 0 {
+		return c.config.Seed
+	}
+	return c.clock().Now().UnixNano()
+}
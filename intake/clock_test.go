@@ -0,0 +1,75 @@
+package intake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock frozen at now, recording the durations waited for
+type fakeClock struct {
+	m     sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.waits = append(f.waits, d)
+	return make(chan time.Time)
+}
+
+func (f *fakeClock) Waits() []time.Duration {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return append([]time.Duration(nil), f.waits...)
+}
+
+func TestSeed(t *testing.T) {
+	intervals := func() []time.Duration {
+		clock := &fakeClock{}
+		c := New(Config{BatchTimeout: 10 * time.Second, BatchJitter: time.Second, Seed: 42, Clock: clock})
+		ok(t, c.Close(context.Background()))
+		return clock.Waits()
+	}
+	first := intervals()
+	equals(t, 1, len(first))
+	equals(t, first, intervals())
+	equals(t, true, first[0] >= 10*time.Second && first[0] < 11*time.Second)
+}
+
+func TestClock(t *testing.T) {
+	srv, reqs := newServer(t)
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var records []AuditRecord
+	var created time.Time
+	c, tick := newClient(srv, Config{
+		Clock:   clock,
+		OnAudit: func(r AuditRecord) { records = append(records, r) },
+		FlushPolicy: policyFunc(func(b BatchInfo, now time.Time) bool {
+			created = b.Created
+			return true
+		}),
+	})
+	ok(t, c.Push([]byte("one")))
+	tick <- clock.now
+	<-reqs
+	ok(t, c.Close(context.Background()))
+	equals(t, clock.now, created)
+	equals(t, clock.now, records[0].Time)
+}
+
+// policyFunc - flush policy deciding on ticks with the function
+type policyFunc func(BatchInfo, time.Time) bool
+
+func (p policyFunc) Interval() time.Duration                { return time.Second }
+func (p policyFunc) OnAdd(BatchInfo) bool                   { return false }
+func (p policyFunc) OnTick(b BatchInfo, now time.Time) bool { return p(b, now) }
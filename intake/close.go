@@ -0,0 +1,118 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var (
+	// ErrClosed - the client was closed and doesn't accept lines anymore
+	ErrClosed = errors.New("intake: client closed")
+	// ErrNotDelivered - Close or Flush could not deliver every entry, the
+	// dropped ones went to the fallback if any
+	ErrNotDelivered = errors.New("intake: entries not delivered")
+)
+
+// DrainProgress reports how far Close got delivering the backlog
+type DrainProgress struct {
+	// EntriesTotal - entries waiting for delivery when draining started
+	EntriesTotal int
+	// EntriesRemaining - entries not yet delivered or dropped
+	EntriesRemaining int
+	// BatchesSent - batches delivered or dropped since draining started
+	BatchesSent int
+}
+
+// Percent - share of the backlog handled so far, 100 when there was nothing to drain
+func (p DrainProgress) Percent() float64 {
+	if p.EntriesTotal == 0 {
+		return 100
+	}
+	return 100 * float64(p.EntriesTotal-p.EntriesRemaining) / float64(p.EntriesTotal)
+}
+
+// Close - stop accepting lines, flush what is buffered and wait for every
+// batch to be delivered or dropped, or for ctx to be done. Batches dropped
+// once Close was called make it return ErrNotDelivered wrapping the last
+// delivery error. Client.Context is canceled when Close returns, and with
+// it the attempts in flight and their retries once Close gave up on its
+// deadline: calling Close again doesn't deliver them, it waits for them to
+// be dropped and reports them.
+func (c *Client) Close(ctx context.Context) error {
+	if c.err != nil {
+		return nil
+	}
+	c.closeOnce.Do(func() {
+		atomic.StoreInt32(&c.closed, 1)
+		close(c.done)
+	})
+
+	select {
+	case <-c.stopped:
+	case <-ctx.Done():
+		c.cancelCallbacks()
+		return ctx.Err()
+	}
+
+	c.drainLock.Lock()
+	if !c.draining {
+		c.draining = true
+		remaining := int(atomic.LoadInt64(&c.pending))
+		c.drain = DrainProgress{EntriesTotal: remaining, EntriesRemaining: remaining}
+		c.notifyDrain(c.drain)
+	}
+	c.drainLock.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		c.cancelCallbacks()
+		if c.conn != nil {
+			c.conn.close()
+		}
+		return c.closeErr()
+	case <-ctx.Done():
+		c.cancelCallbacks()
+		return ctx.Err()
+	}
+}
+
+// closeErr - the terminal error of Close, nil if every entry was delivered
+func (c *Client) closeErr() error {
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+	if c.undelivered == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d entries dropped while closing: %w", ErrNotDelivered, c.undelivered, c.lastErr)
+}
+
+// finish - account a batch of entries delivered or dropped with err,
+// reporting progress while draining
+func (c *Client) finish(entries int, err error) {
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+	if err != nil && atomic.LoadInt32(&c.closed) == 1 {
+		c.undelivered += entries
+		c.lastErr = err
+	}
+	remaining := atomic.AddInt64(&c.pending, -int64(entries))
+	if !c.draining {
+		return
+	}
+	c.drain.BatchesSent++
+	c.drain.EntriesRemaining = int(remaining)
+	c.notifyDrain(c.drain)
+}
+
+func (c *Client) notifyDrain(p DrainProgress) {
+	if fn := c.config.OnDrainProgress; fn != nil {
+		fn(p)
+	}
+}
@@ -0,0 +1,109 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloseDrainProgress(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	var m sync.Mutex
+	var progress []DrainProgress
+	started := make(chan struct{})
+	c, tick := newClient(srv, Config{OnDrainProgress: func(p DrainProgress) {
+		m.Lock()
+		defer m.Unlock()
+		progress = append(progress, p)
+		if len(progress) == 1 {
+			close(started)
+		}
+	}})
+
+	for i := 0; i < 3; i++ {
+		ok(t, c.Push([]byte(fmt.Sprintf("batch %d", i))))
+		tick <- time.Now()
+	}
+	ok(t, c.Push([]byte("buffered")))
+
+	done := make(chan error)
+	go func() { done <- c.Close(context.Background()) }()
+	<-started
+	close(release)
+	ok(t, <-done)
+	equals(t, ErrClosed, c.Push([]byte("late")))
+
+	m.Lock()
+	defer m.Unlock()
+	equals(t, DrainProgress{EntriesTotal: 4, EntriesRemaining: 4}, progress[0])
+	last := progress[len(progress)-1]
+	equals(t, DrainProgress{EntriesTotal: 4, EntriesRemaining: 0, BatchesSent: 4}, last)
+	equals(t, float64(100), last.Percent())
+	equals(t, 5, len(progress))
+}
+
+func TestCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, _ := newClient(srv, Config{})
+	ok(t, c.Push([]byte("stuck")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	equals(t, context.DeadlineExceeded, c.Close(ctx))
+}
+
+func TestDrainProgressPercent(t *testing.T) {
+	equals(t, float64(100), DrainProgress{}.Percent())
+	equals(t, float64(80), DrainProgress{EntriesTotal: 10, EntriesRemaining: 2}.Percent())
+}
+
+// closeDropping - close c, whose batches may be dropped before or after
+// Close was called depending on timing
+func closeDropping(tb testing.TB, c *Client) {
+	if err := c.Close(context.Background()); err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		if !errors.Is(err, ErrNotDelivered) {
+			log.Printf("%s:%d: unexpected error: %s\n\n", filepath.Base(file), line, err.Error())
+			tb.FailNow()
+		}
+	}
+}
+
+func TestCloseNotDelivered(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c, _ := newClient(srv, Config{FlushPolicy: Interval(time.Hour)})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+
+	// the backlog is flushed by Close, and dropped
+	err := c.Close(context.Background())
+	equals(t, true, errors.Is(err, ErrNotDelivered))
+	var status *StatusError
+	equals(t, true, errors.As(err, &status))
+	equals(t, http.StatusServiceUnavailable, status.Code)
+	equals(t, "intake: entries not delivered: 2 entries dropped while closing: intake: 503 Service Unavailable", err.Error())
+	equals(t, err.Error(), c.Close(context.Background()).Error())
+	equals(t, ErrClosed, c.Push([]byte("three")))
+}
@@ -0,0 +1,38 @@
+package intake
+
+import "sync/atomic"
+
+// accompany - add the companion line of Config.Companion to the batch about
+// to be dispatched, left out if it doesn't fit in the limits of the protocol,
+// only called from the pile goroutine
+func (c *Client) accompany(b *batch) {
+	if c.config.Companion == nil || len(b.lines) == 0 {
+		return
+	}
+	line := c.config.Companion(b.info())
+	if line == nil {
+		return
+	}
+	line, err := c.truncate(line)
+	if err != nil {
+		c.Debugf("Companion of a batch of %d entries left out, %v", len(b.lines), err)
+		return
+	}
+	if line = c.frame(line); line == nil {
+		return
+	}
+	proto := c.protocol()
+	size := proto.size(c, line) + b.overhead
+	if !b.fits(size, proto.contentType(c), c.payloadLimit()) || len(b.lines) == proto.maxEntries() {
+		c.Debugf("Companion of %d bytes left out of a full batch", len(line))
+		putBuffer(line)
+		return
+	}
+	b.lines = append(b.lines, line)
+	if b.local != nil {
+		// the fallback gets the line
+		b.local = append(b.local, nil)
+	}
+	b.size += size
+	atomic.AddInt64(&c.stats.pushed, 1)
+}
@@ -0,0 +1,58 @@
+package intake
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompanion(t *testing.T) {
+	var infos []BatchInfo
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{
+		JSON: true,
+		Companion: func(info BatchInfo) []byte {
+			infos = append(infos, info)
+			if info.MaxSeverity < SeverityError {
+				return nil
+			}
+			return []byte(`{"msg":"context"}` + "\n")
+		},
+	})
+
+	ok(t, c.PushEntry(Entry{Line: []byte(`{"msg":"one"}`), Severity: SeverityInfo}))
+	tick <- time.Now()
+	equals(t, `[{"msg":"one"}]`, (<-reqs).body)
+	ok(t, c.PushEntry(Entry{Line: []byte(`{"msg":"two"}`), Severity: SeverityInfo}))
+	ok(t, c.PushEntry(Entry{Line: []byte(`{"msg":"three"}`), Severity: SeverityError}))
+	tick <- time.Now()
+	equals(t, `[{"msg":"two"},{"msg":"three"},{"msg":"context"}]`, (<-reqs).body)
+	ok(t, c.Close(context.Background()))
+
+	equals(t, 2, len(infos))
+	equals(t, 2, infos[1].Entries)
+	equals(t, SeverityError, infos[1].MaxSeverity)
+	// the companion is counted as an entry of its own
+	equals(t, int64(4), c.Stats().Pushed)
+	equals(t, int64(4), c.Stats().Delivered)
+	equals(t, int64(0), c.Stats().Queued)
+}
+
+func TestCompanionFullBatch(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{
+		JSON:      true,
+		Companion: func(BatchInfo) []byte { return []byte(`{"msg":"context"}`) },
+	})
+
+	for i := 0; i < maxArraySize; i++ {
+		ok(t, c.PushEntry(Entry{Line: []byte(`{}`), Severity: SeverityError}))
+	}
+	tick <- time.Now()
+	body := (<-reqs).body
+	equals(t, maxArraySize, strings.Count(body, "{}"))
+	equals(t, false, strings.Contains(body, "context"))
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(maxArraySize), c.Stats().Delivered)
+}
@@ -0,0 +1,54 @@
+package intake
+
+import (
+	"compress/gzip"
+	"sync"
+)
+
+const (
+	// EncodingGzip - the payload is sent compressed with gzip
+	EncodingGzip = "gzip"
+	// EncodingIdentity - the payload is sent as is
+	EncodingIdentity = "identity"
+)
+
+// encoding - the content encoding of a payload of size bytes
+func (s *Settings) encoding(size int) string {
+	if !s.Compress || size < s.CompressionThreshold {
+		return EncodingIdentity
+	}
+	return EncodingGzip
+}
+
+// gzipWriters - compressors are large, so they are reused across batches
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// bufferWriter - io.Writer appending to a pooled buffer
+type bufferWriter struct {
+	b []byte
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
+
+// compress - the payload compressed with gzip into a pooled buffer
+func compress(payload []byte) ([]byte, error) {
+	// log lines compress well, a quarter of the payload is a good first guess
+	w := &bufferWriter{b: getBuffer(len(payload) / 4)}
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(w)
+	if _, err := zw.Write(payload); err != nil {
+		putBuffer(w.b)
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		putBuffer(w.b)
+		return nil, err
+	}
+	return w.b, nil
+}
@@ -0,0 +1,54 @@
+package intake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressionThreshold(t *testing.T) {
+	srv, reqs := newServer(t)
+	var records []AuditRecord
+	c, tick := newClient(srv, Config{
+		Compress:             true,
+		CompressionThreshold: 64,
+		MaxRetry:             1,
+		OnAudit:              func(r AuditRecord) { records = append(records, r) },
+	})
+
+	ok(t, c.Push([]byte("tiny")))
+	tick <- time.Now()
+	r := <-reqs
+	equals(t, "", r.header.Get("Content-Encoding"))
+	equals(t, "tiny\n", r.body)
+
+	line := strings.Repeat("large ", 20)
+	ok(t, c.Push([]byte(line)))
+	tick <- time.Now()
+	r = <-reqs
+	equals(t, EncodingGzip, r.header.Get("Content-Encoding"))
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(r.body)))
+	ok(t, err)
+	b, err := ioutil.ReadAll(zr)
+	ok(t, err)
+	equals(t, line+"\n", string(b))
+
+	ok(t, c.Close(context.Background()))
+	equals(t, 2, len(records))
+	equals(t, EncodingIdentity, records[0].Encoding)
+	equals(t, EncodingGzip, records[1].Encoding)
+	// the record counts the payload before compression
+	equals(t, len(line)+1, records[1].Bytes)
+}
+
+func TestCompressOff(t *testing.T) {
+	c := &Client{config: Config{CompressionThreshold: 1}}
+	equals(t, EncodingIdentity, c.settings().encoding(1024))
+	c.config.Compress = true
+	equals(t, EncodingGzip, c.settings().encoding(1024))
+	equals(t, EncodingIdentity, c.settings().encoding(0))
+}
@@ -0,0 +1,77 @@
+package intake
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// lineConn - connection of the line protocols, kept open between batches
+// and redialed after a failed write
+type lineConn struct {
+	network, addr string
+	tls           *tls.Config
+
+	m    sync.Mutex
+	conn net.Conn
+}
+
+func newLineConn(network, addr string, config *tls.Config) *lineConn {
+	return &lineConn{network: network, addr: addr, tls: config}
+}
+
+func (l *lineConn) write(payload []byte) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.conn == nil {
+		dialer := &net.Dialer{Timeout: defaultTimeout}
+		var conn net.Conn
+		var err error
+		if l.tls != nil {
+			conn, err = tls.DialWithDialer(dialer, l.network, l.addr, l.tls)
+		} else {
+			conn, err = dialer.Dial(l.network, l.addr)
+		}
+		if err != nil {
+			return err
+		}
+		l.conn = conn
+	}
+	l.conn.SetWriteDeadline(time.Now().Add(defaultTimeout))
+	if _, err := l.conn.Write(payload); err != nil {
+		// the connection is redialed on the next attempt
+		l.conn.Close()
+		l.conn = nil
+		return err
+	}
+	return nil
+}
+
+// close - close the connection
+func (l *lineConn) close() {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// tcpTLS - TLS settings of the connection to the TCP intake at addr, a clone
+// of config checking the certificate against the host of addr unless it sets
+// ServerName itself
+func tcpTLS(config *tls.Config, addr string) *tls.Config {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if config == nil {
+		return &tls.Config{ServerName: host}
+	}
+	config = config.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
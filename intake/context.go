@@ -0,0 +1,34 @@
+package intake
+
+import "context"
+
+// Context - context given to OnErrorContext and OnAuditContext and of the
+// requests to the intake, derived from Config.BaseContext. It is canceled once Close returns, delivered or given
+// up on its deadline, so callbacks still running stop instead of holding the
+// shutdown.
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// attemptContext - context of an attempt to deliver a batch, canceled after
+// the request timeout or with Client.Context
+func (c *Client) attemptContext() (context.Context, context.CancelFunc) {
+	timeout := c.config.RequestTimeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if timeout < 0 {
+		return context.WithCancel(c.Context())
+	}
+	return context.WithTimeout(c.Context(), timeout)
+}
+
+// cancelCallbacks - cancel the context of the callbacks, Close is done
+func (c *Client) cancelCallbacks() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
@@ -0,0 +1,110 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextCallbacks(t *testing.T) {
+	var audits int
+	released := make(chan error, 1)
+	c := New(Config{
+		Exporter: ExporterFunc(func(*Batch) error { return errors.New("down") }),
+		OnErrorContext: func(ctx context.Context, err error) {
+			// a callback taking forever, until shutdown gives up
+			<-ctx.Done()
+			released <- ctx.Err()
+		},
+		OnAuditContext: func(ctx context.Context, r AuditRecord) {
+			equals(t, nil, ctx.Err())
+			audits++
+		},
+	})
+	equals(t, nil, c.Context().Err())
+	ok(t, c.Push([]byte("one")))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	equals(t, context.DeadlineExceeded, c.Close(ctx))
+	equals(t, context.Canceled, <-released)
+	// the callback returned, so does the next Close
+	equals(t, true, errors.Is(c.Close(context.Background()), ErrNotDelivered))
+	equals(t, 1, audits)
+}
+
+func TestBaseContext(t *testing.T) {
+	base, cancel := context.WithCancel(context.Background())
+	c := New(Config{BaseContext: base, Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	equals(t, nil, c.Context().Err())
+	cancel()
+	<-c.Context().Done()
+	ok(t, c.Close(context.Background()))
+
+	// a client closed without a deadline cancels its context too
+	c = New(Config{Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	ok(t, c.Close(context.Background()))
+	equals(t, context.Canceled, c.Context().Err())
+}
+
+// hangingServer - intake never answering, the requests it got canceled are
+// sent on the returned channel
+func hangingServer(t *testing.T) (*httptest.Server, <-chan error) {
+	canceled := make(chan error, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the connection is watched for its closing once the body was read
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+		canceled <- r.Context().Err()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, canceled
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv, canceled := hangingServer(t)
+	errs := make(chan error, 1)
+	c, tick := newClient(srv, Config{
+		RequestTimeout: 50 * time.Millisecond,
+		OnError:        func(err error) { errs <- err },
+	})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	equals(t, true, errors.Is(<-errs, context.DeadlineExceeded))
+	equals(t, context.Canceled, <-canceled)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestLongRequestTimeout(t *testing.T) {
+	// nothing but the attempt context bounds a request, so a 45s timeout
+	// outlives the 30s default and a negative one has no deadline at all
+	c := New(Config{RequestTimeout: 45 * time.Second, Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	defer c.Close(context.Background())
+	ctx, cancel := c.attemptContext()
+	defer cancel()
+	deadline, found := ctx.Deadline()
+	equals(t, true, found)
+	equals(t, true, time.Until(deadline) > defaultTimeout)
+
+	c.config.RequestTimeout = -1
+	ctx, cancel = c.attemptContext()
+	defer cancel()
+	_, found = ctx.Deadline()
+	equals(t, false, found)
+}
+
+func TestCloseCancelsRequests(t *testing.T) {
+	srv, canceled := hangingServer(t)
+	c, tick := newClient(srv, Config{RequestTimeout: -1})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	equals(t, context.DeadlineExceeded, c.Close(ctx))
+	// the attempt still hanging is canceled
+	equals(t, context.Canceled, <-canceled)
+	equals(t, true, errors.Is(c.Close(context.Background()), ErrNotDelivered))
+}
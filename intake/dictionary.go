@@ -0,0 +1,232 @@
+package intake
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	// EncodingDictionary - the payload is sent as a zlib stream compressed
+	// with the preset dictionary named by the DictionaryHeader
+	EncodingDictionary = "x-zlib-dictionary"
+	// DictionaryHeader - header carrying the ID of the dictionary a payload
+	// was compressed with
+	DictionaryHeader = "X-Compression-Dictionary"
+
+	// maxDictionarySize - deflate only looks 32kB back, a longer dictionary
+	// is never referenced
+	maxDictionarySize = 32 * 1024
+	// maxTrainingSample - bytes kept of every payload given to a Trainer
+	maxTrainingSample = 64 * 1024
+)
+
+// ErrDictionaryUnsupported - dictionary compression was configured toward a
+// Datadog intake, which only accepts gzip
+var ErrDictionaryUnsupported = errors.New("intake: the Datadog intake doesn't support dictionary compression")
+
+// Dictionary is a preset dictionary compressing payloads made of the same
+// repetitive lines much better than gzip, e.g. toward a relay holding the
+// same dictionary. Safe for concurrent use.
+type Dictionary struct {
+	id      string
+	data    []byte
+	writers sync.Pool
+}
+
+// NewDictionary - the dictionary of data, only its last 32kB are used
+func NewDictionary(data []byte) *Dictionary {
+	if len(data) > maxDictionarySize {
+		data = data[len(data)-maxDictionarySize:]
+	}
+	sum := sha256.Sum256(data)
+	return &Dictionary{id: hex.EncodeToString(sum[:8]), data: append([]byte(nil), data...)}
+}
+
+// ID - name of the dictionary sent in the DictionaryHeader, derived from
+// its content
+func (d *Dictionary) ID() string {
+	return d.id
+}
+
+// Bytes - the content of the dictionary, to be deployed where payloads are
+// decompressed
+func (d *Dictionary) Bytes() []byte {
+	return append([]byte(nil), d.data...)
+}
+
+// Compress - the payload compressed with the dictionary
+func (d *Dictionary) Compress(payload []byte) ([]byte, error) {
+	compressed, err := d.compress(payload)
+	if err != nil {
+		return nil, err
+	}
+	defer putBuffer(compressed)
+	return append([]byte(nil), compressed...), nil
+}
+
+// NewReader - a reader decompressing a payload compressed with the dictionary
+func (d *Dictionary) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReaderDict(r, d.data)
+}
+
+// compress - the payload compressed with the dictionary into a pooled buffer
+func (d *Dictionary) compress(payload []byte) ([]byte, error) {
+	w := &bufferWriter{b: getBuffer(len(payload) / 8)}
+	zw, _ := d.writers.Get().(*zlib.Writer)
+	if zw == nil {
+		var err error
+		if zw, err = zlib.NewWriterLevelDict(w, zlib.DefaultCompression, d.data); err != nil {
+			putBuffer(w.b)
+			return nil, err
+		}
+	} else {
+		// the dictionary is kept across resets
+		zw.Reset(w)
+	}
+	defer d.writers.Put(zw)
+	if _, err := zw.Write(payload); err != nil {
+		putBuffer(w.b)
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		putBuffer(w.b)
+		return nil, err
+	}
+	return w.b, nil
+}
+
+// Trainer collects payloads, set as Config.Trainer, to build a Dictionary
+// from batches which were actually shipped. Safe for concurrent use.
+type Trainer struct {
+	m       sync.Mutex
+	samples [][]byte
+	max     int
+	seen    int
+	rand    *rand.Rand
+}
+
+// NewTrainer - a trainer keeping a uniform sample of at most n payloads
+func NewTrainer(n int) *Trainer {
+	if n <= 0 {
+		n = 1
+	}
+	return &Trainer{max: n, rand: rand.New(rand.NewSource(int64(n)))}
+}
+
+// Add - offer a payload to the sample, it is copied
+func (t *Trainer) Add(payload []byte) {
+	if len(payload) > maxTrainingSample {
+		payload = payload[:maxTrainingSample]
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.seen++
+	if len(t.samples) < t.max {
+		t.samples = append(t.samples, append([]byte(nil), payload...))
+		return
+	}
+	// reservoir sampling, every payload seen is equally likely to be kept
+	if i := t.rand.Intn(t.seen); i < t.max {
+		t.samples[i] = append(t.samples[i][:0], payload...)
+	}
+}
+
+// Samples - payloads kept so far
+func (t *Trainer) Samples() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return len(t.samples)
+}
+
+// Dictionary - a dictionary of at most size bytes of the fragments repeated
+// the most across the samples, nil without samples or repetitions
+func (t *Trainer) Dictionary(size int) *Dictionary {
+	if size <= 0 || size > maxDictionarySize {
+		size = maxDictionarySize
+	}
+	t.m.Lock()
+	data := train(t.samples, size)
+	t.m.Unlock()
+	if len(data) == 0 {
+		return nil
+	}
+	return NewDictionary(data)
+}
+
+// train - the fragments saving the most bytes, the best at the end of the
+// dictionary where deflate references them with the shortest distances
+func train(samples [][]byte, size int) []byte {
+	counts := map[string]int{}
+	for _, sample := range samples {
+		fragments(sample, func(f []byte) {
+			counts[string(f)]++
+		})
+	}
+	type scored struct {
+		fragment string
+		score    int
+	}
+	var candidates []scored
+	for f, n := range counts {
+		if n > 1 {
+			// the first occurrence saves nothing
+			candidates = append(candidates, scored{f, (n - 1) * len(f)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].fragment < candidates[j].fragment
+	})
+	var chosen []string
+	total := 0
+	var seen []byte
+	for _, c := range candidates {
+		if total+len(c.fragment) > size {
+			continue
+		}
+		if bytes.Contains(seen, []byte(c.fragment)) {
+			continue
+		}
+		chosen = append(chosen, c.fragment)
+		seen = append(append(seen, c.fragment...), 0)
+		total += len(c.fragment)
+	}
+	data := make([]byte, 0, total)
+	for i := len(chosen) - 1; i >= 0; i-- {
+		data = append(data, chosen[i]...)
+	}
+	return data
+}
+
+// fragments - call fn with the members of the sample, split after JSON
+// separators, newlines and spaces, and with the keys of the members
+func fragments(sample []byte, fn func([]byte)) {
+	const minFragment, maxFragment = 4, 256
+	emit := func(f []byte) {
+		if len(f) >= minFragment && len(f) <= maxFragment {
+			fn(f)
+		}
+	}
+	start := 0
+	for i, b := range sample {
+		switch b {
+		case ',', '{', '[', '\n', ' ':
+			member := sample[start : i+1]
+			emit(member)
+			if k := bytes.IndexAny(member, ":="); k > 0 {
+				emit(member[:k+1])
+			}
+			start = i + 1
+		}
+	}
+	emit(sample[start:])
+}
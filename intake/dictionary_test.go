@@ -0,0 +1,90 @@
+package intake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// repetitive - a payload of n log lines made of the same fields
+func repetitive(n, offset int) []byte {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"level":"info","msg":"request served","service":"checkout","http.method":"GET","http.status_code":200,"duration":%d,"request_id":"req-%d"}`, (i+offset)*37%1000, i+offset)
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+func TestTrainer(t *testing.T) {
+	trainer := NewTrainer(10)
+	equals(t, (*Dictionary)(nil), trainer.Dictionary(1024))
+	for i := 0; i < 50; i++ {
+		trainer.Add(repetitive(5, i*5))
+	}
+	equals(t, 10, trainer.Samples())
+	d := trainer.Dictionary(1024)
+	equals(t, true, d != nil && len(d.Bytes()) <= 1024)
+	equals(t, true, bytes.Contains(d.Bytes(), []byte(`"service":"checkout",`)))
+	equals(t, d.ID(), NewDictionary(d.Bytes()).ID())
+
+	// a small batch compresses much better with the dictionary than with gzip
+	payload := repetitive(3, 1000)
+	compressed, err := d.Compress(payload)
+	ok(t, err)
+	gzipped, err := compress(payload)
+	ok(t, err)
+	equals(t, true, len(compressed)*2 < len(gzipped))
+
+	r, err := d.NewReader(bytes.NewReader(compressed))
+	ok(t, err)
+	raw, err := ioutil.ReadAll(r)
+	ok(t, err)
+	equals(t, payload, raw)
+}
+
+func TestDictionaryCompression(t *testing.T) {
+	d := NewDictionary([]byte(`"level":"info","msg":"request served",`))
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{JSON: true, Compress: true, Dictionary: d, Trainer: NewTrainer(1)})
+	ok(t, c.Push([]byte(`{"level":"info","msg":"request served"}`)))
+	tick <- time.Now()
+	r := <-reqs
+	equals(t, EncodingDictionary, r.header.Get("Content-Encoding"))
+	equals(t, d.ID(), r.header.Get(DictionaryHeader))
+	zr, err := d.NewReader(bytes.NewReader([]byte(r.body)))
+	ok(t, err)
+	raw, err := ioutil.ReadAll(zr)
+	ok(t, err)
+	equals(t, `[{"level":"info","msg":"request served"}]`, string(raw))
+	// the trainer got the payload before compression
+	equals(t, 1, c.config.Trainer.Samples())
+	ok(t, c.Close(context.Background()))
+
+	// other dictionaries can't read it
+	zr, err = NewDictionary([]byte("other")).NewReader(bytes.NewReader([]byte(r.body)))
+	if err == nil {
+		_, err = ioutil.ReadAll(zr)
+	}
+	equals(t, true, err != nil)
+	_, err = gzip.NewReader(bytes.NewReader([]byte(r.body)))
+	equals(t, true, err != nil)
+}
+
+func TestDictionaryDatadog(t *testing.T) {
+	d := NewDictionary([]byte("dictionary"))
+	err := Config{Host: DatadogEUHost, Dictionary: d}.Validate()
+	equals(t, true, errors.Is(err, ErrDictionaryUnsupported))
+	ok(t, Config{Host: "relay.internal:8080", Dictionary: d}.Validate())
+	ok(t, Config{Host: DatadogEUHost, Dictionary: d, Exporter: ExporterFunc(func(*Batch) error { return nil })}.Validate())
+	equals(t, maxDictionarySize, len(NewDictionary(make([]byte, 2*maxDictionarySize)).Bytes()))
+}
@@ -0,0 +1,62 @@
+package intake
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// dnsFallback - dial remembering the last address every host was reached at,
+// and dialing it again when resolving the host fails. Only the dial changes,
+// so TLS still checks the certificate against the host name sent as SNI.
+type dnsFallback struct {
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	m    sync.Mutex
+	last map[string]string
+}
+
+func newDNSFallback(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *dnsFallback {
+	return &dnsFallback{dial: dial, last: map[string]string{}}
+}
+
+// DialContext - dial addr, or the last known good IP of its host if it
+// cannot be resolved
+func (d *dnsFallback) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return d.dial(ctx, network, addr)
+	}
+	conn, err := d.dial(ctx, network, addr)
+	if err == nil {
+		if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok && net.ParseIP(host) == nil {
+			d.m.Lock()
+			d.last[host] = tcp.IP.String()
+			d.m.Unlock()
+		}
+		return conn, nil
+	}
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		return nil, err
+	}
+	d.m.Lock()
+	ip, ok := d.last[host]
+	d.m.Unlock()
+	if !ok {
+		return nil, err
+	}
+	return d.dial(ctx, network, net.JoinHostPort(ip, port))
+}
+
+// dnsFallbackClient - http client dialing the last known good IP of the
+// intake while DNS is failing, with the settings of the default transport
+func dnsFallbackClient(proxy string, config *tls.Config) *http.Client {
+	client := defaultHTTPClient(proxy, config)
+	dialer := &net.Dialer{Timeout: defaultTimeout, KeepAlive: defaultTimeout}
+	client.Transport.(*http.Transport).DialContext = newDNSFallback(dialer.DialContext).DialContext
+	return client
+}
@@ -0,0 +1,57 @@
+package intake
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDNSFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	resolving := true
+	var dialed []string
+	var dialer net.Dialer
+	d := newDNSFallback(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		host, port, _ := net.SplitHostPort(addr)
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if !resolving || host != "intake.test" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return dialer.DialContext(ctx, network, net.JoinHostPort("127.0.0.1", port))
+	})
+	client := &http.Client{Transport: &http.Transport{DialContext: d.DialContext, DisableKeepAlives: true}}
+	url := "http://" + net.JoinHostPort("intake.test", port) + "/"
+
+	resp, err := client.Get(url)
+	ok(t, err)
+	resp.Body.Close()
+
+	resolving = false
+	resp, err = client.Get(url)
+	ok(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	ok(t, err)
+	resp.Body.Close()
+	// the Host header still names the intake
+	equals(t, net.JoinHostPort("intake.test", port), string(body))
+	equals(t, []string{
+		net.JoinHostPort("intake.test", port),
+		net.JoinHostPort("intake.test", port),
+		net.JoinHostPort("127.0.0.1", port),
+	}, dialed)
+
+	// unknown hosts still fail
+	_, err = client.Get("http://" + net.JoinHostPort("other.test", "1") + "/")
+	equals(t, true, err != nil)
+}
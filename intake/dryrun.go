@@ -0,0 +1,40 @@
+package intake
+
+import "net/http"
+
+// PathDryRun - batches are given to Config.DryRun instead of being sent
+const PathDryRun = "dry-run"
+
+// Preview is a batch as the configured protocol would send it, given to
+// Config.DryRun. Like an exporter, the callback must not keep it.
+type Preview struct {
+	*Batch
+	// Protocol - protocol the batch would be sent with
+	Protocol Protocol
+	// URL - where the batch would be posted, empty for the line protocols
+	URL string
+	// Header - headers it would be sent with, the API key redacted
+	Header http.Header
+	// Size - bytes it would take on the wire, after compression
+	Size int
+}
+
+// dryRun - exporter handing batches to Config.DryRun
+type dryRun struct {
+	c *Client
+}
+
+func (d dryRun) Export(b *Batch) error {
+	c := d.c
+	preview := Preview{Batch: b, Protocol: c.protocol().id(), Size: len(b.Payload)}
+	if p, ok := c.protocol().(httpProtocol); ok {
+		req, payload, err := p.request(c.Context(), c, b)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(apiKeyHeader, Redact(c.config.APIKey))
+		preview.URL, preview.Header, preview.Size = req.URL.String(), req.Header, len(payload)
+	}
+	c.config.DryRun(preview)
+	return nil
+}
@@ -0,0 +1,77 @@
+package intake
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	var m sync.Mutex
+	var previews []Preview
+	var payloads []string
+	c := New(Config{
+		Host:                 DatadogUSHost,
+		APIKey:               "secret-key",
+		JSON:                 true,
+		Compress:             true,
+		CompressionThreshold: 1,
+		Stream:               Stream{Service: "api", Tags: []string{"env:staging"}},
+		DryRun: func(p Preview) {
+			m.Lock()
+			defer m.Unlock()
+			previews = append(previews, p)
+			payloads = append(payloads, string(p.Payload))
+		},
+	})
+	equals(t, PathDryRun, c.Stats().Path)
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	ok(t, c.Close(context.Background()))
+
+	m.Lock()
+	defer m.Unlock()
+	equals(t, 1, len(previews))
+	p := previews[0]
+	equals(t, ProtocolV2HTTP, p.Protocol)
+	equals(t, "https://"+DatadogUSHost+"/api/v2/logs", p.URL)
+	equals(t, "******-key", p.Header.Get(apiKeyHeader))
+	equals(t, EncodingGzip, p.Header.Get("Content-Encoding"))
+	equals(t, `[{"service":"api","ddtags":"env:staging","msg":"one"},{"service":"api","ddtags":"env:staging","msg":"two"}]`, payloads[0])
+	equals(t, true, p.Size > 0 && p.Size != len(payloads[0]))
+	equals(t, int64(2), c.Stats().Delivered)
+}
+
+func TestDryRunTCP(t *testing.T) {
+	var payloads []string
+	c := New(Config{
+		Host:     DatadogUSTCPHost,
+		APIKey:   "secret-key",
+		JSON:     true,
+		Protocol: ProtocolTCP,
+		DryRun: func(p Preview) {
+			payloads = append(payloads, string(p.Payload))
+		},
+	})
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Close(context.Background()))
+	// the key goes in front of the lines only as they are written
+	equals(t, []string{`{"msg":"one"}` + "\n"}, payloads)
+}
+
+func TestDryRunLines(t *testing.T) {
+	var lines []string
+	c := New(Config{
+		Protocol:  ProtocolAgent,
+		AgentAddr: "127.0.0.1:1",
+		DryRun: func(p Preview) {
+			lines = append(lines, strings.TrimSpace(string(p.Payload)))
+			equals(t, ProtocolAgent, p.Protocol)
+			equals(t, "", p.URL)
+		},
+	})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, []string{"one"}, lines)
+}
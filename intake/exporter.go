@@ -0,0 +1,121 @@
+package intake
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Exporter deliver batches to a log backend. The Client batches, retries,
+// audits and falls back the same way whatever the exporter, so backends
+// other than Datadog only implement the delivery of a single batch.
+type Exporter interface {
+	// Export - deliver the batch once, it is called again for every retry
+	// and must not keep the batch or its lines after returning
+	Export(b *Batch) error
+}
+
+// ExporterFunc is an Exporter calling the function
+type ExporterFunc func(b *Batch) error
+
+// Export - call f
+func (f ExporterFunc) Export(b *Batch) error {
+	return f(b)
+}
+
+// StatusError is the error of an attempt the intake answered with an error
+// status, exporters return it for the Client to tell throttling apart
+type StatusError struct {
+	// Code - HTTP status code, such as 429
+	Code int
+	// Status - HTTP status line, such as "429 Too Many Requests"
+	Status string
+	// RetryAfter - wait the intake asked for before retrying, when
+	// HasRetryAfter; a throttled attempt is retried after
+	// Config.RetryAfter otherwise
+	RetryAfter    time.Duration
+	HasRetryAfter bool
+}
+
+func (e *StatusError) Error() string {
+	return "intake: " + e.Status
+}
+
+// throttled - whether err tells the intake is rate limiting
+func throttled(err error) bool {
+	var status *StatusError
+	return errors.As(err, &status) && status.Code == http.StatusTooManyRequests
+}
+
+// Batch is a group of lines of the same stream delivered at once
+type Batch struct {
+	Stream Stream
+	// Lines - the lines of the batch, each a JSON object if JSON is true
+	Lines [][]byte
+	JSON  bool
+	// Payload - the lines as the Datadog intake expects them, a JSON array or
+	// newline separated text, for exporters accepting the same shape
+	Payload []byte
+	// Signature - HMAC of the payload when signing is on, computed before
+	// the payload is compressed
+	Signature string
+	// Encoding - content encoding the payload should be sent with,
+	// EncodingGzip, EncodingDictionary or EncodingIdentity
+	Encoding string
+	// Quota - rate limit reported by the last response to the batch, nil
+	// if none did. Exporters may set it.
+	Quota *Quota
+	// Sequence - sequence number of the batch, the same for every retry, 0
+	// unless Config.Sequence
+	Sequence uint64
+	// Attempts - delivery history of the batch, updated before every
+	// attempt, including the ones made before a restart for replayed lines
+	Attempts Attempts
+
+	bodies     sync.WaitGroup
+	compressed []byte
+	settings   *Settings
+}
+
+// batchSettings - the settings the batch is sent with
+func (c *Client) batchSettings(b *Batch) *Settings {
+	if b.settings != nil {
+		return b.settings
+	}
+	return c.settings()
+}
+
+// exporter - the dry run, the configured exporter, or Datadog
+func (c *Client) exporter() Exporter {
+	if c.config.DryRun != nil {
+		return dryRun{c}
+	}
+	if c.config.Exporter != nil {
+		return c.config.Exporter
+	}
+	return datadogExporter{c}
+}
+
+// datadogExporter - the default exporter delivering batches to Datadog with
+// the configured protocol
+type datadogExporter struct {
+	c *Client
+}
+
+func (e datadogExporter) Export(b *Batch) error {
+	return e.c.protocol().deliver(e.c, b)
+}
+
+// body - request body signaling once it is closed
+type body struct {
+	*bytes.Reader
+	once sync.Once
+	done func()
+}
+
+func (b *body) Close() error {
+	b.once.Do(b.done)
+	return nil
+}
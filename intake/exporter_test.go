@@ -0,0 +1,45 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExporter(t *testing.T) {
+	srv, _ := newServer(t)
+	var got []*Batch
+	var lines []string
+	var records []AuditRecord
+	exporter := ExporterFunc(func(b *Batch) error {
+		got = append(got, b)
+		for _, line := range b.Lines {
+			lines = append(lines, string(line))
+		}
+		if len(got) == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	c, tick := newClient(srv, Config{
+		JSON:     true,
+		MaxRetry: 3,
+		Stream:   Stream{Service: "api"},
+		Exporter: exporter,
+		OnAudit:  func(r AuditRecord) { records = append(records, r) },
+	})
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	tick <- time.Now()
+	ok(t, c.Close(context.Background()))
+
+	// the failed attempt is retried with the same batch
+	equals(t, 2, len(got))
+	equals(t, []string{`{"msg":"one"}`, `{"msg":"two"}`, `{"msg":"one"}`, `{"msg":"two"}`}, lines)
+	equals(t, Stream{Service: "api"}, got[1].Stream)
+	equals(t, true, got[1].JSON)
+	equals(t, 1, len(records))
+	equals(t, true, records[0].Delivered)
+	equals(t, len(`[{"msg":"one"},{"msg":"two"}]`), records[0].Bytes)
+}
@@ -0,0 +1,51 @@
+package intake
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// fallback - write the entries of a dropped batch to the fallback writer,
+// JSON lines carrying the attempts made in AttemptsField
+func (c *Client) fallback(b *batch, a Attempts) {
+	w := c.config.Fallback
+	if w == nil {
+		return
+	}
+	var buf bytes.Buffer
+	for i, line := range b.lines {
+		if i < len(b.local) && b.local[i] != nil {
+			buf.Write(b.local[i])
+			continue
+		}
+		// lines are framed for the payload, with a trailing comma in JSON
+		line = line[:len(line)-1]
+		if c.config.JSON {
+			line = withAttempts(line, a)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	c.fallbackLock.Lock()
+	defer c.fallbackLock.Unlock()
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		c.Debugf("Unable to write fallback, %v", err)
+		return
+	}
+	atomic.AddInt64(&c.stats.fallback, int64(len(b.lines)))
+}
+
+// handleDropped - hand the lines of a batch dropped with err to the error
+// handler, copied so it may keep them
+func (c *Client) handleDropped(b *batch, err error) {
+	fn := c.config.ErrorHandler
+	if fn == nil {
+		return
+	}
+	lines := make([][]byte, len(b.lines))
+	for i, line := range b.lines {
+		// lines are framed for the payload, with a trailing comma in JSON
+		lines[i] = append([]byte(nil), line[:len(line)-1]...)
+	}
+	fn(err, lines)
+}
@@ -0,0 +1,86 @@
+package intake
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	m sync.Mutex
+	b bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.String()
+}
+
+func TestFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var fallback syncBuffer
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	c, tick := newClient(srv, Config{JSON: true, MaxRetry: 2, Fallback: &fallback, Clock: clock})
+	ok(t, c.PushEntry(Entry{Line: []byte(`{"msg":"one"}`), Local: []byte("level=info msg=one\n")}))
+	ok(t, c.PushEntry(Entry{Line: []byte(`{"msg":"two"}`)}))
+	tick <- time.Now()
+	closeDropping(t, c)
+
+	// JSON lines carry the attempts made, local renderings are left as is
+	equals(t, "level=info msg=one\n"+
+		`{"msg":"two","delivery_attempts":{"count":2,"first":"2020-01-02T03:04:05Z","last_error":"intake: 503 Service Unavailable"}}`+"\n",
+		fallback.String())
+	equals(t, int64(2), c.Stats().Fallback)
+}
+
+func TestNoFallbackOnSuccess(t *testing.T) {
+	srv, reqs := newServer(t)
+	var fallback syncBuffer
+	c, tick := newClient(srv, Config{Fallback: &fallback})
+	ok(t, c.PushEntry(Entry{Line: []byte("one"), Local: []byte("local one")}))
+	tick <- time.Now()
+	equals(t, "one\n", (<-reqs).body)
+	ok(t, c.Close(context.Background()))
+	equals(t, "", fallback.String())
+	equals(t, int64(0), c.Stats().Fallback)
+}
+
+func TestErrorHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var m sync.Mutex
+	var dropped [][]byte
+	var dropErr error
+	c, tick := newClient(srv, Config{JSON: true, MaxRetry: 2, ErrorHandler: func(err error, batch [][]byte) {
+		m.Lock()
+		defer m.Unlock()
+		dropped, dropErr = batch, err
+	}})
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	tick <- time.Now()
+	closeDropping(t, c)
+
+	m.Lock()
+	defer m.Unlock()
+	equals(t, [][]byte{[]byte(`{"msg":"one"}`), []byte(`{"msg":"two"}`)}, dropped)
+	equals(t, &StatusError{Code: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}, dropErr)
+}
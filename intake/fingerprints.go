@@ -0,0 +1,156 @@
+package intake
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	// spoolFingerprints - fingerprints of the spool records delivered kept,
+	// the least recently seen forgotten first
+	spoolFingerprints = 4096
+	// fingerprintsFile - file of Config.SpoolDir holding them across restarts
+	fingerprintsFile = "delivered.fingerprints"
+)
+
+// fingerprint - the fingerprint of a spool record as written to disk
+func fingerprint(record []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(record)
+	return h.Sum64()
+}
+
+// fingerprints - bounded LRU of the fingerprints of the records replayed and
+// acknowledged, appended to a file and compacted once it holds twice too many
+type fingerprints struct {
+	path string
+	max  int
+
+	m       sync.Mutex
+	order   *list.List // oldest first
+	index   map[uint64]*list.Element
+	file    *os.File
+	written int // lines of the file
+}
+
+// openFingerprints - the fingerprints of the file of dir, if any
+func openFingerprints(dir string, max int) (*fingerprints, error) {
+	f := &fingerprints{
+		path:  filepath.Join(dir, fingerprintsFile),
+		max:   max,
+		order: list.New(),
+		index: map[uint64]*list.Element{},
+	}
+	file, err := os.Open(f.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if file != nil {
+		sc := bufio.NewScanner(file)
+		for sc.Scan() {
+			// a line cut short by a crash is ignored
+			if fp, err := strconv.ParseUint(sc.Text(), 16, 64); err == nil && len(sc.Text()) == 16 {
+				f.remember(fp)
+				f.written++
+			}
+		}
+		file.Close()
+	}
+	return f, nil
+}
+
+// seen - whether fp was delivered, making it the most recently seen
+func (f *fingerprints) seen(fp uint64) bool {
+	f.m.Lock()
+	defer f.m.Unlock()
+	e, ok := f.index[fp]
+	if ok {
+		f.order.MoveToBack(e)
+	}
+	return ok
+}
+
+// add - remember fp was delivered, persisted before returning
+func (f *fingerprints) add(fp uint64) error {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if _, ok := f.index[fp]; ok {
+		return nil
+	}
+	f.remember(fp)
+	if f.written >= 2*f.max {
+		return f.compact()
+	}
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		f.file = file
+	}
+	if _, err := fmt.Fprintf(f.file, "%016x\n", fp); err != nil {
+		return err
+	}
+	f.written++
+	return nil
+}
+
+// remember - add fp as the most recently seen, forgetting the least
+// recently seen beyond max
+func (f *fingerprints) remember(fp uint64) {
+	if e, ok := f.index[fp]; ok {
+		f.order.MoveToBack(e)
+		return
+	}
+	f.index[fp] = f.order.PushBack(fp)
+	for f.order.Len() > f.max {
+		oldest := f.order.Front()
+		f.order.Remove(oldest)
+		delete(f.index, oldest.Value.(uint64))
+	}
+}
+
+// compact - rewrite the file with the fingerprints remembered only
+func (f *fingerprints) compact() error {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	tmp := f.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for e := f.order.Front(); e != nil; e = e.Next() {
+		fmt.Fprintf(w, "%016x\n", e.Value.(uint64))
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+	f.written = f.order.Len()
+	return nil
+}
+
+// close - release the file appended to
+func (f *fingerprints) close() {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
@@ -0,0 +1,74 @@
+package intake
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	f, err := openFingerprints(dir, 2)
+	ok(t, err)
+	for fp := uint64(1); fp <= 3; fp++ {
+		ok(t, f.add(fp))
+	}
+	// the least recently seen is forgotten first
+	equals(t, false, f.seen(1))
+	equals(t, true, f.seen(2))
+	ok(t, f.add(4))
+	equals(t, true, f.seen(2))
+	equals(t, false, f.seen(3))
+	ok(t, f.add(5))
+	f.close()
+
+	// the file is compacted once it holds twice too many lines, and
+	// fingerprints survive a restart
+	data, err := ioutil.ReadFile(filepath.Join(dir, fingerprintsFile))
+	ok(t, err)
+	equals(t, fmt.Sprintf("%016x\n%016x\n", 2, 5), string(data))
+	f, err = openFingerprints(dir, 2)
+	ok(t, err)
+	equals(t, true, f.seen(2))
+	equals(t, true, f.seen(5))
+	equals(t, false, f.seen(4))
+	f.close()
+}
+
+func TestFingerprintsCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	ok(t, ioutil.WriteFile(filepath.Join(dir, fingerprintsFile), []byte(fmt.Sprintf("%016x\nzz\n%016x\n00ab", 7, 8)), 0600))
+	f, err := openFingerprints(dir, 10)
+	ok(t, err)
+	equals(t, true, f.seen(7))
+	equals(t, true, f.seen(8))
+	equals(t, false, f.seen(0xab))
+	f.close()
+}
+
+func TestSpoolDeduplicated(t *testing.T) {
+	dir := t.TempDir()
+	one, two := `{"stream":{"service":"api"},"lines":["one"]}`, `{"stream":{"service":"api"},"lines":["two"]}`
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "spool-00000000000000000001-000001.log"), []byte(one+"\n"+two+"\n"), 0600))
+	// a previous replay delivered the first record and crashed before removing the file
+	ok(t, ioutil.WriteFile(filepath.Join(dir, fingerprintsFile), []byte(fmt.Sprintf("%016x\n", fingerprint([]byte(one)))), 0600))
+
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"two"}, e.delivered(1))
+	deadline := time.Now().Add(time.Second)
+	for c.spool.pending() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(1), c.Stats().Deduplicated)
+	equals(t, int64(1), c.Stats().Delivered)
+
+	f, err := openFingerprints(dir, spoolFingerprints)
+	ok(t, err)
+	equals(t, true, f.seen(fingerprint([]byte(two))))
+	f.close()
+}
@@ -0,0 +1,78 @@
+package intake
+
+import (
+	"context"
+	"fmt"
+)
+
+// Flush - send the entries pushed so far without waiting for the flush
+// policy, and wait for them to be delivered or dropped, or for ctx to be
+// done. Batches already being sent are waited for too, so every entry
+// pushed before Flush was called is accounted for once it returns. Entries
+// dropped make it return ErrNotDelivered wrapping the last delivery error.
+func (c *Client) Flush(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	reply := make(chan []*batch, 1)
+	select {
+	case c.flushes <- reply:
+	case <-c.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var batches []*batch
+	select {
+	case batches = <-reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	dropped := 0
+	var last error
+	for _, b := range batches {
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if b.err != nil {
+			dropped++
+			last = b.err
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%w: %d of %d batches dropped while flushing: %w", ErrNotDelivered, dropped, len(batches), last)
+	}
+	return nil
+}
+
+// fly - track a batch handed over to a send goroutine
+func (c *Client) fly(b *batch) {
+	c.flightsLock.Lock()
+	defer c.flightsLock.Unlock()
+	c.flights[b] = struct{}{}
+}
+
+// land - the batch was delivered or dropped with err
+func (c *Client) land(b *batch, err error) {
+	c.flightsLock.Lock()
+	delete(c.flights, b)
+	c.flightsLock.Unlock()
+	b.err = err
+	close(b.done)
+}
+
+// flying - the batches being sent
+func (c *Client) flying() []*batch {
+	c.flightsLock.Lock()
+	defer c.flightsLock.Unlock()
+	batches := make([]*batch, 0, len(c.flights))
+	for b := range c.flights {
+		batches = append(batches, b)
+	}
+	return batches
+}
@@ -0,0 +1,71 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, _ := newClient(srv, Config{FlushPolicy: Interval(time.Hour)})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.PushStream(Stream{Service: "other"}, []byte("two")))
+	ok(t, c.Flush(context.Background()))
+	// delivered by the time Flush returned
+	equals(t, 2, len(reqs))
+	equals(t, int64(2), c.Stats().Delivered)
+
+	// nothing to flush
+	ok(t, c.Flush(context.Background()))
+	ok(t, c.Close(context.Background()))
+	equals(t, ErrClosed, c.Flush(context.Background()))
+}
+
+func TestFlushInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+	}))
+	defer srv.Close()
+	c, _ := newClient(srv, Config{FlushPolicy: MaxEntries(1)})
+	ok(t, c.Push([]byte("one")))
+
+	done := make(chan error, 1)
+	go func() { done <- c.Flush(context.Background()) }()
+	// the batch already being sent is waited for
+	select {
+	case err := <-done:
+		t.Fatalf("flushed before delivery: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	ok(t, <-done)
+	equals(t, int32(1), atomic.LoadInt32(&requests))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	equals(t, context.Canceled, c.Flush(ctx))
+	ok(t, c.Close(context.Background()))
+}
+
+func TestFlushNotDelivered(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	c, _ := newClient(srv, Config{FlushPolicy: Interval(time.Hour)})
+	ok(t, c.Push([]byte("one")))
+	err := c.Flush(context.Background())
+	equals(t, true, errors.Is(err, ErrNotDelivered))
+	var status *StatusError
+	equals(t, true, errors.As(err, &status))
+	equals(t, http.StatusBadRequest, status.Code)
+	ok(t, c.Close(context.Background()))
+}
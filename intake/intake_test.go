@@ -0,0 +1,224 @@
+package intake
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ok fails the test if an err is not nil.
+func ok(tb testing.TB, err error) {
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d: unexpected error: %s\n\n", filepath.Base(file), line, err.Error())
+		tb.FailNow()
+	}
+}
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+type request struct {
+	header http.Header
+	path   string
+	query  string
+	body   string
+}
+
+// newServer starts a mock intake recording every request it receives.
+func newServer(t testing.TB) (*httptest.Server, <-chan request) {
+	ch := make(chan request, 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		ok(t, err)
+		ch <- request{header: r.Header, path: r.URL.Path, query: r.URL.RawQuery, body: string(b)}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+// newClient creates a client sending to srv, flushing on the returned ticker.
+// Lines are not queued, so a tick always flushes every line pushed before.
+func newClient(srv *httptest.Server, config Config) (*Client, chan time.Time) {
+	config.Host = srv.Listener.Addr().String()
+	c := &Client{
+		config: config,
+		scheme: "http",
+		client: srv.Client(),
+	}
+	tick := make(chan time.Time)
+	c.start(0, func() <-chan time.Time { return tick })
+	return c, tick
+}
+
+func TestSetDebug(t *testing.T) {
+	c := &Client{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.SetDebug(i%2 == 0)
+			c.Debugf("TestSetDebug - %d", i)
+		}(i)
+	}
+	wg.Wait()
+
+	c.SetDebug(true)
+	equals(t, true, c.Debug())
+	c.SetDebug(false)
+	equals(t, false, c.Debug())
+}
+
+func TestPushJSON(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{
+		APIKey: "key",
+		JSON:   true,
+		Stream: Stream{Source: "go", Service: "svc", Tags: []string{"a:1", "b:2"}},
+	})
+
+	ok(t, c.Push([]byte(`{"msg":"one"}`+"\n")))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	ok(t, c.Push([]byte("")))
+	tick <- time.Now()
+
+	r := <-reqs
+	equals(t, `[{"msg":"one"},{"msg":"two"}]`, r.body)
+	equals(t, "key", r.header.Get(apiKeyHeader))
+	equals(t, string(ContentTypeJSON), r.header.Get("Content-Type"))
+	equals(t, "ddsource=go&ddtags=a%3A1%2Cb%3A2&service=svc", r.query)
+}
+
+func TestPushPlain(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{APIKey: "key"})
+
+	ok(t, c.Push([]byte("one\n")))
+	ok(t, c.Push([]byte("two")))
+	tick <- time.Now()
+
+	r := <-reqs
+	equals(t, "one\ntwo\n", r.body)
+	equals(t, string(ContentTypePlain), r.header.Get("Content-Type"))
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		host string
+		err  error
+	}{
+		{DatadogUSHost, nil},
+		{"localhost:8080", nil},
+		{"user:secret-key@" + DatadogUSHost, ErrAPIKeyInHost},
+		{DatadogUSHost + "?api_key=abc", ErrAPIKeyInHost},
+		{"secret-key." + DatadogUSHost, ErrAPIKeyInHost},
+		{"bad host%", ErrInvalidHost},
+	} {
+		equals(t, tc.err, Config{Host: tc.host, APIKey: "secret-key"}.Validate())
+	}
+
+	c := New(Config{Host: DatadogUSHost + "/?dd-api-key=secret-key", APIKey: "secret-key"})
+	equals(t, ErrAPIKeyInHost, c.Push([]byte("line")))
+}
+
+func TestRedact(t *testing.T) {
+	equals(t, "", Redact(""))
+	equals(t, "***", Redact("abc"))
+	equals(t, "********cdef", Redact("0123456789abcdef"[4:]))
+
+	config := Config{Host: DatadogUSHost, APIKey: "0123456789abcdef"}
+	str := New(config).String()
+	equals(t, false, strings.Contains(str, config.APIKey))
+	equals(t, true, strings.Contains(str, "************cdef"))
+}
+
+func TestDebugfRedactsAPIKey(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(out)
+
+	c := &Client{config: Config{APIKey: "0123456789abcdef"}}
+	c.SetDebug(true)
+	c.Debugf("header %s", c.config.APIKey)
+	equals(t, false, strings.Contains(buf.String(), c.config.APIKey))
+	equals(t, true, strings.Contains(buf.String(), "header ************cdef"))
+}
+
+func TestBatchInterval(t *testing.T) {
+	c := New(Config{Host: DatadogUSHost, BatchTimeout: time.Second})
+	equals(t, minBatchTimeout, c.batchInterval())
+
+	c = &Client{
+		config: Config{BatchTimeout: 10 * time.Second, BatchJitter: 2 * time.Second},
+		rand:   rand.New(rand.NewSource(1)),
+	}
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		d := c.batchInterval()
+		if d < 10*time.Second || d >= 12*time.Second {
+			t.Fatalf("interval %v out of [10s, 12s)", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("interval is not jittered: %v", seen)
+	}
+}
+
+// countingTransport - round tripper counting the requests it forwards
+type countingTransport struct {
+	next     http.RoundTripper
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return t.next.RoundTrip(r)
+}
+
+func TestHTTPClient(t *testing.T) {
+	// a client of its own, never the shared default one
+	c := New(Config{Host: DatadogUSHost, APIKey: "secret-key"})
+	equals(t, true, c.client != http.DefaultClient)
+	equals(t, true, c.client.Transport != http.DefaultTransport)
+	// Config.RequestTimeout bounds the attempts, not the client
+	equals(t, time.Duration(0), c.client.Timeout)
+	equals(t, time.Duration(0), New(Config{DNSFallback: true}).client.Timeout)
+	equals(t, true, http.DefaultClient.Transport == nil)
+
+	srv, _ := newServer(t)
+	transport := &countingTransport{next: srv.Client().Transport}
+	client := &http.Client{Transport: transport, Timeout: time.Second}
+	c = New(Config{
+		Host:         srv.Listener.Addr().String(),
+		APIKey:       "key",
+		HTTPClient:   client,
+		DNSFallback:  true,
+		BatchTimeout: time.Hour,
+	})
+	c.scheme = "http"
+	equals(t, client, c.client)
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, int32(1), atomic.LoadInt32(&transport.requests))
+}
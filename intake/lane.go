@@ -0,0 +1,84 @@
+package intake
+
+// Lane is the queue an entry waits in before batching, by severity, so a
+// flood of entries of one lane cannot hold back the others
+type Lane int
+
+const (
+	// LaneLow - trace and debug entries
+	LaneLow Lane = iota
+	// LaneNormal - info entries and entries of unknown severity
+	LaneNormal
+	// LaneHigh - warnings and worse
+	LaneHigh
+
+	numLanes = 3
+)
+
+// DefaultLaneWeights - entries taken from every lane in turn while they all
+// hold entries, errors first
+var DefaultLaneWeights = map[Lane]int{LaneHigh: 4, LaneNormal: 2, LaneLow: 1}
+
+func (l Lane) String() string {
+	switch l {
+	case LaneLow:
+		return "low"
+	case LaneNormal:
+		return "normal"
+	case LaneHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// laneOf - lane of the entries of severity s
+func laneOf(s Severity) Lane {
+	switch {
+	case s >= SeverityWarn:
+		return LaneHigh
+	case s == SeverityTrace || s == SeverityDebug:
+		return LaneLow
+	default:
+		return LaneNormal
+	}
+}
+
+// lanes - weighted round robin over the lane queues, only used by the pile
+// goroutine
+type lanes struct {
+	queues  [numLanes]chan Entry
+	weights [numLanes]int
+
+	lane   Lane
+	served int
+}
+
+func newLanes(queues [numLanes]chan Entry, weights map[Lane]int) *lanes {
+	l := &lanes{queues: queues, lane: LaneHigh}
+	for lane := range l.weights {
+		w := weights[Lane(lane)]
+		if w <= 0 {
+			w = DefaultLaneWeights[Lane(lane)]
+		}
+		l.weights[lane] = w
+	}
+	return l
+}
+
+// poll - next entry without waiting, taking up to the weight of a lane
+// before moving to the next one, and skipping empty lanes
+func (l *lanes) poll() (Entry, bool) {
+	for i := 0; i <= numLanes; i++ {
+		if l.served < l.weights[l.lane] {
+			select {
+			case e := <-l.queues[l.lane]:
+				l.served++
+				return e, true
+			default:
+			}
+		}
+		l.lane, l.served = (l.lane+numLanes-1)%numLanes, 0
+	}
+	return Entry{}, false
+}
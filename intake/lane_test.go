@@ -0,0 +1,107 @@
+package intake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLaneOf(t *testing.T) {
+	equals(t, LaneLow, laneOf(SeverityTrace))
+	equals(t, LaneLow, laneOf(SeverityDebug))
+	equals(t, LaneNormal, laneOf(SeverityInfo))
+	equals(t, LaneNormal, laneOf(SeverityUnknown))
+	equals(t, LaneHigh, laneOf(SeverityWarn))
+	equals(t, LaneHigh, laneOf(SeverityPanic))
+	equals(t, "high", LaneHigh.String())
+}
+
+// fill - queue n entries of a severity of the lane
+func fill(lanes [numLanes]chan Entry, lane Lane, n int) {
+	severity := map[Lane]Severity{LaneLow: SeverityDebug, LaneNormal: SeverityInfo, LaneHigh: SeverityError}[lane]
+	for i := 0; i < n; i++ {
+		lanes[lane] <- Entry{Severity: severity}
+	}
+}
+
+func TestLanesPoll(t *testing.T) {
+	var queues [numLanes]chan Entry
+	for lane := range queues {
+		queues[lane] = make(chan Entry, 100)
+	}
+	l := newLanes(queues, map[Lane]int{LaneLow: 2})
+	equals(t, [numLanes]int{2, 2, 4}, l.weights)
+
+	// every lane is served in proportion to its weight while all hold entries
+	fill(queues, LaneLow, 100)
+	fill(queues, LaneNormal, 100)
+	fill(queues, LaneHigh, 100)
+	var served [numLanes]int
+	for i := 0; i < 80; i++ {
+		e, ok := l.poll()
+		equals(t, true, ok)
+		served[laneOf(e.Severity)]++
+	}
+	equals(t, [numLanes]int{20, 20, 40}, served)
+
+	// empty lanes are skipped
+	for {
+		if _, ok := l.poll(); !ok {
+			break
+		}
+	}
+	fill(queues, LaneLow, 3)
+	for i := 0; i < 3; i++ {
+		e, ok := l.poll()
+		equals(t, true, ok)
+		equals(t, SeverityDebug, e.Severity)
+	}
+	_, ok := l.poll()
+	equals(t, false, ok)
+}
+
+func TestLanesMixedLoad(t *testing.T) {
+	srv, _ := newServer(t)
+	var m sync.Mutex
+	delivered := map[string]int{}
+	c, _ := newClient(srv, Config{
+		FlushPolicy: MaxEntries(50),
+		Exporter: ExporterFunc(func(b *Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			delivered[b.Stream.Service] += len(b.Lines)
+			return nil
+		}),
+	})
+	// a flood of debug entries
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				c.PushEntry(Entry{Stream: Stream{Service: "debug"}, Line: []byte("debug"), Severity: SeverityDebug})
+			}
+		}()
+	}
+	// errors keep going through
+	start := time.Now()
+	for i := 0; i < 500; i++ {
+		ok(t, c.PushEntry(Entry{Stream: Stream{Service: "error"}, Line: []byte("error"), Severity: SeverityError}))
+	}
+	elapsed := time.Since(start)
+	close(done)
+	wg.Wait()
+	ok(t, c.Close(context.Background()))
+	equals(t, 500, delivered["error"])
+	// neither lane starves the other
+	equals(t, true, delivered["debug"] > 0)
+	equals(t, true, elapsed < 5*time.Second)
+}
@@ -0,0 +1,56 @@
+package intake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets - upper bounds in seconds of the buckets of Latency
+var latencyBuckets = [...]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Latency - histogram of how long the batches delivered took, from their
+// first attempt, retries included
+type Latency struct {
+	// Buckets - batches delivered within each upper bound in seconds,
+	// 0.05 to 60, cumulative
+	Buckets map[float64]uint64
+	// Count - batches delivered
+	Count uint64
+	// Sum - time the batches delivered took
+	Sum time.Duration
+}
+
+// latency - the histogram of a client, only updated atomically
+type latency struct {
+	buckets [len(latencyBuckets)]uint64
+	count   uint64
+	sum     int64
+}
+
+// observe - count a batch delivered in d
+func (l *latency) observe(d time.Duration) {
+	s := d.Seconds()
+	for i, bound := range latencyBuckets {
+		if s <= bound {
+			atomic.AddUint64(&l.buckets[i], 1)
+			break
+		}
+	}
+	atomic.AddInt64(&l.sum, int64(d))
+	atomic.AddUint64(&l.count, 1)
+}
+
+// snapshot - the histogram as of now, buckets made cumulative
+func (l *latency) snapshot() Latency {
+	h := Latency{
+		Buckets: make(map[float64]uint64, len(latencyBuckets)),
+		Count:   atomic.LoadUint64(&l.count),
+		Sum:     time.Duration(atomic.LoadInt64(&l.sum)),
+	}
+	var n uint64
+	for i, bound := range latencyBuckets {
+		n += atomic.LoadUint64(&l.buckets[i])
+		h.Buckets[bound] = n
+	}
+	return h
+}
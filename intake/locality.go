@@ -0,0 +1,33 @@
+package intake
+
+const (
+	// ZoneTag - tag carrying the availability zone entries are shipped
+	// from, the bytes delivered are counted by its value in Stats.Zones
+	ZoneTag = "availability-zone"
+	// RegionTag - tag carrying the region entries are shipped from
+	RegionTag = "region"
+)
+
+// zones - copy of the bytes delivered by availability zone, nil if no stream
+// was tagged with ZoneTag
+func (c *Client) zones() map[string]Usage {
+	c.usageLock.Lock()
+	defer c.usageLock.Unlock()
+	if c.zoneUsage == nil {
+		return nil
+	}
+	return copyUsage(c.zoneUsage)
+}
+
+// accountZone - add u to the usage of the zone of the stream, if tagged;
+// called with usageLock held
+func (c *Client) accountZone(stream Stream, u Usage, limit int) {
+	zone, ok := tagValue(stream.Tags, ZoneTag)
+	if !ok {
+		return
+	}
+	if c.zoneUsage == nil {
+		c.zoneUsage = map[string]Usage{}
+	}
+	addUsage(c.zoneUsage, zone, u, limit)
+}
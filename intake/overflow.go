@@ -0,0 +1,86 @@
+package intake
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Overflow is what a push does when the queue of its lane is full
+type Overflow int
+
+const (
+	// OverflowBlock - wait for room, the default
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest - drop the entry pushed
+	OverflowDropNewest
+	// OverflowDropOldest - drop the oldest entry of the lane to make room
+	OverflowDropOldest
+)
+
+func (o Overflow) String() string {
+	switch o {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrOverflow - the entry was dropped because the queue was full, given to
+// the PushWait callers of the entries dropped
+var ErrOverflow = errors.New("intake: entry dropped, queue full")
+
+// evictTries - oldest entries dropped to make room for one, before the
+// entry itself is dropped to other pushes taking the room first
+const evictTries = 3
+
+// enqueue - queue the entry in its lane as the overflow policy says, false
+// for OverflowBlock, which waits for room, as do the entries replayed
+func (c *Client) enqueue(e Entry) bool {
+	if e.replay {
+		return false
+	}
+	lane := c.lanes[laneOf(e.Severity)]
+	switch c.config.Overflow {
+	case OverflowDropNewest:
+		select {
+		case lane <- e:
+			atomic.AddInt64(&c.stats.pushed, 1)
+		default:
+			c.overflow(e)
+		}
+		return true
+	case OverflowDropOldest:
+		for i := 0; i < evictTries; i++ {
+			select {
+			case lane <- e:
+				atomic.AddInt64(&c.stats.pushed, 1)
+				return true
+			default:
+			}
+			select {
+			case old := <-lane:
+				atomic.AddInt64(&c.stats.evicted, 1)
+				c.overflow(old)
+			default:
+			}
+		}
+		c.overflow(e)
+		return true
+	}
+	return false
+}
+
+// overflow - drop an entry for want of room
+func (c *Client) overflow(e Entry) {
+	c.unreserve(len(e.Line))
+	atomic.AddInt64(&c.stats.overflowed, 1)
+	if e.ack != nil {
+		e.ack <- ErrOverflow
+	}
+	e.release()
+}
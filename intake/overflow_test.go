@@ -0,0 +1,79 @@
+package intake
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// linesExporter - exporter recording the lines delivered
+type linesExporter struct {
+	m     sync.Mutex
+	lines []string
+}
+
+func (e *linesExporter) Export(b *Batch) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	for _, line := range b.Lines {
+		e.lines = append(e.lines, string(line))
+	}
+	return nil
+}
+
+func TestOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow Overflow
+		pushed   int64
+		lines    []string
+	}{
+		{OverflowDropNewest, 2, []string{"1", "2", "held"}},
+		{OverflowDropOldest, 4, []string{"3", "4", "held"}},
+	} {
+		t.Run(tc.overflow.String(), func(t *testing.T) {
+			p := newStalledPolicy()
+			e := &linesExporter{}
+			c := New(Config{QueueSize: 2, Overflow: tc.overflow, FlushPolicy: p, Exporter: e})
+			ok(t, c.Push([]byte("held")))
+			<-p.added
+			// never waiting for room
+			equals(t, tc.pushed, pushes(c, "1", "2", "3", "4"))
+			equals(t, int64(2), c.Stats().Overflowed)
+			equals(t, int64(2), c.Stats().Queued-1)
+			close(p.release)
+			ok(t, c.Close(context.Background()))
+			// batches of one entry, delivered in any order
+			sort.Strings(e.lines)
+			equals(t, tc.lines, e.lines)
+		})
+	}
+}
+
+func TestOverflowPushWait(t *testing.T) {
+	p := newStalledPolicy()
+	c := New(Config{QueueSize: 1, Overflow: OverflowDropOldest, FlushPolicy: p, Exporter: &linesExporter{}})
+	ok(t, c.Push([]byte("held")))
+	<-p.added
+	acked := make(chan error, 1)
+	go func() { acked <- c.PushWait(context.Background(), Entry{Line: []byte("evicted")}) }()
+	for c.Stats().Queued < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	ok(t, c.Push([]byte("newer")))
+	equals(t, ErrOverflow, <-acked)
+	close(p.release)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestOverflowQueueBytes(t *testing.T) {
+	p := newStalledPolicy()
+	c := New(Config{QueueSize: 100, QueueBytes: 8, Overflow: OverflowDropNewest, FlushPolicy: p, Exporter: &linesExporter{}})
+	ok(t, c.Push([]byte("held")))
+	<-p.added
+	equals(t, int64(2), pushes(c, "one", "two", "six"))
+	equals(t, int64(1), c.Stats().Overflowed)
+	close(p.release)
+	ok(t, c.Close(context.Background()))
+}
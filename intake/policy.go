@@ -0,0 +1,183 @@
+package intake
+
+import "time"
+
+// Severity of an entry, higher is more severe, zero is unknown
+type Severity int
+
+// Severities mirror the logrus levels
+const (
+	SeverityUnknown Severity = iota
+	SeverityTrace
+	SeverityDebug
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+	SeverityPanic
+)
+
+// BatchInfo describe a batch being assembled
+type BatchInfo struct {
+	Stream      Stream
+	Entries     int
+	Bytes       int
+	Created     time.Time
+	MaxSeverity Severity
+	// Window - start of the wall-clock window of the entries, zero unless
+	// the policy is aligned
+	Window time.Time
+}
+
+// FlushPolicy decide when a batch is sent. Datadog's payload limits are
+// enforced by the Client whatever the policy decides. Methods are only
+// called from the goroutine assembling batches.
+type FlushPolicy interface {
+	// Interval - time until the next tick, zero to never tick
+	Interval() time.Duration
+	// OnAdd - whether the batch must be sent right after an entry was added
+	OnAdd(b BatchInfo) bool
+	// OnTick - whether the batch must be sent when the policy ticks
+	OnTick(b BatchInfo, now time.Time) bool
+}
+
+// Interval - send every batch every d
+func Interval(d time.Duration) FlushPolicy {
+	return intervalPolicy(d)
+}
+
+type intervalPolicy time.Duration
+
+func (p intervalPolicy) Interval() time.Duration          { return time.Duration(p) }
+func (p intervalPolicy) OnAdd(BatchInfo) bool             { return false }
+func (p intervalPolicy) OnTick(BatchInfo, time.Time) bool { return true }
+
+// MaxBytes - send a batch as soon as it holds n bytes
+func MaxBytes(n int) FlushPolicy {
+	return thresholdPolicy(func(b BatchInfo) bool { return b.Bytes >= n })
+}
+
+// MaxEntries - send a batch as soon as it holds n entries
+func MaxEntries(n int) FlushPolicy {
+	return thresholdPolicy(func(b BatchInfo) bool { return b.Entries >= n })
+}
+
+// OnSeverity - send a batch as soon as it holds an entry at least as severe as min
+func OnSeverity(min Severity) FlushPolicy {
+	return thresholdPolicy(func(b BatchInfo) bool { return b.MaxSeverity >= min })
+}
+
+type thresholdPolicy func(BatchInfo) bool
+
+func (p thresholdPolicy) Interval() time.Duration          { return 0 }
+func (p thresholdPolicy) OnAdd(b BatchInfo) bool           { return p(b) }
+func (p thresholdPolicy) OnTick(BatchInfo, time.Time) bool { return false }
+
+// Any - send a batch as soon as any of the policies wants it sent, ticking
+// at the shortest interval of the policies
+func Any(policies ...FlushPolicy) FlushPolicy {
+	return anyPolicy(policies)
+}
+
+type anyPolicy []FlushPolicy
+
+// window - start of the window of the first aligned policy holding t
+func (p anyPolicy) window(t time.Time) (time.Time, bool) {
+	for _, policy := range p {
+		if w, ok := policy.(windowed); ok {
+			if start, ok := w.window(t); ok {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// until - time until the earliest tick of the policies
+func (p anyPolicy) until(now time.Time) time.Duration {
+	var min time.Duration
+	for _, policy := range p {
+		if d := until(policy, now); d > 0 && (min == 0 || d < min) {
+			min = d
+		}
+	}
+	return min
+}
+
+func (p anyPolicy) Interval() time.Duration {
+	var min time.Duration
+	for _, policy := range p {
+		if d := policy.Interval(); d > 0 && (min == 0 || d < min) {
+			min = d
+		}
+	}
+	return min
+}
+
+func (p anyPolicy) OnAdd(b BatchInfo) bool {
+	for _, policy := range p {
+		if policy.OnAdd(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p anyPolicy) OnTick(b BatchInfo, now time.Time) bool {
+	for _, policy := range p {
+		if policy.OnTick(b, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Aligned - cut batches on wall-clock boundaries every d, e.g. 10:00:00,
+// 10:00:10 for 10s. Entries are grouped by the window their time falls in
+// and every batch of a window is sent once it is over, so shipped counts
+// map to time windows. Combine with Any to also bound the batch size.
+func Aligned(d time.Duration) FlushPolicy {
+	return alignedPolicy(d)
+}
+
+type alignedPolicy time.Duration
+
+func (p alignedPolicy) Interval() time.Duration { return time.Duration(p) }
+func (p alignedPolicy) OnAdd(BatchInfo) bool    { return false }
+
+// OnTick - whether the window of the batch is over
+func (p alignedPolicy) OnTick(b BatchInfo, now time.Time) bool {
+	start, _ := p.window(now)
+	return b.Window.Before(start)
+}
+
+func (p alignedPolicy) window(t time.Time) (time.Time, bool) {
+	if p <= 0 {
+		return time.Time{}, false
+	}
+	return t.Truncate(time.Duration(p)), true
+}
+
+func (p alignedPolicy) until(now time.Time) time.Duration {
+	if p <= 0 {
+		return 0
+	}
+	start, _ := p.window(now)
+	return start.Add(time.Duration(p)).Sub(now)
+}
+
+// windowed - policies grouping entries by wall-clock windows
+type windowed interface {
+	// window - start of the window holding t, false if there is none
+	window(t time.Time) (time.Time, bool)
+	// until - time until the next tick from now
+	until(now time.Time) time.Duration
+}
+
+// until - time until the next tick of the policy from now
+func until(p FlushPolicy, now time.Time) time.Duration {
+	if w, ok := p.(windowed); ok {
+		return w.until(now)
+	}
+	return p.Interval()
+}
@@ -0,0 +1,89 @@
+package intake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicies(t *testing.T) {
+	b := BatchInfo{Entries: 10, Bytes: 2048, MaxSeverity: SeverityWarn}
+	now := time.Now()
+
+	p := Interval(time.Minute)
+	equals(t, time.Minute, p.Interval())
+	equals(t, false, p.OnAdd(b))
+	equals(t, true, p.OnTick(b, now))
+
+	equals(t, true, MaxBytes(2048).OnAdd(b))
+	equals(t, false, MaxBytes(2049).OnAdd(b))
+	equals(t, time.Duration(0), MaxBytes(1).Interval())
+	equals(t, true, MaxEntries(10).OnAdd(b))
+	equals(t, false, MaxEntries(11).OnAdd(b))
+	equals(t, true, OnSeverity(SeverityWarn).OnAdd(b))
+	equals(t, false, OnSeverity(SeverityError).OnAdd(b))
+	equals(t, false, OnSeverity(SeverityError).OnTick(b, now))
+
+	any := Any(MaxEntries(100), Interval(time.Minute), OnSeverity(SeverityWarn), Interval(time.Second))
+	equals(t, time.Second, any.Interval())
+	equals(t, true, any.OnAdd(b))
+	equals(t, true, any.OnTick(b, now))
+	equals(t, false, Any(MaxEntries(100)).OnAdd(b))
+	equals(t, time.Duration(0), Any(MaxEntries(100)).Interval())
+}
+
+func TestFlushPolicy(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, _ := newClient(srv, Config{FlushPolicy: Any(MaxEntries(3), OnSeverity(SeverityError))})
+
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+	ok(t, c.Push([]byte("three")))
+	equals(t, "one\ntwo\nthree\n", (<-reqs).body)
+
+	ok(t, c.PushEntry(Entry{Line: []byte("info"), Severity: SeverityInfo}))
+	ok(t, c.PushEntry(Entry{Line: []byte("error"), Severity: SeverityError}))
+	equals(t, "info\nerror\n", (<-reqs).body)
+}
+
+func TestAlignedPolicy(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 7, 0, time.UTC)
+	window := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+	p := Aligned(10 * time.Second)
+	equals(t, 3*time.Second, until(p, now))
+	equals(t, false, p.OnAdd(BatchInfo{}))
+	equals(t, false, p.OnTick(BatchInfo{Window: window}, now))
+	equals(t, true, p.OnTick(BatchInfo{Window: window}, now.Add(3*time.Second)))
+
+	start, ok := p.(windowed).window(now)
+	equals(t, true, ok)
+	equals(t, window, start)
+
+	any := Any(MaxEntries(100), Aligned(10*time.Second), Interval(time.Minute))
+	equals(t, 3*time.Second, until(any, now))
+	start, ok = any.(windowed).window(now)
+	equals(t, true, ok)
+	equals(t, window, start)
+	_, ok = Any(MaxEntries(100)).(windowed).window(now)
+	equals(t, false, ok)
+	equals(t, time.Minute, until(Interval(time.Minute), now))
+}
+
+func TestAlignedBatches(t *testing.T) {
+	srv, reqs := newServer(t)
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 12, 0, time.UTC)}
+	c, tick := newClient(srv, Config{Clock: clock, FlushPolicy: Aligned(10 * time.Second)})
+	window := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	// entries of two windows are batched apart, whatever order they come in
+	ok(t, c.PushEntry(Entry{Line: []byte("one"), Time: window.Add(9 * time.Second)}))
+	ok(t, c.PushEntry(Entry{Line: []byte("two"), Time: window.Add(10 * time.Second)}))
+	ok(t, c.PushEntry(Entry{Line: []byte("three"), Time: window.Add(time.Second)}))
+	ok(t, c.PushEntry(Entry{Line: []byte("four")}))
+	tick <- clock.now
+	equals(t, "one\nthree\n", (<-reqs).body)
+
+	// the current window is sent once it is over
+	tick <- window.Add(20 * time.Second)
+	equals(t, "two\nfour\n", (<-reqs).body)
+	equals(t, 8*time.Second, c.batchInterval())
+}
@@ -0,0 +1,98 @@
+package intake
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// sizeClasses - capacities of pooled buffers, from a short line up to a full
+// payload. Larger buffers are allocated on demand and never pooled.
+var sizeClasses = [...]int{
+	512,
+	2 * 1024,
+	8 * 1024,
+	32 * 1024,
+	128 * 1024,
+	maxEntryByteSize + 2,
+	1024 * 1024,
+	MaxPayloadSize,
+}
+
+type sizeClass struct {
+	size   int
+	pool   sync.Pool
+	gets   int64
+	puts   int64
+	allocs int64
+}
+
+var pools = func() []*sizeClass {
+	p := make([]*sizeClass, len(sizeClasses))
+	for i, size := range sizeClasses {
+		class := &sizeClass{size: size}
+		class.pool.New = func() interface{} {
+			atomic.AddInt64(&class.allocs, 1)
+			b := make([]byte, 0, class.size)
+			return &b
+		}
+		p[i] = class
+	}
+	return p
+}()
+
+// PoolStats count the use of pooled buffers of a size class
+type PoolStats struct {
+	// Size - capacity of the buffers of the class
+	Size int
+	// Gets - buffers taken from the pool
+	Gets int64
+	// Puts - buffers given back to the pool
+	Puts int64
+	// Allocs - buffers allocated because the pool was empty
+	Allocs int64
+}
+
+// Pools - statistics of the buffer pools shared by every Client, by size class
+func Pools() []PoolStats {
+	stats := make([]PoolStats, len(pools))
+	for i, class := range pools {
+		stats[i] = PoolStats{
+			Size:   class.size,
+			Gets:   atomic.LoadInt64(&class.gets),
+			Puts:   atomic.LoadInt64(&class.puts),
+			Allocs: atomic.LoadInt64(&class.allocs),
+		}
+	}
+	return stats
+}
+
+// classOf - the smallest size class holding n bytes, nil if none does
+func classOf(n int) *sizeClass {
+	for _, class := range pools {
+		if n <= class.size {
+			return class
+		}
+	}
+	return nil
+}
+
+// getBuffer - an empty buffer with a capacity of at least n bytes
+func getBuffer(n int) []byte {
+	class := classOf(n)
+	if class == nil {
+		return make([]byte, 0, n)
+	}
+	atomic.AddInt64(&class.gets, 1)
+	return (*class.pool.Get().(*[]byte))[:0]
+}
+
+// putBuffer - give a buffer from getBuffer back, b must not be used anymore
+func putBuffer(b []byte) {
+	class := classOf(cap(b))
+	if class == nil || cap(b) != class.size {
+		return
+	}
+	atomic.AddInt64(&class.puts, 1)
+	b = b[:0]
+	class.pool.Put(&b)
+}
@@ -0,0 +1,63 @@
+package intake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSizeClasses(t *testing.T) {
+	equals(t, 512, classOf(0).size)
+	equals(t, 512, classOf(512).size)
+	equals(t, 2048, classOf(513).size)
+	equals(t, MaxPayloadSize, classOf(MaxPayloadSize).size)
+	equals(t, (*sizeClass)(nil), classOf(MaxPayloadSize+1))
+
+	b := getBuffer(1000)
+	equals(t, 0, len(b))
+	equals(t, 2048, cap(b))
+
+	// oversized and foreign buffers are never pooled
+	big := getBuffer(MaxPayloadSize + 1)
+	equals(t, MaxPayloadSize+1, cap(big))
+	before := Pools()
+	putBuffer(big)
+	putBuffer(make([]byte, 0, 1000))
+	equals(t, before, Pools())
+}
+
+func TestPoolStats(t *testing.T) {
+	before := Pools()
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{JSON: true})
+	for i := 0; i < 10; i++ {
+		ok(t, c.Push([]byte(`{"msg":"pooled"}`)))
+	}
+	tick <- time.Now()
+	equals(t, 10, len((<-reqs).body)/len(`{"msg":"pooled"},`))
+
+	// lines are given back once copied into the payload, the payload once sent
+	deadline := time.Now().Add(time.Second)
+	for {
+		after := Pools()
+		gets := after[0].Gets - before[0].Gets
+		puts := after[0].Puts - before[0].Puts
+		if gets >= 11 && puts >= gets {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("buffers not given back: %+v", after[0])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func BenchmarkPush(b *testing.B) {
+	srv, _ := newServer(b)
+	c, _ := newClient(srv, Config{JSON: true, FlushPolicy: MaxEntries(maxArraySize)})
+	line := []byte(`{"level":"info","msg":"benchmark entry with a few fields","service":"bench","count":42}` + "\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Push(line)
+	}
+}
@@ -0,0 +1,471 @@
+package intake
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Protocol is the way batches are delivered to Datadog
+type Protocol int
+
+const (
+	// ProtocolV2HTTP - POST to /api/v2/logs, the default. Always a JSON
+	// array of up to 1000 objects carrying the attributes of the stream,
+	// plain text lines are sent as their message.
+	ProtocolV2HTTP Protocol = iota
+	// ProtocolV1HTTP - POST to the deprecated /v1/input, the stream in the
+	// query string, kept for backwards compatibility
+	ProtocolV1HTTP
+	// ProtocolTCP - TLS over TCP to a Datadog TCP intake such as
+	// DatadogUSTCPHost, one JSON object carrying the stream per line
+	ProtocolTCP
+	// ProtocolAgent - one line per entry to the log listener of a local
+	// Agent at Config.AgentAddr, which adds the stream itself
+	ProtocolAgent
+)
+
+const (
+	// DatadogUSTCPHost - TCP intake For Datadog US
+	DatadogUSTCPHost = "intake.logs.datadoghq.com:10516"
+	// DatadogEUTCPHost - TCP intake For Datadog EU
+	DatadogEUTCPHost = "tcp-intake.logs.datadoghq.eu:443"
+
+	basePathV2 = "/api/v2/logs"
+
+	// maxArraySizeV2 - most entries of a batch of the v2 intake
+	maxArraySizeV2 = 1000
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolV1HTTP:
+		return "v1-http"
+	case ProtocolV2HTTP:
+		return "v2-http"
+	case ProtocolTCP:
+		return "tcp"
+	case ProtocolAgent:
+		return "agent"
+	default:
+		return fmt.Sprintf("Protocol(%d)", int(p))
+	}
+}
+
+// protocol - strategy of a protocol, deciding everything between the framed
+// lines of a batch and the wire, so the batcher never changes with them
+type protocol interface {
+	// id - the Protocol of the strategy
+	id() Protocol
+	// payload - the pooled payload of the framed lines of a batch
+	payload(c *Client, stream Stream, lines [][]byte) []byte
+	// compressible - whether payloads may be sent compressed
+	compressible() bool
+	// contentType - format of the payloads
+	contentType(c *Client) ContentType
+	// size - bytes a framed line takes in a payload, its separator included
+	size(c *Client, line []byte) int
+	// overhead - bytes added to every line of the stream in a payload
+	overhead(c *Client, stream Stream) int
+	// maxEntries - most lines in a batch
+	maxEntries() int
+	// deliver - send one attempt of a batch
+	deliver(c *Client, b *Batch) error
+}
+
+// protocol - strategy of the configured protocol
+func (c *Client) protocol() protocol {
+	if c.proto != nil {
+		return c.proto
+	}
+	return httpProtocol{path: basePath}
+}
+
+// newProtocol - strategy of p, connecting to addr for the line protocols
+func (c *Client) newProtocol(p Protocol, addr string) protocol {
+	switch p {
+	case ProtocolV2HTTP:
+		return httpProtocol{path: basePathV2, v2: true}
+	case ProtocolTCP:
+		var config *tls.Config
+		if c.scheme == "https" {
+			config = tcpTLS(c.config.TLSConfig, addr)
+		}
+		c.conn = newLineConn("tcp", addr, config)
+		return tcpProtocol{}
+	case ProtocolAgent:
+		network, address := agentNetwork(addr)
+		c.conn = newLineConn(network, address, nil)
+		return agentProtocol{}
+	default:
+		return httpProtocol{path: basePath}
+	}
+}
+
+// httpProtocol - the HTTP intake at path, stream attributes in the query
+// string of v1 and in every object of v2
+type httpProtocol struct {
+	path string
+	v2   bool
+}
+
+func (p httpProtocol) id() Protocol {
+	if p.v2 {
+		return ProtocolV2HTTP
+	}
+	return ProtocolV1HTTP
+}
+
+func (p httpProtocol) compressible() bool { return true }
+
+func (p httpProtocol) contentType(c *Client) ContentType {
+	if c.config.JSON || p.v2 {
+		return ContentTypeJSON
+	}
+	return ContentTypePlain
+}
+
+func (p httpProtocol) size(c *Client, line []byte) int {
+	if p.v2 && (!c.config.JSON || !isObject(line[:len(line)-1])) {
+		// sent as the message of an object
+		return messageSize(line)
+	}
+	return len(line)
+}
+
+func (p httpProtocol) overhead(c *Client, stream Stream) int {
+	if !p.v2 {
+		return 0
+	}
+	return len(streamAttributes(stream))
+}
+
+func (p httpProtocol) maxEntries() int {
+	if p.v2 {
+		return maxArraySizeV2
+	}
+	return maxArraySize
+}
+
+// url - where batches of the stream are posted, the v2 intake reads the
+// stream in the objects rather than the query string
+func (p httpProtocol) url(c *Client, stream Stream) string {
+	if p.v2 {
+		stream = Stream{}
+	}
+	return c.intakeURL(p.path, stream)
+}
+
+func (p httpProtocol) payload(c *Client, stream Stream, lines [][]byte) []byte {
+	if !p.v2 {
+		return c.payload(lines)
+	}
+	attributes := streamAttributes(stream)
+	size := 2
+	for _, line := range lines {
+		size += p.size(c, line) + len(attributes)
+	}
+	buf := getBuffer(size)
+	buf = append(buf, '[')
+	for i, line := range lines {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if line = line[:len(line)-1]; c.config.JSON {
+			buf = appendObject(buf, attributes, line)
+		} else {
+			buf = appendMessageObject(buf, attributes, line)
+		}
+	}
+	return append(buf, ']')
+}
+
+// request - the request posting the batch without its body, and the body
+func (p httpProtocol) request(ctx context.Context, c *Client, b *Batch) (*http.Request, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url(c, b.Stream), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Add(apiKeyHeader, c.config.APIKey)
+	req.Header.Add("Content-Type", string(p.contentType(c)))
+	req.Header.Add("charset", "UTF-8")
+	if b.Signature != "" {
+		req.Header.Add(c.batchSettings(b).signatureHeader(), b.Signature)
+	}
+	if b.Sequence != 0 {
+		req.Header.Add(SequenceHeader, strconv.FormatUint(b.Sequence, 10))
+	}
+	payload := b.Payload
+	switch b.Encoding {
+	case EncodingGzip, EncodingDictionary:
+		// compressed once, retries send the same bytes
+		if b.compressed == nil {
+			if b.Encoding == EncodingGzip {
+				b.compressed, err = compress(b.Payload)
+			} else {
+				b.compressed, err = c.config.Dictionary.compress(b.Payload)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		payload = b.compressed
+		req.Header.Add("Content-Encoding", b.Encoding)
+		if b.Encoding == EncodingDictionary {
+			req.Header.Add(DictionaryHeader, c.config.Dictionary.ID())
+		}
+	}
+	return req, payload, nil
+}
+
+func (p httpProtocol) deliver(c *Client, b *Batch) error {
+	ctx, cancel := c.attemptContext()
+	defer cancel()
+	req, payload, err := p.request(ctx, c, b)
+	if err != nil {
+		return err
+	}
+	b.bodies.Add(1)
+	req.Body = &body{Reader: bytes.NewReader(payload), done: b.bodies.Done}
+	req.ContentLength = int64(len(payload))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if q := c.observeQuota(resp.Header); q != nil {
+		b.Quota = q
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		c.Debugf("resp = %s", resp.Status)
+		status := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+		status.RetryAfter, status.HasRetryAfter = retryAfter(resp.Header, c.clock().Now())
+		return status
+	}
+	c.Debugf("Success - %d", resp.StatusCode)
+	return nil
+}
+
+// tcpProtocol - lines prefixed with the API key over TLS, each a JSON
+// object carrying the attributes of its stream. The payload holds the
+// objects alone, the key is added as they are written, so it doesn't
+// reach dry runs, payload stages and exporters.
+type tcpProtocol struct{}
+
+func (tcpProtocol) id() Protocol { return ProtocolTCP }
+
+func (tcpProtocol) compressible() bool { return false }
+
+func (tcpProtocol) contentType(*Client) ContentType { return ContentTypePlain }
+
+// size - lines are sent one by one, the intake limits them only
+func (tcpProtocol) size(c *Client, line []byte) int { return len(line) }
+
+func (tcpProtocol) overhead(*Client, Stream) int { return 0 }
+
+func (tcpProtocol) maxEntries() int { return maxArraySize }
+
+func (tcpProtocol) payload(c *Client, stream Stream, lines [][]byte) []byte {
+	attributes := streamAttributes(stream)
+	size := 0
+	for _, line := range lines {
+		size += len(attributes) + len(line) + 32
+	}
+	buf := getBuffer(size)
+	for _, line := range lines {
+		line = line[:len(line)-1]
+		if !c.config.JSON {
+			buf = appendObject(buf, attributes, appendMessage(nil, line))
+		} else {
+			buf = appendObject(buf, attributes, line)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+func (tcpProtocol) deliver(c *Client, b *Batch) error {
+	key := c.config.APIKey
+	buf := getBuffer(len(b.Payload) + (len(key)+1)*bytes.Count(b.Payload, []byte{'\n'}))
+	defer putBuffer(buf)
+	for payload := b.Payload; len(payload) > 0; {
+		n := bytes.IndexByte(payload, '\n') + 1
+		if n == 0 {
+			n = len(payload)
+		}
+		buf = append(append(append(buf, key...), ' '), payload[:n]...)
+		payload = payload[n:]
+	}
+	return c.conn.write(buf)
+}
+
+// agentProtocol - one line per entry, the Agent adds the stream configured
+// for its listener
+type agentProtocol struct{}
+
+func (agentProtocol) id() Protocol { return ProtocolAgent }
+
+func (agentProtocol) compressible() bool { return false }
+
+func (agentProtocol) contentType(*Client) ContentType { return ContentTypePlain }
+
+func (agentProtocol) size(c *Client, line []byte) int { return len(line) }
+
+func (agentProtocol) overhead(*Client, Stream) int { return 0 }
+
+func (agentProtocol) maxEntries() int { return maxArraySize }
+
+func (agentProtocol) payload(c *Client, stream Stream, lines [][]byte) []byte {
+	size := 0
+	for _, line := range lines {
+		size += len(line)
+	}
+	buf := getBuffer(size)
+	for _, line := range lines {
+		buf = append(append(buf, line[:len(line)-1]...), '\n')
+	}
+	return buf
+}
+
+func (agentProtocol) deliver(c *Client, b *Batch) error {
+	return c.conn.write(b.Payload)
+}
+
+// streamAttributes - the JSON members of the reserved attributes of a stream
+func streamAttributes(s Stream) []byte {
+	var buf []byte
+	member := func(k, v string) {
+		if v == "" {
+			return
+		}
+		key, _ := json.Marshal(k)
+		value, _ := json.Marshal(v)
+		buf = append(append(append(append(buf, key...), ':'), value...), ',')
+	}
+	member("ddsource", s.Source)
+	member("service", s.Service)
+	member("hostname", s.Hostname)
+	member("ddtags", escapeTags(s.Tags))
+	return buf
+}
+
+// isObject - whether appendObject merges obj rather than sending it as the
+// message of an object
+func isObject(obj []byte) bool {
+	trimmed := bytes.TrimSpace(obj)
+	return len(trimmed) >= 2 && trimmed[0] == '{'
+}
+
+// appendObject - append the JSON object obj with the members in front
+func appendObject(dst, members, obj []byte) []byte {
+	if !isObject(obj) {
+		// not an object, sent as the message
+		return appendObject(dst, members, appendMessage(nil, obj))
+	}
+	rest := bytes.TrimSpace(bytes.TrimSpace(obj)[1:])
+	dst = append(dst, '{')
+	if rest[0] == '}' && len(members) > 0 {
+		dst = append(dst, members[:len(members)-1]...)
+	} else {
+		dst = append(dst, members...)
+	}
+	return append(dst, rest...)
+}
+
+// appendMessage - append the JSON object with msg as message
+func appendMessage(dst, msg []byte) []byte {
+	return appendMessageObject(dst, nil, msg)
+}
+
+// appendMessageObject - append the JSON object with the members in front
+// of msg as message
+func appendMessageObject(dst, members, msg []byte) []byte {
+	value, _ := json.Marshal(string(msg))
+	dst = append(dst, '{')
+	dst = append(dst, members...)
+	dst = append(dst, `"message":`...)
+	dst = append(dst, value...)
+	return append(dst, '}')
+}
+
+// ErrNoURL - the protocol doesn't post batches to an HTTP intake
+var ErrNoURL = errors.New("intake: protocol has no URL")
+
+// URL - URL batches of the stream are posted to over HTTPS at host with
+// the protocol. host may carry the path of a relay in front of the intake,
+// empty fields of the stream are left out of the query. The v2 intake reads
+// the stream in the payload, its URL has no query.
+func URL(host string, p Protocol, stream Stream) (string, error) {
+	path, err := httpPath(p)
+	if err != nil {
+		return "", err
+	}
+	if p == ProtocolV2HTTP {
+		stream = Stream{}
+	}
+	return intakeURL("https", host, path, stream)
+}
+
+// httpPath - path of the HTTP intake of the protocol
+func httpPath(p Protocol) (string, error) {
+	switch p {
+	case ProtocolV1HTTP:
+		return basePath, nil
+	case ProtocolV2HTTP:
+		return basePathV2, nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrNoURL, p)
+	}
+}
+
+// intakeURL - URL of the HTTP intake at path of host for the stream
+func intakeURL(scheme, host, path string, o Stream) (string, error) {
+	if host == "" {
+		return "", ErrInvalidHost
+	}
+	u, err := url.Parse(scheme + "://" + host)
+	if err != nil || u.Host == "" {
+		return "", ErrInvalidHost
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	parameters := url.Values{}
+	if o.Source != "" {
+		parameters.Add("ddsource", o.Source)
+	}
+	if o.Service != "" {
+		parameters.Add("service", o.Service)
+	}
+	if o.Hostname != "" {
+		parameters.Add("hostname", o.Hostname)
+	}
+	if tags := escapeTags(o.Tags); tags != "" {
+		parameters.Add("ddtags", tags)
+	}
+	u.RawQuery = parameters.Encode()
+	return u.String(), nil
+}
+
+// intakeURL - URL of the HTTP intake at path for the stream
+func (c *Client) intakeURL(path string, o Stream) string {
+	u, err := intakeURL(c.scheme, c.config.Host, path, o)
+	if err != nil {
+		c.Debugf("%v", err)
+	}
+	return u
+}
+
+// datadogURL - URL of the v1 HTTP intake for the stream
+func (c *Client) datadogURL(o Stream) string {
+	return c.intakeURL(basePath, o)
+}
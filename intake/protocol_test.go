@@ -0,0 +1,168 @@
+package intake
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProtocolV2(t *testing.T) {
+	srv, requests := newServer(t)
+	c, tick := newClient(srv, Config{Stream: Stream{Service: "api"}})
+	c.proto = c.newProtocol(ProtocolV2HTTP, c.config.Host)
+	ok(t, c.Push([]byte(`plain "one"`)))
+	ok(t, c.Push([]byte(`two`)))
+	tick <- time.Now()
+	r := <-requests
+	equals(t, basePathV2, r.path)
+	equals(t, string(ContentTypeJSON), r.header.Get("Content-Type"))
+	// the stream is in every object rather than the query
+	equals(t, "", r.query)
+	equals(t, `[{"service":"api","message":"plain \"one\""},{"service":"api","message":"two"}]`, r.body)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestProtocolV2Limits(t *testing.T) {
+	var m sync.Mutex
+	var sizes []int
+	c := New(Config{
+		Exporter: ExporterFunc(func(b *Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			sizes = append(sizes, len(b.Payload))
+			return nil
+		}),
+		Stream: Stream{Service: "api"},
+	})
+	equals(t, ProtocolV2HTTP, c.protocol().id())
+	for i := 0; i < 1500; i++ {
+		ok(t, c.Push([]byte("x")))
+	}
+	ok(t, c.Close(context.Background()))
+	m.Lock()
+	defer m.Unlock()
+	// 1000 entries a batch, each counted with the attributes of the stream
+	one := len(`{"service":"api","message":"x"},`)
+	equals(t, []int{1000*one + 1, 500*one + 1}, sizes)
+}
+
+func TestProtocolTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	defer l.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	c := New(Config{
+		Host:     l.Addr().String(),
+		APIKey:   "key",
+		JSON:     true,
+		Protocol: ProtocolTCP,
+		Stream:   Stream{Source: "go", Service: "api", Tags: []string{"env:prod"}},
+	})
+	// the listener does not speak TLS
+	c.conn.tls = nil
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{}`)))
+	ok(t, c.Push([]byte(`not json`)))
+	ok(t, c.Close(context.Background()))
+	equals(t, `key {"ddsource":"go","service":"api","ddtags":"env:prod","msg":"one"}`, <-lines)
+	equals(t, `key {"ddsource":"go","service":"api","ddtags":"env:prod"}`, <-lines)
+	equals(t, `key {"ddsource":"go","service":"api","ddtags":"env:prod","message":"not json"}`, <-lines)
+}
+
+func TestProtocolAgent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	defer l.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	// no detection, the Agent is the configured protocol
+	c := New(Config{Protocol: ProtocolAgent, AgentAddr: l.Addr().String()})
+	equals(t, PathAgent, c.Stats().Path)
+	ok(t, c.Push([]byte(`one`)))
+	ok(t, c.Close(context.Background()))
+	equals(t, `one`, <-lines)
+}
+
+func TestProtocolString(t *testing.T) {
+	equals(t, "v1-http", ProtocolV1HTTP.String())
+	equals(t, "v2-http", ProtocolV2HTTP.String())
+	equals(t, "tcp", ProtocolTCP.String())
+	equals(t, "agent", ProtocolAgent.String())
+	equals(t, "Protocol(9)", Protocol(9).String())
+}
+
+func TestURL(t *testing.T) {
+	for _, test := range []struct {
+		host     string
+		protocol Protocol
+		stream   Stream
+		url      string
+	}{
+		{DatadogUSHost, ProtocolV1HTTP, Stream{}, "https://" + DatadogUSHost + "/v1/input"},
+		{DatadogEUHost, ProtocolV2HTTP, Stream{}, "https://" + DatadogEUHost + "/api/v2/logs"},
+		{
+			DatadogUSHost, ProtocolV1HTTP,
+			Stream{Source: "go", Service: "billing api", Hostname: "web-1", Tags: []string{"env:prod"}},
+			"https://" + DatadogUSHost + "/v1/input?ddsource=go&ddtags=env%3Aprod&hostname=web-1&service=billing+api",
+		},
+		// empty fields and tags escaped to nothing are left out
+		{"relay:8080", ProtocolV1HTTP, Stream{Service: "svc", Tags: []string{" ", ""}}, "https://relay:8080/v1/input?service=svc"},
+		// values are query escaped, tags escaped as one tag each
+		{
+			"relay", ProtocolV1HTTP,
+			Stream{Service: "a&b=c", Tags: []string{"team:a,b", "url:http://x/?y=1", "région:ouest"}},
+			"https://relay/v1/input?ddtags=team%3Aa_b%2Curl%3Ahttp%3A%2F%2Fx%2F%3Fy%3D1%2Cr%C3%A9gion%3Aouest&service=a%26b%3Dc",
+		},
+		// the v2 intake reads the stream in the payload
+		{DatadogUSHost, ProtocolV2HTTP, Stream{Service: "api", Tags: []string{"env:prod"}}, "https://" + DatadogUSHost + "/api/v2/logs"},
+		// the path of a relay is kept in front of the intake path
+		{"relay.example.com/datadog", ProtocolV1HTTP, Stream{}, "https://relay.example.com/datadog/v1/input"},
+		{"relay.example.com/datadog/", ProtocolV2HTTP, Stream{}, "https://relay.example.com/datadog/api/v2/logs"},
+	} {
+		u, err := URL(test.host, test.protocol, test.stream)
+		ok(t, err)
+		equals(t, test.url, u)
+	}
+
+	c := &Client{scheme: "https", config: Config{Host: "relay"}}
+	stream := Stream{Source: "go", Tags: []string{"a:1"}}
+	u, err := URL("relay", ProtocolV1HTTP, stream)
+	ok(t, err)
+	equals(t, c.datadogURL(stream), u)
+
+	_, err = URL(DatadogUSTCPHost, ProtocolTCP, Stream{})
+	equals(t, true, errors.Is(err, ErrNoURL))
+	_, err = URL("", ProtocolAgent, Stream{})
+	equals(t, true, errors.Is(err, ErrNoURL))
+	_, err = URL("", ProtocolV1HTTP, Stream{})
+	equals(t, ErrInvalidHost, err)
+	_, err = URL("bad host%", ProtocolV1HTTP, Stream{})
+	equals(t, ErrInvalidHost, err)
+}
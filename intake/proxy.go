@@ -0,0 +1,66 @@
+package intake
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ErrInvalidProxy - Config.Proxy is not an http, https or socks5 URL
+var ErrInvalidProxy = errors.New("intake: invalid proxy")
+
+// parseProxy - the URL of the proxy, ErrInvalidProxy if it can't be used
+func parseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, ErrInvalidProxy
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	default:
+		return nil, ErrInvalidProxy
+	}
+}
+
+// redactProxy - the proxy URL without its password, for logs
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// proxyFunc - the proxy of the requests, the environment's if proxy is
+// empty, otherwise proxy but for the hosts of NO_PROXY
+func proxyFunc(proxy string) func(*http.Request) (*url.URL, error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment
+	}
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	fn := (&httpproxy.Config{HTTPProxy: proxy, HTTPSProxy: proxy, NoProxy: noProxy}).ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return fn(r.URL)
+	}
+}
+
+// defaultHTTPClient - http client of the client alone, with a clone of the
+// default transport so neither changes http.DefaultClient sees the other,
+// going through proxy with the TLS settings of config. It has no timeout of
+// its own: Config.RequestTimeout alone bounds every attempt.
+func defaultHTTPClient(proxy string, config *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(proxy)
+	if config != nil {
+		transport.TLSClientConfig = config.Clone()
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,93 @@
+package intake
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	for _, proxy := range []string{"proxy:3128", "ftp://proxy", "http://", "://x"} {
+		err := Config{Proxy: proxy}.Validate()
+		equals(t, true, errors.Is(err, ErrInvalidProxy))
+	}
+	ok(t, Config{Proxy: "socks5://proxy:1080"}.Validate())
+
+	// the proxy is given the request for the intake
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+	}))
+	defer proxy.Close()
+	c := New(Config{Host: "intake.test", APIKey: "secret-key", Proxy: "http://user:password@" + proxy.Listener.Addr().String()})
+	c.scheme = "http"
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, "http://intake.test/api/v2/logs", <-proxied)
+	equals(t, true, strings.Contains(c.config.String(), "proxy=http://user:xxxxx@"))
+}
+
+func TestProxyNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "direct.test")
+	fn := proxyFunc("http://proxy:3128")
+	u, err := fn(&http.Request{URL: &url.URL{Scheme: "https", Host: "intake.test"}})
+	ok(t, err)
+	equals(t, "http://proxy:3128", u.String())
+	u, err = fn(&http.Request{URL: &url.URL{Scheme: "https", Host: "direct.test"}})
+	ok(t, err)
+	equals(t, (*url.URL)(nil), u)
+}
+
+func TestTLSConfig(t *testing.T) {
+	versions := make(chan uint16, 10)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a client certificate is required by the gateway
+		if len(r.TLS.PeerCertificates) == 1 {
+			versions <- r.TLS.Version
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	config := &tls.Config{RootCAs: roots, Certificates: srv.TLS.Certificates, MinVersion: tls.VersionTLS13}
+
+	c := New(Config{Host: srv.Listener.Addr().String(), APIKey: "key", TLSConfig: config})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, uint16(tls.VersionTLS13), <-versions)
+	equals(t, int64(1), c.Stats().Delivered)
+
+	// with DNSFallback as well
+	c = New(Config{Host: srv.Listener.Addr().String(), APIKey: "key", TLSConfig: config, DNSFallback: true})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, uint16(tls.VersionTLS13), <-versions)
+
+	// the private CA is not trusted without it
+	c = New(Config{Host: srv.Listener.Addr().String(), APIKey: "key", TLSConfig: &tls.Config{Certificates: srv.TLS.Certificates}})
+	ok(t, c.Push([]byte("one")))
+	equals(t, true, c.Close(context.Background()) != nil)
+	equals(t, int64(0), c.Stats().Delivered)
+	equals(t, 0, len(versions))
+}
+
+func TestTCPTLS(t *testing.T) {
+	config := tcpTLS(nil, "intake.test:10516")
+	equals(t, "intake.test", config.ServerName)
+	custom := &tls.Config{MinVersion: tls.VersionTLS13}
+	config = tcpTLS(custom, "intake.test:10516")
+	equals(t, "intake.test", config.ServerName)
+	equals(t, uint16(tls.VersionTLS13), config.MinVersion)
+	// cloned
+	equals(t, "", custom.ServerName)
+	config = tcpTLS(&tls.Config{ServerName: "gateway.corp"}, "10.0.0.1:10516")
+	equals(t, "gateway.corp", config.ServerName)
+}
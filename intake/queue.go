@@ -0,0 +1,77 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// errQueueFull - no room for a line in the queue, while the overflow
+// policy does not wait
+var errQueueFull = errors.New("intake: queue full")
+
+// queueCapacity - entries every lane holds before pushes wait, 1 unless
+// Config.QueueSize
+func (config Config) queueCapacity() int {
+	if config.QueueSize > 0 {
+		return config.QueueSize
+	}
+	return 1
+}
+
+// reserve - wait for room for a line of n bytes in the queue, or ctx or the
+// client to be done, errQueueFull right away unless the overflow policy
+// blocks or wait is set. A line larger than Config.QueueBytes gets in once the queue is
+// empty.
+func (c *Client) reserve(ctx context.Context, n int, wait bool) error {
+	max := c.config.QueueBytes
+	if max <= 0 {
+		return nil
+	}
+	for {
+		queued := atomic.LoadInt64(&c.queued)
+		if queued == 0 || queued+int64(n) <= max {
+			if !atomic.CompareAndSwapInt64(&c.queued, queued, queued+int64(n)) {
+				continue
+			}
+			if queued+int64(n) < max {
+				// pushes waiting may fit as well
+				c.wakeQueue()
+			}
+			return nil
+		}
+		if c.config.Overflow != OverflowBlock && !wait {
+			return errQueueFull
+		}
+		select {
+		case <-c.room:
+		case <-c.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// unreserve - give back the room of a line of n bytes leaving the queue
+func (c *Client) unreserve(n int) {
+	if c.config.QueueBytes <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.queued, -int64(n))
+	c.wakeQueue()
+}
+
+// wakeQueue - let a push waiting for room check again
+func (c *Client) wakeQueue() {
+	select {
+	case c.room <- struct{}{}:
+	default:
+	}
+}
+
+// QueuedBytes - bytes of the lines waiting to be batched, only counted
+// with Config.QueueBytes
+func (c *Client) QueuedBytes() int64 {
+	return atomic.LoadInt64(&c.queued)
+}
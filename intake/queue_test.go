@@ -0,0 +1,62 @@
+package intake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stalledPolicy - flush policy holding the batcher in OnAdd until released
+type stalledPolicy struct {
+	added   chan struct{}
+	release chan struct{}
+}
+
+func newStalledPolicy() *stalledPolicy {
+	return &stalledPolicy{added: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (p *stalledPolicy) Interval() time.Duration { return 0 }
+func (p *stalledPolicy) OnAdd(BatchInfo) bool {
+	p.added <- struct{}{}
+	<-p.release
+	return true
+}
+func (p *stalledPolicy) OnTick(BatchInfo, time.Time) bool { return true }
+
+// pushes - how many of the lines got in the queue, each waiting a moment
+func pushes(c *Client, lines ...string) int64 {
+	before := c.Stats().Pushed
+	for _, line := range lines {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		c.push(ctx, Entry{Line: []byte(line)}, nil)
+		cancel()
+	}
+	return c.Stats().Pushed - before
+}
+
+func TestQueueSize(t *testing.T) {
+	p := newStalledPolicy()
+	c := New(Config{QueueSize: 5, FlushPolicy: p, Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	ok(t, c.Push([]byte("held")))
+	<-p.added
+	// the batcher is held, the lanes take the burst
+	equals(t, int64(5), pushes(c, "1", "2", "3", "4", "5", "6"))
+	close(p.release)
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(6), c.Stats().Delivered)
+}
+
+func TestQueueBytes(t *testing.T) {
+	p := newStalledPolicy()
+	c := New(Config{QueueSize: 100, QueueBytes: 10, FlushPolicy: p, Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	ok(t, c.Push([]byte("held")))
+	<-p.added
+	// lines are framed with their newline, 4 bytes each
+	equals(t, int64(2), pushes(c, "one", "two", "six"))
+	equals(t, int64(8), c.QueuedBytes())
+	close(p.release)
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(0), c.QueuedBytes())
+	equals(t, int64(3), c.Stats().Delivered)
+}
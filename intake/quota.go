@@ -0,0 +1,72 @@
+package intake
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Rate limit headers of the responses of the intake
+const (
+	// RateLimitLimitHeader - requests allowed in a period
+	RateLimitLimitHeader = "X-RateLimit-Limit"
+	// RateLimitPeriodHeader - seconds of a period
+	RateLimitPeriodHeader = "X-RateLimit-Period"
+	// RateLimitRemainingHeader - requests left in the current period
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	// RateLimitResetHeader - seconds until the current period ends
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
+
+// Quota is the rate limit the intake reported in a response, for callers to
+// slow down before being answered 429 Too Many Requests
+type Quota struct {
+	// Limit - requests allowed in a period, 0 if not reported
+	Limit int64
+	// Remaining - requests left in the current period
+	Remaining int64
+	// Period - length of a period, 0 if not reported
+	Period time.Duration
+	// Reset - when the current period ends, zero if not reported
+	Reset time.Time
+	// Time - when the response was received
+	Time time.Time
+}
+
+// ParseQuota - the quota reported by the rate limit headers of a response
+// received at now, false if it has no X-RateLimit-Remaining header
+func ParseQuota(h http.Header, now time.Time) (Quota, bool) {
+	remaining, err := strconv.ParseInt(h.Get(RateLimitRemainingHeader), 10, 64)
+	if err != nil {
+		return Quota{}, false
+	}
+	q := Quota{Remaining: remaining, Time: now}
+	q.Limit, _ = strconv.ParseInt(h.Get(RateLimitLimitHeader), 10, 64)
+	if period, err := strconv.ParseInt(h.Get(RateLimitPeriodHeader), 10, 64); err == nil {
+		q.Period = time.Duration(period) * time.Second
+	}
+	if reset, err := strconv.ParseInt(h.Get(RateLimitResetHeader), 10, 64); err == nil {
+		q.Reset = now.Add(time.Duration(reset) * time.Second)
+	}
+	return q, true
+}
+
+// Quota - the rate limit of the last response which reported one, false
+// until then, safe to call at any time
+func (c *Client) Quota() (Quota, bool) {
+	q := c.quota.Load()
+	if q == nil {
+		return Quota{}, false
+	}
+	return *q, true
+}
+
+// observeQuota - record the quota reported by a response, nil if it has none
+func (c *Client) observeQuota(h http.Header) *Quota {
+	q, ok := ParseQuota(h, c.clock().Now())
+	if !ok {
+		return nil
+	}
+	c.quota.Store(&q)
+	return &q
+}
@@ -0,0 +1,75 @@
+package intake
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseQuota(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := http.Header{}
+	_, ok := ParseQuota(h, now)
+	equals(t, false, ok)
+
+	h.Set(RateLimitRemainingHeader, "12")
+	q, ok := ParseQuota(h, now)
+	equals(t, true, ok)
+	equals(t, Quota{Remaining: 12, Time: now}, q)
+
+	h.Set(RateLimitLimitHeader, "100")
+	h.Set(RateLimitPeriodHeader, "60")
+	h.Set(RateLimitResetHeader, "7")
+	q, _ = ParseQuota(h, now)
+	equals(t, Quota{Limit: 100, Remaining: 12, Period: time.Minute, Reset: now.Add(7 * time.Second), Time: now}, q)
+}
+
+func TestQuota(t *testing.T) {
+	var m sync.Mutex
+	remaining := 3
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		throttled := remaining == 0
+		if !throttled {
+			remaining--
+		}
+		w.Header().Set(RateLimitLimitHeader, "3")
+		w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(remaining))
+		w.Header().Set(RateLimitResetHeader, "10")
+		if throttled {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	audits := make(chan AuditRecord, 1)
+	c, tick := newClient(srv, Config{
+		Clock:   clock,
+		OnAudit: func(r AuditRecord) { audits <- r },
+	})
+	_, found := c.Quota()
+	equals(t, false, found)
+	equals(t, (*Quota)(nil), c.Stats().Quota)
+
+	var records []AuditRecord
+	for i := 0; i < 4; i++ {
+		ok(t, c.Push([]byte("line")))
+		tick <- clock.Now()
+		records = append(records, <-audits)
+	}
+	equals(t, int64(2), records[0].Quota.Remaining)
+	// answered 429, the quota is reported all the same
+	equals(t, false, records[3].Delivered)
+	equals(t, int64(0), records[3].Quota.Remaining)
+	ok(t, c.Close(context.Background()))
+	q, found := c.Quota()
+	equals(t, true, found)
+	equals(t, Quota{Limit: 3, Remaining: 0, Reset: clock.Now().Add(10 * time.Second), Time: clock.Now()}, q)
+	equals(t, &q, c.Stats().Quota)
+}
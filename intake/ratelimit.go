@@ -0,0 +1,75 @@
+package intake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// bucket - token bucket refilled at rate per second up to burst, going into
+// debt so a request bigger than the burst still goes after waiting
+type bucket struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newBucket(rate float64) bucket {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return bucket{rate: rate, burst: burst, tokens: burst}
+}
+
+// take - take n tokens at now, how long to wait for the bucket to be out of debt
+func (b *bucket) take(now time.Time, n float64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// rateLimit - the requests and bytes sent per second allowed by
+// Config.MaxRequestsPerSecond and Config.MaxBytesPerSecond. Only send uses
+// it, with the client locked.
+type rateLimit struct {
+	requests, bytes bucket
+}
+
+func newRateLimit(config Config) *rateLimit {
+	if config.MaxRequestsPerSecond <= 0 && config.MaxBytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimit{
+		requests: newBucket(config.MaxRequestsPerSecond),
+		bytes:    newBucket(float64(config.MaxBytesPerSecond)),
+	}
+}
+
+// rateWait - how long to wait before an attempt sending size bytes
+func (c *Client) rateWait(size int) time.Duration {
+	l := c.rateLimit
+	if l == nil {
+		return 0
+	}
+	now := c.clock().Now()
+	d := l.requests.take(now, 1)
+	if b := l.bytes.take(now, float64(size)); b > d {
+		d = b
+	}
+	if d > 0 {
+		atomic.AddInt64(&c.stats.rateLimited, 1)
+	}
+	return d
+}
@@ -0,0 +1,46 @@
+package intake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := newBucket(100)
+	equals(t, time.Duration(0), b.take(now, 60))
+	equals(t, time.Duration(0), b.take(now, 40))
+	equals(t, 500*time.Millisecond, b.take(now, 50))
+	// refilled at the rate, never beyond the burst
+	equals(t, time.Duration(0), b.take(now.Add(time.Second), 50))
+	equals(t, time.Duration(0), b.take(now.Add(time.Hour), 100))
+	equals(t, 2*time.Second, b.take(now.Add(time.Hour), 200))
+
+	unlimited := newBucket(0)
+	equals(t, time.Duration(0), unlimited.take(now, 1e9))
+}
+
+func TestRateLimit(t *testing.T) {
+	srv, reqs := newServer(t)
+	clock := &instantClock{fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	c, tick := newClient(srv, Config{MaxRequestsPerSecond: 1, MaxBytesPerSecond: 1 << 20, Clock: clock})
+	for _, line := range []string{"one", "two", "three"} {
+		ok(t, c.Push([]byte(line)))
+		tick <- clock.Now()
+		<-reqs
+	}
+	ok(t, c.Close(context.Background()))
+	// the clock is frozen, the debt of every request adds up
+	equals(t, []time.Duration{time.Second, 2 * time.Second}, clock.Waits())
+	equals(t, int64(2), c.Stats().RateLimited)
+
+	c, tick = newClient(srv, Config{MaxBytesPerSecond: 4, Clock: clock})
+	clock.waits = nil
+	ok(t, c.Push([]byte("four")))
+	tick <- clock.Now()
+	<-reqs
+	ok(t, c.Close(context.Background()))
+	// "four\n" is a byte over the second allowed
+	equals(t, []time.Duration{250 * time.Millisecond}, clock.Waits())
+}
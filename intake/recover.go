@@ -0,0 +1,43 @@
+package intake
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// PanicError is a panic recovered by the client, the batching loop is
+// restarted and a batch being sent is dropped
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("intake: recovered panic: %v", e.Value)
+}
+
+// Incidents - panics recovered by the client since it was created
+func (c *Client) Incidents() int64 {
+	return atomic.LoadInt64(&c.incidents)
+}
+
+// recovered - count and report a recovered panic
+func (c *Client) recovered(v interface{}) {
+	atomic.AddInt64(&c.incidents, 1)
+	err := &PanicError{Value: v, Stack: debug.Stack()}
+	c.Debugf("%v\n%s", err, err.Stack)
+	c.notifyError(err)
+}
+
+// notifyError - report an error no caller can be given, a panicking
+// OnError is not recovered again
+func (c *Client) notifyError(err error) {
+	atomic.StoreInt64(&c.stats.lastError, c.clock().Now().UnixNano())
+	if fn := c.config.OnError; fn != nil {
+		fn(err)
+	}
+	if fn := c.config.OnErrorContext; fn != nil {
+		fn(c.Context(), err)
+	}
+}
@@ -0,0 +1,71 @@
+package intake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// panicPolicy - flush policy panicking on the first entry added
+type panicPolicy struct {
+	once sync.Once
+}
+
+func (p *panicPolicy) Interval() time.Duration { return time.Second }
+func (p *panicPolicy) OnAdd(BatchInfo) bool {
+	p.once.Do(func() { panic("policy bug") })
+	return false
+}
+func (p *panicPolicy) OnTick(BatchInfo, time.Time) bool { return true }
+
+func TestRecoverBatching(t *testing.T) {
+	srv, reqs := newServer(t)
+	var m sync.Mutex
+	var errs []error
+	c, tick := newClient(srv, Config{
+		FlushPolicy: &panicPolicy{},
+		OnError: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+	tick <- time.Now()
+	// the entry piled up before the panic is still delivered
+	equals(t, "one\ntwo\n", (<-reqs).body)
+	ok(t, c.Close(context.Background()))
+
+	equals(t, int64(1), c.Incidents())
+	equals(t, 1, len(errs))
+	pe, isPanic := errs[0].(*PanicError)
+	equals(t, true, isPanic)
+	equals(t, "policy bug", pe.Value)
+	equals(t, "intake: recovered panic: policy bug", pe.Error())
+}
+
+func TestRecoverSend(t *testing.T) {
+	srv, reqs := newServer(t)
+	var m sync.Mutex
+	var errs []error
+	c, tick := newClient(srv, Config{
+		OnAudit: func(AuditRecord) { panic("audit bug") },
+		OnError: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	<-reqs
+	// the client keeps shipping after a panic in a send
+	ok(t, c.Push([]byte("two")))
+	tick <- time.Now()
+	equals(t, "two\n", (<-reqs).body)
+	closeDropping(t, c)
+	equals(t, int64(2), c.Incidents())
+	equals(t, "audit bug", errs[0].(*PanicError).Value)
+}
@@ -0,0 +1,154 @@
+package intake
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReplayOptions configure ReplayFiles
+type ReplayOptions struct {
+	// Config - of the client the lines are sent with. JSON must match the
+	// lines of the files, and a Fallback gets the lines dropped again.
+	Config Config
+	// Rate - lines pushed per second at most, unlimited if 0
+	Rate float64
+	// OnFile - called once a file was read, with the lines read from it
+	OnFile func(path string, lines int)
+}
+
+// ReplayResult tell what ReplayFiles did
+type ReplayResult struct {
+	// Files - files read in full
+	Files int
+	// Lines - lines pushed
+	Lines int64
+	// Delivered - lines delivered
+	Delivered int64
+	// Dropped - lines dropped again after retries
+	Dropped int64
+}
+
+// maxReplayLine - longest line read from a file, the intake refuses longer ones
+const maxReplayLine = 2 * maxEntryByteSize
+
+// ReplayFiles - send the lines of dead-letter files, such as the ones a
+// Config.Fallback file collected during an outage, through a client of
+// opts.Config at opts.Rate, stopping when ctx is done. Files ending in .gz
+// are decompressed. Lines are sent to the stream of the config, the
+// fallback does not record the stream of a line. JSON lines resume the
+// delivery history they carry in AttemptsField.
+func ReplayFiles(ctx context.Context, paths []string, opts ReplayOptions) (ReplayResult, error) {
+	var result ReplayResult
+	c := New(opts.Config)
+	if c.err != nil {
+		return result, c.err
+	}
+	limit := newLimiter(opts.Rate)
+	var err error
+	for _, path := range paths {
+		var lines int
+		if lines, err = replayFile(ctx, c, path, limit); err != nil {
+			break
+		}
+		result.Files++
+		if opts.OnFile != nil {
+			opts.OnFile(path, lines)
+		}
+	}
+	closeCtx := ctx
+	if ctx.Err() != nil {
+		// the lines already pushed were read, they may still be delivered
+		var cancel context.CancelFunc
+		closeCtx, cancel = context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+	}
+	if cerr := c.Close(closeCtx); err == nil {
+		err = cerr
+	}
+	stats := c.Stats()
+	result.Lines, result.Delivered, result.Dropped = stats.Pushed, stats.Delivered, stats.Dropped
+	return result, err
+}
+
+// replayFile - push the lines of the file at path, the count of lines pushed
+func replayFile(ctx context.Context, c *Client, path string, limit *limiter) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		z, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer z.Close()
+		r = z
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxReplayLine)
+	lines := 0
+	for s.Scan() {
+		if len(strings.TrimSpace(s.Text())) == 0 {
+			continue
+		}
+		if err := limit.wait(ctx); err != nil {
+			return lines, err
+		}
+		e := Entry{Stream: c.config.Stream, Line: s.Bytes()}
+		if c.config.JSON {
+			e.attempts = lineAttempts(e.Line)
+		}
+		if err := c.push(ctx, e, nil); err != nil {
+			return lines, err
+		}
+		lines++
+	}
+	return lines, s.Err()
+}
+
+// limiter - spacing of the lines pushed at a rate
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait - wait for the turn of the next line, or ctx to be done
+func (l *limiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.interval == 0 {
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	d := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package intake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplayFiles(t *testing.T) {
+	var m sync.Mutex
+	var bodies []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		m.Lock()
+		defer m.Unlock()
+		bodies = append(bodies, r.URL.Query().Get("service")+" "+string(b))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "dead-letter.log")
+	ok(t, ioutil.WriteFile(plain, []byte("{\"msg\":\"one\"}\n\n{\"msg\":\"two\"}\n"), 0644))
+	var z bytes.Buffer
+	w := gzip.NewWriter(&z)
+	w.Write([]byte("{\"msg\":\"three\"}\n"))
+	w.Close()
+	compressed := filepath.Join(dir, "dead-letter.log.1.gz")
+	ok(t, ioutil.WriteFile(compressed, z.Bytes(), 0644))
+
+	files := map[string]int{}
+	start := time.Now()
+	result, err := ReplayFiles(context.Background(), []string{plain, compressed}, ReplayOptions{
+		Config: Config{
+			Host:       srv.Listener.Addr().String(),
+			APIKey:     "key",
+			JSON:       true,
+			Stream:     Stream{Service: "replayed"},
+			HTTPClient: srv.Client(),
+		},
+		Rate:   20,
+		OnFile: func(path string, lines int) { files[filepath.Base(path)] = lines },
+	})
+	ok(t, err)
+	// 3 lines at 20 per second
+	equals(t, true, time.Since(start) >= 100*time.Millisecond)
+	equals(t, ReplayResult{Files: 2, Lines: 3, Delivered: 3}, result)
+	equals(t, map[string]int{"dead-letter.log": 2, "dead-letter.log.1.gz": 1}, files)
+	m.Lock()
+	defer m.Unlock()
+	equals(t, []string{` [{"service":"replayed","msg":"one"},{"service":"replayed","msg":"two"},{"service":"replayed","msg":"three"}]`}, bodies)
+}
+
+func TestReplayFilesErrors(t *testing.T) {
+	_, err := ReplayFiles(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, ReplayOptions{
+		Config: Config{Exporter: ExporterFunc(func(*Batch) error { return nil })},
+	})
+	equals(t, true, os.IsNotExist(err))
+
+	_, err = ReplayFiles(context.Background(), nil, ReplayOptions{Config: Config{APIKey: "key", Host: "key.example.com"}})
+	equals(t, ErrAPIKeyInHost, err)
+
+	// a cancelled replay stops reading, the lines read are still delivered
+	path := filepath.Join(t.TempDir(), "dead-letter.log")
+	ok(t, ioutil.WriteFile(path, []byte(strings.Repeat("line\n", 100)), 0644))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var m sync.Mutex
+	delivered := 0
+	result, err := ReplayFiles(ctx, []string{path}, ReplayOptions{
+		Config: Config{Exporter: ExporterFunc(func(b *Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			delivered += len(b.Lines)
+			return nil
+		})},
+		Rate: 100,
+	})
+	equals(t, context.DeadlineExceeded, err)
+	equals(t, 0, result.Files)
+	equals(t, true, result.Lines > 0 && result.Lines < 100)
+	equals(t, result.Lines, result.Delivered)
+	equals(t, int(result.Lines), delivered)
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(0)
+	ok(t, l.wait(context.Background()))
+	l = newLimiter(1000)
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		ok(t, l.wait(context.Background()))
+	}
+	equals(t, true, time.Since(start) >= 10*time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package intake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// SequenceHeader - header carrying the sequence number of a batch
+const SequenceHeader = "X-Log-Batch-Sequence"
+
+// sequence - sequence numbers of the batches, only taken under the send lock.
+// The next number is persisted before a batch is sent, so a restart resumes
+// after the last batch attempted: downstream sees a gap for a batch lost in
+// a crash, a duplicate for a batch retried, and never a number reused.
+type sequence struct {
+	path string
+	next uint64 // atomic, read by Sequence while sending
+}
+
+// loadSequence - sequence resuming from the file at path, starting at 1 if
+// it does not exist or path is empty
+func loadSequence(path string) (*sequence, error) {
+	s := &sequence{path: path, next: 1}
+	if path == "" {
+		return s, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.next, err = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64); err != nil || s.next == 0 {
+		return nil, fmt.Errorf("intake: invalid sequence file %s", path)
+	}
+	return s, nil
+}
+
+// take - the number of the next batch, persisted first. On a persistence
+// error the number is still used, a restart may then repeat it.
+func (s *sequence) take() (uint64, error) {
+	n := atomic.AddUint64(&s.next, 1) - 1
+	if s.path == "" {
+		return n, nil
+	}
+	return n, writeFileAtomic(s.path, []byte(strconv.FormatUint(n+1, 10)+"\n"))
+}
+
+// writeFileAtomic - replace the file at path with b, never leaving it partly written
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Sequence - number of the last batch sent, 0 if none or sequencing is off.
+// Batches are numbered from 1 without gaps while the process runs.
+func (c *Client) Sequence() uint64 {
+	if c.sequence == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.sequence.next) - 1
+}
@@ -0,0 +1,84 @@
+package intake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequence(t *testing.T) {
+	var m sync.Mutex
+	var sequences []string
+	fail := true
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		sequences = append(sequences, r.Header.Get(SequenceHeader))
+		if fail {
+			// the first attempt fails, the retry carries the same number
+			fail = false
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "sequence")
+	var records []AuditRecord
+	config := Config{
+		Host:         srv.Listener.Addr().String(),
+		MaxRetry:     2,
+		HTTPClient:   srv.Client(),
+		SequenceFile: path,
+		FlushPolicy:  MaxEntries(1),
+		OnAudit: func(r AuditRecord) {
+			m.Lock()
+			defer m.Unlock()
+			records = append(records, r)
+		},
+	}
+	c := New(config)
+	equals(t, uint64(0), c.Sequence())
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+	ok(t, c.Close(context.Background()))
+	equals(t, uint64(2), c.Sequence())
+	b, err := ioutil.ReadFile(path)
+	ok(t, err)
+	equals(t, "3\n", string(b))
+
+	// a restart resumes from the file
+	c = New(config)
+	ok(t, c.Push([]byte("three")))
+	ok(t, c.Close(context.Background()))
+	m.Lock()
+	defer m.Unlock()
+	equals(t, []string{"1", "1", "2", "3"}, sequences)
+	equals(t, []uint64{1, 2, 3}, []uint64{records[0].Sequence, records[1].Sequence, records[2].Sequence})
+}
+
+func TestSequenceOff(t *testing.T) {
+	srv, requests := newServer(t)
+	c, tick := newClient(srv, Config{})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	equals(t, "", (<-requests).header.Get(SequenceHeader))
+	equals(t, uint64(0), c.Sequence())
+	ok(t, c.Close(context.Background()))
+}
+
+func TestSequenceInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequence")
+	ok(t, ioutil.WriteFile(path, []byte("garbage"), 0644))
+	c := New(Config{SequenceFile: path})
+	equals(t, true, c.Push([]byte("one")) != nil)
+
+	s, err := loadSequence("")
+	ok(t, err)
+	n, err := s.take()
+	ok(t, err)
+	equals(t, uint64(1), n)
+}
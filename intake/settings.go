@@ -0,0 +1,54 @@
+package intake
+
+// Settings - the part of the config which may change while the client runs.
+// Updates are copy-on-write: every batch is sent with the snapshot taken
+// when its sending started, so a batch never mixes the fields of two updates,
+// e.g. the HMAC key of one and the header of another.
+type Settings struct {
+	MaxRetry             int
+	Compress             bool
+	CompressionThreshold int
+	HMACKey              []byte
+	HMACHeader           string
+}
+
+// settingsOf - the settings of config
+func settingsOf(config Config) *Settings {
+	return &Settings{
+		MaxRetry:             config.MaxRetry,
+		Compress:             config.Compress,
+		CompressionThreshold: config.CompressionThreshold,
+		HMACKey:              config.HMACKey,
+		HMACHeader:           config.HMACHeader,
+	}
+}
+
+// clone - a copy of the settings sharing no memory with them
+func (s Settings) clone() *Settings {
+	s.HMACKey = append([]byte(nil), s.HMACKey...)
+	return &s
+}
+
+// Settings - a copy of the current settings
+func (c *Client) Settings() Settings {
+	return *c.settings().clone()
+}
+
+// UpdateSettings - change the settings with update, applied to a copy of
+// the current ones. Batches being sent keep the settings they started with,
+// the next batches get the new ones. Safe to call at any time.
+func (c *Client) UpdateSettings(update func(*Settings)) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+	s := c.settings().clone()
+	update(s)
+	c.current.Store(s.clone())
+}
+
+// settings - the current snapshot, never modified once stored
+func (c *Client) settings() *Settings {
+	if s := c.current.Load(); s != nil {
+		return s
+	}
+	return settingsOf(c.config)
+}
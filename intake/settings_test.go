@@ -0,0 +1,78 @@
+package intake
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUpdateSettings(t *testing.T) {
+	c := &Client{config: Config{MaxRetry: 3, HMACKey: []byte("key")}}
+	c.UpdateSettings(func(s *Settings) { s.MaxRetry = 5 })
+	s := c.Settings()
+	equals(t, 5, s.MaxRetry)
+	equals(t, []byte("key"), s.HMACKey)
+	// snapshots are copies, changing them changes nothing
+	s.HMACKey[0] = 'x'
+	equals(t, []byte("key"), c.Settings().HMACKey)
+	equals(t, 3, c.config.MaxRetry)
+}
+
+// TestSettingsWhileSending - batches are sent with one snapshot of the
+// settings whatever the updates racing with them
+func TestSettingsWhileSending(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, _ := newClient(srv, Config{FlushPolicy: MaxEntries(1), HMACKey: []byte("a"), HMACHeader: "X-A"})
+	a := func(s *Settings) { *s = Settings{HMACKey: []byte("a"), HMACHeader: "X-A"} }
+	b := func(s *Settings) {
+		*s = Settings{HMACKey: []byte("b"), HMACHeader: "X-B", Compress: true, CompressionThreshold: 1}
+	}
+
+	const n = 100
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				c.UpdateSettings(a)
+				c.UpdateSettings(b)
+				_ = c.Settings()
+				runtime.Gosched()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		ok(t, c.Push([]byte("line")))
+	}
+	for i := 0; i < n; i++ {
+		r := <-reqs
+		payload := r.body
+		if r.header.Get("X-A") != "" {
+			equals(t, "", r.header.Get("X-B"))
+			equals(t, "", r.header.Get("Content-Encoding"))
+			equals(t, true, Verify([]byte("a"), []byte(payload), r.header.Get("X-A")))
+			continue
+		}
+		// compressed and signed with the key of the same update
+		equals(t, EncodingGzip, r.header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(strings.NewReader(payload))
+		ok(t, err)
+		raw, err := ioutil.ReadAll(zr)
+		ok(t, err)
+		equals(t, true, Verify([]byte("b"), raw, r.header.Get("X-B")))
+	}
+	close(done)
+	wg.Wait()
+	ok(t, c.Close(context.Background()))
+}
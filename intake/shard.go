@@ -0,0 +1,153 @@
+package intake
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultShardSize - entries a shard holds before pushes go to the lanes
+	DefaultShardSize = 1024
+	// defaultShardSweep - interval the batcher sweeps the shards at
+	defaultShardSweep = time.Millisecond
+)
+
+// shard - entries appended by the goroutines which picked it, swept by the
+// batcher
+type shard struct {
+	m       sync.Mutex
+	entries []Entry
+	closed  bool
+
+	spare []Entry  // only used by the batcher, the entries swept last
+	_     [64]byte // a cache line apart from the next shard
+}
+
+// shards - the buffers pushes append to with Config.Shards, a random one
+// each so goroutines seldom wait on one another
+type shards struct {
+	shards []shard
+	size   int
+
+	// armed - set once an entry was appended since the last sweep, the
+	// batcher being woken up on wake to arm the timer of the next one
+	armed int32
+	wake  chan struct{}
+}
+
+func newShards(n, size int) *shards {
+	if size <= 0 {
+		size = DefaultShardSize
+	}
+	s := &shards{shards: make([]shard, n), size: size, wake: make(chan struct{}, 1)}
+	for i := range s.shards {
+		s.shards[i].entries = make([]Entry, 0, size)
+		s.shards[i].spare = make([]Entry, 0, size)
+	}
+	return s
+}
+
+// push - append the entry to a shard, false if it was full so the entry
+// waits in the lanes instead, ErrClosed once the shards were drained
+func (s *shards) push(e Entry) (bool, error) {
+	sh := &s.shards[rand.IntN(len(s.shards))]
+	sh.m.Lock()
+	defer sh.m.Unlock()
+	if sh.closed {
+		return false, ErrClosed
+	}
+	if len(sh.entries) >= s.size {
+		return false, nil
+	}
+	sh.entries = append(sh.entries, e)
+	s.arm()
+	return true, nil
+}
+
+// arm - wake the batcher up to sweep, unless a sweep is already due
+func (s *shards) arm() {
+	if !atomic.CompareAndSwapInt32(&s.armed, 0, 1) {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sweep - hand the entries of every shard to add, closing the shards on
+// the final sweep, only called from the pile goroutine. The entries
+// appended from now on arm the next sweep.
+func (s *shards) sweep(add func(Entry), final bool) {
+	atomic.StoreInt32(&s.armed, 0)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.m.Lock()
+		swept := sh.entries
+		sh.entries = sh.spare[:0]
+		sh.closed = sh.closed || final
+		sh.m.Unlock()
+		s.hand(sh, swept, add)
+	}
+}
+
+// hand - give the entries swept from sh to add. Should add panic, the
+// entries after the one it panicked on are put back in sh for the next
+// sweep rather than lost while the batching restarts.
+func (s *shards) hand(sh *shard, swept []Entry, add func(Entry)) {
+	next := 0
+	defer func() {
+		if next < len(swept) {
+			sh.m.Lock()
+			rest := make([]Entry, 0, len(swept)-next+len(sh.entries))
+			sh.entries = append(append(rest, swept[next:]...), sh.entries...)
+			sh.m.Unlock()
+			for j := next; j < len(swept); j++ {
+				swept[j] = Entry{}
+			}
+			s.arm()
+		}
+		sh.spare = swept
+	}()
+	for next < len(swept) {
+		e := swept[next]
+		swept[next] = Entry{}
+		next++
+		add(e)
+	}
+}
+
+// sweeper - the channel waking the batcher up once entries were appended to
+// the shards, and the timer of the sweep it then arms with the clock of the
+// client, so idle shards cost no wakeups. Both are nil without shards.
+type sweeper struct {
+	wake  <-chan struct{}
+	timer <-chan time.Time
+}
+
+func (c *Client) sweeper() *sweeper {
+	if c.shards == nil {
+		return &sweeper{}
+	}
+	s := &sweeper{wake: c.shards.wake}
+	if atomic.LoadInt32(&c.shards.armed) == 1 {
+		// entries appended before the batching restarted
+		c.armSweep(s)
+	}
+	return s
+}
+
+// armSweep - time the sweep of the entries appended, unless it is timed
+// already
+func (c *Client) armSweep(s *sweeper) {
+	if s.timer != nil {
+		return
+	}
+	d := c.config.ShardSweep
+	if d <= 0 {
+		d = defaultShardSweep
+	}
+	s.timer = c.clock().After(d)
+}
@@ -0,0 +1,120 @@
+package intake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShards(t *testing.T) {
+	var delivered int64
+	c := New(Config{Shards: 4, ShardSize: 16, FlushPolicy: MaxEntries(100), Exporter: ExporterFunc(func(b *Batch) error {
+		atomic.AddInt64(&delivered, int64(len(b.Lines)))
+		return nil
+	})})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				ok(t, c.Push([]byte(fmt.Sprintf("%d-%d", g, i))))
+			}
+		}(g)
+	}
+	wg.Wait()
+	ok(t, c.Flush(context.Background()))
+	equals(t, int64(2000), atomic.LoadInt64(&delivered))
+	ok(t, c.Push([]byte("last")))
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(2001), atomic.LoadInt64(&delivered))
+	equals(t, ErrClosed, c.Push([]byte("closed")))
+}
+
+func TestShardsFull(t *testing.T) {
+	p := newStalledPolicy()
+	c := New(Config{Shards: 1, ShardSize: 2, FlushPolicy: p, Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	ok(t, c.Push([]byte("held")))
+	<-p.added
+	// the shard takes two, the lane one more
+	equals(t, int64(3), pushes(c, "1", "2", "3", "4"))
+	close(p.release)
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(4), c.Stats().Delivered)
+}
+
+func TestShardSweepPanic(t *testing.T) {
+	s := newShards(1, 16)
+	for _, line := range []string{"1", "2", "3"} {
+		_, err := s.push(Entry{Line: []byte(line)})
+		ok(t, err)
+	}
+	var added []string
+	func() {
+		defer func() { recover() }()
+		s.sweep(func(e Entry) {
+			if string(e.Line) == "2" {
+				panic("add")
+			}
+			added = append(added, string(e.Line))
+		}, false)
+	}()
+	// the entries after the one add panicked on are swept next time
+	equals(t, int32(1), atomic.LoadInt32(&s.armed))
+	_, err := s.push(Entry{Line: []byte("4")})
+	ok(t, err)
+	s.sweep(func(e Entry) { added = append(added, string(e.Line)) }, false)
+	equals(t, []string{"1", "3", "4"}, added)
+}
+
+func TestShardSweepClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := New(Config{Shards: 2, ShardSweep: 7 * time.Millisecond, Clock: clock, FlushPolicy: MaxEntries(100), Exporter: ExporterFunc(func(*Batch) error { return nil })})
+	sweeps := func() int {
+		n := 0
+		for _, d := range clock.Waits() {
+			if d == 7*time.Millisecond {
+				n++
+			}
+		}
+		return n
+	}
+	// idle shards arm no sweep
+	time.Sleep(20 * time.Millisecond)
+	equals(t, 0, sweeps())
+
+	// an entry arms one on the clock of the client, which the fake one
+	// never fires, so the entry waits for Flush
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+	deadline := time.Now().Add(time.Second)
+	for sweeps() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	equals(t, 1, sweeps())
+	equals(t, int64(0), c.Stats().Delivered)
+	ok(t, c.Flush(context.Background()))
+	equals(t, int64(2), c.Stats().Delivered)
+	ok(t, c.Close(context.Background()))
+}
+
+// BenchmarkPushParallel - pushes from every P, to the lanes or to shards
+func BenchmarkPushParallel(b *testing.B) {
+	line := []byte(`{"level":"info","msg":"benchmark entry with a few fields","service":"bench","count":42}` + "\n")
+	for _, shards := range []int{0, 4, 16} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := New(Config{JSON: true, Shards: shards, FlushPolicy: MaxEntries(maxArraySize), Exporter: ExporterFunc(func(*Batch) error { return nil })})
+			defer c.Close(context.Background())
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					c.Push(line)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package intake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader - default header carrying the HMAC of a batch
+	SignatureHeader = "X-Log-Batch-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// AuditRecord describe a batch delivered or dropped
+type AuditRecord struct {
+	Time      time.Time
+	Stream    Stream
+	Entries   int
+	Bytes     int
+	Signature string
+	// Encoding - content encoding the payload was sent with
+	Encoding string
+	// Sequence - sequence number of the batch, 0 unless Config.Sequence
+	Sequence  uint64
+	Delivered bool
+	// Quota - rate limit reported by the last response to the batch, nil if
+	// none did
+	Quota *Quota
+}
+
+// Sign - HMAC-SHA256 signature of a batch payload, as sent in the signature header
+func Sign(key, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - whether signature is the valid signature of payload, for relays
+// checking batches before forwarding them
+func Verify(key, payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// sign - signature of the payload, empty if signing is off
+func (s *Settings) sign(payload []byte) string {
+	if len(s.HMACKey) == 0 {
+		return ""
+	}
+	return Sign(s.HMACKey, payload)
+}
+
+func (s *Settings) signatureHeader() string {
+	if s.HMACHeader != "" {
+		return s.HMACHeader
+	}
+	return SignatureHeader
+}
+
+func (c *Client) audit(record AuditRecord) {
+	if fn := c.config.OnAudit; fn != nil {
+		fn(record)
+	}
+	if fn := c.config.OnAuditContext; fn != nil {
+		fn(c.Context(), record)
+	}
+}
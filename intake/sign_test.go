@@ -0,0 +1,53 @@
+package intake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("secret")
+	sig := Sign(key, []byte(`[{"msg":"one"}]`))
+	equals(t, true, Verify(key, []byte(`[{"msg":"one"}]`), sig))
+	equals(t, false, Verify(key, []byte(`[{"msg":"two"}]`), sig))
+	equals(t, false, Verify([]byte("other"), []byte(`[{"msg":"one"}]`), sig))
+	equals(t, false, Verify(key, []byte(`[{"msg":"one"}]`), sig[len(signaturePrefix):]))
+	equals(t, false, Verify(key, []byte(`[{"msg":"one"}]`), signaturePrefix+"zz"))
+}
+
+func TestSignedBatch(t *testing.T) {
+	srv, reqs := newServer(t)
+	audits := make(chan AuditRecord, 1)
+	c, tick := newClient(srv, Config{
+		JSON:    true,
+		HMACKey: []byte("secret"),
+		Stream:  Stream{Service: "audit"},
+		OnAudit: func(r AuditRecord) { audits <- r },
+	})
+
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	tick <- time.Now()
+
+	r := <-reqs
+	sig := r.header.Get(SignatureHeader)
+	equals(t, true, Verify([]byte("secret"), []byte(r.body), sig))
+
+	a := <-audits
+	equals(t, sig, a.Signature)
+	equals(t, true, a.Delivered)
+	equals(t, 1, a.Entries)
+	equals(t, len(r.body), a.Bytes)
+	equals(t, "audit", a.Stream.Service)
+}
+
+func TestUnsignedBatch(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{HMACHeader: "X-Sig"})
+
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+
+	r := <-reqs
+	equals(t, "", r.header.Get("X-Sig"))
+	equals(t, "", r.header.Get(SignatureHeader))
+}
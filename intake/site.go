@@ -0,0 +1,87 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// SiteTag - tag carrying the Datadog site batches are sent to, added to
+	// every batch when Config.AllowedSites is set
+	SiteTag = "datadog_site"
+)
+
+// ErrSiteNotAllowed - the host is not in a site of Config.AllowedSites
+var ErrSiteNotAllowed = errors.New("intake: site not allowed")
+
+// sites - Datadog sites by domain, the most specific first
+var sites = []struct {
+	name, domain string
+}{
+	{"us3", "us3.datadoghq.com"},
+	{"us5", "us5.datadoghq.com"},
+	{"ap1", "ap1.datadoghq.com"},
+	{"ap2", "ap2.datadoghq.com"},
+	{"us", "datadoghq.com"},
+	{"eu", "datadoghq.eu"},
+	{"gov", "ddog-gov.com"},
+}
+
+// Site - the Datadog site of host, "us", "us3", "us5", "eu", "ap1", "ap2"
+// or "gov", empty if host is not a Datadog intake
+func Site(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, s := range sites {
+		if host == s.domain || strings.HasSuffix(host, "."+s.domain) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// SiteHost - the HTTP intake of the Datadog site named as by Site, false if
+// there is no such site
+func SiteHost(site string) (string, bool) {
+	for _, s := range sites {
+		if strings.EqualFold(site, s.name) {
+			return "http-intake.logs." + s.domain, true
+		}
+	}
+	return "", false
+}
+
+// checkSite - refuse a config sending outside of the allowed sites. The
+// Agent forwards to a site of its own configuration, so Config.AllowedSites
+// rules it out.
+func (config Config) checkSite() error {
+	if len(config.AllowedSites) == 0 || config.Exporter != nil {
+		return nil
+	}
+	if config.Protocol == ProtocolAgent {
+		return fmt.Errorf("%w: the Agent forwards to a site of its own", ErrSiteNotAllowed)
+	}
+	site := Site(config.Host)
+	for _, allowed := range config.AllowedSites {
+		if site != "" && strings.EqualFold(site, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in %s", ErrSiteNotAllowed, config.Host, strings.Join(config.AllowedSites, ", "))
+}
+
+// siteStream - the stream tagged with the site batches are sent to when
+// sites are enforced
+func (c *Client) siteStream(s Stream) Stream {
+	if len(c.config.AllowedSites) == 0 || c.config.Exporter != nil {
+		return s
+	}
+	tags := make([]string, 0, len(s.Tags)+1)
+	tags = append(append(tags, s.Tags...), SiteTag+":"+Site(c.config.Host))
+	s.Tags = tags
+	return s
+}
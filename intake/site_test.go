@@ -0,0 +1,70 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSite(t *testing.T) {
+	equals(t, "us", Site(DatadogUSHost))
+	equals(t, "eu", Site(DatadogEUHost))
+	equals(t, "eu", Site(DatadogEUTCPHost))
+	equals(t, "us3", Site("http-intake.logs.us3.datadoghq.com"))
+	equals(t, "gov", Site("http-intake.logs.ddog-gov.com:443"))
+	equals(t, "", Site("logs.example.com"))
+	equals(t, "", Site("datadoghq.eu.example.com"))
+}
+
+func TestSiteHost(t *testing.T) {
+	for _, site := range []string{"us", "us3", "us5", "eu", "ap1", "ap2", "gov"} {
+		host, ok := SiteHost(site)
+		equals(t, true, ok)
+		equals(t, site, Site(host))
+	}
+	host, _ := SiteHost("US")
+	equals(t, DatadogUSHost, host)
+	host, _ = SiteHost("eu")
+	equals(t, DatadogEUHost, host)
+	_, ok := SiteHost("mars")
+	equals(t, false, ok)
+}
+
+func TestAllowedSites(t *testing.T) {
+	for _, tc := range []struct {
+		config  Config
+		allowed bool
+	}{
+		{Config{Host: DatadogEUHost}, true},
+		{Config{Host: DatadogUSHost}, false},
+		{Config{Host: "localhost:8443"}, false},
+		{Config{Host: DatadogEUHost, Protocol: ProtocolAgent}, false},
+		{Config{Host: DatadogUSHost, Exporter: ExporterFunc(func(*Batch) error { return nil })}, true},
+	} {
+		tc.config.AllowedSites = []string{"EU"}
+		err := tc.config.Validate()
+		equals(t, tc.allowed, err == nil)
+		if err != nil {
+			equals(t, true, errors.Is(err, ErrSiteNotAllowed))
+			// the client refuses lines
+			equals(t, true, errors.Is(New(tc.config).Push([]byte("one")), ErrSiteNotAllowed))
+		}
+	}
+}
+
+func TestSiteTag(t *testing.T) {
+	var tags [][]string
+	c := New(Config{
+		Host:         DatadogEUHost,
+		AllowedSites: []string{"eu"},
+		DetectAgent:  true,
+		Stream:       Stream{Tags: []string{"env:prod"}},
+		DryRun:       func(p Preview) { tags = append(tags, p.Stream.Tags) },
+	})
+	// no fail over to an Agent
+	equals(t, PathDryRun, c.Stats().Path)
+	equals(t, ProtocolV2HTTP, c.protocol().id())
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Close(context.Background()))
+	equals(t, [][]string{{"env:prod", "datadog_site:eu"}}, tags)
+}
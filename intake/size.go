@@ -0,0 +1,149 @@
+package intake
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrPayloadTooLarge - a line which alone makes a payload larger than
+// MaxPayloadSize, never sent as the intake would reject it
+var ErrPayloadTooLarge = errors.New("intake: line above the payload limit")
+
+// ContentType is the format of the payload of a batch
+type ContentType string
+
+const (
+	// ContentTypePlain - newline separated lines
+	ContentTypePlain ContentType = "text/plain"
+	// ContentTypeJSON - a JSON array of the lines
+	ContentTypeJSON ContentType = "application/json"
+
+	// MaxPayloadSize - largest payload accepted by the intake, before
+	// compression: 5MB
+	MaxPayloadSize = 5 * 1024 * 1024
+)
+
+// messageOverhead - bytes around a line sent as the message of an object
+const messageOverhead = len(`{"message":}`)
+
+// EstimateBatchSize - size of the payload of a batch of entries in the
+// format, separators, brackets and commas included, the batcher keeps it at
+// most MaxPayloadSize. When compressed, an upper bound of the size of the
+// gzip payload, which is never smaller than the data is incompressible.
+func EstimateBatchSize(entries [][]byte, format ContentType, compressed bool) int {
+	total := 0
+	for _, entry := range entries {
+		total += len(entry) + 1
+	}
+	size := payloadSize(total, len(entries), format)
+	if compressed {
+		return gzipBound(size)
+	}
+	return size
+}
+
+// payloadSize - size of a payload of n lines taking total bytes with their separator
+func payloadSize(total, n int, format ContentType) int {
+	if format != ContentTypeJSON {
+		return total
+	}
+	if n == 0 {
+		return len("[]")
+	}
+	// the brackets, the last line has no comma
+	return total + 1
+}
+
+// maxCompressedPayload - largest payload whose gzip stream is at most
+// MaxPayloadSize whatever the data, the limit of batches compressed
+var maxCompressedPayload = func() int {
+	size := MaxPayloadSize
+	for gzipBound(size) > MaxPayloadSize {
+		size -= gzipBound(size) - MaxPayloadSize
+	}
+	// a block less may have been counted
+	for gzipBound(size+1) <= MaxPayloadSize {
+		size++
+	}
+	return size
+}()
+
+// payloadLimit - largest payload a batch may take, so it is at most
+// MaxPayloadSize on the wire, compressed or not
+func (c *Client) payloadLimit() int {
+	if c.settings().Compress && c.protocol().compressible() {
+		return maxCompressedPayload
+	}
+	return MaxPayloadSize
+}
+
+// fits - whether a line taking size bytes of the payload, separator and
+// stream attributes included, can join the batch within limit
+func (b *batch) fits(size int, format ContentType, limit int) bool {
+	return payloadSize(b.size+size, len(b.lines)+1, format) <= limit
+}
+
+// checkLineSize - refuse a framed line which would not fit a payload of its
+// own, such as one escaped many times its size as a message
+func (c *Client) checkLineSize(stream Stream, line []byte) error {
+	// escaping at most makes lines 6 times larger, smaller ones always fit
+	if len(line) <= MaxPayloadSize/8 {
+		return nil
+	}
+	proto := c.protocol()
+	if !proto.compressible() {
+		// sent line by line
+		return nil
+	}
+	size := proto.size(c, line) + proto.overhead(c, stream)
+	if payloadSize(size, 1, proto.contentType(c)) > c.payloadLimit() {
+		return ErrPayloadTooLarge
+	}
+	return nil
+}
+
+// gzipBound - largest gzip stream of size bytes: stored in deflate blocks
+// of at most 64kB with a 5 bytes header, the 2 bytes ending the deflate
+// stream, and the 18 bytes of gzip header and trailer
+func gzipBound(size int) int {
+	const block = 1<<16 - 1
+	return size + 5*((size+block-1)/block) + 2 + 18
+}
+
+// messageSize - bytes of the framed line sent as the message of an object,
+// followed by a comma
+func messageSize(line []byte) int {
+	return messageOverhead + quotedSize(line[:len(line)-1]) + 1
+}
+
+// quotedSize - length of s marshaled as a JSON string, escaped as
+// encoding/json does
+func quotedSize(s []byte) int {
+	n := 2
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			switch {
+			case b == '"' || b == '\\' || b == '\b' || b == '\f' || b == '\n' || b == '\r' || b == '\t':
+				n += 2
+			case b < 0x20 || b == '<' || b == '>' || b == '&':
+				n += len(`\u0000`)
+			default:
+				n++
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// replaced by the replacement character, not escaped
+			n += utf8.RuneLen(utf8.RuneError)
+		case r == '\u2028' || r == '\u2029':
+			n += len(`\u2028`)
+		default:
+			n += size
+		}
+		i += size
+	}
+	return n
+}
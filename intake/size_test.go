@@ -0,0 +1,262 @@
+package intake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEstimateBatchSize(t *testing.T) {
+	lines := [][]byte{[]byte(`{"a":1}`), []byte(`{"b":"two"}`), []byte(`3`)}
+	for _, test := range []struct {
+		name       string
+		entries    [][]byte
+		format     ContentType
+		compressed bool
+		size       int
+	}{
+		{"empty plain", nil, ContentTypePlain, false, 0},
+		{"empty json", nil, ContentTypeJSON, false, len("[]")},
+		{"plain", lines, ContentTypePlain, false, len("{\"a\":1}\n{\"b\":\"two\"}\n3\n")},
+		{"json", lines, ContentTypeJSON, false, len(`[{"a":1},{"b":"two"},3]`)},
+		{"one json", lines[:1], ContentTypeJSON, false, len(`[{"a":1}]`)},
+		{"empty line", [][]byte{{}}, ContentTypePlain, false, 1},
+		{"compressed", lines, ContentTypeJSON, true, len(`[{"a":1},{"b":"two"},3]`) + 5 + 20},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			equals(t, test.size, EstimateBatchSize(test.entries, test.format, test.compressed))
+		})
+	}
+}
+
+// TestEstimateBatchSizePayload - estimates are the size of the payloads sent
+func TestEstimateBatchSizePayload(t *testing.T) {
+	lines := [][]byte{[]byte(`{"a":1}`), []byte(`{"b":"two"}`), []byte(`3`)}
+	for _, test := range []struct {
+		name   string
+		json   bool
+		format ContentType
+	}{
+		{"plain", false, ContentTypePlain},
+		{"json", true, ContentTypeJSON},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{config: Config{JSON: test.json}}
+			framed := make([][]byte, len(lines))
+			for i, line := range lines {
+				framed[i] = c.frame(line)
+			}
+			payload := c.protocol().payload(c, Stream{}, framed)
+			equals(t, len(payload), EstimateBatchSize(lines, test.format, false))
+			compressed, err := compress(payload)
+			ok(t, err)
+			equals(t, true, len(compressed) <= EstimateBatchSize(lines, test.format, true))
+		})
+	}
+}
+
+func TestGzipBound(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 10, 1<<16 - 2, 1<<16 - 1, 1 << 16, 3<<16 + 7, MaxPayloadSize} {
+		data := make([]byte, size)
+		r.Read(data)
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		ok(t, err)
+		ok(t, w.Close())
+		equals(t, true, buf.Len() <= gzipBound(size))
+	}
+}
+
+func TestQuotedSize(t *testing.T) {
+	for _, s := range []string{
+		"", "plain", `"quoted" \ back`, "\n\r\t\b\f", "\x00\x01\x1f\x7f", "<a href=x>&amp;</a>",
+		"région", "日本語", "emoji 🎉", "  ", "\xff\xfe bad", "tail \xe2\x82",
+	} {
+		value, err := json.Marshal(s)
+		ok(t, err)
+		equals(t, len(value), quotedSize([]byte(s)))
+		framed := append([]byte(s), '\n')
+		equals(t, len(appendMessage(nil, []byte(s)))+1, messageSize(framed))
+	}
+}
+
+// repeat - a line of n bytes b
+func repeat(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+// TestPayloadBoundary - batches are cut so their payload is never over
+// MaxPayloadSize, even by the separators or brackets around the lines
+func TestPayloadBoundary(t *testing.T) {
+	const mb = 1 << 20
+	for _, test := range []struct {
+		name     string
+		json     bool
+		protocol Protocol
+		lines    [][]byte
+		batches  []int
+	}{
+		// n lines and their newlines
+		{"plain exact", false, ProtocolV1HTTP, [][]byte{repeat('a', mb), repeat('a', mb), repeat('a', mb), repeat('a', MaxPayloadSize-4-3*mb)}, []int{4}},
+		{"plain over", false, ProtocolV1HTTP, [][]byte{repeat('a', mb), repeat('a', mb), repeat('a', mb), repeat('a', MaxPayloadSize-3-3*mb)}, []int{3, 1}},
+		// n lines, n-1 commas and the brackets
+		{"json exact", true, ProtocolV1HTTP, [][]byte{repeat('1', mb), repeat('1', mb), repeat('1', mb), repeat('1', MaxPayloadSize-5-3*mb)}, []int{4}},
+		{"json over", true, ProtocolV1HTTP, [][]byte{repeat('1', mb), repeat('1', mb), repeat('1', mb), repeat('1', MaxPayloadSize-4-3*mb)}, []int{3, 1}},
+		// quotes are escaped when lines are sent as messages, an object of n
+		// quotes takes 2n+15 bytes with its comma, the brackets one more
+		{"v2 exact", false, ProtocolV2HTTP, [][]byte{append(repeat('"', mb), 'a'), repeat('"', (MaxPayloadSize-32)/2-mb)}, []int{2}},
+		{"v2 over", false, ProtocolV2HTTP, [][]byte{append(repeat('"', mb), 'a'), repeat('"', (MaxPayloadSize-32)/2-mb+1)}, []int{1, 1}},
+		// JSON lines which aren't objects are sent as messages as well
+		{"v2 json exact", true, ProtocolV2HTTP, [][]byte{append(repeat('"', mb), 'a'), repeat('"', (MaxPayloadSize-32)/2-mb)}, []int{2}},
+		{"v2 json over", true, ProtocolV2HTTP, [][]byte{append(repeat('"', mb), 'a'), repeat('"', (MaxPayloadSize-32)/2-mb+1)}, []int{1, 1}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var m sync.Mutex
+			var batches []int
+			var sizes []int
+			srv, _ := newServer(t)
+			c, _ := newClient(srv, Config{
+				JSON:        test.json,
+				FlushPolicy: Interval(time.Hour),
+				// lines as large as payloads, exporters are not bound to the intake's limit
+				MaxEntryBytes: MaxPayloadSize,
+				Exporter: ExporterFunc(func(b *Batch) error {
+					m.Lock()
+					defer m.Unlock()
+					batches = append(batches, len(b.Lines))
+					sizes = append(sizes, len(b.Payload))
+					return nil
+				}),
+			})
+			c.proto = c.newProtocol(test.protocol, c.config.Host)
+			for _, line := range test.lines {
+				ok(t, c.Push(line))
+			}
+			ok(t, c.Close(context.Background()))
+			m.Lock()
+			defer m.Unlock()
+			equals(t, len(test.batches), len(batches))
+			total := 0
+			for i, size := range sizes {
+				equals(t, true, size <= MaxPayloadSize)
+				total += batches[i]
+			}
+			equals(t, len(test.lines), total)
+			if len(test.batches) == 1 {
+				equals(t, MaxPayloadSize, sizes[0])
+			}
+		})
+	}
+}
+
+// TestPayloadAccounting - the size the batcher accounts for a batch is the
+// size of its payload, whatever the protocol and the lines
+func TestPayloadAccounting(t *testing.T) {
+	lines := [][]byte{
+		[]byte(`{"a":1}`),
+		[]byte(`{"msg":"quoted \"value\" <b>"}`),
+		[]byte(`"a string"`),
+		[]byte(`[1,2]`),
+		[]byte("plain <text> & \"quotes\""),
+		[]byte("bad \xff utf-8 \u2028"),
+		[]byte("région 日本語"),
+	}
+	streams := []Stream{{}, {Source: "go", Service: "checkout", Hostname: "web-1", Tags: []string{"env:prod", "team:a"}}}
+	for _, protocol := range []Protocol{ProtocolV1HTTP, ProtocolV2HTTP} {
+		for _, json := range []bool{false, true} {
+			for _, stream := range streams {
+				c := &Client{config: Config{JSON: json}}
+				c.proto = c.newProtocol(protocol, "localhost")
+				proto := c.protocol()
+				for n := 1; n <= len(lines); n++ {
+					framed := make([][]byte, n)
+					size := 0
+					for i, line := range lines[:n] {
+						framed[i] = c.frame(line)
+						size += proto.size(c, framed[i]) + proto.overhead(c, stream)
+					}
+					payload := proto.payload(c, stream, framed)
+					equals(t, len(payload), payloadSize(size, n, proto.contentType(c)))
+				}
+				// the comma after the attributes is left out of empty
+				// objects, the payload is smaller than accounted
+				framed := c.frame([]byte(` {} `))
+				payload := proto.payload(c, stream, [][]byte{framed})
+				equals(t, true, len(payload) <= payloadSize(proto.size(c, framed)+proto.overhead(c, stream), 1, proto.contentType(c)))
+			}
+		}
+	}
+}
+
+// TestPayloadBoundaryCompressed - compressed batches are cut so even an
+// incompressible payload is at most MaxPayloadSize once compressed
+func TestPayloadBoundaryCompressed(t *testing.T) {
+	equals(t, true, gzipBound(maxCompressedPayload) <= MaxPayloadSize)
+	equals(t, true, gzipBound(maxCompressedPayload+1) > MaxPayloadSize)
+	const mb = 1 << 20
+	for _, test := range []struct {
+		name    string
+		last    int
+		batches int
+	}{
+		{"exact", maxCompressedPayload - 4 - 3*mb, 1},
+		{"over", maxCompressedPayload - 3 - 3*mb, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var m sync.Mutex
+			var sizes []int
+			srv, _ := newServer(t)
+			c, _ := newClient(srv, Config{
+				Compress:      true,
+				FlushPolicy:   Interval(time.Hour),
+				MaxEntryBytes: MaxPayloadSize,
+				Exporter: ExporterFunc(func(b *Batch) error {
+					m.Lock()
+					defer m.Unlock()
+					sizes = append(sizes, len(b.Payload))
+					return nil
+				}),
+			})
+			for _, line := range [][]byte{repeat('a', mb), repeat('a', mb), repeat('a', mb), repeat('a', test.last)} {
+				ok(t, c.Push(line))
+			}
+			ok(t, c.Close(context.Background()))
+			m.Lock()
+			defer m.Unlock()
+			equals(t, test.batches, len(sizes))
+			for _, size := range sizes {
+				equals(t, true, gzipBound(size) <= MaxPayloadSize)
+			}
+			if test.batches == 1 {
+				equals(t, maxCompressedPayload, sizes[0])
+			}
+		})
+	}
+}
+
+// TestLineAbovePayload - a line which alone makes a payload too large is
+// refused rather than sent to be rejected
+func TestLineAbovePayload(t *testing.T) {
+	srv, _ := newServer(t)
+	client := func(p Protocol) *Client {
+		c, _ := newClient(srv, Config{MaxEntryBytes: MaxPayloadSize, FlushPolicy: Interval(time.Hour)})
+		t.Cleanup(func() { c.Close(context.Background()) })
+		c.proto = c.newProtocol(p, c.config.Host)
+		return c
+	}
+	c := client(ProtocolV2HTTP)
+	// escaped to twice its size as a message
+	equals(t, ErrPayloadTooLarge, c.Push(repeat('"', MaxPayloadSize/2)))
+	ok(t, c.Push(repeat('"', (MaxPayloadSize-32)/2)))
+	// lines are not escaped for v1
+	c = client(ProtocolV1HTTP)
+	ok(t, c.Push(repeat('"', MaxPayloadSize-1)))
+	equals(t, ErrPayloadTooLarge, c.Push(repeat('"', MaxPayloadSize)))
+}
@@ -0,0 +1,492 @@
+package intake
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSpoolMaxBytes - size the files of Config.SpoolDir may take by default
+const DefaultSpoolMaxBytes = 100 << 20
+
+const (
+	spoolPrefix = "spool-"
+	spoolSuffix = ".log"
+	// spoolQuarantine - subdirectory of the spool the records which can't be
+	// read are moved to, for inspection instead of replay
+	spoolQuarantine = "quarantine"
+)
+
+// spoolRecord - a batch spilled to disk, one JSON object per line of a file
+type spoolRecord struct {
+	Stream   Stream   `json:"stream"`
+	Attempts Attempts `json:"attempts"`
+	Lines    []string `json:"lines"`
+}
+
+// spool - append-only files holding the batches dropped during an outage
+// until the intake delivers again
+type spool struct {
+	dir      string
+	max      int64
+	maxFiles int
+	maxAge   time.Duration
+	clock    Clock
+
+	m           sync.Mutex
+	file        *os.File // file appended to, sealed before files are replayed
+	size        int64    // bytes of every file of the directory
+	quarantined int64    // bytes of the quarantine
+	seq         int
+
+	// pruned - files removed unreplayed, records - records quarantined
+	pruned, records int64
+
+	// delivered - fingerprints of the records replayed and delivered, so a
+	// file replayed again after a crash skips them
+	delivered *fingerprints
+
+	wake chan struct{}
+}
+
+// openSpool - the spool of config.SpoolDir, created if missing, accounting
+// the files left by a previous run and pruning the ones beyond its limits
+func openSpool(config Config, clock Clock) (*spool, error) {
+	dir, max := config.SpoolDir, config.SpoolMaxBytes
+	if max <= 0 {
+		max = DefaultSpoolMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Join(dir, spoolQuarantine), 0700); err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:      dir,
+		max:      max,
+		maxFiles: config.SpoolMaxFiles,
+		maxAge:   config.SpoolMaxAge,
+		clock:    clock,
+		wake:     make(chan struct{}, 1),
+	}
+	var err error
+	if s.delivered, err = openFingerprints(dir, spoolFingerprints); err != nil {
+		return nil, err
+	}
+	paths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			s.size += fi.Size()
+		}
+	}
+	quarantine, _ := filepath.Glob(filepath.Join(dir, spoolQuarantine, spoolPrefix+"*"+spoolSuffix))
+	for _, path := range quarantine {
+		if fi, err := os.Stat(path); err == nil {
+			s.quarantined += fi.Size()
+		}
+	}
+	s.m.Lock()
+	s.prune(0)
+	s.m.Unlock()
+	if s.size > 0 {
+		// replayed on start
+		s.signal()
+	}
+	return s, nil
+}
+
+// files - the spool files of the directory, oldest first
+func (s *spool) files() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, spoolPrefix+"*"+spoolSuffix))
+	sort.Strings(paths)
+	return paths, err
+}
+
+// write - append a dropped batch, false if it did not fit or failed
+func (s *spool) write(b *batch, a Attempts) (bool, error) {
+	r := spoolRecord{Stream: b.stream, Attempts: a, Lines: make([]string, len(b.lines))}
+	for i, line := range b.lines {
+		// lines are framed for the payload, with a trailing comma in JSON
+		r.Lines[i] = string(line[:len(line)-1])
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return false, err
+	}
+	data = append(data, '\n')
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.file == nil {
+		// room for the new file
+		s.prune(1)
+	}
+	if s.size+s.quarantined+int64(len(data)) > s.max {
+		return false, nil
+	}
+	if s.file == nil {
+		// names sort in the order files were created, at the time they were
+		s.seq++
+		name := fmt.Sprintf("%s%020d-%06d%s", spoolPrefix, s.clock.Now().UnixNano(), s.seq, spoolSuffix)
+		if s.file, err = os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+			return false, err
+		}
+	}
+	if _, err := s.file.Write(data); err != nil {
+		return false, err
+	}
+	s.size += int64(len(data))
+	return true, nil
+}
+
+// seal - stop appending to the current file and list the files to replay
+// within the limits, under one lock so a spill landing meanwhile opens a
+// file which isn't listed, rather than appending to one being replayed
+func (s *spool) seal() ([]string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	s.prune(0)
+	return s.files()
+}
+
+// prune - remove the oldest files to keep room for room more within
+// SpoolMaxFiles, and the files older than SpoolMaxAge, which the intake
+// would refuse, called with s.m held and no file appended to
+func (s *spool) prune(room int) {
+	if s.maxFiles <= 0 && s.maxAge <= 0 {
+		return
+	}
+	paths, err := s.files()
+	if err != nil {
+		return
+	}
+	now := s.clock.Now()
+	for i, path := range paths {
+		excess := s.maxFiles > 0 && len(paths)-i+room > s.maxFiles
+		created, ok := spoolTime(path)
+		stale := s.maxAge > 0 && ok && now.Sub(created) > s.maxAge
+		if !excess && !stale {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil || os.Remove(path) != nil {
+			continue
+		}
+		s.size -= fi.Size()
+		s.pruned++
+	}
+}
+
+// quarantine - move a record which can't be read out of the way of the
+// replay, into the quarantine under the name of its file, dropped if it
+// doesn't fit
+func (s *spool) quarantine(path string, record []byte) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.records++
+	if s.size+s.quarantined+int64(len(record))+1 > s.max {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, spoolQuarantine, filepath.Base(path)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := f.Write(append(append([]byte(nil), record...), '\n'))
+	s.quarantined += int64(n)
+	return err
+}
+
+// spoolStats - the state of the spool
+type spoolStats struct {
+	bytes           int64
+	files           int
+	oldest          time.Duration
+	pruned, records int64
+}
+
+// stats - the state of the spool at now
+func (s *spool) stats(now time.Time) spoolStats {
+	paths, _ := s.files()
+	s.m.Lock()
+	defer s.m.Unlock()
+	st := spoolStats{bytes: s.size, files: len(paths), pruned: s.pruned, records: s.records}
+	if len(paths) > 0 {
+		if created, ok := spoolTime(paths[0]); ok {
+			st.oldest = now.Sub(created)
+		}
+	}
+	return st
+}
+
+// spoolTime - when the spool file at path was created, read from its name
+func spoolTime(path string) (time.Time, bool) {
+	name := strings.TrimPrefix(filepath.Base(path), spoolPrefix)
+	i := strings.IndexByte(name, '-')
+	if i < 0 {
+		return time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(name[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// pending - whether files wait to be replayed
+func (s *spool) pending() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.size > 0
+}
+
+// signal - replay the files once the replaying goroutine is idle
+func (s *spool) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove - delete a file replayed in full
+func (s *spool) remove(path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if os.Remove(path) == nil {
+		s.m.Lock()
+		s.size -= fi.Size()
+		s.m.Unlock()
+	}
+}
+
+// rewrite - keep only records of a file replayed in part, for the next
+// replay, the file keeping its name and so its age
+func (s *spool) rewrite(path string, records [][]byte) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.m.Lock()
+	s.size += int64(buf.Len()) - fi.Size()
+	s.m.Unlock()
+	return nil
+}
+
+// errSpooled - the batch was written to the spool rather than delivered:
+// neither delivered nor dropped, it is replayed later
+var errSpooled = errors.New("intake: batch spooled")
+
+// spill - write a batch dropped after retries to the spool, false if it
+// went nowhere
+func (c *Client) spill(b *batch, a Attempts) bool {
+	if c.spool == nil {
+		return false
+	}
+	ok, err := c.spool.write(b, a)
+	if err != nil {
+		c.Debugf("Unable to spool batch, %v", err)
+	}
+	if ok {
+		atomic.AddInt64(&c.stats.spooled, int64(len(b.lines)))
+	}
+	return ok
+}
+
+// unspool - replay the spool files whenever a batch was delivered, and the
+// ones left by a previous run on start, until the client is closed
+func (c *Client) unspool() {
+	defer c.inflight.Done()
+	s := c.spool
+	defer s.delivered.close()
+	for {
+		select {
+		case <-s.wake:
+		case <-c.done:
+			return
+		}
+		if err := c.replaySpool(); err == ErrClosed {
+			return
+		} else if err != nil {
+			// the files are kept, and tried again on the next signal
+			c.Debugf("Unable to replay spool, %v", err)
+		}
+	}
+}
+
+// replaySpool - push the batches of the sealed files again, deleting each
+// file once its lines were delivered or spooled again, and keeping the
+// records which were neither for the next replay
+func (c *Client) replaySpool() error {
+	s := c.spool
+	paths, err := s.seal()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		left, err := c.replaySpoolFile(path)
+		if err == ErrClosed {
+			return err
+		}
+		if err != nil {
+			// a file failing doesn't hold back the next ones
+			c.Debugf("Unable to replay %s, %v", path, err)
+			continue
+		}
+		if len(left) > 0 {
+			if err := s.rewrite(path, left); err != nil {
+				c.Debugf("Unable to keep the records of %s not replayed, %v", path, err)
+			}
+			continue
+		}
+		s.remove(path)
+	}
+	return nil
+}
+
+// maxSpoolRecord - longest record of a spool file replayed, the longer ones
+// are quarantined
+const maxSpoolRecord = 2 * MaxPayloadSize
+
+// replaySpoolFile - push the batches of a file and wait for their delivery,
+// remembering the fingerprint of the records delivered in full and skipping
+// the ones delivered by a replay a crash interrupted. The records neither
+// delivered nor spooled again, such as the ones dropped for want of room
+// in the queue, are returned to be kept.
+func (c *Client) replaySpoolFile(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	type replayed struct {
+		data        []byte
+		fingerprint uint64
+		acks        []chan error
+	}
+	var records []replayed
+	r := bufio.NewReader(f)
+	for {
+		data, readErr := r.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		if line := bytes.TrimSpace(data); len(line) > 0 {
+			record := replayed{data: line, fingerprint: fingerprint(line)}
+			if record.acks, err = c.replayRecord(path, line, record.fingerprint); err != nil {
+				return nil, err
+			}
+			if record.acks != nil {
+				records = append(records, record)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	var left [][]byte
+	for _, record := range records {
+		delivered, kept := true, true
+		for _, ack := range record.acks {
+			switch <-ack {
+			case nil:
+			case errSpooled:
+				delivered = false
+			default:
+				delivered, kept = false, false
+			}
+		}
+		if !kept {
+			left = append(left, record.data)
+		}
+		if !delivered {
+			continue
+		}
+		if err := c.spool.delivered.add(record.fingerprint); err != nil {
+			c.Debugf("Unable to record spool fingerprint, %v", err)
+		}
+	}
+	return left, nil
+}
+
+// replayRecord - push the lines of a record of the spool file at path, the
+// acks of the lines pushed, none if it was quarantined as it can't be read
+// or skipped as a previous replay delivered it. The lines from the first one
+// refused on are quarantined, not the ones pushed before. ErrClosed stops
+// the replay.
+func (c *Client) replayRecord(path string, data []byte, fp uint64) ([]chan error, error) {
+	var r spoolRecord
+	err := fmt.Errorf("record of %d bytes over %d", len(data), maxSpoolRecord)
+	if len(data) <= maxSpoolRecord {
+		err = json.Unmarshal(data, &r)
+	}
+	if err != nil {
+		// a record cut short by a crash or garbage, the others still count
+		c.Debugf("Quarantining spool record of %s, %v", path, err)
+		if err := c.spool.quarantine(path, data); err != nil {
+			c.Debugf("Unable to quarantine spool record, %v", err)
+		}
+		return nil, nil
+	}
+	if c.spool.delivered.seen(fp) {
+		atomic.AddInt64(&c.stats.deduplicated, int64(len(r.Lines)))
+		return nil, nil
+	}
+	acks := make([]chan error, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		ack := make(chan error, 1)
+		err := c.push(c.Context(), Entry{Stream: r.Stream, Line: []byte(line), attempts: r.Attempts, replay: true}, ack)
+		if err == ErrClosed || c.Context().Err() != nil {
+			return nil, ErrClosed
+		}
+		if err != nil {
+			// a line the client refuses, such as one over its entry limit,
+			// the lines before it are on their way
+			c.Debugf("Quarantining spool record of %s, %v", path, err)
+			r.Lines = r.Lines[len(acks):]
+			rest, err := json.Marshal(r)
+			if err == nil {
+				err = c.spool.quarantine(path, rest)
+			}
+			if err != nil {
+				c.Debugf("Unable to quarantine spool record, %v", err)
+			}
+			if len(acks) == 0 {
+				return nil, nil
+			}
+			return acks, nil
+		}
+		acks = append(acks, ack)
+	}
+	return acks, nil
+}
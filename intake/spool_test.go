@@ -0,0 +1,339 @@
+package intake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyExporter - exporter failing while down is set, recording the
+// batches delivered
+type flakyExporter struct {
+	down    int32
+	m       sync.Mutex
+	batches []*Batch
+}
+
+func (e *flakyExporter) Export(b *Batch) error {
+	if atomic.LoadInt32(&e.down) == 1 {
+		return errors.New("down")
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	lines := make([][]byte, len(b.Lines))
+	for i, line := range b.Lines {
+		lines[i] = append([]byte(nil), line...)
+	}
+	e.batches = append(e.batches, &Batch{Stream: b.Stream, Lines: lines, Attempts: b.Attempts})
+	return nil
+}
+
+// delivered - the lines delivered, waiting up to a second for want of them
+func (e *flakyExporter) delivered(want int) []string {
+	deadline := time.Now().Add(time.Second)
+	for {
+		e.m.Lock()
+		var lines []string
+		for _, b := range e.batches {
+			for _, line := range b.Lines {
+				lines = append(lines, string(line))
+			}
+		}
+		e.m.Unlock()
+		if len(lines) >= want || time.Now().After(deadline) {
+			return lines
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+	c := New(Config{SpoolDir: dir, Exporter: &flakyExporter{down: 1}})
+	written, err := c.spool.write(&batch{stream: Stream{Service: "api"}, lines: [][]byte{[]byte("one,")}}, Attempts{})
+	ok(t, err)
+	equals(t, true, written)
+	equals(t, 1, c.Stats().SpoolFiles)
+	equals(t, true, c.Stats().SpoolBytes > 0)
+	ok(t, c.Close(context.Background()))
+
+	// replayed on start, in its stream, and removed once delivered
+	e := &flakyExporter{}
+	c = New(Config{SpoolDir: dir, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"one"}, e.delivered(1))
+	ok(t, c.Close(context.Background()))
+	e.m.Lock()
+	defer e.m.Unlock()
+	equals(t, Stream{Service: "api"}, e.batches[0].Stream)
+	files, err := c.spool.files()
+	ok(t, err)
+	equals(t, 0, len(files))
+	stats := c.Stats()
+	equals(t, int64(0), stats.SpoolBytes)
+	equals(t, 0, stats.SpoolFiles)
+	equals(t, time.Duration(0), stats.SpoolOldest)
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	e := &flakyExporter{down: 1}
+	c := New(Config{
+		SpoolDir:    dir,
+		MaxRetry:    1,
+		FlushPolicy: MaxEntries(1),
+		Exporter:    e,
+		// a batch spooled is not dropped
+		ErrorHandler: func(err error, lines [][]byte) { t.Errorf("dropped %q, %v", lines, err) },
+	})
+	ok(t, c.PushWait(context.Background(), Entry{Line: []byte("one")}))
+	stats := c.Stats()
+	equals(t, int64(1), stats.Spooled)
+	equals(t, int64(0), stats.Dropped)
+	equals(t, int64(0), stats.Queued)
+	equals(t, 1, stats.SpoolFiles)
+	equals(t, true, stats.SpoolBytes > 0)
+	equals(t, true, stats.SpoolOldest >= 0)
+	files, err := c.spool.files()
+	ok(t, err)
+	equals(t, 1, len(files))
+
+	// the intake is back, the spooled batch follows the next one delivered
+	atomic.StoreInt32(&e.down, 0)
+	ok(t, c.Push([]byte("two")))
+	ok(t, c.Flush(context.Background()))
+	lines := e.delivered(2)
+	sort.Strings(lines)
+	equals(t, []string{"one", "two"}, lines)
+	ok(t, c.Close(context.Background()))
+	files, err = c.spool.files()
+	ok(t, err)
+	equals(t, 0, len(files))
+	equals(t, false, c.spool.pending())
+	stats = c.Stats()
+	equals(t, int64(0), stats.SpoolBytes)
+	equals(t, 0, stats.SpoolFiles)
+	equals(t, time.Duration(0), stats.SpoolOldest)
+	// delivered once by the replay, never dropped
+	equals(t, int64(2), stats.Delivered)
+	equals(t, int64(0), stats.Dropped)
+	equals(t, int64(0), stats.Queued)
+}
+
+func TestSpoolRestart(t *testing.T) {
+	dir := t.TempDir()
+	c := New(Config{SpoolDir: dir, MaxRetry: 1, Exporter: &flakyExporter{down: 1}})
+	ok(t, c.PushStream(Stream{Service: "api"}, []byte("one")))
+	ok(t, c.Close(context.Background()))
+
+	// replayed on start, in its stream and with its history
+	e := &flakyExporter{}
+	c = New(Config{SpoolDir: dir, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"one"}, e.delivered(1))
+	ok(t, c.Close(context.Background()))
+	e.m.Lock()
+	defer e.m.Unlock()
+	equals(t, Stream{Service: "api"}, e.batches[0].Stream)
+	equals(t, 2, e.batches[0].Attempts.Count)
+}
+
+func TestSpoolFull(t *testing.T) {
+	var fallback syncBuffer
+	c := New(Config{SpoolDir: t.TempDir(), SpoolMaxBytes: 10, MaxRetry: 1, Fallback: &fallback, Exporter: &flakyExporter{down: 1}})
+	ok(t, c.Push([]byte("one")))
+	equals(t, true, errors.Is(c.Close(context.Background()), ErrNotDelivered))
+	equals(t, int64(0), c.Stats().Spooled)
+	equals(t, "one\n", fallback.String())
+}
+
+func TestSpoolCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	ok(t, ioutil.WriteFile(dir+"/spool-00000000000000000001-000001.log", []byte(`{"lines":["one"]}`+"\n"+`{"lines":["tw`), 0600))
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"one"}, e.delivered(1))
+	ok(t, c.Close(context.Background()))
+	// moved to the quarantine rather than replayed again
+	quarantined, err := ioutil.ReadFile(dir + "/quarantine/spool-00000000000000000001-000001.log")
+	ok(t, err)
+	equals(t, `{"lines":["tw`+"\n", string(quarantined))
+	equals(t, int64(1), c.Stats().SpoolQuarantined)
+}
+
+// spoolFile - write a spool file created at created holding a record of line
+func spoolFile(t *testing.T, dir string, created time.Time, line string) string {
+	path := fmt.Sprintf("%s/spool-%020d-000001.log", dir, created.UnixNano())
+	ok(t, ioutil.WriteFile(path, []byte(`{"lines":["`+line+`"]}`+"\n"), 0600))
+	return path
+}
+
+func TestSpoolMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	for i := 1; i <= 3; i++ {
+		spoolFile(t, dir, time.Unix(int64(i), 0), "old")
+	}
+	s, err := openSpool(Config{SpoolDir: dir, SpoolMaxFiles: 2}, clock)
+	ok(t, err)
+	defer s.delivered.close()
+	files, err := s.files()
+	ok(t, err)
+	equals(t, 2, len(files))
+	created, _ := spoolTime(files[0])
+	equals(t, time.Unix(2, 0), created)
+
+	// the oldest file makes room for the new one
+	b := &batch{lines: [][]byte{[]byte("new,")}}
+	written, err := s.write(b, Attempts{})
+	ok(t, err)
+	equals(t, true, written)
+	s.seal()
+	files, err = s.files()
+	ok(t, err)
+	equals(t, 2, len(files))
+	created, _ = spoolTime(files[1])
+	equals(t, time.Unix(1000, 0), created)
+	st := s.stats(clock.Now())
+	equals(t, int64(2), st.pruned)
+	equals(t, 2, st.files)
+	equals(t, 997*time.Second, st.oldest)
+	var size int64
+	for _, path := range files {
+		fi, err := os.Stat(path)
+		ok(t, err)
+		size += fi.Size()
+	}
+	equals(t, size, st.bytes)
+}
+
+func TestSpoolMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(100000, 0)
+	spoolFile(t, dir, now.Add(-20*time.Hour), "stale")
+	spoolFile(t, dir, now.Add(-time.Hour), "fresh")
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, SpoolMaxAge: 18 * time.Hour, Clock: &fakeClock{now: now}, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"fresh"}, e.delivered(1))
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(1), c.Stats().SpoolPruned)
+	e.m.Lock()
+	defer e.m.Unlock()
+	equals(t, 1, len(e.batches))
+}
+
+func TestSpoolReplayOverflow(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("%q", fmt.Sprint("line ", i)))
+	}
+	ok(t, ioutil.WriteFile(dir+"/spool-00000000000000000001-000001.log", []byte(`{"lines":[`+strings.Join(lines, ",")+`]}`+"\n"), 0600))
+	// the replay waits for room, dropping no spooled entry
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, QueueSize: 1, Overflow: OverflowDropNewest, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, 20, len(e.delivered(20)))
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(0), c.Stats().Overflowed)
+	files, err := c.spool.files()
+	ok(t, err)
+	equals(t, 0, len(files))
+}
+
+func TestSpoolReplayKept(t *testing.T) {
+	dir := t.TempDir()
+	path := spoolFile(t, dir, time.Unix(1, 0), "one")
+	fi, err := os.Stat(path)
+	ok(t, err)
+	// the replay fails with no room to spool the batch again, the record
+	// stays in its file rather than being lost
+	e := &flakyExporter{down: 1}
+	c := New(Config{SpoolDir: dir, SpoolMaxBytes: fi.Size(), MaxRetry: 1, Exporter: e, FlushPolicy: MaxEntries(1)})
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Dropped == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	equals(t, int64(1), c.Stats().Dropped)
+	data, err := ioutil.ReadFile(path)
+	ok(t, err)
+	equals(t, `{"lines":["one"]}`+"\n", string(data))
+
+	// replayed again once the intake is back
+	atomic.StoreInt32(&e.down, 0)
+	ok(t, c.PushWait(context.Background(), Entry{Line: []byte("two")}))
+	lines := e.delivered(2)
+	sort.Strings(lines)
+	equals(t, []string{"one", "two"}, lines)
+	ok(t, c.Close(context.Background()))
+	files, err := c.spool.files()
+	ok(t, err)
+	equals(t, 0, len(files))
+}
+
+func TestSpoolLongRecord(t *testing.T) {
+	dir := t.TempDir()
+	long := `{"lines":["` + strings.Repeat("a", maxSpoolRecord) + `"]}`
+	ok(t, ioutil.WriteFile(dir+"/spool-00000000000000000001-000001.log", []byte(long+"\n"+`{"lines":["two"]}`+"\n"), 0600))
+	spoolFile(t, dir, time.Unix(2, 0), "three")
+	// quarantined, the replay going on with the next records and files
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, Exporter: e, FlushPolicy: MaxEntries(1)})
+	lines := e.delivered(2)
+	sort.Strings(lines)
+	equals(t, []string{"three", "two"}, lines)
+	ok(t, c.Close(context.Background()))
+	equals(t, int64(1), c.Stats().SpoolQuarantined)
+	quarantined, err := ioutil.ReadFile(dir + "/quarantine/spool-00000000000000000001-000001.log")
+	ok(t, err)
+	equals(t, long+"\n", string(quarantined))
+}
+
+func TestSpoolRefusedLine(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("a", 20)
+	ok(t, ioutil.WriteFile(dir+"/spool-00000000000000000001-000001.log", []byte(`{"lines":["one","`+long+`","three"]}`+"\n"), 0600))
+	// the line pushed before the refused one is delivered, only the rest is
+	// quarantined
+	e := &flakyExporter{}
+	c := New(Config{SpoolDir: dir, JSON: true, MaxEntryBytes: 10, Exporter: e, FlushPolicy: MaxEntries(1)})
+	equals(t, []string{"one"}, e.delivered(1))
+	ok(t, c.Close(context.Background()))
+	quarantined, err := ioutil.ReadFile(dir + "/quarantine/spool-00000000000000000001-000001.log")
+	ok(t, err)
+	var rest spoolRecord
+	ok(t, json.Unmarshal(quarantined, &rest))
+	equals(t, []string{long, "three"}, rest.Lines)
+	files, err := c.spool.files()
+	ok(t, err)
+	equals(t, 0, len(files))
+}
+
+func TestSpoolSealDuringSpill(t *testing.T) {
+	s, err := openSpool(Config{SpoolDir: t.TempDir()}, &fakeClock{now: time.Unix(1000, 0)})
+	ok(t, err)
+	written, err := s.write(&batch{lines: [][]byte{[]byte("one,")}}, Attempts{})
+	ok(t, err)
+	equals(t, true, written)
+	files, err := s.seal()
+	ok(t, err)
+	equals(t, 1, len(files))
+	// a spill after the seal goes to a file of its own, not one replayed
+	written, err = s.write(&batch{lines: [][]byte{[]byte("two,")}}, Attempts{})
+	ok(t, err)
+	equals(t, true, written)
+	all, err := s.files()
+	ok(t, err)
+	equals(t, 2, len(all))
+	equals(t, files[0], all[0])
+	data, err := ioutil.ReadFile(files[0])
+	ok(t, err)
+	equals(t, false, strings.Contains(string(data), "two"))
+	s.seal()
+}
@@ -0,0 +1,42 @@
+package intake
+
+// PayloadStage transforms the payload of every batch, after the lines are
+// batched and before the payload is signed and compressed, such as to
+// encrypt it for a relay holding the key. Batch.Lines stay as batched, and
+// batches are cut for the payload before the stages, which must keep it
+// within the limits of the receiver.
+type PayloadStage interface {
+	// Payload - the payload to send instead, payload itself if unchanged. It
+	// must not be kept after Payload returns. An error drops the batch.
+	Payload(payload []byte, stream Stream) ([]byte, error)
+}
+
+// PayloadStageFunc - a function as a PayloadStage
+type PayloadStageFunc func(payload []byte, stream Stream) ([]byte, error)
+
+// Payload - call f
+func (f PayloadStageFunc) Payload(payload []byte, stream Stream) ([]byte, error) {
+	return f(payload, stream)
+}
+
+// stagePayload - the pooled payload through Config.PayloadStages, the
+// payload given back to the pool on error
+func (c *Client) stagePayload(payload []byte, stream Stream) ([]byte, error) {
+	for _, stage := range c.config.PayloadStages {
+		out, err := stage.Payload(payload, stream)
+		if err != nil {
+			putBuffer(payload)
+			return nil, err
+		}
+		if len(out) > 0 && len(payload) > 0 && &out[0] == &payload[0] && cap(out) == cap(payload) {
+			// changed in place
+			payload = out
+			continue
+		}
+		// the stage may return a part of the payload, copy before giving it back
+		buf := append(getBuffer(len(out)), out...)
+		putBuffer(payload)
+		payload = buf
+	}
+	return payload, nil
+}
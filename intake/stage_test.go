@@ -0,0 +1,66 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPayloadStages(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{
+		JSON:    true,
+		HMACKey: []byte("secret"),
+		Stream:  Stream{Service: "checkout"},
+		PayloadStages: []PayloadStage{
+			// in place, in a new buffer, in place again
+			PayloadStageFunc(func(payload []byte, stream Stream) ([]byte, error) {
+				copy(payload[3:], "MSG")
+				return payload, nil
+			}),
+			PayloadStageFunc(func(payload []byte, stream Stream) ([]byte, error) {
+				return append([]byte(stream.Service+":"), payload...), nil
+			}),
+			PayloadStageFunc(func(payload []byte, _ Stream) ([]byte, error) {
+				copy(payload, "CHECK")
+				return payload, nil
+			}),
+		},
+	})
+	defer c.Close(context.Background())
+
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	tick <- time.Now()
+	r := <-reqs
+	equals(t, `CHECKout:[{"MSG":"one"}]`, r.body)
+	// the payload is signed as sent
+	equals(t, true, Verify([]byte("secret"), []byte(r.body), r.header.Get(SignatureHeader)))
+}
+
+func TestPayloadStageError(t *testing.T) {
+	var dropped, posted int64
+	errStage := errors.New("no encryption key")
+	var handled error
+	srv, _ := newServer(t)
+	c, tick := newClient(srv, Config{
+		JSON:         true,
+		ErrorHandler: func(err error, batch [][]byte) { handled = err; atomic.AddInt64(&dropped, int64(len(batch))) },
+		PayloadStages: []PayloadStage{PayloadStageFunc(func([]byte, Stream) ([]byte, error) {
+			atomic.AddInt64(&posted, 1)
+			return nil, errStage
+		})},
+	})
+
+	ok(t, c.Push([]byte(`{"msg":"one"}`)))
+	ok(t, c.Push([]byte(`{"msg":"two"}`)))
+	tick <- time.Now()
+	closeDropping(t, c)
+	equals(t, errStage, handled)
+	equals(t, int64(2), atomic.LoadInt64(&dropped))
+	equals(t, int64(1), atomic.LoadInt64(&posted))
+	stats := c.Stats()
+	equals(t, int64(2), stats.Dropped)
+	equals(t, int64(0), stats.Attempts)
+}
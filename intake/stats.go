@@ -0,0 +1,144 @@
+package intake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats count what a client did since it was created
+type Stats struct {
+	// Pushed - entries queued
+	Pushed int64
+	// Delivered - entries of the batches delivered
+	Delivered int64
+	// Dropped - entries of the batches dropped after retries or on a panic
+	Dropped int64
+	// Batches - batches delivered
+	Batches int64
+	// BatchesDropped - batches dropped
+	BatchesDropped int64
+	// Bytes - payload bytes delivered, before compression
+	Bytes int64
+	// Retries - attempts made after a failed one
+	Retries int64
+	// Attempts - deliveries attempted, including retries
+	Attempts int64
+	// AttemptsFailed - attempts which failed, counted when they return
+	AttemptsFailed int64
+	// Throttled - attempts the intake answered 429 Too Many Requests
+	Throttled int64
+	// Spooled - entries of failed batches written to Config.SpoolDir, neither
+	// delivered nor dropped until replayed
+	Spooled int64
+	// SpoolBytes - bytes of the files of Config.SpoolDir waiting to be
+	// replayed, SpoolFiles - their number
+	SpoolBytes int64
+	SpoolFiles int
+	// SpoolOldest - age of the oldest file of Config.SpoolDir, zero if none
+	SpoolOldest time.Duration
+	// SpoolPruned - files of Config.SpoolDir removed unreplayed, over
+	// Config.SpoolMaxFiles or Config.SpoolMaxAge
+	SpoolPruned int64
+	// SpoolQuarantined - records of Config.SpoolDir which couldn't be read,
+	// moved to its quarantine subdirectory
+	SpoolQuarantined int64
+	// Truncated - lines cut to the entry limit
+	Truncated int64
+	// LastError - when an error was last given to OnError, zero if never
+	LastError time.Time
+	// Fallback - entries of dropped batches written to Config.Fallback
+	Fallback int64
+	// CircuitOpens - times the circuit breaker opened, see
+	// Config.CircuitFailures
+	CircuitOpens int64
+	// RateLimited - attempts which waited for their turn, see
+	// Config.MaxRequestsPerSecond and Config.MaxBytesPerSecond
+	RateLimited int64
+	// Deduplicated - entries of Config.SpoolDir not replayed, as a replay
+	// interrupted by a crash delivered them already
+	Deduplicated int64
+	// Overflowed - entries dropped because the queue was full, see
+	// Config.Overflow
+	Overflowed int64
+	// Queued - entries pushed not yet delivered, dropped nor spooled
+	Queued int64
+	// Latency - how long the batches delivered took
+	Latency Latency
+	// Zones - usage delivered by value of the ZoneTag of the streams, to
+	// attribute egress across availability zones; nil if none was tagged
+	Zones map[string]Usage
+	// Path - how batches are delivered, PathDirect, PathAgent, PathExporter
+	// or PathDryRun
+	Path string
+	// Quota - rate limit reported by the last response which had one, nil
+	// until then
+	Quota *Quota
+}
+
+// stats - counters of a client, only updated atomically
+type stats struct {
+	pushed, delivered, dropped int64
+	batches, batchesDropped    int64
+	bytes, retries             int64
+	attempts, throttled        int64
+	attemptsFailed             int64
+	truncated, lastError       int64
+	spooled, fallback          int64
+	deduplicated, circuitOpens int64
+	rateLimited                int64
+	overflowed, evicted        int64 // evicted - the overflowed once queued
+	latency                    latency
+}
+
+// Stats - snapshot of the counters, safe to call at any time
+func (c *Client) Stats() Stats {
+	stats := Stats{
+		Pushed:         atomic.LoadInt64(&c.stats.pushed),
+		Delivered:      atomic.LoadInt64(&c.stats.delivered),
+		Dropped:        atomic.LoadInt64(&c.stats.dropped),
+		Batches:        atomic.LoadInt64(&c.stats.batches),
+		BatchesDropped: atomic.LoadInt64(&c.stats.batchesDropped),
+		Bytes:          atomic.LoadInt64(&c.stats.bytes),
+		Retries:        atomic.LoadInt64(&c.stats.retries),
+		Attempts:       atomic.LoadInt64(&c.stats.attempts),
+		AttemptsFailed: atomic.LoadInt64(&c.stats.attemptsFailed),
+		Throttled:      atomic.LoadInt64(&c.stats.throttled),
+		Truncated:      atomic.LoadInt64(&c.stats.truncated),
+		Spooled:        atomic.LoadInt64(&c.stats.spooled),
+		Fallback:       atomic.LoadInt64(&c.stats.fallback),
+		Deduplicated:   atomic.LoadInt64(&c.stats.deduplicated),
+		CircuitOpens:   atomic.LoadInt64(&c.stats.circuitOpens),
+		RateLimited:    atomic.LoadInt64(&c.stats.rateLimited),
+		Overflowed:     atomic.LoadInt64(&c.stats.overflowed),
+		Latency:        c.stats.latency.snapshot(),
+		Zones:          c.zones(),
+		Path:           c.path,
+	}
+	evicted := atomic.LoadInt64(&c.stats.evicted)
+	if stats.Queued = stats.Pushed - stats.Delivered - stats.Dropped - stats.Spooled - evicted; stats.Queued < 0 {
+		stats.Queued = 0
+	}
+	if c.spool != nil {
+		s := c.spool.stats(c.clock().Now())
+		stats.SpoolBytes, stats.SpoolFiles, stats.SpoolOldest = s.bytes, s.files, s.oldest
+		stats.SpoolPruned, stats.SpoolQuarantined = s.pruned, s.records
+	}
+	if t := atomic.LoadInt64(&c.stats.lastError); t != 0 {
+		stats.LastError = time.Unix(0, t)
+	}
+	if q, ok := c.Quota(); ok {
+		stats.Quota = &q
+	}
+	return stats
+}
+
+// count - account a batch of entries delivered if err is nil, dropped otherwise
+func (c *Client) count(entries int, err error) {
+	if err == nil {
+		atomic.AddInt64(&c.stats.delivered, int64(entries))
+		atomic.AddInt64(&c.stats.batches, 1)
+		return
+	}
+	atomic.AddInt64(&c.stats.dropped, int64(entries))
+	atomic.AddInt64(&c.stats.batchesDropped, 1)
+}
@@ -0,0 +1,94 @@
+package intake
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	start := time.Now()
+	srv, _ := newServer(t)
+	attempts := 0
+	c, tick := newClient(srv, Config{
+		MaxRetry: 2,
+		Exporter: ExporterFunc(func(b *Batch) error {
+			attempts++
+			if string(b.Lines[0]) == "bad" {
+				return errors.New("rejected")
+			}
+			return nil
+		}),
+	})
+	ok(t, c.Push([]byte("one")))
+	ok(t, c.Push([]byte("two")))
+	tick <- time.Now()
+	ok(t, c.PushStream(Stream{Service: "other"}, []byte("bad")))
+	closeDropping(t, c)
+
+	stats := c.Stats()
+	// the dropped batch is reported
+	equals(t, false, stats.LastError.Before(start))
+	stats.LastError = time.Time{}
+	// the delivered batch is timed
+	equals(t, uint64(1), stats.Latency.Count)
+	stats.Latency = Latency{}
+	equals(t, Stats{
+		Pushed:         3,
+		Delivered:      2,
+		Dropped:        1,
+		Batches:        1,
+		BatchesDropped: 1,
+		Bytes:          int64(len("one\ntwo\n")),
+		Retries:        1,
+		Attempts:       3,
+		AttemptsFailed: 2,
+	}, stats)
+	equals(t, 3, attempts)
+}
+
+func TestStatsThrottled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	errs := make(chan error, 1)
+	c, _ := newClient(srv, Config{MaxRetry: 2, RetryAfter: time.Millisecond, OnError: func(err error) { errs <- err }})
+	ok(t, c.Push([]byte("one")))
+	closeDropping(t, c)
+	equals(t, int64(2), c.Stats().Attempts)
+	equals(t, int64(2), c.Stats().AttemptsFailed)
+	equals(t, int64(2), c.Stats().Throttled)
+	equals(t, int64(1), c.Stats().Dropped)
+	equals(t, false, c.Stats().LastError.IsZero())
+
+	var status *StatusError
+	equals(t, true, errors.As(<-errs, &status))
+	equals(t, http.StatusTooManyRequests, status.Code)
+	equals(t, "intake: 429 Too Many Requests", status.Error())
+}
+
+func TestStatsLastError(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &Client{config: Config{Clock: &fakeClock{now: at}}}
+	equals(t, true, c.Stats().LastError.IsZero())
+	c.notifyError(errors.New("down"))
+	equals(t, true, at.Equal(c.Stats().LastError))
+}
+
+func TestLatency(t *testing.T) {
+	var l latency
+	l.observe(30 * time.Millisecond)
+	l.observe(2 * time.Second)
+	l.observe(time.Minute + time.Second)
+	h := l.snapshot()
+	equals(t, uint64(3), h.Count)
+	equals(t, 2*time.Second+time.Minute+time.Second+30*time.Millisecond, h.Sum)
+	equals(t, uint64(1), h.Buckets[0.05])
+	equals(t, uint64(1), h.Buckets[1])
+	equals(t, uint64(2), h.Buckets[2.5])
+	// above the last bucket, only in the count
+	equals(t, uint64(2), h.Buckets[60])
+}
@@ -0,0 +1,48 @@
+package intake
+
+// Stream identify where lines land in Datadog, lines of different streams are
+// never sent in the same batch
+type Stream struct {
+	Source   string
+	Service  string
+	Hostname string
+	Tags     []string
+}
+
+// Merge - override the stream with the non-empty fields of o, tags are appended
+func (s Stream) Merge(o Stream) Stream {
+	if o.Source != "" {
+		s.Source = o.Source
+	}
+	if o.Service != "" {
+		s.Service = o.Service
+	}
+	if o.Hostname != "" {
+		s.Hostname = o.Hostname
+	}
+	if len(o.Tags) > 0 {
+		tags := make([]string, 0, len(s.Tags)+len(o.Tags))
+		tags = append(tags, s.Tags...)
+		s.Tags = append(tags, o.Tags...)
+	}
+	return s
+}
+
+// appendKey - append the identity of the stream used to group lines into batches
+func (s Stream) appendKey(dst []byte) []byte {
+	dst = append(dst, s.Source...)
+	dst = append(dst, 0)
+	dst = append(dst, s.Service...)
+	dst = append(dst, 0)
+	dst = append(dst, s.Hostname...)
+	for _, tag := range s.Tags {
+		dst = append(dst, 0)
+		dst = append(dst, tag...)
+	}
+	return dst
+}
+
+// Stream - the stream lines pushed without an explicit stream land in
+func (c *Client) Stream() Stream {
+	return c.config.Stream
+}
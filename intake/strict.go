@@ -0,0 +1,34 @@
+package intake
+
+import (
+	"context"
+	"errors"
+)
+
+// errDropped - the batch was dropped without a delivery error, e.g. on a panic
+var errDropped = errors.New("intake: batch dropped")
+
+// PushWait - queue an entry and wait for the batch holding it to be delivered
+// or dropped, returning the error of its delivery, nil once spooled to
+// Config.SpoolDir. Waiting stops with the
+// error of ctx once it is done, so a caller with a deadline never blocks
+// longer than its remaining time, a queued entry is still sent afterwards.
+func (c *Client) PushWait(ctx context.Context, e Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ack := make(chan error, 1)
+	if err := c.push(ctx, e, ack); err != nil {
+		return err
+	}
+	select {
+	case err := <-ack:
+		if err == errSpooled {
+			// kept in Config.SpoolDir until the intake is back
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
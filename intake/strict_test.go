@@ -0,0 +1,63 @@
+package intake
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPushWait(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{})
+	result := make(chan error)
+	go func() { result <- c.PushWait(context.Background(), Entry{Line: []byte("one")}) }()
+	// PushWait returns once the line is queued and its batch delivered
+	for sent := false; !sent; {
+		select {
+		case tick <- time.Now():
+		case r := <-reqs:
+			equals(t, "one\n", r.body)
+			sent = true
+		}
+	}
+	ok(t, <-result)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestPushWaitDeadline(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	equals(t, context.DeadlineExceeded, c.PushWait(ctx, Entry{Line: []byte("one")}))
+	equals(t, true, time.Since(start) < time.Second)
+	equals(t, context.DeadlineExceeded, c.PushWait(ctx, Entry{Line: []byte("two")}))
+
+	// the entry queued before the deadline is still sent
+	tick <- time.Now()
+	equals(t, "one\n", (<-reqs).body)
+	ok(t, c.Close(context.Background()))
+}
+
+func TestPushWaitDropped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c, tick := newClient(srv, Config{MaxRetry: 1})
+	result := make(chan error)
+	go func() { result <- c.PushWait(context.Background(), Entry{Line: []byte("one")}) }()
+	for {
+		select {
+		case tick <- time.Now():
+			continue
+		case err := <-result:
+			equals(t, "intake: 403 Forbidden", err.Error())
+		}
+		break
+	}
+	ok(t, c.Close(context.Background()))
+}
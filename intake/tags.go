@@ -0,0 +1,82 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTagLength - longer tags are truncated by Datadog
+const maxTagLength = 200
+
+// ErrInvalidTag - the tag would be altered, or split into several, by the intake
+var ErrInvalidTag = errors.New("intake: invalid tag")
+
+// EscapeTag - the tag made safe to send, the intake splits ddtags on commas
+// so commas, as well as spaces, control characters and invalid UTF-8, are
+// replaced by underscores. Unicode letters are kept, and colons after the
+// first one stay part of the value as Datadog does with "url:http://host".
+func EscapeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if ValidateTag(tag) == nil {
+		return tag
+	}
+	var b strings.Builder
+	n := 0
+	for i, r := range tag {
+		if n == maxTagLength {
+			break
+		}
+		n++
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(tag[i:]); size <= 1 {
+				b.WriteByte('_')
+				continue
+			}
+		}
+		if !tagRune(r) {
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ValidateTag - nil if the tag is sent as is, otherwise an ErrInvalidTag
+// telling why EscapeTag changes it
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("%w: empty tag", ErrInvalidTag)
+	}
+	if !utf8.ValidString(tag) {
+		return fmt.Errorf("%w: %q is not valid UTF-8", ErrInvalidTag, tag)
+	}
+	if n := utf8.RuneCountInString(tag); n > maxTagLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, maxTagLength)
+	}
+	for _, r := range tag {
+		if !tagRune(r) {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidTag, tag, r)
+		}
+	}
+	return nil
+}
+
+// tagRune - whether r is kept as is in a tag
+func tagRune(r rune) bool {
+	return r != ',' && !unicode.IsSpace(r) && !unicode.IsControl(r)
+}
+
+// escapeTags - the ddtags query parameter of tags
+func escapeTags(tags []string) string {
+	escaped := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = EscapeTag(tag); tag != "" {
+			escaped = append(escaped, tag)
+		}
+	}
+	return strings.Join(escaped, ",")
+}
@@ -0,0 +1,35 @@
+package intake
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEscapeTag(t *testing.T) {
+	equals(t, "env:prod", EscapeTag(" env:prod "))
+	equals(t, "url:http://host:8080", EscapeTag("url:http://host:8080"))
+	equals(t, "région:île-de-france", EscapeTag("région:île-de-france"))
+	equals(t, "team:a_b", EscapeTag("team:a,b"))
+	equals(t, "msg:hello_world", EscapeTag("msg:hello world"))
+	equals(t, "bad:_", EscapeTag("bad:\xff"))
+	equals(t, 200, len([]rune(EscapeTag(strings.Repeat("é", 300)))))
+}
+
+func TestValidateTag(t *testing.T) {
+	equals(t, nil, ValidateTag("url:http://host:8080"))
+	equals(t, nil, ValidateTag("région:île-de-france"))
+	for _, tag := range []string{"", "a,b", "a b", "\xff", strings.Repeat("a", 201)} {
+		err := ValidateTag(tag)
+		equals(t, true, errors.Is(err, ErrInvalidTag))
+	}
+}
+
+func TestDatadogURLTags(t *testing.T) {
+	c := &Client{scheme: "https", config: Config{Host: DatadogUSHost}}
+	u, err := url.Parse(c.datadogURL(Stream{Tags: []string{"team:a,b", "région:ouest", " "}}))
+	ok(t, err)
+	// every tag stays a single tag once the intake splits ddtags on commas
+	equals(t, []string{"team:a_b", "région:ouest"}, strings.Split(u.Query().Get("ddtags"), ","))
+}
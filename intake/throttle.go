@@ -0,0 +1,72 @@
+package intake
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetryAfter - wait before retrying an attempt the intake throttled
+	// without telling for how long
+	DefaultRetryAfter = time.Second
+	// MaxRetryAfter - longest wait before retrying a throttled attempt,
+	// whatever the intake asks for
+	MaxRetryAfter = time.Minute
+)
+
+// retryAfter - the wait a Retry-After header asks for, in seconds or as an
+// HTTP date, and whether it had a valid one
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// throttleDelay - how long to wait before retrying after err, zero unless
+// the intake throttled
+func (c *Client) throttleDelay(err error) time.Duration {
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusTooManyRequests {
+		return 0
+	}
+	d := status.RetryAfter
+	if d == 0 && !status.HasRetryAfter {
+		if d = c.config.RetryAfter; d == 0 {
+			d = DefaultRetryAfter
+		}
+	}
+	if d > MaxRetryAfter {
+		d = MaxRetryAfter
+	}
+	return d
+}
+
+// backOff - wait d before the next attempt, or until Close gave up
+func (c *Client) backOff(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.Debugf("Next attempt in %v", d)
+	select {
+	case <-c.clock().After(d):
+	case <-c.Context().Done():
+	}
+}
@@ -0,0 +1,82 @@
+package intake
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// instantClock is a Clock frozen at now, whose waits are over right away
+type instantClock struct {
+	fakeClock
+}
+
+func (c *instantClock) After(d time.Duration) <-chan time.Time {
+	c.fakeClock.After(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for v, exp := range map[string]time.Duration{
+		"3":                             3 * time.Second,
+		" 0 ":                           0,
+		"Thu, 02 Jan 2020 03:04:15 GMT": 10 * time.Second,
+		"Thu, 02 Jan 2020 03:00:00 GMT": 0,
+	} {
+		d, found := retryAfter(http.Header{"Retry-After": {v}}, now)
+		equals(t, true, found)
+		equals(t, exp, d)
+	}
+	for _, v := range []string{"", "-1", "soon"} {
+		_, found := retryAfter(http.Header{"Retry-After": {v}}, now)
+		equals(t, false, found)
+	}
+}
+
+func TestThrottleRetryAfter(t *testing.T) {
+	var m sync.Mutex
+	answers := []string{"7", "", "0", "3600"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		if len(answers) == 0 {
+			return
+		}
+		if answers[0] != "" {
+			w.Header().Set("Retry-After", answers[0])
+		}
+		answers = answers[1:]
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	clock := &instantClock{fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	c, tick := newClient(srv, Config{MaxRetry: 5, RetryAfter: 2 * time.Second, Clock: clock})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	ok(t, c.Flush(context.Background()))
+	equals(t, int64(1), c.Stats().Delivered)
+	equals(t, int64(4), c.Stats().Throttled)
+	// the header, the default without one, none when asked and the cap
+	equals(t, []time.Duration{7 * time.Second, 2 * time.Second, MaxRetryAfter}, clock.Waits())
+}
+
+func TestThrottleNoWaitOnOtherErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	clock := &instantClock{fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	c, tick := newClient(srv, Config{MaxRetry: 2, Clock: clock})
+	ok(t, c.Push([]byte("one")))
+	tick <- time.Now()
+	closeDropping(t, c)
+	equals(t, []time.Duration(nil), clock.Waits())
+}
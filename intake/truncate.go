@@ -0,0 +1,72 @@
+package intake
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// TruncatedMarker - suffix of the lines cut to the entry limit, as the
+// Datadog intake marks the ones it truncates
+const TruncatedMarker = "...TRUNCATED..."
+
+var (
+	// ErrEntryTooLarge - a JSON line above the entry limit, which cannot be
+	// cut without breaking it
+	ErrEntryTooLarge = errors.New("intake: JSON entry above the entry limit")
+	// ErrEntryLimit - an entry limit above the 256kB the Datadog intake
+	// accepts, while sending to it directly
+	ErrEntryLimit = errors.New("intake: entry limit above the 256kB of the Datadog intake")
+)
+
+// MaxEntryBytes - size above which lines are truncated, Config.MaxEntryBytes
+// or the 256kB of the Datadog intake
+func (c *Client) MaxEntryBytes() int {
+	if c.config.MaxEntryBytes > 0 {
+		return c.config.MaxEntryBytes
+	}
+	return maxEntryByteSize
+}
+
+// checkEntryLimit - refuse an entry limit the Datadog intake would not
+// honor. Relays and exporters may accept more, they are not checked.
+func (config Config) checkEntryLimit() error {
+	if config.MaxEntryBytes > maxEntryByteSize && config.Exporter == nil && Site(config.Host) != "" {
+		return ErrEntryLimit
+	}
+	return nil
+}
+
+// truncate - line cut to the entry limit, trailing newlines aside. JSON
+// lines above it are refused.
+func (c *Client) truncate(line []byte) ([]byte, error) {
+	n, max := len(bytes.TrimRight(line, "\n")), c.MaxEntryBytes()
+	if n <= max {
+		return line, nil
+	}
+	if c.config.JSON {
+		return nil, ErrEntryTooLarge
+	}
+	atomic.AddInt64(&c.stats.truncated, 1)
+	return Truncate(line[:n], max), nil
+}
+
+// Truncate - a copy of line cut to at most max bytes on a rune boundary and
+// ending with TruncatedMarker, line itself if it fits
+func Truncate(line []byte, max int) []byte {
+	if len(line) <= max {
+		return line
+	}
+	marker := TruncatedMarker
+	if max < len(marker) {
+		marker = ""
+	}
+	cut := max - len(marker)
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	out := make([]byte, 0, cut+len(marker))
+	out = append(out, line[:cut]...)
+	return append(out, marker...)
+}
@@ -0,0 +1,60 @@
+package intake
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		max  int
+		exp  string
+	}{
+		{"short", 10, "short"},
+		{strings.Repeat("a", 20), 18, "aaa" + TruncatedMarker},
+		// never splitting a rune
+		{"aé" + strings.Repeat("b", 20), 17, "a" + TruncatedMarker},
+		// no room for the marker
+		{"abcdef", 4, "abcd"},
+	} {
+		equals(t, tc.exp, string(Truncate([]byte(tc.line), tc.max)))
+	}
+}
+
+func TestMaxEntryBytes(t *testing.T) {
+	srv, reqs := newServer(t)
+	c, tick := newClient(srv, Config{MaxEntryBytes: 20})
+	equals(t, 20, c.MaxEntryBytes())
+
+	ok(t, c.Push([]byte("fits\n")))
+	ok(t, c.Push([]byte(strings.Repeat("x", 30)+"\n")))
+	tick <- time.Now()
+
+	r := <-reqs
+	equals(t, "fits\nxxxxx"+TruncatedMarker+"\n", r.body)
+	equals(t, int64(1), c.Stats().Truncated)
+	ok(t, c.Close(context.Background()))
+
+	c, _ = newClient(srv, Config{MaxEntryBytes: 20, JSON: true})
+	equals(t, ErrEntryTooLarge, c.Push([]byte(`{"message":"`+strings.Repeat("x", 30)+`"}`)))
+	ok(t, c.Close(context.Background()))
+
+	equals(t, maxEntryByteSize, (&Client{}).MaxEntryBytes())
+}
+
+func TestCheckEntryLimit(t *testing.T) {
+	for _, tc := range []struct {
+		config Config
+		err    error
+	}{
+		{Config{Host: DatadogUSHost, MaxEntryBytes: 64 * 1024}, nil},
+		{Config{Host: DatadogUSHost, MaxEntryBytes: maxEntryByteSize + 1}, ErrEntryLimit},
+		{Config{Host: "relay.internal:8080", MaxEntryBytes: maxEntryByteSize + 1}, nil},
+		{Config{Host: DatadogUSHost, MaxEntryBytes: maxEntryByteSize + 1, Exporter: ExporterFunc(nil)}, nil},
+	} {
+		equals(t, tc.err, tc.config.Validate())
+	}
+}
@@ -0,0 +1,71 @@
+//go:build integration
+
+// Package integration runs the hook end to end against the mock intake of
+// the datadogtest package under latency and failure profiles.
+package integration
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/datadogtest"
+	"github.com/sirupsen/logrus"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func TestIntegration(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		profile  datadogtest.Profile
+		options  datadog.Options
+		lossless bool
+	}{
+		{name: "healthy", profile: datadogtest.Profile{}, lossless: true},
+		{name: "slow", profile: datadogtest.Profile{Latency: 200 * time.Millisecond, Jitter: 100 * time.Millisecond}, lossless: true},
+		{name: "compressed", options: datadog.Options{Compress: true}, lossless: true},
+		{name: "flaky", profile: datadogtest.Profile{FailureRate: 0.2}},
+		{name: "down", profile: datadogtest.Profile{FailureRate: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := datadogtest.NewServer(tc.profile)
+			defer srv.Close()
+			options := tc.options
+			options.HTTPClient = srv.Client()
+			hook := datadog.NewHook(srv.Host(), "key", 100*time.Millisecond, 3, logrus.InfoLevel, &logrus.JSONFormatter{}, options)
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+			logger.AddHook(hook)
+
+			result := datadogtest.Run(logger, datadogtest.Load{Rate: 2000, Duration: time.Second, Workers: 4, Size: 100})
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := hook.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+			report := datadogtest.Report{Result: result, Client: hook.Stats().Client, Server: srv.Stats()}
+			t.Log(report)
+
+			// every entry is accounted for, and the intake got what was delivered
+			equals(t, result.Fired, report.Client.Delivered+report.Client.Dropped)
+			equals(t, report.Client.Delivered, report.Server.Lines)
+			if tc.lossless {
+				equals(t, int64(0), report.Client.Dropped)
+			}
+		})
+	}
+}
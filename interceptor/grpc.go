@@ -0,0 +1,104 @@
+// Package interceptor logs gRPC calls with Datadog attributes. Entries carry
+// the call context, so the datadog Hook can correlate them with traces.
+package interceptor
+
+import (
+	"context"
+	"net"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor - log every unary call served
+func UnaryServerInterceptor(logger logrus.FieldLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, "server", info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor - log every stream served once it ends
+func StreamServerInterceptor(logger logrus.FieldLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, "server", info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+// UnaryClientInterceptor - log every unary call made
+func UnaryClientInterceptor(logger logrus.FieldLogger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logCall(ctx, logger, "client", method, err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor - log every stream opened, failures to open it included
+func StreamClientInterceptor(logger logrus.FieldLogger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		logCall(ctx, logger, "client", method, err, time.Since(start))
+		return cs, err
+	}
+}
+
+func logCall(ctx context.Context, logger logrus.FieldLogger, kind, method string, err error, duration time.Duration) {
+	code := status.Code(err)
+	fields := Fields(ctx, kind, method, code, duration)
+	entry := logger.WithFields(fields).WithContext(ctx)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Log(Level(code), method+" "+code.String())
+}
+
+// Fields - Datadog attributes describing a finished call
+func Fields(ctx context.Context, kind, method string, code codes.Code, duration time.Duration) logrus.Fields {
+	service, name := path.Split(method)
+	fields := logrus.Fields{
+		"grpc": map[string]interface{}{
+			"full_method": method,
+			"service":     path.Base(service),
+			"method":      name,
+			"code":        code.String(),
+		},
+		"span": map[string]interface{}{"kind": kind},
+		// Datadog expects the duration in nanoseconds
+		"duration": duration.Nanoseconds(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip := p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		fields["network"] = map[string]interface{}{"client": map[string]interface{}{"ip": ip}}
+	}
+	return fields
+}
+
+// Level - level of the call log, Error for server side faults and Warn for client errors
+func Level(code codes.Code) logrus.Level {
+	switch code {
+	case codes.OK, codes.Canceled:
+		return logrus.InfoLevel
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted,
+		codes.OutOfRange:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
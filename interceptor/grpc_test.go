@@ -0,0 +1,105 @@
+package interceptor
+
+import (
+	"context"
+	"log"
+	"net"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+type ctxKey struct{}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace")
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5000}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.v1.Users/Get"}
+
+	i := UnaryServerInterceptor(logger)
+	_, err := i(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such user")
+	})
+	equals(t, codes.NotFound, status.Code(err))
+
+	e := hook.LastEntry()
+	equals(t, logrus.WarnLevel, e.Level)
+	equals(t, "/users.v1.Users/Get NotFound", e.Message)
+	equals(t, "trace", e.Context.Value(ctxKey{}))
+	equals(t, map[string]interface{}{
+		"full_method": "/users.v1.Users/Get",
+		"service":     "users.v1.Users",
+		"method":      "Get",
+		"code":        "NotFound",
+	}, e.Data["grpc"])
+	equals(t, "10.1.2.3", e.Data["network"].(map[string]interface{})["client"].(map[string]interface{})["ip"])
+	equals(t, err, e.Data[logrus.ErrorKey])
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	i := UnaryClientInterceptor(logger)
+	err := i(context.Background(), "/users.v1.Users/Get", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return nil
+		})
+	equals(t, nil, err)
+
+	e := hook.LastEntry()
+	equals(t, logrus.InfoLevel, e.Level)
+	equals(t, map[string]interface{}{"kind": "client"}, e.Data["span"])
+	_, ok := e.Data["network"]
+	equals(t, false, ok)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	i := StreamServerInterceptor(logger)
+	err := i(nil, serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/logs.v1.Logs/Tail"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			time.Sleep(time.Millisecond)
+			return status.Error(codes.Internal, "boom")
+		})
+	equals(t, codes.Internal, status.Code(err))
+
+	e := hook.LastEntry()
+	equals(t, logrus.ErrorLevel, e.Level)
+	if d := e.Data["duration"].(int64); d < int64(time.Millisecond) {
+		t.Fatalf("duration %d is too short", d)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	equals(t, logrus.InfoLevel, Level(codes.OK))
+	equals(t, logrus.WarnLevel, Level(codes.PermissionDenied))
+	equals(t, logrus.ErrorLevel, Level(codes.Unavailable))
+	equals(t, logrus.ErrorLevel, Level(codes.Unknown))
+}
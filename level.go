@@ -0,0 +1,54 @@
+package datadog
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Level - the least severe level shipped
+func (h *Hook) Level() logrus.Level {
+	return logrus.Level(atomic.LoadUint32(&h.level))
+}
+
+// SetLevel - change the least severe level shipped, safe to call at any time
+func (h *Hook) SetLevel(level logrus.Level) {
+	atomic.StoreUint32(&h.level, uint32(level))
+}
+
+// Sampling - share of the entries shipped, between 0 and 1
+func (h *Hook) Sampling() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&h.sampling))
+}
+
+// SetSampling - ship only this share of the entries, picked at random or by
+// Options.SampleBy, every entry if rate is 0 or not below 1. Safe to call at
+// any time.
+func (h *Hook) SetSampling(rate float64) {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	atomic.StoreUint64(&h.sampling, math.Float64bits(rate))
+}
+
+// sampled - whether an entry is kept by sampling
+func (h *Hook) sampled(entry *logrus.Entry) bool {
+	rate, ok := h.options.LevelSampling[entry.Level]
+	if !ok {
+		rate = h.Sampling()
+	}
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		// a level set to 0, SetSampling stores 1 for it
+		return false
+	}
+	if key, ok := sampleKey(entry, h.options.SampleBy); ok {
+		return sampleHash(key) < rate
+	}
+	h.samplerLock.Lock()
+	defer h.samplerLock.Unlock()
+	return h.sampler.Float64() < rate
+}
@@ -0,0 +1,40 @@
+package datadog
+
+import (
+	"bytes"
+
+	"github.com/sirupsen/logrus"
+)
+
+// local - render the entry for the local sinks and write it to the tee.
+// Returns the rendering the fallback needs, nil when it is the line itself.
+func (h *Hook) local(entry *logrus.Entry, line []byte) []byte {
+	o := h.options
+	if o.Tee == nil && o.Fallback == nil {
+		return nil
+	}
+	var local []byte
+	if o.LocalFormatter != nil {
+		var err error
+		if local, err = o.LocalFormatter.Format(entry); err != nil {
+			h.client.Debugf("Unable to format entry locally, %v", err)
+			local = nil
+		}
+	}
+	if o.Tee != nil {
+		out := local
+		if out == nil {
+			out = line
+		}
+		h.teeLock.Lock()
+		_, err := o.Tee.Write(out)
+		if err == nil && !bytes.HasSuffix(out, []byte("\n")) {
+			_, err = o.Tee.Write([]byte("\n"))
+		}
+		h.teeLock.Unlock()
+		if err != nil {
+			h.client.Debugf("Unable to write tee, %v", err)
+		}
+	}
+	return local
+}
@@ -0,0 +1,34 @@
+package datadog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLocal(t *testing.T) {
+	var tee bytes.Buffer
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Tee:            &tee,
+		LocalFormatter: &logrus.TextFormatter{DisableTimestamp: true, DisableColors: true},
+	})
+	entry := &logrus.Entry{Message: "hello", Level: logrus.InfoLevel, Data: logrus.Fields{"user": "bob"}}
+
+	local := hook.local(entry, []byte(`{"msg":"hello"}`+"\n"))
+	equals(t, "level=info msg=hello user=bob\n", string(local))
+	equals(t, "level=info msg=hello user=bob\n", tee.String())
+}
+
+func TestLocalWithoutFormatter(t *testing.T) {
+	var tee bytes.Buffer
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{Tee: &tee})
+
+	local := hook.local(&logrus.Entry{Message: "hello"}, []byte(`{"msg":"hello"}`))
+	equals(t, []byte(nil), local)
+	equals(t, `{"msg":"hello"}`+"\n", tee.String())
+
+	hook = NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+	equals(t, []byte(nil), hook.local(&logrus.Entry{Message: "hello"}, []byte(`{"msg":"hello"}`)))
+}
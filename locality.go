@@ -0,0 +1,189 @@
+package datadog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+const (
+	// ZoneTag - tag carrying the availability zone of the host, the bytes
+	// delivered are counted by its value in Stats().Client.Zones
+	ZoneTag = intake.ZoneTag
+	// RegionTag - tag carrying the region of the host
+	RegionTag = intake.RegionTag
+)
+
+var (
+	// zoneEnv, regionEnv - variables read for the locality, the first set wins
+	zoneEnv   = []string{"DD_AVAILABILITY_ZONE", "AWS_AVAILABILITY_ZONE", "AVAILABILITY_ZONE"}
+	regionEnv = []string{"DD_REGION", "AWS_REGION", "AWS_DEFAULT_REGION", "REGION"}
+
+	// metadataURL - instance metadata service of AWS and GCP
+	metadataURL     = "http://169.254.169.254"
+	metadataTimeout = 500 * time.Millisecond
+
+	localityOnce sync.Once
+	locality     []string
+	// localityResolved - set once LocalityTags has its answer
+	localityResolved int32
+)
+
+// LocalityTags - the availability-zone and region tags of the host, read from
+// the environment or else from the instance metadata service of AWS or GCP.
+// The metadata service is asked once per process, never through a proxy,
+// and tags whose value is unknown are left out.
+func LocalityTags() []string {
+	localityOnce.Do(func() {
+		locality = localityTags(os.Getenv, metadataClient(), metadataURL)
+		atomic.StoreInt32(&localityResolved, 1)
+	})
+	return locality
+}
+
+// metadataClient - client of the metadata service, which is link-local: a
+// proxy of the environment would see the instance metadata, or not reach it
+func metadataClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{Proxy: nil}, Timeout: metadataTimeout}
+}
+
+// localityReady - whether LocalityTags answers without asking the metadata
+// service
+func localityReady() bool {
+	return atomic.LoadInt32(&localityResolved) == 1 || lookupEnv(os.Getenv, zoneEnv) != ""
+}
+
+func localityTags(getenv func(string) string, client *http.Client, url string) []string {
+	zone, region := lookupEnv(getenv, zoneEnv), lookupEnv(getenv, regionEnv)
+	if zone == "" {
+		zone = metadataZone(client, url)
+	}
+	if region == "" {
+		region = zoneRegion(zone)
+	}
+	var tags []string
+	if zone != "" {
+		tags = append(tags, ZoneTag+":"+zone)
+	}
+	if region != "" {
+		tags = append(tags, RegionTag+":"+region)
+	}
+	return tags
+}
+
+func lookupEnv(getenv func(string) string, names []string) string {
+	for _, name := range names {
+		if v := strings.TrimSpace(getenv(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// metadataZone - the zone from the metadata service, AWS IMDSv2 first then
+// GCP, empty if neither answered
+func metadataZone(client *http.Client, url string) string {
+	req, _ := http.NewRequest(http.MethodPut, url+"/latest/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if token, ok := metadataGet(client, req); ok {
+		req, _ = http.NewRequest(http.MethodGet, url+"/latest/meta-data/placement/availability-zone", nil)
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		if zone, ok := metadataGet(client, req); ok {
+			return zone
+		}
+	}
+	req, _ = http.NewRequest(http.MethodGet, url+"/computeMetadata/v1/instance/zone", nil)
+	req.Header.Set("Metadata-Flavor", "Google")
+	if zone, ok := metadataGet(client, req); ok {
+		// projects/<number>/zones/<zone>
+		return zone[strings.LastIndex(zone, "/")+1:]
+	}
+	return ""
+}
+
+func metadataGet(client *http.Client, req *http.Request) (string, bool) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+	v := strings.TrimSpace(string(body))
+	return v, v != ""
+}
+
+// zoneRegion - the region of an AWS (us-east-1a) or GCP (us-central1-a)
+// zone, empty if it does not look like one
+func zoneRegion(zone string) string {
+	n := len(zone)
+	if n < 3 || !isLower(zone[n-1]) {
+		return ""
+	}
+	switch c := zone[n-2]; {
+	case c == '-':
+		return zone[:n-2]
+	case c >= '0' && c <= '9':
+		return zone[:n-1]
+	}
+	return ""
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// withLocality - the tags with the locality ones they don't carry yet
+func withLocality(tags, locality []string) []string {
+	tags = append([]string(nil), tags...)
+	for _, tag := range locality {
+		key := tag[:strings.IndexByte(tag, ':')]
+		if !hasTagKey(tags, key) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// resolveLocality - add the locality tags to the ones of the entries when
+// Locality is set: right away when known without the metadata service,
+// else once it answered, so New never waits for it
+func (h *Hook) resolveLocality() {
+	if !h.options.Locality {
+		return
+	}
+	if localityReady() {
+		h.options.Tags = withLocality(h.options.Tags, LocalityTags())
+		return
+	}
+	tags := h.options.Tags
+	go func() {
+		h.locality.Store(withLocality(tags, LocalityTags()))
+	}()
+}
+
+// localityStream - the stream with the locality tags once they were resolved
+// in the background
+func (h *Hook) localityStream(stream Stream) Stream {
+	if tags, ok := h.locality.Load().([]string); ok {
+		stream.Tags = tags
+	}
+	return stream
+}
+
+func hasTagKey(tags []string, key string) bool {
+	for _, tag := range tags {
+		if tag == key || strings.HasPrefix(tag, key+":") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package datadog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLocalityTagsEnv(t *testing.T) {
+	env := map[string]string{"AWS_AVAILABILITY_ZONE": "eu-west-1b", "REGION": " "}
+	tags := localityTags(func(k string) string { return env[k] }, http.DefaultClient, "http://127.0.0.1:0")
+	equals(t, []string{"availability-zone:eu-west-1b", "region:eu-west-1"}, tags)
+
+	env = map[string]string{"AWS_REGION": "us-east-2"}
+	tags = localityTags(func(k string) string { return env[k] }, http.DefaultClient, "http://127.0.0.1:0")
+	equals(t, []string{"region:us-east-2"}, tags)
+}
+
+func TestLocalityTagsMetadata(t *testing.T) {
+	aws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.Write([]byte("token"))
+		case r.URL.Path == "/latest/meta-data/placement/availability-zone" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+			w.Write([]byte("us-east-1a\n"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer aws.Close()
+	getenv := func(string) string { return "" }
+	equals(t, []string{"availability-zone:us-east-1a", "region:us-east-1"}, localityTags(getenv, aws.Client(), aws.URL))
+
+	gcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/computeMetadata/v1/instance/zone" || r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("projects/123/zones/us-central1-c"))
+	}))
+	defer gcp.Close()
+	equals(t, []string{"availability-zone:us-central1-c", "region:us-central1"}, localityTags(getenv, gcp.Client(), gcp.URL))
+
+	gcp.Close()
+	equals(t, []string(nil), localityTags(getenv, gcp.Client(), gcp.URL))
+}
+
+func TestZoneRegion(t *testing.T) {
+	for zone, region := range map[string]string{
+		"us-east-1a":    "us-east-1",
+		"us-central1-f": "us-central1",
+		"2":             "",
+		"westeurope":    "",
+		"":              "",
+	} {
+		equals(t, region, zoneRegion(zone))
+	}
+}
+
+func TestWithLocality(t *testing.T) {
+	tags := withLocality([]string{"env:prod", "region:custom"}, []string{"availability-zone:us-east-1a", "region:us-east-1"})
+	equals(t, []string{"env:prod", "region:custom", "availability-zone:us-east-1a"}, tags)
+}
+
+func TestMetadataClient(t *testing.T) {
+	// the link-local metadata service is never asked through a proxy
+	transport := metadataClient().Transport.(*http.Transport)
+	equals(t, true, transport.Proxy == nil)
+}
+
+func TestLocalityBackground(t *testing.T) {
+	localityOnce.Do(func() {})
+	saved, resolved := locality, atomic.LoadInt32(&localityResolved)
+	defer func() {
+		locality = saved
+		atomic.StoreInt32(&localityResolved, resolved)
+	}()
+	locality = []string{"availability-zone:us-east-1a", "region:us-east-1"}
+	for _, name := range zoneEnv {
+		t.Setenv(name, "")
+	}
+
+	// known already, the tags are there from the first entry
+	atomic.StoreInt32(&localityResolved, 1)
+	var m sync.Mutex
+	var tags [][]string
+	exporter := intake.ExporterFunc(func(b *intake.Batch) error {
+		m.Lock()
+		defer m.Unlock()
+		tags = append(tags, b.Stream.Tags)
+		return nil
+	})
+	hook := New("key", WithTags("env:prod"), WithExporter(exporter), WithOption(func(o *Options) { o.Locality = true }))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, [][]string{{"env:prod", "availability-zone:us-east-1a", "region:us-east-1"}}, tags)
+
+	// asked in the background, the entries get them once answered
+	atomic.StoreInt32(&localityResolved, 0)
+	tags = nil
+	hook = New("key", WithTags("env:prod"), WithExporter(exporter), WithOption(func(o *Options) { o.Locality = true }))
+	deadline := time.Now().Add(time.Second)
+	for hook.localityStream(Stream{}).Tags == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, [][]string{{"env:prod", "availability-zone:us-east-1a", "region:us-east-1"}}, tags)
+}
@@ -0,0 +1,133 @@
+// Package middleware generates access logs carrying Datadog standard
+// attributes, so requests land in Datadog with the right facets when the
+// logger ships through the datadog Hook.
+//
+// The net/http Handler works with any router built on net/http. Other
+// frameworks, such as gin, only need to call Fields once the request is
+// served:
+//
+//	router.Use(func(c *gin.Context) {
+//		start := time.Now()
+//		c.Next()
+//		fields := middleware.Fields(c.Request, c.Writer.Status(), int64(c.Writer.Size()), time.Since(start))
+//		logger.WithFields(fields).Log(middleware.Level(c.Writer.Status()), middleware.Message(c.Request, c.Writer.Status()))
+//	})
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler - serve with next and log every request through logger
+func Handler(logger logrus.FieldLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		fields := Fields(r, rw.status, rw.written, time.Since(start))
+		logger.WithFields(fields).Log(Level(rw.status), Message(r, rw.status))
+	})
+}
+
+// Fields - Datadog standard attributes describing a served request
+func Fields(r *http.Request, status int, written int64, duration time.Duration) logrus.Fields {
+	h := map[string]interface{}{
+		"method":      r.Method,
+		"status_code": status,
+		"url":         r.URL.String(),
+		"url_details": map[string]interface{}{
+			"path": r.URL.Path,
+			"host": r.Host,
+		},
+		"version": fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor),
+	}
+	if ua := r.UserAgent(); ua != "" {
+		h["useragent"] = ua
+	}
+	if ref := r.Referer(); ref != "" {
+		h["referer"] = ref
+	}
+	if id := r.Header.Get("X-Request-Id"); id != "" {
+		h["request_id"] = id
+	}
+	return logrus.Fields{
+		"http": h,
+		"network": map[string]interface{}{
+			"client":        map[string]interface{}{"ip": ClientIP(r)},
+			"bytes_read":    r.ContentLength,
+			"bytes_written": written,
+		},
+		// Datadog expects the duration in nanoseconds
+		"duration": duration.Nanoseconds(),
+	}
+}
+
+// Level - level of the access log, Error for 5xx and Warn for 4xx responses
+func Level(status int) logrus.Level {
+	switch {
+	case status >= 500:
+		return logrus.ErrorLevel
+	case status >= 400:
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+// Message - short summary of the served request
+func Message(r *http.Request, status int) string {
+	return fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, status)
+}
+
+// ClientIP - address of the client, honoring the first X-Forwarded-For hop
+func ClientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseWriter records status and size of the response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush - implement http.Flusher when the wrapped writer does
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap - expose the wrapped writer to http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
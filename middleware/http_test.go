@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func TestHandler(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	h := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+
+	r := httptest.NewRequest("GET", "http://example.com/users/1?x=y", nil)
+	r.Header.Set("User-Agent", "curl/7.0")
+	r.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	e := hook.LastEntry()
+	equals(t, logrus.WarnLevel, e.Level)
+	equals(t, "GET /users/1 404", e.Message)
+
+	hf := e.Data["http"].(map[string]interface{})
+	equals(t, "GET", hf["method"])
+	equals(t, http.StatusNotFound, hf["status_code"])
+	equals(t, "http://example.com/users/1?x=y", hf["url"])
+	equals(t, "curl/7.0", hf["useragent"])
+	equals(t, "/users/1", hf["url_details"].(map[string]interface{})["path"])
+
+	nf := e.Data["network"].(map[string]interface{})
+	equals(t, "10.0.0.1", nf["client"].(map[string]interface{})["ip"])
+	equals(t, int64(9), nf["bytes_written"])
+	if _, ok := e.Data["duration"].(int64); !ok {
+		t.Fatalf("duration is not nanoseconds: %#v", e.Data["duration"])
+	}
+}
+
+func TestLevel(t *testing.T) {
+	equals(t, logrus.InfoLevel, Level(200))
+	equals(t, logrus.InfoLevel, Level(302))
+	equals(t, logrus.WarnLevel, Level(429))
+	equals(t, logrus.ErrorLevel, Level(503))
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.168.1.1:1234"
+	equals(t, "192.168.1.1", ClientIP(r))
+	r.RemoteAddr = "pipe"
+	equals(t, "pipe", ClientIP(r))
+}
@@ -0,0 +1,80 @@
+package datadog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mutes - fingerprints of message templates muted until a deadline
+type mutes struct {
+	active int32 // atomic, number of fingerprints muted
+
+	m     sync.Mutex
+	until map[string]time.Time
+}
+
+// Mute - drop the entries whose message has this Fingerprint for d, e.g. a
+// known-noisy log signature during an incident, without touching the other
+// entries. Muting again replaces the deadline, a d not above 0 unmutes.
+func (h *Hook) Mute(fingerprint string, d time.Duration) {
+	m := &h.mutes
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.until == nil {
+		m.until = map[string]time.Time{}
+	}
+	if d <= 0 {
+		delete(m.until, fingerprint)
+	} else {
+		m.until[fingerprint] = h.now().Add(d)
+	}
+	atomic.StoreInt32(&m.active, int32(len(m.until)))
+}
+
+// Muted - fingerprints muted and when they are unmuted, nil if none
+func (h *Hook) Muted() map[string]time.Time {
+	m := &h.mutes
+	m.m.Lock()
+	defer m.m.Unlock()
+	now := h.now()
+	var muted map[string]time.Time
+	for fingerprint, until := range m.until {
+		if now.Before(until) {
+			if muted == nil {
+				muted = map[string]time.Time{}
+			}
+			muted[fingerprint] = until
+		}
+	}
+	return muted
+}
+
+// muted - whether the entry is muted, only fingerprinting it while some
+// fingerprint is muted. The message is scrubbed first, as it is before
+// Options.Fingerprint adds the FingerprintField shipped.
+func (h *Hook) muted(entry *logrus.Entry) bool {
+	m := &h.mutes
+	if atomic.LoadInt32(&m.active) == 0 {
+		return false
+	}
+	message := entry.Message
+	if r, ok := h.rules.Load().(*Rules); ok {
+		message = r.scrub(message)
+	}
+	fingerprint := Fingerprint(Template(message))
+	m.m.Lock()
+	defer m.m.Unlock()
+	until, ok := m.until[fingerprint]
+	if !ok {
+		return false
+	}
+	if !h.now().Before(until) {
+		delete(m.until, fingerprint)
+		atomic.StoreInt32(&m.active, int32(len(m.until)))
+		return false
+	}
+	return true
+}
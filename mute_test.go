@@ -0,0 +1,117 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeClock is a Clock moved by hand, whose timers never fire
+type fakeClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(time.Duration) <-chan time.Time {
+	return nil
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestMute(t *testing.T) {
+	var tee lockedBuffer
+	start := time.Unix(1000, 0)
+	clock := &fakeClock{now: start}
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Tee:      &tee,
+		Clock:    clock,
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	noisy := Fingerprint(Template("cache miss for key 42"))
+
+	hook.Mute(noisy, time.Minute)
+	equals(t, map[string]time.Time{noisy: start.Add(time.Minute)}, hook.Muted())
+	// the whole template is muted, other messages are not
+	ok(t, hook.Fire(&logrus.Entry{Message: "cache miss for key 7", Level: logrus.InfoLevel}))
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel}))
+	equals(t, 1, strings.Count(tee.String(), "\n"))
+	equals(t, int64(1), hook.Stats().Muted)
+	equals(t, int64(0), hook.Stats().Skipped)
+
+	// unmuted once the duration elapsed
+	clock.advance(time.Minute)
+	equals(t, map[string]time.Time(nil), hook.Muted())
+	ok(t, hook.Fire(&logrus.Entry{Message: "cache miss for key 8", Level: logrus.InfoLevel}))
+	equals(t, 2, strings.Count(tee.String(), "\n"))
+
+	hook.Mute(noisy, time.Minute)
+	hook.Mute(noisy, 0)
+	equals(t, map[string]time.Time(nil), hook.Muted())
+}
+
+func TestMuteScrubbed(t *testing.T) {
+	var tee lockedBuffer
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Tee:         &tee,
+		Fingerprint: true,
+		Exporter:    intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	hook.SetRules(Rules{Scrub: []ScrubRule{{Pattern: regexp.MustCompile(`user \w+`), Replacement: "user [user]"}}})
+
+	// the fingerprint shipped is the one muting works with
+	ok(t, hook.Fire(&logrus.Entry{Message: "login of user alice", Level: logrus.InfoLevel}))
+	var shipped map[string]interface{}
+	ok(t, json.Unmarshal([]byte(strings.TrimSpace(tee.String())), &shipped))
+	hook.Mute(shipped[FingerprintField].(string), time.Minute)
+	ok(t, hook.Fire(&logrus.Entry{Message: "login of user bob", Level: logrus.InfoLevel}))
+	equals(t, int64(1), hook.Stats().Muted)
+}
+
+func TestMuteHandler(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	srv := httptest.NewServer(hook.Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"mute":{"abc":"30m"}}`))
+	ok(t, err)
+	resp.Body.Close()
+	equals(t, http.StatusOK, resp.StatusCode)
+	_, muted := hook.Status().Muted["abc"]
+	equals(t, true, muted)
+
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"mute":{"abc":"0"},"level":"debug"}`))
+	ok(t, err)
+	resp.Body.Close()
+	equals(t, 0, len(hook.Muted()))
+
+	// nothing is applied when a duration is invalid
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"mute":{"abc":"soon"},"level":"error"}`))
+	ok(t, err)
+	resp.Body.Close()
+	equals(t, http.StatusBadRequest, resp.StatusCode)
+	equals(t, logrus.DebugLevel, hook.Level())
+}
@@ -0,0 +1,139 @@
+package datadog
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TimeISO8601 - encode time.Time field values as RFC 3339 strings in UTC
+	// with milliseconds, the default
+	TimeISO8601 = "iso8601"
+	// TimeEpochMillis - encode time.Time field values as milliseconds since epoch
+	TimeEpochMillis = "epoch_millis"
+
+	// BytesBase64 - encode []byte field values in standard base64, the default
+	BytesBase64 = "base64"
+	// BytesHex - encode []byte field values in lowercase hex
+	BytesHex = "hex"
+
+	iso8601Millis = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// normalizer - encoding of the field values formatters marshal poorly
+type normalizer struct {
+	epochMillis bool
+	hex         bool
+}
+
+func newNormalizer(options Options) *normalizer {
+	return &normalizer{
+		epochMillis: options.NormalizeTime == TimeEpochMillis,
+		hex:         options.NormalizeBytes == BytesHex,
+	}
+}
+
+// apply - copy of the entry with its field values normalized, the entry
+// itself when none needs to be
+func (n *normalizer) apply(entry *logrus.Entry) *logrus.Entry {
+	var data logrus.Fields
+	for k, v := range entry.Data {
+		nv, changed := n.value(v)
+		if !changed {
+			continue
+		}
+		if data == nil {
+			data = make(logrus.Fields, len(entry.Data))
+			for k, v := range entry.Data {
+				data[k] = v
+			}
+		}
+		data[k] = nv
+	}
+	if data == nil {
+		return entry
+	}
+	e := *entry
+	e.Data = data
+	return &e
+}
+
+// value - the normalized value and whether it differs: times in the
+// configured format, errors as their message, byte slices in base64 or hex,
+// Stringers as their string, recursing in slices and maps of interfaces.
+// Durations stay nanoseconds, the unit of the duration attribute of Datadog,
+// and values marshaling themselves to JSON are left alone.
+func (n *normalizer) value(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Duration:
+		return v, false
+	case time.Time:
+		if n.epochMillis {
+			return v.UnixNano() / int64(time.Millisecond), true
+		}
+		return v.UTC().Format(iso8601Millis), true
+	case *time.Time:
+		if v == nil {
+			return nil, false
+		}
+		return n.value(*v)
+	case error:
+		return v.Error(), true
+	case []byte:
+		if n.hex {
+			return hex.EncodeToString(v), true
+		}
+		return base64.StdEncoding.EncodeToString(v), true
+	case json.Marshaler:
+		return v, false
+	case fmt.Stringer:
+		return v.String(), true
+	case []interface{}:
+		var out []interface{}
+		for i, item := range v {
+			nv, changed := n.value(item)
+			if changed && out == nil {
+				out = append(make([]interface{}, 0, len(v)), v[:i]...)
+			}
+			if out != nil {
+				out = append(out, nv)
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[string]interface{}:
+		return n.fields(v)
+	case logrus.Fields:
+		return n.fields(v)
+	}
+	return v, false
+}
+
+// fields - the normalized map of interfaces and whether it differs
+func (n *normalizer) fields(v map[string]interface{}) (interface{}, bool) {
+	var out map[string]interface{}
+	for k, item := range v {
+		nv, changed := n.value(item)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(v))
+			for k, item := range v {
+				out[k] = item
+			}
+		}
+		out[k] = nv
+	}
+	if out == nil {
+		return v, false
+	}
+	return out, true
+}
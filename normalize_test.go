@@ -0,0 +1,79 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+type color int
+
+func (c color) String() string { return [...]string{"red", "green"}[c] }
+
+type raw struct{}
+
+func (raw) MarshalJSON() ([]byte, error) { return []byte(`"raw"`), nil }
+func (raw) String() string               { return "string" }
+
+func TestNormalize(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("CEST", 2*3600))
+	data := logrus.Fields{
+		"time":     at,
+		"ptr":      &at,
+		"err":      errors.New("boom"),
+		"bytes":    []byte{0xde, 0xad},
+		"color":    color(1),
+		"raw":      raw{},
+		"duration": 1500 * time.Millisecond,
+		"count":    3,
+		"list":     []interface{}{"a", errors.New("nested")},
+		"map":      map[string]interface{}{"color": color(0), "n": 1},
+	}
+	entry := &logrus.Entry{Data: data}
+
+	n := newNormalizer(Options{})
+	e := n.apply(entry)
+	equals(t, logrus.Fields{
+		"time":     "2024-05-06T05:08:09.123Z",
+		"ptr":      "2024-05-06T05:08:09.123Z",
+		"err":      "boom",
+		"bytes":    "3q0=",
+		"color":    "green",
+		"raw":      raw{},
+		"duration": 1500 * time.Millisecond,
+		"count":    3,
+		"list":     []interface{}{"a", "nested"},
+		"map":      map[string]interface{}{"color": "red", "n": 1},
+	}, e.Data)
+	// the entry of the caller is left alone
+	equals(t, at, data["time"])
+
+	n = newNormalizer(Options{NormalizeTime: TimeEpochMillis, NormalizeBytes: BytesHex})
+	e = n.apply(entry)
+	equals(t, at.UnixNano()/int64(time.Millisecond), e.Data["time"])
+	equals(t, "dead", e.Data["bytes"])
+
+	// nothing to normalize, nothing copied
+	plain := &logrus.Entry{Data: logrus.Fields{"n": 1, "s": "x"}}
+	equals(t, true, n.apply(plain) == plain)
+}
+
+func TestNormalizeValues(t *testing.T) {
+	var tee lockedBuffer
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Tee:             &tee,
+		NormalizeValues: true,
+		Exporter:        intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel, Data: logrus.Fields{"color": color(0)}}))
+	var line map[string]interface{}
+	ok(t, json.Unmarshal([]byte(strings.TrimSpace(tee.String())), &line))
+	equals(t, "red", line["color"])
+}
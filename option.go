@@ -0,0 +1,346 @@
+package datadog
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultBatchTimeout - interval batches are sent at by New
+	DefaultBatchTimeout = 5 * time.Second
+	// DefaultMaxRetry - attempts New makes at a batch, the first one included
+	DefaultMaxRetry = 3
+)
+
+// Option configures the hook created by New
+type Option func(*config)
+
+// config - what New creates the hook with, the arguments of NewHook
+type config struct {
+	host         string
+	batchTimeout time.Duration
+	maxRetry     int
+	minLevel     logrus.Level
+	formatter    logrus.Formatter
+	options      Options
+}
+
+// New - create hook sending to Datadog with apiKey, by default to
+// DatadogUSHost every DefaultBatchTimeout, attempting DefaultMaxRetry times,
+// entries at info level and above formatted by a logrus.JSONFormatter.
+// Options are applied in order, a later one overriding an earlier one.
+func New(apiKey string, opts ...Option) *Hook {
+	c := config{
+		host:         DatadogUSHost,
+		batchTimeout: DefaultBatchTimeout,
+		maxRetry:     DefaultMaxRetry,
+		minLevel:     logrus.InfoLevel,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.formatter == nil {
+		c.formatter = &logrus.JSONFormatter{}
+	}
+	return newHook(apiKey, c)
+}
+
+// WithHost - send to this intake, such as DatadogEUHost
+func WithHost(host string) Option {
+	return func(c *config) { c.host = host }
+}
+
+// WithBatchTimeout - send batches at this interval, raised to 5s if shorter
+// unless Options.FlushPolicy decides instead
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *config) { c.batchTimeout = d }
+}
+
+// WithMaxRetry - attempt a batch up to n times, the first one included,
+// before dropping it: 0 or 1 make a single attempt
+func WithMaxRetry(n int) Option {
+	return func(c *config) { c.maxRetry = n }
+}
+
+// WithLevel - ship entries at this level and above
+func WithLevel(level logrus.Level) Option {
+	return func(c *config) { c.minLevel = level }
+}
+
+// WithFormatter - format entries with f, sent as JSON when it is a JSON formatter
+func WithFormatter(f logrus.Formatter) Option {
+	return func(c *config) { c.formatter = f }
+}
+
+// WithOptions - replace every option of the Options struct by options, the
+// options given before it included
+func WithOptions(options Options) Option {
+	return func(c *config) { c.options = options }
+}
+
+// WithOption - change the Options struct, for the options without a With
+// function of their own
+func WithOption(update func(*Options)) Option {
+	return func(c *config) { update(&c.options) }
+}
+
+// WithSource - ddsource of the entries
+func WithSource(source string) Option {
+	return WithOption(func(o *Options) { o.Source = source })
+}
+
+// WithService - service of the entries
+func WithService(service string) Option {
+	return WithOption(func(o *Options) { o.Service = service })
+}
+
+// WithHostname - hostname of the entries
+func WithHostname(hostname string) Option {
+	return WithOption(func(o *Options) { o.Hostname = hostname })
+}
+
+// WithTags - add tags to every entry
+func WithTags(tags ...string) Option {
+	return WithOption(func(o *Options) { o.Tags = append(o.Tags[:len(o.Tags):len(o.Tags)], tags...) })
+}
+
+// WithBuildInfo - add the BuildInfoTags of the binary to the tags
+func WithBuildInfo() Option {
+	return WithOption(func(o *Options) { o.BuildInfo = true })
+}
+
+// WithFileAttributes - add to every entry the content of the files read on
+// startup by attribute name, read again every Options.FileAttributesInterval
+// if set
+func WithFileAttributes(files map[string]string) Option {
+	return WithOption(func(o *Options) {
+		attributes := make(map[string]string, len(o.FileAttributes)+len(files))
+		for name, path := range o.FileAttributes {
+			attributes[name] = path
+		}
+		for name, path := range files {
+			attributes[name] = path
+		}
+		o.FileAttributes = attributes
+	})
+}
+
+// WithAllowedSites - refuse to send entries outside of these Datadog sites,
+// such as "eu", and tag batches with the site they are sent to
+func WithAllowedSites(sites ...string) Option {
+	return WithOption(func(o *Options) {
+		o.AllowedSites = append(o.AllowedSites[:len(o.AllowedSites):len(o.AllowedSites)], sites...)
+	})
+}
+
+// WithProtocol - deliver batches with p
+func WithProtocol(p Protocol) Option {
+	return WithOption(func(o *Options) { o.Protocol = p })
+}
+
+// WithAgent - forward entries to the local Datadog Agent listening for logs
+// at addr, intake.DefaultAgentAddr if empty, instead of the intake, for hosts
+// without direct internet access
+func WithAgent(addr string) Option {
+	return WithOption(func(o *Options) { o.Protocol, o.AgentAddr = ProtocolAgent, addr })
+}
+
+// WithCompression - send batches compressed with gzip
+func WithCompression() Option {
+	return WithOption(func(o *Options) { o.Compress = true })
+}
+
+// WithCompressionThreshold - send batches smaller than n bytes uncompressed
+// when compressing
+func WithCompressionThreshold(n int) Option {
+	return WithOption(func(o *Options) { o.CompressionThreshold = n })
+}
+
+// WithHTTPClient - post to the intake with client
+func WithHTTPClient(client *http.Client) Option {
+	return WithOption(func(o *Options) { o.HTTPClient = client })
+}
+
+// WithRequestTimeout - give up an attempt to post a batch after d
+func WithRequestTimeout(d time.Duration) Option {
+	return WithOption(func(o *Options) { o.RequestTimeout = d })
+}
+
+// WithProxy - reach the intake through the proxy at this URL
+func WithProxy(proxy string) Option {
+	return WithOption(func(o *Options) { o.Proxy = proxy })
+}
+
+// WithTLSConfig - connect to the intake with the TLS settings of config
+func WithTLSConfig(config *tls.Config) Option {
+	return WithOption(func(o *Options) { o.TLSConfig = config })
+}
+
+// WithMaxEntryBytes - truncate entries above n bytes, for relays with a
+// smaller limit than the Datadog intake
+func WithMaxEntryBytes(n int) Option {
+	return WithOption(func(o *Options) { o.MaxEntryBytes = n })
+}
+
+// WithOversize - do o with entries above the entry limit, telling fn about
+// the ones dropped if not nil
+func WithOversize(o Oversize, fn func(entry *logrus.Entry, size int)) Option {
+	return WithOption(func(opts *Options) { opts.Oversize, opts.OnOversize = o, fn })
+}
+
+// WithQueue - queue up to size entries by lane and maxBytes in all while
+// the batcher is busy, before Fire waits
+func WithQueue(size int, maxBytes int64) Option {
+	return WithOption(func(o *Options) { o.QueueSize, o.QueueBytes = size, maxBytes })
+}
+
+// WithOverflow - what Fire does when the queue is full
+func WithOverflow(o Overflow) Option {
+	return WithOption(func(opts *Options) { opts.Overflow = o })
+}
+
+// WithSampling - ship only this share of the entries, those sharing the
+// value of field together if not empty, picked at random otherwise
+func WithSampling(rate float64, field string) Option {
+	return WithOption(func(o *Options) { o.Sampling, o.SampleBy = rate, field })
+}
+
+// WithLevelSampling - ship only this share of the entries of level,
+// whatever the sampling of other levels, none of them if rate is 0
+func WithLevelSampling(level logrus.Level, rate float64) Option {
+	return WithOption(func(o *Options) {
+		rates := make(map[logrus.Level]float64, len(o.LevelSampling)+1)
+		for l, r := range o.LevelSampling {
+			rates[l] = r
+		}
+		rates[level] = rate
+		o.LevelSampling = rates
+	})
+}
+
+// WithLevelQuota - ship at most entries entries and bytes bytes of level
+// per Options.LevelQuotaWindow, either unlimited if 0
+func WithLevelQuota(level logrus.Level, entries int, bytes int64) Option {
+	return WithOption(func(o *Options) {
+		quotas := make(map[logrus.Level]LevelQuota, len(o.LevelQuotas)+1)
+		for l, q := range o.LevelQuotas {
+			quotas[l] = q
+		}
+		quotas[level] = LevelQuota{Entries: entries, Bytes: bytes}
+		o.LevelQuotas = quotas
+	})
+}
+
+// WithRateLimit - send at most requests batches and bytes payload bytes per
+// second, either unlimited if 0, deliveries waiting for their turn
+func WithRateLimit(requests float64, bytes int64) Option {
+	return WithOption(func(o *Options) {
+		o.MaxRequestsPerSecond = requests
+		o.MaxBytesPerSecond = bytes
+	})
+}
+
+// WithCircuitBreaker - stop attempting deliveries for cooldown after
+// failures consecutive failed attempts, spooling or queuing the batches
+// meanwhile, then probe the intake with a single attempt
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return WithOption(func(o *Options) {
+		o.CircuitFailures = failures
+		o.CircuitCooldown = cooldown
+	})
+}
+
+// WithTraceCorrelation - add the dd.trace_id and dd.span_id of the span the
+// context of an entry carries, as told by fn
+func WithTraceCorrelation(fn SpanFunc) Option {
+	return WithOption(func(o *Options) { o.SpanFromContext = fn })
+}
+
+// WithErrorChains - send the errors of entries as the error object of
+// Datadog, causes included
+func WithErrorChains() Option {
+	return WithOption(func(o *Options) { o.ErrorChains = true })
+}
+
+// WithSystemSnapshot - add a snapshot of the system to the batches holding
+// errors
+func WithSystemSnapshot() Option {
+	return WithOption(func(o *Options) { o.SystemSnapshot = true })
+}
+
+// WithPipeline - run the custom stages of p
+func WithPipeline(p Pipeline) Option {
+	return WithOption(func(o *Options) { o.Pipeline = p })
+}
+
+// WithSourceResolver - derive the ddsource of every entry with r
+func WithSourceResolver(r Resolver) Option {
+	return WithOption(func(o *Options) { o.SourceResolver = r })
+}
+
+// WithServiceResolver - derive the service of every entry with r
+func WithServiceResolver(r Resolver) Option {
+	return WithOption(func(o *Options) { o.ServiceResolver = r })
+}
+
+// WithHostnameResolver - derive the hostname of every entry with r
+func WithHostnameResolver(r Resolver) Option {
+	return WithOption(func(o *Options) { o.HostnameResolver = r })
+}
+
+// WithTagsField - read the Datadog tags of an entry from the field name
+// instead of TagsField
+func WithTagsField(name string) Option {
+	return WithOption(func(o *Options) { o.TagsField = name })
+}
+
+// WithShards - append entries to n buffers swept by the batcher instead of
+// the queue, for services sensitive to the latency of Fire
+func WithShards(n int) Option {
+	return WithOption(func(o *Options) { o.Shards = n })
+}
+
+// WithErrorRateAlarm - call fn once the share of failed delivery attempts
+// over window reaches threshold, and once again when it recovers
+func WithErrorRateAlarm(threshold float64, window time.Duration, fn func(Alarm)) Option {
+	return WithOption(func(o *Options) {
+		o.ErrorRateAlarm, o.ErrorRateThreshold, o.ErrorRateWindow = fn, threshold, window
+	})
+}
+
+// WithSpool - spill the batches dropped during an outage to dir, replayed
+// once the intake delivers again
+func WithSpool(dir string, maxBytes int64) Option {
+	return WithOption(func(o *Options) { o.SpoolDir, o.SpoolMaxBytes = dir, maxBytes })
+}
+
+// WithSpoolLimits - keep at most maxFiles spool files, none older than
+// maxAge, either unlimited if 0
+func WithSpoolLimits(maxFiles int, maxAge time.Duration) Option {
+	return WithOption(func(o *Options) { o.SpoolMaxFiles, o.SpoolMaxAge = maxFiles, maxAge })
+}
+
+// WithRetryPolicy - decide whether failed batches are tried again, in
+// place of the retry count
+func WithRetryPolicy(p RetryPolicy) Option {
+	return WithOption(func(o *Options) { o.RetryPolicy = p })
+}
+
+// WithExporter - deliver batches to e instead of Datadog
+func WithExporter(e Exporter) Option {
+	return WithOption(func(o *Options) { o.Exporter = e })
+}
+
+// WithErrorHandler - give fn the last error and the entries of every batch
+// dropped after retries
+func WithErrorHandler(fn func(err error, batch [][]byte)) Option {
+	return WithOption(func(o *Options) { o.ErrorHandler = fn })
+}
+
+// WithOnError - report the errors Fire cannot return to fn
+func WithOnError(fn func(error)) Option {
+	return WithOption(func(o *Options) { o.OnError = fn })
+}
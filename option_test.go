@@ -0,0 +1,175 @@
+package datadog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNew(t *testing.T) {
+	hook := New("key", WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })))
+	defer hook.Close(context.Background())
+	equals(t, logrus.InfoLevel, hook.Level())
+	equals(t, true, hook.json)
+	equals(t, DefaultMaxRetry, hook.Settings().MaxRetry)
+	equals(t, true, strings.Contains(hook.String(), DatadogUSHost))
+
+	tags := []string{"env:prod"}
+	hook = New("key",
+		WithHost(DatadogEUHost),
+		WithMaxRetry(1),
+		WithLevel(logrus.DebugLevel),
+		WithFormatter(&logrus.TextFormatter{}),
+		WithOptions(Options{Source: "go", Tags: tags}),
+		WithService("api"),
+		WithTags("team:a"),
+		WithCompression(),
+		WithCompressionThreshold(10),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })),
+	)
+	defer hook.Close(context.Background())
+	equals(t, logrus.DebugLevel, hook.Level())
+	equals(t, false, hook.json)
+	equals(t, Settings{MaxRetry: 1, Compress: true, CompressionThreshold: 10}, hook.Settings())
+	equals(t, true, strings.Contains(hook.String(), DatadogEUHost))
+	equals(t, intake.Stream{Source: "go", Service: "api", Tags: []string{"env:prod", "team:a"}}, hook.options.Stream())
+	// the tags given are not changed
+	equals(t, []string{"env:prod"}, tags)
+
+	// the options given after WithOptions are kept, the ones before are not
+	hook = New("key", WithService("api"), WithOptions(Options{Source: "go"}), WithHostname("web-1"))
+	defer hook.Close(context.Background())
+	equals(t, intake.Stream{Source: "go", Hostname: "web-1"}, hook.options.Stream())
+}
+
+func TestWithAllowedSites(t *testing.T) {
+	hook := New("key", WithHost(DatadogUSHost), WithAllowedSites("eu"))
+	defer hook.Close(context.Background())
+	err := hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}})
+	assert(t, errors.Is(err, intake.ErrSiteNotAllowed), "us should be refused: %v", err)
+
+	var c config
+	WithAllowedSites("eu")(&c)
+	WithAllowedSites("us3", "us5")(&c)
+	equals(t, []string{"eu", "us3", "us5"}, c.options.AllowedSites)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	var counts []int
+	hook := New("key",
+		WithRetryPolicy(func(a Attempts, err error) bool {
+			counts = append(counts, a.Count)
+			return a.Count < 2
+		}),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return errors.New("down") })),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	equals(t, true, errors.Is(hook.Close(context.Background()), intake.ErrNotDelivered))
+	equals(t, []int{1, 2}, counts)
+}
+
+func TestWithSpool(t *testing.T) {
+	hook := New("key",
+		WithMaxRetry(1),
+		WithSpool(t.TempDir(), 0),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return errors.New("down") })),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	// spooled rather than dropped
+	ok(t, hook.Close(context.Background()))
+	equals(t, int64(1), hook.Stats().Client.Spooled)
+}
+
+func TestWithTagsField(t *testing.T) {
+	var batches []*intake.Batch
+	hook := New("key",
+		WithTags("env:prod"),
+		WithTagsField("tags"),
+		WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			batches = append(batches, b)
+			return nil
+		})),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{"tags": "team:payments,tier:db"}}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, 1, len(batches))
+	equals(t, []string{"env:prod", "team:payments", "tier:db"}, batches[0].Stream.Tags)
+	equals(t, false, strings.Contains(string(batches[0].Lines[0]), "payments"))
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	hook := New("key",
+		WithMaxRetry(3),
+		WithCircuitBreaker(2, time.Hour),
+		WithSpool(t.TempDir(), 0),
+		WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return errors.New("down") })),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	// spooled rather than dropped
+	ok(t, hook.Close(context.Background()))
+	equals(t, int64(2), hook.Stats().Client.Attempts)
+	equals(t, int64(1), hook.Stats().Client.CircuitOpens)
+	equals(t, int64(1), hook.Stats().Client.Spooled)
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	hook := New("key", WithHost(srv.Listener.Addr().String()), WithTLSConfig(&tls.Config{RootCAs: roots}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, "/api/v2/logs", <-received)
+}
+
+func TestWithAgent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	defer l.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	hook := New("key", WithAgent(l.Addr().String()))
+	equals(t, intake.PathAgent, hook.Stats().Client.Path)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: "disk full", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, true, strings.Contains(<-lines, `"msg":"disk full"`))
+
+	c := config{}
+	WithAgent("")(&c)
+	equals(t, ProtocolAgent, c.options.Protocol)
+	equals(t, "", c.options.AgentAddr)
+}
+
+func TestWithSpoolLimits(t *testing.T) {
+	c := config{}
+	WithSpoolLimits(10, 18*time.Hour)(&c)
+	equals(t, 10, c.options.SpoolMaxFiles)
+	equals(t, 18*time.Hour, c.options.SpoolMaxAge)
+}
@@ -0,0 +1,413 @@
+package datadog
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// Options define the options for Datadog log stream
+type Options struct {
+	Source   string
+	Service  string
+	Hostname string
+	Tags     []string
+	// BuildInfo - add the BuildInfoTags of the binary to Tags, so every
+	// entry is attributable to an exact build
+	BuildInfo bool
+	// Locality - add the LocalityTags of the host to Tags, so the bytes
+	// shipped are counted by availability zone in Stats. An answer of the
+	// metadata service is waited for in the background.
+	Locality bool
+	// TagsField - entry field holding Datadog tags of the entry, TagsField
+	// if empty
+	TagsField string
+
+	// BatchJitter - random extra delay up to this duration added to every
+	// batch interval, de-correlating flushes of replicas started together
+	BatchJitter time.Duration
+	// OnDrainProgress - called while Close delivers the remaining backlog
+	OnDrainProgress func(DrainProgress)
+	// HMACKey - sign every batch with HMAC-SHA256, so a relay can tell
+	// batches were not tampered with before forwarding them
+	HMACKey []byte
+	// HMACHeader - header carrying the signature, intake.SignatureHeader if empty
+	HMACHeader string
+	// OnAudit - called once for every batch delivered or dropped
+	OnAudit func(AuditRecord)
+	// FlushPolicy - decide when batches are sent, every batchTimeout if nil
+	FlushPolicy FlushPolicy
+	// AlignBatches - cut batches on wall-clock boundaries every
+	// AlignBatches, e.g. every 10s aligned, on top of FlushPolicy when set
+	AlignBatches time.Duration
+	// Compress - send batches compressed with gzip
+	Compress bool
+	// CompressionThreshold - batches smaller than this many bytes are sent
+	// uncompressed, as compressing them wastes CPU and can grow them
+	CompressionThreshold int
+	// Dictionary - compress with this preset dictionary instead of gzip, for
+	// a relay holding the same dictionary, refused toward Datadog
+	Dictionary *Dictionary
+	// Trainer - collect the payloads shipped to build a Dictionary from them
+	Trainer *Trainer
+	// Clock - source of time of batching, the wall clock if nil
+	Clock Clock
+	// Seed - seed of the random jitter and sampling so load tests can be
+	// reproduced, random if 0
+	Seed int64
+	// OnError - called with errors Fire cannot return, such as panics
+	// recovered from callbacks and batches dropped after retries
+	OnError func(error)
+	// OnErrorContext - like OnError, given Hook.Context so slow callbacks
+	// can stop when Close gives up, called after OnError
+	OnErrorContext func(context.Context, error)
+	// OnAuditContext - like OnAudit, given Hook.Context
+	OnAuditContext func(context.Context, AuditRecord)
+	// BaseContext - parent of Hook.Context, canceling it cancels the
+	// context of the callbacks
+	BaseContext context.Context
+	// Sampling - share of the entries shipped, picked at random, every entry if 0
+	Sampling float64
+	// SampleBy - field whose value picks the sampled entries instead of
+	// chance, so entries sharing it, a request ID say, are kept or skipped
+	// together. Entries without it are picked at random.
+	SampleBy string
+	// LevelSampling - share of the entries of a level shipped, instead of
+	// Sampling, none of the level if 0. Levels left out follow Sampling.
+	LevelSampling map[logrus.Level]float64
+	// SpanFromContext - add the TraceIDField and SpanIDField of the span the
+	// context of an entry carries, so its logs correlate with APM traces
+	SpanFromContext SpanFunc
+	// Fingerprint - add the message_template and fingerprint fields to every
+	// entry, grouping messages which only differ by numbers or identifiers
+	Fingerprint bool
+	// SelfReport - ship a summary of the errors given to OnError at most
+	// this often, through a client of its own under SelfReportService, so
+	// they are visible in Datadog while delivery partially works
+	SelfReport time.Duration
+	// SelfReportService - service of the summaries, DefaultSelfReportService
+	// if empty
+	SelfReportService string
+	// Heartbeat - ship an info entry carrying the stats of the hook at this
+	// interval, and once when the hook is created, so its absence can be
+	// alerted on in Datadog
+	Heartbeat time.Duration
+	// SystemSnapshot - add to every batch holding errors an info entry
+	// SnapshotMessage carrying the load average, memory and goroutines of
+	// the host and process under SnapshotField, machine context for the
+	// first responders
+	SystemSnapshot bool
+	// LevelQuotas - entries and bytes each level ships per
+	// LevelQuotaWindow at most, the ones over summarized in a single
+	// QuotaMessage entry once the window is over
+	LevelQuotas map[logrus.Level]LevelQuota
+	// LevelQuotaWindow - DefaultLevelQuotaWindow if 0
+	LevelQuotaWindow time.Duration
+	// SummarizeDebug - debug and trace entries are not shipped one by one
+	// but counted by message template, and a summary entry is shipped at this interval
+	SummarizeDebug time.Duration
+	// Pipeline - custom stages run at defined points of the way entries
+	// take to the intake
+	Pipeline Pipeline
+	// VerifyFormat - check the formatter output, reporting empty output,
+	// duplicate keys and output which isn't JSON despite being sent as JSON
+	// to OnError with a sample. Lines which aren't JSON are not shipped but
+	// given to ErrorHandler, unlimited, with a *FormatError.
+	VerifyFormat bool
+	// QuickVerify - verify like VerifyFormat, checking only that JSON lines
+	// are objects with matching brackets and closed strings, several times
+	// cheaper than parsing them but blind to invalid values and duplicate keys
+	QuickVerify bool
+	// FireBudget - time formatting and queuing an entry may take in Fire
+	// before it is counted in Stats.SlowFires and, with debug on, warned
+	// about; DefaultFireBudget if 0, never counted if negative
+	FireBudget time.Duration
+	// Strict - Fire waits for the batch holding the entry to be delivered and
+	// returns its error, never longer than the deadline of entry.Context
+	Strict bool
+	// BreakdownTags - tag keys, such as "team", whose values break down the
+	// usage reported by Hook.Breakdown next to services and sources
+	BreakdownTags []string
+	// BreakdownLimit - distinct values counted by dimension of the breakdown,
+	// 100 if 0
+	BreakdownLimit int
+	// DNSFallback - connect to the last IP the intake was reached at while
+	// its name cannot be resolved
+	DNSFallback bool
+	// AdaptiveSampling - halve the sampling of the entries less severe than
+	// warnings every AdaptiveInterval the intake answers 429 to at least
+	// AdaptiveThreshold of the attempts, down to AdaptiveMinRate, and double
+	// it back every interval without 429
+	AdaptiveSampling bool
+	// AdaptiveInterval - interval the rate is adjusted at, 10s if 0
+	AdaptiveInterval time.Duration
+	// AdaptiveMinRate - lowest rate, 0.01 if 0
+	AdaptiveMinRate float64
+	// AdaptiveThreshold - share of throttled attempts lowering the rate, 0.1 if 0
+	AdaptiveThreshold float64
+	// ErrorRateAlarm - called once when at least ErrorRateThreshold of the
+	// delivery attempts failed over the last ErrorRateWindow, and once again
+	// when the rate goes back below, for programs without metrics to tell
+	// log shipping broke or was restored
+	ErrorRateAlarm func(Alarm)
+	// ErrorRateThreshold - share of failed attempts raising the alarm, 0.5
+	// if 0
+	ErrorRateThreshold float64
+	// ErrorRateWindow - window the rate is measured over, sliding by a tenth
+	// of it, 1m if 0
+	ErrorRateWindow time.Duration
+	// FileAttributes - attributes added to every entry from the content of
+	// files read on startup, by name, e.g. {"machine_id": "/etc/machine-id"}.
+	// Files of key="value" lines, like Downward API pod labels, are added
+	// as a map.
+	FileAttributes map[string]string
+	// FileAttributesInterval - read the files again at this interval to
+	// follow updates, never if 0
+	FileAttributesInterval time.Duration
+	// DryRun - get batches as they would be sent instead of sending them
+	DryRun func(Preview)
+	// Sequence - number batches from 1 in the intake.SequenceHeader header
+	// and AuditRecord.Sequence, for downstream reconciliation to detect gaps
+	// and duplicates
+	Sequence bool
+	// SequenceFile - file the sequence resumes from after a restart, turns
+	// Sequence on
+	SequenceFile string
+	// QueueSize - entries queued by lane while the batcher is busy before
+	// Fire waits, 1 if 0, to absorb bursts at the cost of memory
+	QueueSize int
+	// Shards - buffers Fire appends to instead of the queue, a random one
+	// each, swept by the batcher every millisecond, so goroutines firing
+	// seldom wait on one another. Off if 0, see intake.Config.Shards.
+	Shards int
+	// Overflow - what Fire does when the queue is full: OverflowBlock waits
+	// for room, the default, OverflowDropNewest drops the entry fired and
+	// OverflowDropOldest the oldest one queued. Drops are counted in
+	// Stats().Client.Overflowed.
+	Overflow Overflow
+	// QueueBytes - bytes the queued entries may take before Fire waits,
+	// unbounded if 0
+	QueueBytes int64
+	// RetryPolicy - whether a failed batch is tried again given its
+	// Attempts, including the ones made before a restart for the lines
+	// replayed from the fallback, maxRetry decides if nil
+	RetryPolicy RetryPolicy
+	// RetryAfter - wait before retrying an attempt throttled without a
+	// Retry-After header, intake.DefaultRetryAfter if 0
+	RetryAfter time.Duration
+	// CircuitFailures, CircuitCooldown - after this many consecutive
+	// failed attempts no attempt is made for the cooldown, batches being
+	// spooled or held in the queue, see WithCircuitBreaker
+	CircuitFailures int
+	CircuitCooldown time.Duration
+	// Destinations - backends entries are fanned out to besides Host, each
+	// with a queue, retries and circuit breaker of its own
+	Destinations []Destination
+	// MaxRequestsPerSecond, MaxBytesPerSecond - deliveries attempted and
+	// payload bytes sent per second at most, unlimited if 0
+	MaxRequestsPerSecond float64
+	MaxBytesPerSecond    int64
+	// LaneWeights - entries batched from a lane in turn while the others
+	// hold entries too, intake.DefaultLaneWeights for the lanes missing
+	LaneWeights map[Lane]int
+	// AllowedSites - Datadog sites entries may be sent to, such as "eu". A
+	// host outside of them, the Agent protocol and detection are refused,
+	// and batches are tagged with the site in intake.SiteTag.
+	AllowedSites []string
+	// HTTPClient - client posting to the intake, for timeouts, proxies,
+	// custom CAs, instrumented transports or a mock intake, a client of the
+	// hook alone with a 30s timeout if nil
+	HTTPClient *http.Client
+	// RequestTimeout - longest an attempt to post a batch may take, 30s if
+	// 0, none if negative
+	RequestTimeout time.Duration
+	// Proxy - URL of the proxy to reach the intake through, in place of
+	// HTTPS_PROXY, hosts in NO_PROXY excepted, the environment if empty
+	Proxy string
+	// TLSConfig - TLS settings of the connections to the intake, such as
+	// the root CAs of a TLS-inspecting gateway, client certificates or the
+	// minimum version, ignored with HTTPClient
+	TLSConfig *tls.Config
+	// MaxEntryBytes - size above which entries are truncated, for relays
+	// accepting less than the 256kB of the Datadog intake, the default.
+	// Above it is refused when sending to the intake directly.
+	MaxEntryBytes int
+	// Oversize - what is done with entries above MaxEntryBytes:
+	// OversizeTruncate cuts the message, the default, OversizeDrop drops the
+	// entry and OversizeSplit ships its message in several entries. JSON
+	// entries which cannot fit are dropped whatever the policy, so the
+	// intake never rejects a batch for them.
+	Oversize Oversize
+	// OnOversize - called with the entries dropped for their size and how
+	// many bytes they were formatted in
+	OnOversize func(entry *logrus.Entry, size int)
+	// Protocol - how batches are delivered, ProtocolV2HTTP if zero
+	Protocol Protocol
+	// DetectAgent - forward entries to a local Datadog Agent when one listens
+	// at AgentAddr on startup, go straight to the intake otherwise. The path
+	// chosen is reported in Stats().Client.Path.
+	DetectAgent bool
+	// AgentAddr - address of the log listener of the Agent,
+	// intake.DefaultAgentAddr if empty
+	AgentAddr string
+	// Exporter - backend batches are delivered to, Datadog at host if nil
+	Exporter Exporter
+
+	// Tee - every entry shipped is also written there, e.g. a local file
+	Tee io.Writer
+	// Fallback - entries of batches dropped after retries are written there
+	Fallback io.Writer
+	// SpoolDir - directory batches dropped after retries are spilled to and
+	// replayed from once the intake delivers again, and on start. The ones
+	// not fitting in SpoolMaxBytes go to Fallback.
+	SpoolDir string
+	// SpoolMaxBytes - size the spool may take, 100MB if 0
+	SpoolMaxBytes int64
+	// SpoolMaxFiles - spool files kept at most, the oldest removed
+	// unreplayed beyond, unlimited if 0
+	SpoolMaxFiles int
+	// SpoolMaxAge - age beyond which spool files are removed unreplayed,
+	// such as the 18h past which Datadog refuses entries, unlimited if 0
+	SpoolMaxAge time.Duration
+	// ErrorHandler - called with the last error and the formatted entries of
+	// every batch dropped after retries, which it may keep
+	ErrorHandler func(err error, batch [][]byte)
+	// ErrorChains - send the error of logrus.ErrorKey as the error object
+	// of Datadog: its message, the type of its root cause as kind and the
+	// errors it wraps as causes, so Error Tracking groups wrapped errors
+	ErrorChains bool
+	// NormalizeValues - encode field values for Datadog facets before
+	// formatting: times in NormalizeTime, errors as their message, byte
+	// slices in NormalizeBytes and fmt.Stringers as their string
+	NormalizeValues bool
+	// NormalizeTime - TimeISO8601 if empty, or TimeEpochMillis
+	NormalizeTime string
+	// NormalizeBytes - BytesBase64 if empty, or BytesHex
+	NormalizeBytes string
+	// FastFormat - format with an equivalent FastFormatter when the formatter
+	// is a logrus.JSONFormatter, unless it pretty prints
+	FastFormat bool
+	// FormatCache - reuse the line last formatted when the same entry is
+	// fired again, same level, message, caller, time and field values, for
+	// tight loops logging one entry over and over. Only entries with basic
+	// field values, strings, numbers and bools, are cached.
+	FormatCache bool
+	// FormatCachePrecision - precision entry times are compared at, such as
+	// time.Second for formatters printing seconds, exactly if 0. The line
+	// reused carries the time of the first entry.
+	FormatCachePrecision time.Duration
+	// LocalFormatter - formatter of the entries written to Tee and Fallback,
+	// such as a human-readable logrus.TextFormatter while Datadog gets JSON.
+	// The hook's formatter is used if nil.
+	LocalFormatter logrus.Formatter
+
+	// SourceField - entry field naming the framework which produced the
+	// entry, used to pick the ddsource Datadog pipelines expect for it
+	SourceField string
+	// Sources - ddsource by framework name, on top of the built-in ones
+	Sources map[string]string
+	// SourceResolver, ServiceResolver, HostnameResolver - derive the
+	// ddsource, service and hostname of every entry, over Source with
+	// SourceField, Service and Hostname. Routes and entry tags still apply.
+	SourceResolver   Resolver
+	ServiceResolver  Resolver
+	HostnameResolver Resolver
+}
+
+// DrainProgress reports how far Close got delivering the backlog
+type DrainProgress = intake.DrainProgress
+
+// AuditRecord describe a batch delivered or dropped
+type AuditRecord = intake.AuditRecord
+
+// FlushPolicy decide when a batch is sent, see the intake package for built-ins
+type FlushPolicy = intake.FlushPolicy
+
+// Breakdown is the usage delivered by service, source and tag
+type Breakdown = intake.Breakdown
+
+// Clock is the source of time of a hook, replaced to run load tests and
+// simulations on a virtual time
+type Clock = intake.Clock
+
+// Preview is a batch as it would be sent, given to Options.DryRun
+type Preview = intake.Preview
+
+// Lane is the queue entries wait in by severity, errors, info or debug
+type Lane = intake.Lane
+
+// Protocol is the way batches are delivered to Datadog
+type Protocol = intake.Protocol
+
+// Dictionary is a preset compression dictionary, see intake.NewDictionary
+type Dictionary = intake.Dictionary
+
+// Trainer builds a Dictionary from the payloads shipped, see intake.NewTrainer
+type Trainer = intake.Trainer
+
+// Settings is the part of the delivery config which may change at runtime
+type Settings = intake.Settings
+
+// Quota is the rate limit reported by the intake, see Stats().Client.Quota
+type Quota = intake.Quota
+
+// Overflow is what Fire does when the queue is full, see Options.Overflow
+type Overflow = intake.Overflow
+
+// Attempts is the delivery history of a batch, see Options.RetryPolicy
+type Attempts = intake.Attempts
+
+// RetryPolicy decides whether a failed batch is tried again
+type RetryPolicy = intake.RetryPolicy
+
+// Exporter deliver batches to a log backend, see the exporter subpackages
+type Exporter = intake.Exporter
+
+// Stream - the stream entries land in unless overridden per entry
+func (o Options) Stream() Stream {
+	return Stream{Source: o.Source, Service: o.Service, Hostname: o.Hostname, Tags: o.Tags}
+}
+
+// tagsField - the entry field holding Datadog tags of the entry
+func (o Options) tagsField() string {
+	if o.TagsField == "" {
+		return TagsField
+	}
+	return o.TagsField
+}
+
+// withBuildInfo - the options with the build info tags when BuildInfo is set
+func (o Options) withBuildInfo() Options {
+	if o.BuildInfo {
+		o.Tags = append(append([]string(nil), o.Tags...), BuildInfoTags()...)
+	}
+	return o
+}
+
+// DatadogURL - URL a hook created with options posts batches to at host with
+// the protocol, so relays and tests can construct identical URLs. It does no
+// I/O, so the tags of Locality are left out: add LocalityTags to Tags for
+// them.
+func DatadogURL(options Options, host string, protocol Protocol) (string, error) {
+	stream := options.withBuildInfo().Stream()
+	if len(options.AllowedSites) > 0 {
+		stream.Tags = append(append([]string(nil), stream.Tags...), intake.SiteTag+":"+intake.Site(host))
+	}
+	return intake.URL(host, protocol, stream)
+}
+
+// flushPolicy - the flush policy with the aligned boundaries if any
+func (o Options) flushPolicy() FlushPolicy {
+	if o.AlignBatches <= 0 {
+		return o.FlushPolicy
+	}
+	if o.FlushPolicy == nil {
+		return intake.Aligned(o.AlignBatches)
+	}
+	return intake.Any(o.FlushPolicy, intake.Aligned(o.AlignBatches))
+}
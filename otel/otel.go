@@ -0,0 +1,183 @@
+// Package otel bridges the OpenTelemetry logs SDK to the intake client, so
+// services instrumented with OpenTelemetry ship their logs through the same
+// batching and delivery as the logrus hook, without an OTLP collector.
+//
+//	client := intake.New(intake.Config{Host: intake.DatadogUSHost, APIKey: apiKey, JSON: true})
+//	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(otel.New(client))))
+package otel
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// resource attributes naming the Datadog service and host of the records
+const (
+	serviceNameKey = attribute.Key("service.name")
+	hostNameKey    = attribute.Key("host.name")
+)
+
+// Exporter is the sdklog.Exporter pushing records to an intake client, which
+// must be created with Config.JSON set
+type Exporter struct {
+	client   *intake.Client
+	shutdown int32
+}
+
+var _ sdklog.Exporter = (*Exporter)(nil)
+
+// New - create exporter pushing records to client
+func New(client *intake.Client) *Exporter {
+	return &Exporter{client: client}
+}
+
+// Export - queue the records as JSON lines with Datadog attributes, the
+// service and host come from the resource of each record
+func (e *Exporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if atomic.LoadInt32(&e.shutdown) == 1 {
+		return sdklog.ErrExporterShutdown
+	}
+	var errs []error
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r := &records[i]
+		line, err := json.Marshal(fields(r))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := e.client.PushEntry(intake.Entry{Stream: e.stream(r), Line: line, Severity: Severity(r.Severity())}); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown - close the client, delivering the queued records before ctx is done
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&e.shutdown, 0, 1) {
+		return nil
+	}
+	return e.client.Close(ctx)
+}
+
+// ForceFlush - deliver the records exported so far before ctx is done
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	return e.client.Flush(ctx)
+}
+
+// Severity - the intake severity of an OpenTelemetry severity
+func Severity(s log.Severity) intake.Severity {
+	switch {
+	case s <= log.SeverityUndefined:
+		return intake.SeverityUnknown
+	case s <= log.SeverityTrace4:
+		return intake.SeverityTrace
+	case s <= log.SeverityDebug4:
+		return intake.SeverityDebug
+	case s <= log.SeverityInfo4:
+		return intake.SeverityInfo
+	case s <= log.SeverityWarn4:
+		return intake.SeverityWarn
+	case s <= log.SeverityError4:
+		return intake.SeverityError
+	default:
+		return intake.SeverityFatal
+	}
+}
+
+// statuses - Datadog status of every intake severity
+var statuses = map[intake.Severity]string{
+	intake.SeverityTrace: "trace",
+	intake.SeverityDebug: "debug",
+	intake.SeverityInfo:  "info",
+	intake.SeverityWarn:  "warning",
+	intake.SeverityError: "error",
+	intake.SeverityFatal: "critical",
+}
+
+// fields - the JSON object of a record, attributes are kept as they are
+// next to the Datadog reserved attributes
+func fields(r *sdklog.Record) map[string]interface{} {
+	f := make(map[string]interface{}, r.AttributesLen()+6)
+	r.WalkAttributes(func(kv attribute.KeyValue) bool {
+		f[string(kv.Key)] = value(kv.Value)
+		return true
+	})
+	ts := r.Timestamp()
+	if ts.IsZero() {
+		ts = r.ObservedTimestamp()
+	}
+	f["timestamp"] = ts.Format(time.RFC3339Nano)
+	body := r.Body()
+	if body.Type() == attribute.STRING {
+		f["message"] = body.AsString()
+	} else if body.Type() != attribute.EMPTY {
+		f["message"] = body.String()
+	}
+	if status, ok := statuses[Severity(r.Severity())]; ok {
+		f["status"] = status
+	}
+	if text := r.SeverityText(); text != "" {
+		f["otel.severity_text"] = text
+	}
+	if tid := r.TraceID(); tid.IsValid() {
+		f["otel.trace_id"] = hex.EncodeToString(tid[:])
+		f["dd.trace_id"] = strconv.FormatUint(TraceID(tid), 10)
+	}
+	if sid := r.SpanID(); sid.IsValid() {
+		f["otel.span_id"] = hex.EncodeToString(sid[:])
+		f["dd.span_id"] = strconv.FormatUint(SpanID(sid), 10)
+	}
+	return f
+}
+
+// value - the Go value of an attribute encoded as plain JSON
+func value(v attribute.Value) interface{} {
+	switch v.Type() {
+	case attribute.MAP:
+		m := map[string]interface{}{}
+		for _, kv := range v.AsMap() {
+			m[string(kv.Key)] = value(kv.Value)
+		}
+		return m
+	case attribute.SLICE:
+		var l []interface{}
+		for _, e := range v.AsSlice() {
+			l = append(l, value(e))
+		}
+		return l
+	default:
+		return v.AsInterface()
+	}
+}
+
+// stream - the default stream of the client with the service and host of
+// the resource of the record
+func (e *Exporter) stream(r *sdklog.Record) intake.Stream {
+	s := intake.Stream{}
+	res := r.Resource()
+	if res == nil {
+		return e.client.Stream()
+	}
+	set := res.Set()
+	if v, ok := set.Value(serviceNameKey); ok {
+		s.Service = v.AsString()
+	}
+	if v, ok := set.Value(hostNameKey); ok {
+		s.Hostname = v.AsString()
+	}
+	return e.client.Stream().Merge(s)
+}
@@ -0,0 +1,125 @@
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// equals fails the test if exp is not equal to act.
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		log.Printf("%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func TestExporter(t *testing.T) {
+	var m sync.Mutex
+	var batches []*intake.Batch
+	var lines []string
+	client := intake.New(intake.Config{
+		JSON:   true,
+		Stream: intake.Stream{Source: "go", Service: "default"},
+		Exporter: intake.ExporterFunc(func(b *intake.Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			batches = append(batches, &intake.Batch{Stream: b.Stream})
+			for _, line := range b.Lines {
+				lines = append(lines, string(line))
+			}
+			return nil
+		}),
+	})
+	res := resource.NewSchemaless(attribute.String("service.name", "checkout"), attribute.String("host.name", "web-1"))
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(New(client))),
+	)
+
+	var record otellog.Record
+	record.SetTimestamp(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	record.SetSeverity(otellog.SeverityWarn)
+	record.SetSeverityText("WARN")
+	record.SetBody(attribute.StringValue("payment slow"))
+	record.AddAttributes(attribute.Int("attempt", 2))
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		SpanID:  trace.SpanID{0, 0, 0, 0, 0, 0, 0, 2},
+	}))
+	provider.Logger("test").Emit(ctx, record)
+	equals(t, nil, provider.Shutdown(context.Background()))
+
+	equals(t, 1, len(lines))
+	equals(t, intake.Stream{Source: "go", Service: "checkout", Hostname: "web-1"}, batches[0].Stream)
+	var got map[string]interface{}
+	equals(t, nil, json.Unmarshal([]byte(lines[0]), &got))
+	equals(t, map[string]interface{}{
+		"timestamp":          "2020-01-02T03:04:05Z",
+		"message":            "payment slow",
+		"status":             "warning",
+		"otel.severity_text": "WARN",
+		"attempt":            float64(2),
+		"otel.trace_id":      "00000000000000000000000000000100",
+		"dd.trace_id":        "256",
+		"otel.span_id":       "0000000000000002",
+		"dd.span_id":         "2",
+	}, got)
+
+	// the client is closed with the provider
+	equals(t, intake.ErrClosed, client.Push([]byte(`{}`)))
+}
+
+func TestSeverity(t *testing.T) {
+	equals(t, intake.SeverityUnknown, Severity(otellog.SeverityUndefined))
+	equals(t, intake.SeverityTrace, Severity(otellog.SeverityTrace2))
+	equals(t, intake.SeverityInfo, Severity(otellog.SeverityInfo))
+	equals(t, intake.SeverityError, Severity(otellog.SeverityError4))
+	equals(t, intake.SeverityFatal, Severity(otellog.SeverityFatal))
+}
+
+func TestValue(t *testing.T) {
+	v := attribute.MapValue(attribute.String("a", "b"), attribute.Slice("l", attribute.IntValue(1), attribute.BoolValue(true)))
+	b, err := json.Marshal(value(v))
+	equals(t, nil, err)
+	equals(t, `{"a":"b","l":[1,true]}`, string(b))
+}
+
+func TestForceFlush(t *testing.T) {
+	var m sync.Mutex
+	lines := 0
+	client := intake.New(intake.Config{
+		JSON:        true,
+		FlushPolicy: intake.Interval(time.Hour),
+		Exporter: intake.ExporterFunc(func(b *intake.Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			lines += len(b.Lines)
+			return nil
+		}),
+	})
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(New(client))))
+	var record otellog.Record
+	record.SetBody(attribute.StringValue("checkpoint"))
+	provider.Logger("test").Emit(context.Background(), record)
+
+	equals(t, nil, provider.ForceFlush(context.Background()))
+	m.Lock()
+	equals(t, 1, lines)
+	m.Unlock()
+	equals(t, nil, provider.Shutdown(context.Background()))
+}
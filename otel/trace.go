@@ -0,0 +1,39 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanFromContext - the Datadog trace and span IDs of the active span of
+// ctx, the datadog.SpanFunc of services traced with OpenTelemetry:
+//
+//	hook := datadog.New(apiKey, datadog.WithTraceCorrelation(otel.SpanFromContext))
+//	l.WithContext(ctx).Info("charged")
+func SpanFromContext(ctx context.Context) (traceID, spanID uint64, ok bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return 0, 0, false
+	}
+	return TraceID(sc.TraceID()), SpanID(sc.SpanID()), true
+}
+
+// TraceID - the Datadog trace ID of an OpenTelemetry one, Datadog correlating
+// traces on its lower 64 bits
+func TraceID(id trace.TraceID) uint64 {
+	var low uint64
+	for _, b := range id[8:] {
+		low = low<<8 | uint64(b)
+	}
+	return low
+}
+
+// SpanID - the Datadog span ID of an OpenTelemetry one
+func SpanID(id trace.SpanID) uint64 {
+	var v uint64
+	for _, b := range id {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package otel
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	datadog "github.com/bin3377/logrus-datadog-hook"
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanFromContext(t *testing.T) {
+	_, _, found := SpanFromContext(context.Background())
+	equals(t, false, found)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		SpanID:  trace.SpanID{0, 0, 0, 0, 0, 0, 0, 2},
+	}))
+	traceID, spanID, found := SpanFromContext(ctx)
+	equals(t, true, found)
+	equals(t, uint64(256), traceID)
+	equals(t, uint64(2), spanID)
+
+	var lines []string
+	hook := datadog.New("key",
+		datadog.WithTraceCorrelation(SpanFromContext),
+		datadog.WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			for _, line := range b.Lines {
+				lines = append(lines, string(line))
+			}
+			return nil
+		})),
+	)
+	l := logrus.New()
+	l.AddHook(hook)
+	l.SetLevel(logrus.InfoLevel)
+	l.WithContext(ctx).Info("one")
+	equals(t, nil, hook.Close(context.Background()))
+	equals(t, 1, len(lines))
+	equals(t, true, strings.Contains(lines[0], `"dd.span_id":"2","dd.trace_id":"256"`))
+}
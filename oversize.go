@@ -0,0 +1,204 @@
+package datadog
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// Oversize is what Fire does with entries above the entry limit, see
+// Options.Oversize
+type Oversize int
+
+const (
+	// OversizeTruncate - cut the message to fit and set TruncatedField, the
+	// default
+	OversizeTruncate Oversize = iota
+	// OversizeDrop - drop the entry, telling Options.OnOversize
+	OversizeDrop
+	// OversizeSplit - ship the message in as many entries as it takes, each
+	// with the fields of the entry and SplitIDField, SplitIndexField and
+	// SplitCountField
+	OversizeSplit
+)
+
+const (
+	// TruncatedField - attribute set to true on entries whose message was
+	// cut to the entry limit
+	TruncatedField = "truncated"
+	// SplitIDField - attribute shared by the parts of a split entry
+	SplitIDField = "split_id"
+	// SplitIndexField - position of a part of a split entry, from 1
+	SplitIndexField = "split_index"
+	// SplitCountField - number of parts of a split entry
+	SplitCountField = "split_count"
+)
+
+// maxSplitParts - most parts an entry is split in, larger ones are dropped
+const maxSplitParts = 100
+
+func (o Oversize) String() string {
+	switch o {
+	case OversizeTruncate:
+		return "truncate"
+	case OversizeDrop:
+		return "drop"
+	case OversizeSplit:
+		return "split"
+	default:
+		return "unknown"
+	}
+}
+
+// oversized - an entry of size bytes not shipped as it is above the entry
+// limit, err returned by Fire
+func (h *Hook) oversized(entry *logrus.Entry, size int, err error) error {
+	atomic.AddInt64(&h.stats.oversized, 1)
+	h.client.Debugf("Entry of %d bytes above the limit of %d bytes dropped", size, h.client.MaxEntryBytes())
+	if h.options.OnOversize != nil {
+		h.options.OnOversize(entry, size)
+	}
+	return err
+}
+
+// shorten - entry formatted again with its message cut to make the line fit
+// max and TruncatedField set, the line itself when the message alone cannot
+// make it fit
+func (h *Hook) shorten(entry *logrus.Entry, line []byte, max int) ([]byte, error) {
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[TruncatedField] = true
+	base, err := h.formatEmpty(&e)
+	if err != nil {
+		return nil, err
+	}
+	keep := len(entry.Message)
+	for entrySize(line) > max {
+		if keep = fitMessage(keep, entrySize(line)-base, max-base); keep < len(intake.TruncatedMarker) {
+			return line, nil
+		}
+		e.Message = string(intake.Truncate([]byte(entry.Message), keep))
+		e.Buffer = nil
+		if line, err = h.formatter.Format(&e); err != nil {
+			return nil, err
+		}
+	}
+	return line, nil
+}
+
+// split - the parts of an entry whose line is above max, each formatted
+// within it, nil when the message alone cannot make them fit
+func (h *Hook) split(entry *logrus.Entry, line []byte, max int) ([]*logrus.Entry, [][]byte, error) {
+	msg := []byte(entry.Message)
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+3)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	id := splitID(entry)
+	e.Data[SplitIDField] = id
+	e.Data[SplitIndexField] = maxSplitParts
+	e.Data[SplitCountField] = maxSplitParts
+	base, err := h.formatEmpty(&e)
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fitMessage(len(msg), entrySize(line)-base, max-base)
+	for size > 0 && (len(msg)+size-1)/size <= maxSplitParts {
+		chunks := splitMessage(msg, size)
+		entries := make([]*logrus.Entry, len(chunks))
+		lines := make([][]byte, len(chunks))
+		for i, chunk := range chunks {
+			part := e
+			part.Data = make(logrus.Fields, len(e.Data))
+			for k, v := range e.Data {
+				part.Data[k] = v
+			}
+			part.Data[SplitIndexField] = i + 1
+			part.Data[SplitCountField] = len(chunks)
+			part.Message = string(chunk)
+			part.Buffer = nil
+			if lines[i], err = h.formatter.Format(&part); err != nil {
+				return nil, nil, err
+			}
+			if entrySize(lines[i]) > max {
+				// escaping made it longer than the others, smaller chunks
+				size = fitMessage(len(chunk), entrySize(lines[i])-base, max-base)
+				entries = nil
+				break
+			}
+			entries[i] = &part
+		}
+		if entries != nil {
+			return entries, lines, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// formatEmpty - bytes of the entry formatted without its message
+func (h *Hook) formatEmpty(entry *logrus.Entry) (int, error) {
+	e := *entry
+	e.Message = ""
+	e.Buffer = nil
+	line, err := h.formatter.Format(&e)
+	return entrySize(line), err
+}
+
+// entrySize - bytes of a formatted line counted against the entry limit,
+// without the trailing newlines the client trims as well
+func entrySize(line []byte) int {
+	return len(bytes.TrimRight(line, "\n"))
+}
+
+// fitMessage - bytes of a message of n bytes taking formatted bytes in a
+// line, formatters escaping some, to take at most room, less than n
+func fitMessage(n, formatted, room int) int {
+	if room <= 0 || formatted <= 0 {
+		return 0
+	}
+	fit := n * room / formatted
+	if fit >= n {
+		fit = n - 1
+	}
+	return fit
+}
+
+// splitMessage - msg cut in chunks of at most size bytes on rune boundaries
+func splitMessage(msg []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(msg) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(msg[cut]) {
+			cut--
+		}
+		// a rune longer than size makes a chunk of its own
+		for cut == 0 || cut < len(msg) && !utf8.RuneStart(msg[cut]) {
+			cut++
+		}
+		chunks = append(chunks, msg[:cut])
+		msg = msg[cut:]
+	}
+	if len(msg) > 0 {
+		chunks = append(chunks, msg)
+	}
+	return chunks
+}
+
+// splitID - identifier of the parts of a split entry
+func splitID(entry *logrus.Entry) string {
+	h := fnv.New64a()
+	var buf [20]byte
+	h.Write(strconv.AppendInt(buf[:0], entry.Time.UnixNano(), 10))
+	h.Write([]byte(entry.Message))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
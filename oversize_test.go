@@ -0,0 +1,169 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// oversizeHook - hook with an entry limit of max bytes and policy o, and the
+// lines it shipped
+func oversizeHook(t *testing.T, max int, o Oversize, fn func(*logrus.Entry, int)) (*Hook, func() []map[string]interface{}) {
+	var mu sync.Mutex
+	var lines [][]byte
+	hook := New("key", WithBatchTimeout(time.Hour), WithMaxEntryBytes(max), WithOversize(o, fn), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, b.Lines...)
+		return nil
+	})))
+	return hook, func() []map[string]interface{} {
+		ok(t, hook.Flush())
+		mu.Lock()
+		defer mu.Unlock()
+		var entries []map[string]interface{}
+		for _, line := range lines {
+			assert(t, len(line) <= max, "line of %d bytes above %d", len(line), max)
+			var entry map[string]interface{}
+			ok(t, json.Unmarshal(line, &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestOversizeTruncate(t *testing.T) {
+	var dropped []int
+	hook, shipped := oversizeHook(t, 300, OversizeTruncate, func(_ *logrus.Entry, size int) { dropped = append(dropped, size) })
+	defer hook.Close(context.Background())
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: strings.Repeat("é", 300), Data: logrus.Fields{}}))
+	// escaped by the formatter to 6 bytes each
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: strings.Repeat("<", 300), Data: logrus.Fields{}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "fits", Data: logrus.Fields{}}))
+	// the message alone cannot make it fit
+	err := hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "m", Data: logrus.Fields{"big": strings.Repeat("x", 400)}})
+	equals(t, intake.ErrEntryTooLarge, err)
+
+	entries := shipped()
+	equals(t, 3, len(entries))
+	for _, entry := range entries[:2] {
+		assert(t, strings.HasSuffix(entry["msg"].(string), intake.TruncatedMarker), "message not truncated: %q", entry["msg"])
+		equals(t, true, entry[TruncatedField])
+	}
+	equals(t, nil, entries[2][TruncatedField])
+	equals(t, 1, len(dropped))
+	assert(t, dropped[0] > 400, "size %d of the dropped entry", dropped[0])
+	equals(t, int64(1), hook.Stats().Oversized)
+}
+
+func TestOversizeDrop(t *testing.T) {
+	var dropped []string
+	hook, shipped := oversizeHook(t, 300, OversizeDrop, func(entry *logrus.Entry, _ int) { dropped = append(dropped, entry.Message[:3]) })
+	defer hook.Close(context.Background())
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "big" + strings.Repeat("x", 400), Data: logrus.Fields{}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "fits", Data: logrus.Fields{}}))
+	equals(t, 1, len(shipped()))
+	equals(t, []string{"big"}, dropped)
+	equals(t, int64(1), hook.Stats().Oversized)
+}
+
+func TestOversizeSplit(t *testing.T) {
+	hook, shipped := oversizeHook(t, 300, OversizeSplit, nil)
+	defer hook.Close(context.Background())
+	msg := strings.Repeat("é<abc", 200)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: msg, Data: logrus.Fields{"request_id": "r1"}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "fits", Data: logrus.Fields{}}))
+	err := hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "m", Data: logrus.Fields{"big": strings.Repeat("x", 400)}})
+	equals(t, intake.ErrEntryTooLarge, err)
+
+	entries := shipped()
+	parts := entries[:len(entries)-1]
+	assert(t, len(parts) > 1, "expected several parts, got %d", len(parts))
+	var joined strings.Builder
+	for i, part := range parts {
+		equals(t, parts[0][SplitIDField], part[SplitIDField])
+		equals(t, float64(i+1), part[SplitIndexField])
+		equals(t, float64(len(parts)), part[SplitCountField])
+		equals(t, "r1", part["request_id"])
+		joined.WriteString(part["msg"].(string))
+	}
+	equals(t, msg, joined.String())
+	equals(t, "fits", entries[len(entries)-1]["msg"])
+	equals(t, nil, entries[len(entries)-1][SplitIDField])
+	stats := hook.Stats()
+	equals(t, int64(1), stats.Split)
+	equals(t, int64(1), stats.Oversized)
+}
+
+func TestOversizeExactFit(t *testing.T) {
+	entry := func() *logrus.Entry {
+		return &logrus.Entry{Level: logrus.InfoLevel, Message: "fits exactly", Data: logrus.Fields{}}
+	}
+	hook, shipped := oversizeHook(t, 1000, OversizeDrop, nil)
+	ok(t, hook.Fire(entry()))
+	raw, err := json.Marshal(shipped()[0])
+	ok(t, err)
+	ok(t, hook.Close(context.Background()))
+
+	// a line at the limit fits, the trailing newline of the formatter aside
+	var dropped int
+	hook, shipped = oversizeHook(t, len(raw), OversizeDrop, func(*logrus.Entry, int) { dropped++ })
+	defer hook.Close(context.Background())
+	ok(t, hook.Fire(entry()))
+	equals(t, 1, len(shipped()))
+	equals(t, 0, dropped)
+
+	// a byte less does not
+	short, _ := oversizeHook(t, len(raw)-1, OversizeDrop, func(*logrus.Entry, int) { dropped++ })
+	defer short.Close(context.Background())
+	ok(t, short.Fire(entry()))
+	equals(t, 1, dropped)
+}
+
+func TestSplitMessage(t *testing.T) {
+	equals(t, [][]byte{[]byte("abc"), []byte("def"), []byte("g")}, splitMessage([]byte("abcdefg"), 3))
+	// runes are not cut
+	equals(t, [][]byte{[]byte("a"), []byte("é"), []byte("é")}, splitMessage([]byte("aéé"), 2))
+	equals(t, [][]byte{[]byte("ab")}, splitMessage([]byte("ab"), 3))
+	equals(t, [][]byte{[]byte("é"), []byte("é")}, splitMessage([]byte("éé"), 1))
+}
+
+func TestOversizeString(t *testing.T) {
+	equals(t, "truncate", OversizeTruncate.String())
+	equals(t, "drop", OversizeDrop.String())
+	equals(t, "split", OversizeSplit.String())
+	equals(t, "unknown", Oversize(42).String())
+}
+
+func TestMaxEntryBytes(t *testing.T) {
+	var mu sync.Mutex
+	var lines [][]byte
+	hook := New("key", WithBatchTimeout(time.Hour), WithMaxEntryBytes(200), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, b.Lines...)
+		return nil
+	})))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: strings.Repeat("é", 300), Data: logrus.Fields{}}))
+	// the message alone cannot make it fit
+	err := hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "m", Data: logrus.Fields{"big": strings.Repeat("x", 300)}})
+	equals(t, intake.ErrEntryTooLarge, err)
+	ok(t, hook.Flush())
+
+	mu.Lock()
+	defer mu.Unlock()
+	equals(t, 1, len(lines))
+	var entry struct {
+		Message string `json:"msg"`
+	}
+	ok(t, json.Unmarshal(lines[0], &entry))
+	assert(t, len(lines[0]) <= 200, "line of %d bytes", len(lines[0]))
+	assert(t, strings.HasSuffix(entry.Message, intake.TruncatedMarker), "message not truncated: %q", entry.Message)
+	ok(t, hook.Close(context.Background()))
+}
@@ -0,0 +1,98 @@
+package datadog
+
+import (
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// Pipeline - custom stages run at defined points of the way an entry takes
+// to the intake, so power users can add their own, such as schema tagging or
+// encryption, without forking. An entry fired goes through, in order:
+//
+//  1. the level, sampling, mutes and quotas of the hook
+//  2. the built-in scrubbing, filtering and routing of Rules
+//  3. the built-in enrichment: tags, file attributes, error chains,
+//     normalized values, trace correlation and fingerprints
+//  4. Enrich, then Scrub
+//  5. the formatter and the entry size limit
+//  6. Format on the line, then verification and the queue of the client
+//  7. batching, then Batch on the payload of every batch
+//  8. signing, compression and delivery
+type Pipeline struct {
+	// Enrich - entry stages adding to the entries enriched by the hook
+	Enrich []EntryStage
+	// Scrub - entry stages run after Enrich, the last look at entries
+	// before they are formatted, such as to redact custom secrets
+	Scrub []EntryStage
+	// Format - line stages run on every formatted line, including the parts
+	// of entries truncated or split for the entry limit, before it is
+	// verified and queued. JSON lines they make larger than the limit are
+	// dropped as oversized.
+	Format []LineStage
+	// Batch - payload stages run by the client on the payload of every
+	// batch before it is signed and compressed
+	Batch []PayloadStage
+}
+
+// EntryStage transforms entries before they are formatted
+type EntryStage interface {
+	// Entry - the entry and stream to go on with, a nil entry skips it and
+	// an error is returned by Fire. The entry may be shared with other
+	// hooks, change a copy.
+	Entry(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error)
+}
+
+// EntryStageFunc - a function as an EntryStage
+type EntryStageFunc func(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error)
+
+// Entry - call f
+func (f EntryStageFunc) Entry(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error) {
+	return f(entry, stream)
+}
+
+// LineStage transforms formatted lines before they are queued
+type LineStage interface {
+	// Line - the line to queue instead, line itself if unchanged, nil to
+	// skip the entry. The line may be cached, return a new one to change it.
+	// An error is returned by Fire.
+	Line(line []byte, entry *logrus.Entry) ([]byte, error)
+}
+
+// LineStageFunc - a function as a LineStage
+type LineStageFunc func(line []byte, entry *logrus.Entry) ([]byte, error)
+
+// Line - call f
+func (f LineStageFunc) Line(line []byte, entry *logrus.Entry) ([]byte, error) {
+	return f(line, entry)
+}
+
+// PayloadStage transforms the payload of every batch, see
+// intake.PayloadStage
+type PayloadStage = intake.PayloadStage
+
+// PayloadStageFunc - a function as a PayloadStage
+type PayloadStageFunc = intake.PayloadStageFunc
+
+// stageEntry - the entry through the Enrich and Scrub stages, nil if skipped
+func (p Pipeline) stageEntry(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error) {
+	for _, stages := range [][]EntryStage{p.Enrich, p.Scrub} {
+		for _, stage := range stages {
+			var err error
+			if entry, stream, err = stage.Entry(entry, stream); err != nil || entry == nil {
+				return nil, stream, err
+			}
+		}
+	}
+	return entry, stream, nil
+}
+
+// stageLine - the line through the Format stages, nil if skipped
+func (p Pipeline) stageLine(line []byte, entry *logrus.Entry) ([]byte, error) {
+	for _, stage := range p.Format {
+		var err error
+		if line, err = stage.Line(line, entry); err != nil || line == nil {
+			return nil, err
+		}
+	}
+	return line, nil
+}
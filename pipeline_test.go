@@ -0,0 +1,113 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// withField - a copy of the entry with the field set
+func withField(entry *logrus.Entry, key string, value interface{}) *logrus.Entry {
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[key] = value
+	return &e
+}
+
+func TestPipeline(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	var payloads []string
+	errStage := errors.New("stage failed")
+	var order []string
+	stage := func(name string) EntryStage {
+		return EntryStageFunc(func(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error) {
+			order = append(order, name)
+			return entry, stream, nil
+		})
+	}
+	hook := New("key", WithBatchTimeout(time.Hour), WithService("checkout"), WithPipeline(Pipeline{
+		Enrich: []EntryStage{stage("enrich"), EntryStageFunc(func(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error) {
+			switch entry.Message {
+			case "skip":
+				return nil, stream, nil
+			case "fail":
+				return nil, stream, errStage
+			}
+			stream.Service = "payments"
+			return withField(entry, "schema", "v2"), stream, nil
+		})},
+		Scrub: []EntryStage{stage("scrub"), EntryStageFunc(func(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream, error) {
+			return withField(entry, "card", "[redacted]"), stream, nil
+		})},
+		Format: []LineStage{LineStageFunc(func(line []byte, entry *logrus.Entry) ([]byte, error) {
+			if entry.Message == "drop line" {
+				return nil, nil
+			}
+			return bytes.Replace(line, []byte(`"msg"`), []byte(`"message"`), 1), nil
+		})},
+		Batch: []PayloadStage{PayloadStageFunc(func(payload []byte, stream Stream) ([]byte, error) {
+			return append([]byte(stream.Service+" "), payload...), nil
+		})},
+	}), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range b.Lines {
+			lines = append(lines, string(line))
+		}
+		payloads = append(payloads, string(b.Payload))
+		return nil
+	})))
+
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "hello", Data: logrus.Fields{"card": "4242"}}))
+	equals(t, []string{"enrich", "scrub"}, order)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "skip", Data: logrus.Fields{}}))
+	equals(t, errStage, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "fail", Data: logrus.Fields{}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "drop line", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	equals(t, 1, len(lines))
+	var entry map[string]interface{}
+	ok(t, json.Unmarshal([]byte(lines[0]), &entry))
+	equals(t, "hello", entry["message"])
+	equals(t, "v2", entry["schema"])
+	equals(t, "[redacted]", entry["card"])
+	equals(t, 1, len(payloads))
+	assert(t, strings.HasPrefix(payloads[0], "payments ["), "stage not run on payload %q", payloads[0])
+	s := hook.Stats()
+	equals(t, int64(2), s.Skipped)
+	equals(t, int64(1), s.Failed)
+}
+
+func TestPipelineLineLimit(t *testing.T) {
+	var dropped int
+	hook := New("key", WithBatchTimeout(time.Hour), WithMaxEntryBytes(100), WithOversize(OversizeTruncate, func(*logrus.Entry, int) { dropped++ }),
+		WithPipeline(Pipeline{Format: []LineStage{LineStageFunc(func(line []byte, entry *logrus.Entry) ([]byte, error) {
+			return append(bytes.TrimRight(line, "}\n"), []byte(`,"padding":"`+strings.Repeat("x", 100)+`"}`)...), nil
+		})}}), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error { return nil })))
+	defer hook.Close(context.Background())
+	// lines the stage makes too large are not shipped
+	equals(t, intake.ErrEntryTooLarge, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "hello", Data: logrus.Fields{}}))
+	equals(t, 1, dropped)
+	equals(t, int64(1), hook.Stats().Oversized)
+}
+
+func TestWithPipeline(t *testing.T) {
+	c := config{}
+	p := Pipeline{Format: []LineStage{LineStageFunc(func(line []byte, _ *logrus.Entry) ([]byte, error) { return line, nil })}}
+	WithPipeline(p)(&c)
+	equals(t, 1, len(c.options.Pipeline.Format))
+}
@@ -0,0 +1,154 @@
+package datadog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// QuotaMessage - message of the entries summarizing the entries over
+	// the quota of their level
+	QuotaMessage = "level quota exceeded"
+	// QuotaField - field of the summary holding the entries and bytes not
+	// shipped by level
+	QuotaField = "quota_dropped"
+
+	// DefaultLevelQuotaWindow - window the level quotas are counted over by default
+	DefaultLevelQuotaWindow = time.Minute
+)
+
+// LevelQuota caps what the entries of a level ship per window, either
+// unlimited if 0
+type LevelQuota struct {
+	Entries int
+	Bytes   int64
+}
+
+// QuotaUsage counts the entries of a level and their bytes
+type QuotaUsage struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// levelQuotas - what every level shipped and is over of its quota in the
+// current window, a summary of the latter shipped once the window is over
+type levelQuotas struct {
+	hook   *Hook
+	quotas map[logrus.Level]LevelQuota
+	window time.Duration
+
+	m       sync.Mutex
+	used    map[logrus.Level]QuotaUsage
+	dropped map[logrus.Level]QuotaUsage
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newLevelQuotas(h *Hook, options Options) *levelQuotas {
+	q := &levelQuotas{
+		hook:    h,
+		quotas:  options.LevelQuotas,
+		window:  options.LevelQuotaWindow,
+		used:    map[logrus.Level]QuotaUsage{},
+		dropped: map[logrus.Level]QuotaUsage{},
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if q.window <= 0 {
+		q.window = DefaultLevelQuotaWindow
+	}
+	go q.run()
+	return q
+}
+
+func (q *levelQuotas) run() {
+	defer close(q.stopped)
+	for {
+		select {
+		case <-q.hook.after(q.window):
+			q.flush()
+		case <-q.done:
+			q.flush()
+			return
+		}
+	}
+}
+
+// full - whether the level shipped as many entries as its quota allows, so
+// the entry need not be formatted; counted as dropped if so
+func (q *levelQuotas) full(level logrus.Level) bool {
+	quota, ok := q.quotas[level]
+	if !ok || quota.Entries <= 0 {
+		return false
+	}
+	q.m.Lock()
+	defer q.m.Unlock()
+	if q.used[level].Entries < quota.Entries {
+		return false
+	}
+	q.drop(level, 0)
+	return true
+}
+
+// admit - account an entry of size bytes, false and counted as dropped if
+// it is over the quota of its level
+func (q *levelQuotas) admit(level logrus.Level, size int) bool {
+	quota, ok := q.quotas[level]
+	if !ok {
+		return true
+	}
+	q.m.Lock()
+	defer q.m.Unlock()
+	used := q.used[level]
+	if (quota.Entries > 0 && used.Entries >= quota.Entries) || (quota.Bytes > 0 && used.Bytes+int64(size) > quota.Bytes) {
+		q.drop(level, size)
+		return false
+	}
+	used.Entries++
+	used.Bytes += int64(size)
+	q.used[level] = used
+	return true
+}
+
+// drop - count an entry of size bytes over the quota, with the lock held
+func (q *levelQuotas) drop(level logrus.Level, size int) {
+	dropped := q.dropped[level]
+	dropped.Entries++
+	dropped.Bytes += int64(size)
+	q.dropped[level] = dropped
+}
+
+// flush - start a new window, shipping the summary of the entries dropped
+// in the last one if any
+func (q *levelQuotas) flush() {
+	q.m.Lock()
+	dropped := q.dropped
+	q.used, q.dropped = map[logrus.Level]QuotaUsage{}, map[logrus.Level]QuotaUsage{}
+	q.m.Unlock()
+	if len(dropped) == 0 {
+		return
+	}
+	byLevel := make(map[string]QuotaUsage, len(dropped))
+	for level, usage := range dropped {
+		byLevel[level.String()] = usage
+	}
+	entry := &logrus.Entry{
+		Time:    q.hook.now(),
+		Level:   logrus.WarnLevel,
+		Message: QuotaMessage,
+		Data:    logrus.Fields{QuotaField: byLevel},
+	}
+	if err := q.hook.shipEntry(entry, false); err != nil {
+		q.hook.client.Debugf("Unable to ship quota summary, %v", err)
+	}
+}
+
+// stop - ship the last summary and stop
+func (q *levelQuotas) stop() {
+	q.once.Do(func() { close(q.done) })
+	<-q.stopped
+}
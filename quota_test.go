@@ -0,0 +1,84 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLevelQuotas(t *testing.T) {
+	var m sync.Mutex
+	var lines []string
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.DebugLevel, &logrus.JSONFormatter{}, Options{
+		Clock: &fakeClock{now: time.Unix(1000, 0)},
+		LevelQuotas: map[logrus.Level]LevelQuota{
+			logrus.DebugLevel: {Entries: 2},
+			logrus.InfoLevel:  {Bytes: 200},
+		},
+		Exporter: intake.ExporterFunc(func(b *intake.Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			for _, line := range b.Lines {
+				lines = append(lines, string(line))
+			}
+			return nil
+		}),
+	})
+	fire := func(level logrus.Level, msg string) {
+		ok(t, hook.Fire(&logrus.Entry{Level: level, Message: msg, Data: logrus.Fields{}}))
+	}
+	for i := 0; i < 5; i++ {
+		fire(logrus.DebugLevel, "debug")
+	}
+	fire(logrus.InfoLevel, strings.Repeat("a", 100))
+	fire(logrus.InfoLevel, strings.Repeat("b", 100))
+	fire(logrus.WarnLevel, "warn")
+	ok(t, hook.Close(context.Background()))
+	equals(t, int64(4), hook.Stats().OverQuota)
+
+	m.Lock()
+	defer m.Unlock()
+	// 2 debug, 1 info, the warning and the summary
+	equals(t, 5, len(lines))
+	var summary struct {
+		Msg     string                `json:"msg"`
+		Level   string                `json:"level"`
+		Dropped map[string]QuotaUsage `json:"quota_dropped"`
+	}
+	ok(t, json.Unmarshal([]byte(lines[4]), &summary))
+	equals(t, QuotaMessage, summary.Msg)
+	equals(t, "warning", summary.Level)
+	equals(t, 3, summary.Dropped["debug"].Entries)
+	equals(t, int64(0), summary.Dropped["debug"].Bytes)
+	equals(t, 1, summary.Dropped["info"].Entries)
+	assert(t, summary.Dropped["info"].Bytes > 100, "the bytes of the info entry should be counted, got %d", summary.Dropped["info"].Bytes)
+}
+
+func TestLevelQuotasWindow(t *testing.T) {
+	q := &levelQuotas{
+		quotas:  map[logrus.Level]LevelQuota{logrus.InfoLevel: {Entries: 1, Bytes: 10}},
+		used:    map[logrus.Level]QuotaUsage{},
+		dropped: map[logrus.Level]QuotaUsage{},
+	}
+	equals(t, false, q.full(logrus.InfoLevel))
+	equals(t, false, q.admit(logrus.InfoLevel, 11))
+	equals(t, true, q.admit(logrus.InfoLevel, 10))
+	equals(t, true, q.full(logrus.InfoLevel))
+	equals(t, true, q.admit(logrus.ErrorLevel, 1000))
+	equals(t, map[logrus.Level]QuotaUsage{logrus.InfoLevel: {Entries: 2, Bytes: 11}}, q.dropped)
+}
+
+func TestWithLevelQuota(t *testing.T) {
+	quotas := map[logrus.Level]LevelQuota{logrus.DebugLevel: {Entries: 10}}
+	c := config{options: Options{LevelQuotas: quotas}}
+	WithLevelQuota(logrus.InfoLevel, 0, 1<<20)(&c)
+	equals(t, map[logrus.Level]LevelQuota{logrus.DebugLevel: {Entries: 10}, logrus.InfoLevel: {Bytes: 1 << 20}}, c.options.LevelQuotas)
+	// the map given is not changed
+	equals(t, 1, len(quotas))
+}
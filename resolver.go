@@ -0,0 +1,53 @@
+package datadog
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Resolver derive a value of the stream, such as the service, from an
+// entry; an empty result keeps the value of the options
+type Resolver func(*logrus.Entry) string
+
+// Static - resolver of value whatever the entry
+func Static(value string) Resolver {
+	return func(*logrus.Entry) string { return value }
+}
+
+// FieldResolver - resolver of the value of field, or of def when the entry
+// doesn't have it
+func FieldResolver(field string, def Resolver) Resolver {
+	return func(entry *logrus.Entry) string {
+		if v, ok := entry.Data[field]; ok {
+			if s := fmt.Sprint(v); s != "" {
+				return s
+			}
+		}
+		if def == nil {
+			return ""
+		}
+		return def(entry)
+	}
+}
+
+// resolve - the stream of the entry: the options resolved for it, before
+// routes and entry tags apply
+func (o Options) resolve(entry *logrus.Entry) Stream {
+	stream := o.Stream()
+	stream.Source = o.source(entry, stream.Source)
+	stream.Source = resolveWith(o.SourceResolver, entry, stream.Source)
+	stream.Service = resolveWith(o.ServiceResolver, entry, stream.Service)
+	stream.Hostname = resolveWith(o.HostnameResolver, entry, stream.Hostname)
+	return stream
+}
+
+func resolveWith(r Resolver, entry *logrus.Entry, def string) string {
+	if r == nil {
+		return def
+	}
+	if v := r(entry); v != "" {
+		return v
+	}
+	return def
+}
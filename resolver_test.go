@@ -0,0 +1,44 @@
+package datadog
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestResolve(t *testing.T) {
+	o := Options{Source: "go", Service: "api", Hostname: "web-1", SourceField: "framework"}
+	entry := &logrus.Entry{Data: logrus.Fields{"framework": "gin", "tenant": "acme"}}
+	equals(t, Stream{Source: SourceGin, Service: "api", Hostname: "web-1"}, o.resolve(entry))
+
+	o.ServiceResolver = FieldResolver("tenant", Static("shared"))
+	o.HostnameResolver = func(*logrus.Entry) string { return "" }
+	o.SourceResolver = Static("custom")
+	equals(t, Stream{Source: "custom", Service: "acme", Hostname: "web-1"}, o.resolve(entry))
+	equals(t, "shared", o.ServiceResolver(&logrus.Entry{Data: logrus.Fields{}}))
+	equals(t, "", FieldResolver("tenant", nil)(&logrus.Entry{Data: logrus.Fields{"tenant": ""}}))
+}
+
+func TestWithServiceResolver(t *testing.T) {
+	var m sync.Mutex
+	var services []string
+	hook := New("key",
+		WithService("api"),
+		WithServiceResolver(FieldResolver("tenant", nil)),
+		WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			m.Lock()
+			defer m.Unlock()
+			services = append(services, b.Stream.Service)
+			return nil
+		})),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{"tenant": "acme"}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "two", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+	sort.Strings(services)
+	equals(t, []string{"acme", "api"}, services)
+}
@@ -0,0 +1,187 @@
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// Stream identify where entries land in Datadog
+type Stream = intake.Stream
+
+// Rules define how entries are scrubbed, filtered and routed before shipping
+type Rules struct {
+	// Scrub - replacements applied to the message and the values of the
+	// fields of entries, see Rules.scrubValue
+	Scrub []ScrubRule
+	// Drop - entries matching any of the filters are not shipped
+	Drop []Filter
+	// Route - the first matching route overrides the stream of the entry
+	Route []Route
+}
+
+// ScrubRule replace every match of Pattern with Replacement
+type ScrubRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Filter match entries whose Field, or message if Field is empty, matches Pattern
+type Filter struct {
+	Field   string
+	Pattern *regexp.Regexp
+}
+
+// Route send entries matching the filter to Stream, merged over the hook's options
+type Route struct {
+	Filter
+	Stream Stream
+}
+
+// SetRules - atomically replace the scrubbing, filtering and routing rules,
+// safe to call at any time
+func (h *Hook) SetRules(rules Rules) {
+	h.rules.Store(&rules)
+}
+
+// Rules - the rules currently applied
+func (h *Hook) Rules() Rules {
+	if r, ok := h.rules.Load().(*Rules); ok {
+		return *r
+	}
+	return Rules{}
+}
+
+// Match - whether the entry matches the filter
+func (f Filter) Match(entry *logrus.Entry) bool {
+	if f.Pattern == nil {
+		return false
+	}
+	if f.Field == "" {
+		return f.Pattern.MatchString(entry.Message)
+	}
+	v, ok := entry.Data[f.Field]
+	if !ok {
+		return false
+	}
+	return f.Pattern.MatchString(fmt.Sprint(v))
+}
+
+// apply - the scrubbed copy of entry and its stream, or a nil entry if dropped
+func (r *Rules) apply(entry *logrus.Entry, stream Stream) (*logrus.Entry, Stream) {
+	for _, f := range r.Drop {
+		if f.Match(entry) {
+			return nil, stream
+		}
+	}
+	for _, route := range r.Route {
+		if route.Match(entry) {
+			stream = stream.Merge(route.Stream)
+			break
+		}
+	}
+	if len(r.Scrub) == 0 {
+		return entry, stream
+	}
+
+	scrubbed := *entry
+	scrubbed.Message = r.scrub(entry.Message)
+	scrubbed.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		scrubbed.Data[k] = r.scrubValue(v)
+	}
+	return &scrubbed, stream
+}
+
+// scrubValue - the value of a field scrubbed: strings, byte slices and the
+// string of fmt.Stringers replaced, errors kept as errors whose message and
+// causes are scrubbed, and maps, slices and structs scrubbed as the JSON
+// they ship as. Numbers, booleans and times are kept as is.
+func (r *Rules) scrubValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, bool, time.Time, time.Duration:
+		return v
+	case string:
+		return r.scrub(value)
+	case []byte:
+		return []byte(r.scrub(string(value)))
+	case error:
+		return scrubbedError{err: value, rules: r}
+	case fmt.Stringer:
+		return r.scrub(value.String())
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return r.scrub(fmt.Sprint(v))
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return r.scrub(fmt.Sprint(v))
+		}
+		return r.scrubJSON(generic)
+	case reflect.String:
+		return r.scrub(reflect.ValueOf(v).String())
+	}
+	return v
+}
+
+// scrubJSON - the strings of a decoded JSON value scrubbed
+func (r *Rules) scrubJSON(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.scrub(value)
+	case []interface{}:
+		for i, item := range value {
+			value[i] = r.scrubJSON(item)
+		}
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = r.scrubJSON(item)
+		}
+	}
+	return v
+}
+
+// scrubbedError - an error of a field with its message scrubbed, wrapping
+// the causes of the error scrubbed too so Options.ErrorChains still lists
+// them
+type scrubbedError struct {
+	err   error
+	rules *Rules
+}
+
+func (e scrubbedError) Error() string {
+	return e.rules.scrub(e.err.Error())
+}
+
+func (e scrubbedError) Unwrap() []error {
+	causes := unwrap(e.err)
+	scrubbed := make([]error, 0, len(causes))
+	for _, cause := range causes {
+		if cause != nil {
+			scrubbed = append(scrubbed, scrubbedError{err: cause, rules: e.rules})
+		}
+	}
+	return scrubbed
+}
+
+// Is - whether the error scrubbed is target, so errors.Is sees through
+func (e scrubbedError) Is(target error) bool {
+	return reflect.TypeOf(e.err).Comparable() && e.err == target
+}
+
+func (r *Rules) scrub(s string) string {
+	for _, rule := range r.Scrub {
+		if rule.Pattern != nil {
+			s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+		}
+	}
+	return s
+}
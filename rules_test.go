@@ -0,0 +1,107 @@
+package datadog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRulesApply(t *testing.T) {
+	rules := &Rules{
+		Scrub: []ScrubRule{{Pattern: regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`), Replacement: "[card]"}},
+		Drop:  []Filter{{Field: "path", Pattern: regexp.MustCompile(`^/health`)}},
+		Route: []Route{
+			{Filter: Filter{Pattern: regexp.MustCompile(`^audit:`)}, Stream: Stream{Service: "audit", Tags: []string{"audit:true"}}},
+			{Filter: Filter{Pattern: regexp.MustCompile(`.`)}, Stream: Stream{Service: "unused"}},
+		},
+	}
+	stream := Stream{Source: "go", Service: "api", Tags: []string{"env:prod"}}
+
+	entry := &logrus.Entry{Message: "hello", Data: logrus.Fields{"path": "/health/live"}}
+	e, _ := rules.apply(entry, stream)
+	assert(t, e == nil, "entry should be dropped")
+
+	entry = &logrus.Entry{Message: "audit: paid with 1234-5678-9012-3456", Data: logrus.Fields{
+		"card":  "1234-5678-9012-3456",
+		"err":   errors.New("card 1234-5678-9012-3456 declined"),
+		"count": 1,
+	}}
+	e, s := rules.apply(entry, stream)
+	equals(t, "audit: paid with [card]", e.Message)
+	equals(t, "card [card] declined", e.Data["err"].(error).Error())
+	delete(e.Data, "err")
+	equals(t, logrus.Fields{"card": "[card]", "count": 1}, e.Data)
+	equals(t, Stream{Source: "go", Service: "audit", Tags: []string{"env:prod", "audit:true"}}, s)
+	// the original entry is shared with other hooks and must stay untouched
+	equals(t, "1234-5678-9012-3456", entry.Data["card"])
+	equals(t, []string{"env:prod"}, stream.Tags)
+}
+
+type cardStringer struct{}
+
+func (cardStringer) String() string { return "card 1234-5678-9012-3456" }
+
+func TestRulesScrubValues(t *testing.T) {
+	rules := &Rules{Scrub: []ScrubRule{{Pattern: regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`), Replacement: "[card]"}}}
+	card := "1234-5678-9012-3456"
+	entry := &logrus.Entry{Message: "paid", Data: logrus.Fields{
+		"stringer": cardStringer{},
+		"map":      map[string]interface{}{"card": card, "n": 1},
+		"slice":    []string{card},
+		"struct":   struct{ Card string }{card},
+		"bytes":    []byte(card),
+		"at":       time.Unix(0, 0),
+	}}
+	e, _ := rules.apply(entry, Stream{})
+	equals(t, "card [card]", e.Data["stringer"])
+	equals(t, map[string]interface{}{"card": "[card]", "n": float64(1)}, e.Data["map"])
+	equals(t, []interface{}{"[card]"}, e.Data["slice"])
+	equals(t, map[string]interface{}{"Card": "[card]"}, e.Data["struct"])
+	equals(t, []byte("[card]"), e.Data["bytes"])
+	equals(t, time.Unix(0, 0), e.Data["at"])
+}
+
+func TestRulesScrubErrorChain(t *testing.T) {
+	rules := &Rules{Scrub: []ScrubRule{{Pattern: regexp.MustCompile(`secret`), Replacement: "[redacted]"}}}
+	root := &os.PathError{Op: "open", Path: "/secret", Err: os.ErrNotExist}
+	err := fmt.Errorf("load secret: %w", root)
+	entry := &logrus.Entry{Message: "failed", Data: logrus.Fields{logrus.ErrorKey: err}}
+	e, _ := rules.apply(entry, Stream{})
+
+	scrubbed := e.Data[logrus.ErrorKey].(error)
+	equals(t, "load [redacted]: open /[redacted]: file does not exist", scrubbed.Error())
+	assert(t, errors.Is(scrubbed, os.ErrNotExist), "the chain should be kept")
+	chained := errorChain(e).Data[logrus.ErrorKey].(map[string]interface{})
+	equals(t, "load [redacted]: open /[redacted]: file does not exist", chained["message"])
+	equals(t, "*errors.errorString", chained["kind"])
+	equals(t, []ErrorCause{
+		{Kind: "*fs.PathError", Message: "open /[redacted]: file does not exist"},
+		{Kind: "*errors.errorString", Message: "file does not exist"},
+	}, chained["causes"])
+}
+
+func TestSetRules(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{})
+	equals(t, 0, len(hook.Rules().Drop))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hook.SetRules(Rules{Drop: []Filter{{Pattern: regexp.MustCompile(`.`)}}})
+			// every entry is dropped before reaching the client
+			if err := hook.Fire(&logrus.Entry{Message: "dropped"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	equals(t, 1, len(hook.Rules().Drop))
+}
@@ -0,0 +1,38 @@
+package datadog
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sampleKey - the value of field in the entry, hashed to sample the entries
+// sharing it together
+func sampleKey(entry *logrus.Entry, field string) (string, bool) {
+	if field == "" {
+		return "", false
+	}
+	v, ok := entry.Data[field]
+	if !ok || v == nil {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, s != ""
+	}
+	return fmt.Sprint(v), true
+}
+
+// sampleHash - map the key evenly to [0, 1), the same for every process
+func sampleHash(key string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// mix the bits, FNV alone clusters keys differing by their last bytes
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,119 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSampleKey(t *testing.T) {
+	entry := &logrus.Entry{Data: logrus.Fields{"request_id": "abc", "user": 42, "empty": "", "nil": nil}}
+	for _, c := range []struct {
+		field, key string
+		ok         bool
+	}{
+		{"", "", false},
+		{"request_id", "abc", true},
+		{"user", "42", true},
+		{"empty", "", false},
+		{"nil", "", false},
+		{"missing", "", false},
+	} {
+		key, ok := sampleKey(entry, c.field)
+		equals(t, c.key, key)
+		equals(t, c.ok, ok)
+	}
+}
+
+func TestSampleHash(t *testing.T) {
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		h := sampleHash(fmt.Sprint("request-", i))
+		assert(t, h >= 0 && h < 1, "hash %v is not in [0, 1)", h)
+		if h < 0.1 {
+			kept++
+		}
+	}
+	assert(t, kept > 900 && kept < 1100, "expected about 1000 keys below 0.1, got %d", kept)
+	equals(t, sampleHash("abc"), sampleHash("abc"))
+}
+
+func TestSampleBy(t *testing.T) {
+	exporter := &countingExporter{}
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Clock:    &fakeClock{now: time.Unix(1000, 0)},
+		Sampling: 0.5,
+		SampleBy: "request_id",
+		Exporter: exporter,
+	})
+	kept := 0
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprint("request-", i)
+		want := sampleHash(id) < 0.5
+		if want {
+			kept++
+		}
+		// every line of a request is kept or skipped with the first
+		for j := 0; j < 3; j++ {
+			ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "line", Data: logrus.Fields{"request_id": id}}))
+		}
+	}
+	ok(t, hook.Close(context.Background()))
+	stats := hook.Stats()
+	equals(t, int64(3*kept), exporter.lines)
+	equals(t, int64(3*(200-kept)), stats.Skipped)
+}
+
+func TestWithSampling(t *testing.T) {
+	c := config{}
+	WithSampling(0.1, "request_id")(&c)
+	equals(t, 0.1, c.options.Sampling)
+	equals(t, "request_id", c.options.SampleBy)
+}
+
+func TestLevelSampling(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.DebugLevel, &logrus.JSONFormatter{}, Options{
+		Clock:    &fakeClock{now: time.Unix(1000, 0)},
+		Sampling: 0.5,
+		SampleBy: "request_id",
+		LevelSampling: map[logrus.Level]float64{
+			logrus.ErrorLevel: 1,
+			logrus.DebugLevel: 0.01,
+			logrus.TraceLevel: 0,
+		},
+		Exporter: &countingExporter{},
+	})
+	defer hook.Close(context.Background())
+	count := func(level logrus.Level) int {
+		kept := 0
+		for i := 0; i < 1000; i++ {
+			if hook.sampled(&logrus.Entry{Level: level, Data: logrus.Fields{"request_id": fmt.Sprint("request-", i)}}) {
+				kept++
+			}
+		}
+		return kept
+	}
+	equals(t, 1000, count(logrus.ErrorLevel))
+	equals(t, 0, count(logrus.TraceLevel))
+	kept := count(logrus.DebugLevel)
+	assert(t, kept > 0 && kept < 30, "expected about 10 debug entries of 1000, got %d", kept)
+	// levels without a rate of their own follow Sampling
+	kept = count(logrus.InfoLevel)
+	assert(t, kept > 420 && kept < 580, "expected about 500 info entries of 1000, got %d", kept)
+	hook.SetSampling(0)
+	equals(t, 1000, count(logrus.InfoLevel))
+	kept = count(logrus.DebugLevel)
+	assert(t, kept > 0 && kept < 30, "expected about 10 debug entries of 1000, got %d", kept)
+}
+
+func TestWithLevelSampling(t *testing.T) {
+	rates := map[logrus.Level]float64{logrus.DebugLevel: 0.01}
+	c := config{options: Options{LevelSampling: rates}}
+	WithLevelSampling(logrus.InfoLevel, 0.2)(&c)
+	equals(t, map[logrus.Level]float64{logrus.DebugLevel: 0.01, logrus.InfoLevel: 0.2}, c.options.LevelSampling)
+	// the map given is not changed
+	equals(t, 1, len(rates))
+}
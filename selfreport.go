@@ -0,0 +1,154 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+const (
+	// SelfReportMessage - message of the summaries of the errors of the hook
+	SelfReportMessage = "log shipping errors"
+	// SelfReportField - field of the summary holding the errors by message
+	SelfReportField = "hook_errors"
+	// DefaultSelfReportService - service of the summaries unless
+	// Options.SelfReportService
+	DefaultSelfReportService = "logrus-datadog-hook"
+
+	// maxSelfReportKinds - distinct messages counted by summary, the others
+	// are counted as suppressed
+	maxSelfReportKinds = 10
+	// maxSelfReportMessage - longest message kept, longer ones are truncated
+	maxSelfReportMessage = 200
+)
+
+// selfReport - summaries of the errors of the hook shipped at most once an
+// interval through a client of its own, so they reach Datadog while the
+// main pipeline is struggling. The errors of that client are not reported,
+// summaries never report themselves.
+type selfReport struct {
+	hook     *Hook
+	client   *intake.Client
+	interval time.Duration
+
+	m          sync.Mutex
+	counts     map[string]int64
+	total      int64
+	suppressed int64
+	closed     bool
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSelfReport(h *Hook, host, apiKey string, options Options) *selfReport {
+	stream := options.Stream()
+	stream.Service = options.SelfReportService
+	if stream.Service == "" {
+		stream.Service = DefaultSelfReportService
+	}
+	r := &selfReport{
+		hook:     h,
+		interval: options.SelfReport,
+		counts:   map[string]int64{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	r.client = intake.New(intake.Config{
+		Host:           host,
+		APIKey:         apiKey,
+		MaxRetry:       1,
+		JSON:           true,
+		Stream:         stream,
+		FlushPolicy:    intake.MaxEntries(1),
+		Clock:          options.Clock,
+		DryRun:         options.DryRun,
+		AllowedSites:   options.AllowedSites,
+		HTTPClient:     options.HTTPClient,
+		RequestTimeout: options.RequestTimeout,
+		Proxy:          options.Proxy,
+		TLSConfig:      options.TLSConfig,
+		Protocol:       options.Protocol,
+		AgentAddr:      options.AgentAddr,
+		Exporter:       options.Exporter,
+	})
+	go r.run()
+	return r
+}
+
+func (r *selfReport) run() {
+	defer close(r.stopped)
+	for {
+		select {
+		case <-r.hook.after(r.interval):
+			r.ship()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// add - count an error in the next summary
+func (r *selfReport) add(err error) {
+	msg := err.Error()
+	if len(msg) > maxSelfReportMessage {
+		msg = msg[:maxSelfReportMessage]
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.closed {
+		return
+	}
+	r.total++
+	if _, ok := r.counts[msg]; !ok && len(r.counts) == maxSelfReportKinds {
+		r.suppressed++
+		return
+	}
+	r.counts[msg]++
+}
+
+// ship - push the summary of the errors since the last one, if any
+func (r *selfReport) ship() {
+	r.m.Lock()
+	if r.total == 0 {
+		r.m.Unlock()
+		return
+	}
+	summary := map[string]interface{}{
+		"errors":     r.total,
+		"messages":   r.counts,
+		"suppressed": r.suppressed,
+	}
+	r.counts, r.total, r.suppressed = map[string]int64{}, 0, 0
+	r.m.Unlock()
+
+	line, err := json.Marshal(map[string]interface{}{
+		"message":       SelfReportMessage,
+		"status":        "error",
+		"time":          r.hook.now().Format(time.RFC3339Nano),
+		SelfReportField: summary,
+	})
+	if err == nil {
+		err = r.client.Push(line)
+	}
+	if err != nil {
+		r.client.Debugf("Unable to ship self report, %v", err)
+	}
+}
+
+// stop - ship the last summary and close the client before ctx is done
+func (r *selfReport) stop(ctx context.Context) {
+	r.once.Do(func() { close(r.done) })
+	<-r.stopped
+	r.ship()
+	r.m.Lock()
+	r.closed = true
+	r.m.Unlock()
+	if err := r.client.Close(ctx); err != nil {
+		r.client.Debugf("Unable to close self report, %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSelfReport(t *testing.T) {
+	var m sync.Mutex
+	var reports []string
+	var errs int
+	hook := New("key",
+		WithBatchTimeout(time.Hour),
+		WithMaxRetry(0),
+		WithService("api"),
+		WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			if b.Stream.Service != DefaultSelfReportService {
+				return errors.New("intake down")
+			}
+			m.Lock()
+			defer m.Unlock()
+			for _, line := range b.Lines {
+				reports = append(reports, string(line))
+			}
+			return nil
+		})),
+		WithOnError(func(error) {
+			m.Lock()
+			defer m.Unlock()
+			errs++
+		}),
+		WithOption(func(o *Options) {
+			o.SelfReport = time.Hour
+			o.FileAttributes = map[string]string{"machine_id": filepath.Join(t.TempDir(), "missing")}
+		}),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Message: "one", Level: logrus.InfoLevel}))
+	equals(t, true, errors.Is(hook.Flush(), intake.ErrNotDelivered))
+	// the last summary is shipped on close
+	ok(t, hook.Close(context.Background()))
+
+	m.Lock()
+	defer m.Unlock()
+	equals(t, 2, errs)
+	equals(t, 1, len(reports))
+	var report struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Errors  struct {
+			Errors     int64            `json:"errors"`
+			Messages   map[string]int64 `json:"messages"`
+			Suppressed int64            `json:"suppressed"`
+		} `json:"hook_errors"`
+	}
+	ok(t, json.Unmarshal([]byte(reports[0]), &report))
+	equals(t, SelfReportMessage, report.Message)
+	equals(t, "error", report.Status)
+	equals(t, int64(2), report.Errors.Errors)
+	equals(t, int64(1), report.Errors.Messages["intake down"])
+	equals(t, 2, len(report.Errors.Messages))
+}
+
+func TestSelfReportLimits(t *testing.T) {
+	var m sync.Mutex
+	var reports []string
+	hook := New("key", WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		m.Lock()
+		defer m.Unlock()
+		for _, line := range b.Lines {
+			reports = append(reports, string(line))
+		}
+		return nil
+	})), WithOption(func(o *Options) { o.SelfReport = time.Hour }))
+	for i := 0; i < maxSelfReportKinds+2; i++ {
+		hook.self.add(fmt.Errorf("error %d", i))
+	}
+	hook.self.add(errors.New("error 0"))
+	ok(t, hook.Close(context.Background()))
+	// closed, errors are not counted anymore
+	hook.self.add(errors.New("late"))
+	equals(t, int64(0), hook.self.total)
+
+	m.Lock()
+	defer m.Unlock()
+	equals(t, 1, len(reports))
+	var report struct {
+		Errors map[string]interface{} `json:"hook_errors"`
+	}
+	ok(t, json.Unmarshal([]byte(reports[0]), &report))
+	equals(t, float64(maxSelfReportKinds+3), report.Errors["errors"])
+	equals(t, float64(2), report.Errors["suppressed"])
+	equals(t, float64(2), report.Errors["messages"].(map[string]interface{})["error 0"])
+}
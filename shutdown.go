@@ -0,0 +1,47 @@
+package datadog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+// ShutdownReport tells what happened to the entries left when the hook was
+// shut down, to log in a single line
+type ShutdownReport struct {
+	// Flushed - entries delivered while shutting down
+	Flushed int64
+	// Batches - batches delivered while shutting down
+	Batches int64
+	// Dropped - entries dropped while shutting down
+	Dropped int64
+	// Fallback - entries of the dropped written to Options.Fallback
+	Fallback int64
+	// Spooled - entries written to the spool directory, not counted as
+	// dropped
+	Spooled int64
+	// Remaining - entries neither delivered nor dropped when Shutdown
+	// returned, e.g. once ctx was done
+	Remaining int64
+	// Duration - how long shutting down took
+	Duration time.Duration
+}
+
+func newShutdownReport(before, after intake.Stats, d time.Duration) ShutdownReport {
+	return ShutdownReport{
+		Flushed:   after.Delivered - before.Delivered,
+		Batches:   after.Batches - before.Batches,
+		Dropped:   after.Dropped - before.Dropped,
+		Fallback:  after.Fallback - before.Fallback,
+		Spooled:   after.Spooled - before.Spooled,
+		Remaining: after.Queued,
+		Duration:  d,
+	}
+}
+
+// String - the report as a log line
+func (r ShutdownReport) String() string {
+	return fmt.Sprintf("shutdown in %v: %d entries flushed in %d batches, %d dropped (%d to fallback), %d spooled, %d remaining",
+		r.Duration, r.Flushed, r.Batches, r.Dropped, r.Fallback, r.Spooled, r.Remaining)
+}
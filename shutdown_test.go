@@ -0,0 +1,51 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestShutdown(t *testing.T) {
+	var fallback bytes.Buffer
+	var fail atomic.Bool
+	hook := New("key",
+		WithMaxRetry(1),
+		WithOption(func(o *Options) { o.Fallback = &fallback }),
+		WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			if fail.Load() {
+				return errors.New("down")
+			}
+			return nil
+		})),
+	)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "before", Data: logrus.Fields{}}))
+	ok(t, hook.Flush())
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "two", Data: logrus.Fields{}}))
+	ok(t, hook.Flush())
+	fail.Store(true)
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "three", Data: logrus.Fields{}}))
+
+	r, err := hook.Shutdown(context.Background())
+	equals(t, true, errors.Is(err, intake.ErrNotDelivered))
+	r.Duration = 0
+	equals(t, ShutdownReport{Dropped: 1, Fallback: 1}, r)
+	equals(t, "shutdown in 0s: 0 entries flushed in 0 batches, 1 dropped (1 to fallback), 0 spooled, 0 remaining", r.String())
+}
+
+func TestShutdownFlushed(t *testing.T) {
+	hook := New("key", WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}}))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "two", Data: logrus.Fields{}}))
+	r, err := hook.Shutdown(context.Background())
+	ok(t, err)
+	equals(t, int64(2), r.Flushed)
+	equals(t, int64(1), r.Batches)
+	equals(t, int64(0), r.Remaining)
+}
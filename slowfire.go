@@ -0,0 +1,45 @@
+package datadog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultFireBudget - time formatting and queuing an entry may take in
+	// Fire before it counts as slow
+	DefaultFireBudget = time.Millisecond
+
+	// slowFireWarnInterval - the debug log warns about slow fires at most this often
+	slowFireWarnInterval = time.Minute
+)
+
+// fireBudget - the budget of Fire, disabled if negative
+func (o Options) fireBudget() time.Duration {
+	if o.FireBudget == 0 {
+		return DefaultFireBudget
+	}
+	return o.FireBudget
+}
+
+// timeFire - account the time shipping an entry took since start, counting
+// it as a slow fire over the budget unless Strict made it wait for delivery
+func (h *Hook) timeFire(start time.Time) {
+	d := h.now().Sub(start)
+	atomic.AddInt64(&h.stats.fireTime, int64(d))
+	budget := h.options.fireBudget()
+	if budget < 0 || d <= budget || h.options.Strict {
+		return
+	}
+	atomic.AddInt64(&h.stats.slowFires, 1)
+	if !h.client.Debug() {
+		return
+	}
+	now := h.now().UnixNano()
+	last := atomic.LoadInt64(&h.stats.slowWarned)
+	if now-last < int64(slowFireWarnInterval) || !atomic.CompareAndSwapInt64(&h.stats.slowWarned, last, now) {
+		return
+	}
+	h.client.Debugf("Slow Fire, %v formatting and queuing an entry over the budget of %v, %d slow so far",
+		d, budget, atomic.LoadInt64(&h.stats.slowFires))
+}
@@ -0,0 +1,47 @@
+package datadog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// slowFormatter advances the clock while formatting entries whose message is "slow"
+type slowFormatter struct {
+	clock *fakeClock
+}
+
+func (f slowFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Message == "slow" {
+		f.clock.advance(5 * time.Millisecond)
+	}
+	return (&logrus.JSONFormatter{}).Format(entry)
+}
+
+func TestFireBudget(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	fire := func(options Options, messages ...string) Stats {
+		options.Clock = clock
+		options.Exporter = intake.ExporterFunc(func(*intake.Batch) error { return nil })
+		hook := NewHook(DatadogUSHost, "key", time.Hour, 0, logrus.InfoLevel, slowFormatter{clock}, options)
+		for _, msg := range messages {
+			ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: msg, Data: logrus.Fields{}}))
+		}
+		ok(t, hook.Close(context.Background()))
+		return hook.Stats()
+	}
+
+	stats := fire(Options{}, "fast", "slow", "slow")
+	equals(t, int64(2), stats.SlowFires)
+	equals(t, 10*time.Millisecond, stats.FireTime)
+
+	stats = fire(Options{FireBudget: 10 * time.Millisecond}, "slow")
+	equals(t, int64(0), stats.SlowFires)
+	equals(t, 5*time.Millisecond, stats.FireTime)
+
+	stats = fire(Options{FireBudget: -1}, "slow")
+	equals(t, int64(0), stats.SlowFires)
+}
@@ -0,0 +1,118 @@
+package datadog
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SnapshotMessage - message of the system snapshots accompanying the
+	// batches holding errors
+	SnapshotMessage = "system snapshot"
+	// SnapshotField - field of the snapshot holding the system metrics
+	SnapshotField = "system"
+)
+
+// snapshot - line of a snapshot of the system for the batches holding errors,
+// see Options.SystemSnapshot, nil for the others
+func (h *Hook) snapshot(info intake.BatchInfo) []byte {
+	if info.MaxSeverity < intake.SeverityError {
+		return nil
+	}
+	entry := &logrus.Entry{
+		Time:    h.now(),
+		Level:   logrus.InfoLevel,
+		Message: SnapshotMessage,
+		Data:    logrus.Fields{SnapshotField: systemSnapshot()},
+	}
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		h.client.Debugf("Unable to format system snapshot, %v", err)
+		return nil
+	}
+	return line
+}
+
+// systemSnapshot - load average, memory and goroutines of the process and of
+// the host where known, without stopping the world as runtime.ReadMemStats
+func systemSnapshot() map[string]interface{} {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/objects:bytes"},
+	}
+	metrics.Read(samples)
+	system := map[string]interface{}{
+		"goroutines": runtime.NumGoroutine(),
+	}
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		system["process_memory"] = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		system["heap"] = samples[1].Value.Uint64()
+	}
+	if b, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if load, ok := parseLoadAvg(b); ok {
+			system["load1"], system["load5"], system["load15"] = load[0], load[1], load[2]
+		}
+	}
+	if b, err := os.ReadFile("/proc/meminfo"); err == nil {
+		if total, available, ok := parseMemInfo(b); ok {
+			system["memory_total"], system["memory_available"] = total, available
+		}
+	}
+	return system
+}
+
+// parseLoadAvg - the 1, 5 and 15 minutes load averages of /proc/loadavg
+func parseLoadAvg(b []byte) ([3]float64, bool) {
+	var load [3]float64
+	fields := bytes.Fields(b)
+	if len(fields) < len(load) {
+		return load, false
+	}
+	for i := range load {
+		v, err := strconv.ParseFloat(string(fields[i]), 64)
+		if err != nil {
+			return load, false
+		}
+		load[i] = v
+	}
+	return load, true
+}
+
+// parseMemInfo - the total and available memory of /proc/meminfo in bytes
+func parseMemInfo(b []byte) (int64, int64, bool) {
+	var total, available int64 = -1, -1
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := bytes.Fields(s.Bytes())
+		if len(fields) < 2 {
+			continue
+		}
+		var v *int64
+		switch string(fields[0]) {
+		case "MemTotal:":
+			v = &total
+		case "MemAvailable:":
+			v = &available
+		default:
+			continue
+		}
+		n, err := strconv.ParseInt(string(fields[1]), 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if len(fields) > 2 && string(fields[2]) == "kB" {
+			n *= 1024
+		}
+		*v = n
+	}
+	return total, available, total >= 0 && available >= 0
+}
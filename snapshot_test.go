@@ -0,0 +1,70 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSystemSnapshot(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][][]byte
+	hook := New("key", WithBatchTimeout(time.Hour), WithSystemSnapshot(), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, b.Lines)
+		return nil
+	})))
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "started", Data: logrus.Fields{}}))
+	ok(t, hook.Flush())
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: "payment failed", Data: logrus.Fields{}}))
+	ok(t, hook.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	equals(t, 2, len(batches))
+	// no snapshot without errors
+	equals(t, 1, len(batches[0]))
+	equals(t, 2, len(batches[1]))
+	var snapshot struct {
+		Msg    string             `json:"msg"`
+		Level  string             `json:"level"`
+		System map[string]float64 `json:"system"`
+	}
+	ok(t, json.Unmarshal(batches[1][1], &snapshot))
+	equals(t, SnapshotMessage, snapshot.Msg)
+	equals(t, "info", snapshot.Level)
+	equals(t, true, snapshot.System["goroutines"] > 0)
+	equals(t, true, snapshot.System["process_memory"] > 0)
+	equals(t, int64(3), hook.Stats().Client.Delivered)
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	load, parsed := parseLoadAvg([]byte("0.52 1.05 2.50 2/1234 5678\n"))
+	equals(t, true, parsed)
+	equals(t, [3]float64{0.52, 1.05, 2.5}, load)
+	_, parsed = parseLoadAvg([]byte("0.52 1.05"))
+	equals(t, false, parsed)
+	_, parsed = parseLoadAvg([]byte("a b c"))
+	equals(t, false, parsed)
+}
+
+func TestParseMemInfo(t *testing.T) {
+	total, available, parsed := parseMemInfo([]byte("MemTotal:       16302028 kB\nMemFree:         1200000 kB\nMemAvailable:    8151014 kB\n"))
+	equals(t, true, parsed)
+	equals(t, int64(16302028*1024), total)
+	equals(t, int64(8151014*1024), available)
+	_, _, parsed = parseMemInfo([]byte("MemTotal:       16302028 kB\n"))
+	equals(t, false, parsed)
+}
+
+func TestWithSystemSnapshot(t *testing.T) {
+	c := config{}
+	WithSystemSnapshot()(&c)
+	equals(t, true, c.options.SystemSnapshot)
+}
@@ -0,0 +1,49 @@
+package datadog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SourceGin - ddsource of logs from the gin web framework
+	SourceGin = "gin"
+	// SourceGRPC - ddsource of logs from grpc-go
+	SourceGRPC = "grpc"
+	// SourceSQL - ddsource of logs from database/sql and friends
+	SourceSQL = "sql"
+)
+
+// frameworkSources - built-in ddsource of well-known frameworks and their aliases
+var frameworkSources = map[string]string{
+	"gin":          SourceGin,
+	"gin-gonic":    SourceGin,
+	"grpc":         SourceGRPC,
+	"grpc-go":      SourceGRPC,
+	"sql":          SourceSQL,
+	"database/sql": SourceSQL,
+	"sqlx":         SourceSQL,
+	"gorm":         SourceSQL,
+}
+
+// source - ddsource of the entry derived from Options.SourceField, or def
+// when the field is missing or names an unknown framework
+func (o Options) source(entry *logrus.Entry, def string) string {
+	if o.SourceField == "" {
+		return def
+	}
+	v, ok := entry.Data[o.SourceField]
+	if !ok {
+		return def
+	}
+	name := strings.ToLower(fmt.Sprint(v))
+	if s, ok := o.Sources[name]; ok {
+		return s
+	}
+	if s, ok := frameworkSources[name]; ok {
+		return s
+	}
+	return def
+}
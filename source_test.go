@@ -0,0 +1,27 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSource(t *testing.T) {
+	o := Options{SourceField: "framework", Sources: map[string]string{"echo": "echo", "gorm": "gorm"}}
+	for _, tc := range []struct {
+		fields logrus.Fields
+		exp    string
+	}{
+		{logrus.Fields{}, "go"},
+		{logrus.Fields{"framework": "gin"}, SourceGin},
+		{logrus.Fields{"framework": "GRPC-Go"}, SourceGRPC},
+		{logrus.Fields{"framework": "database/sql"}, SourceSQL},
+		{logrus.Fields{"framework": "echo"}, "echo"},
+		{logrus.Fields{"framework": "gorm"}, "gorm"},
+		{logrus.Fields{"framework": "unknown"}, "go"},
+	} {
+		equals(t, tc.exp, o.source(&logrus.Entry{Data: tc.fields}, "go"))
+	}
+
+	equals(t, "go", Options{}.source(&logrus.Entry{Data: logrus.Fields{"framework": "gin"}}, "go"))
+}
@@ -0,0 +1,78 @@
+package datadog
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+// Stats count what a hook did since it was created
+type Stats struct {
+	// Fired - entries given to Fire
+	Fired int64
+	// Skipped - entries not shipped because of the level, sampling or rules
+	Skipped int64
+	// Muted - entries dropped because their fingerprint is muted
+	Muted int64
+	// Summarized - debug entries counted in summaries instead of shipped
+	Summarized int64
+	// FormatCached - entries shipped with the line formatted for the same
+	// entry before, see Options.FormatCache
+	FormatCached int64
+	// OverQuota - entries not shipped as their level was over its quota,
+	// see Options.LevelQuotas
+	OverQuota int64
+	// Invalid - entries whose formatted line was refused by
+	// Options.VerifyFormat, also counted in Skipped
+	Invalid int64
+	// Oversized - entries not shipped as they were above the entry limit,
+	// see Options.Oversize
+	Oversized int64
+	// Split - entries shipped in several parts by OversizeSplit
+	Split int64
+	// Failed - entries Fire returned an error for
+	Failed int64
+	// SlowFires - entries Fire took longer than Options.FireBudget to format
+	// and queue
+	SlowFires int64
+	// FireTime - time spent formatting and queuing entries in Fire
+	FireTime time.Duration
+	// AdaptiveRate - active sampling rate of the entries less severe than
+	// warnings, lowered by Options.AdaptiveSampling while the intake throttles
+	AdaptiveRate float64
+	// Client - counters of the batching and delivery
+	Client intake.Stats
+	// Destinations - counters of the clients of Options.Destinations by
+	// name, nil without destinations
+	Destinations map[string]intake.Stats
+}
+
+// stats - counters of a hook, only updated atomically
+type stats struct {
+	fired, skipped, muted, summarized, failed int64
+	formatCached                              int64
+	slowFires, fireTime, slowWarned           int64
+	overQuota, oversized, split, invalid      int64
+}
+
+// Stats - snapshot of the counters, safe to call at any time
+func (h *Hook) Stats() Stats {
+	return Stats{
+		Fired:        atomic.LoadInt64(&h.stats.fired),
+		Skipped:      atomic.LoadInt64(&h.stats.skipped),
+		Muted:        atomic.LoadInt64(&h.stats.muted),
+		Summarized:   atomic.LoadInt64(&h.stats.summarized),
+		Failed:       atomic.LoadInt64(&h.stats.failed),
+		Invalid:      atomic.LoadInt64(&h.stats.invalid),
+		Oversized:    atomic.LoadInt64(&h.stats.oversized),
+		Split:        atomic.LoadInt64(&h.stats.split),
+		FormatCached: atomic.LoadInt64(&h.stats.formatCached),
+		SlowFires:    atomic.LoadInt64(&h.stats.slowFires),
+		OverQuota:    atomic.LoadInt64(&h.stats.overQuota),
+		FireTime:     time.Duration(atomic.LoadInt64(&h.stats.fireTime)),
+		AdaptiveRate: h.AdaptiveRate(),
+		Client:       h.client.Stats(),
+		Destinations: h.destinationStats(),
+	}
+}
@@ -0,0 +1,97 @@
+package datadog
+
+import (
+	"context"
+	"io/ioutil"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// newStressHook creates a hook delivering to an exporter counting lines
+func newStressHook(lines *int64) *Hook {
+	return NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Service:     "stress",
+		Tee:         ioutil.Discard,
+		FlushPolicy: intake.MaxEntries(100),
+		Exporter: intake.ExporterFunc(func(b *intake.Batch) error {
+			atomic.AddInt64(lines, int64(len(b.Lines)))
+			return nil
+		}),
+	})
+}
+
+// TestFireStress fires from hundreds of goroutines while settings change,
+// meant to be run with the race detector
+func TestFireStress(t *testing.T) {
+	goroutines, entries := 200, 50
+	if testing.Short() {
+		goroutines, entries = 50, 10
+	}
+	var lines int64
+	hook := newStressHook(&lines)
+
+	stop := make(chan struct{})
+	var tweaks sync.WaitGroup
+	tweaks.Add(1)
+	go func() {
+		defer tweaks.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			hook.SetRules(Rules{Drop: []Filter{{Field: "n", Pattern: regexp.MustCompile(`^7$`)}}})
+			hook.SetDebug(false)
+			hook.SetSampling(1)
+			hook.SetLevel(logrus.InfoLevel)
+			_ = hook.Stats()
+			_ = hook.String()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < entries; i++ {
+				entry := &logrus.Entry{Message: "stress", Level: logrus.InfoLevel, Data: logrus.Fields{"g": g, "n": i}}
+				if err := hook.Fire(entry); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(stop)
+	tweaks.Wait()
+	ok(t, hook.Close(context.Background()))
+
+	s := hook.Stats()
+	equals(t, int64(goroutines*entries), s.Fired)
+	equals(t, s.Fired-s.Skipped, s.Client.Delivered)
+	equals(t, s.Client.Delivered, atomic.LoadInt64(&lines))
+	equals(t, int64(0), s.Failed)
+}
+
+func BenchmarkFireParallel(b *testing.B) {
+	var lines int64
+	hook := newStressHook(&lines)
+	defer hook.Close(context.Background())
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		entry := &logrus.Entry{Message: "benchmark", Level: logrus.InfoLevel, Data: logrus.Fields{"user": "bob", "n": 42}}
+		for pb.Next() {
+			if err := hook.Fire(entry); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
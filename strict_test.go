@@ -0,0 +1,25 @@
+package datadog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStrictDeadline(t *testing.T) {
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		Strict:   true,
+		Exporter: intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	defer hook.Close(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := hook.Fire(&logrus.Entry{Message: "hello", Level: logrus.InfoLevel, Context: ctx})
+	equals(t, context.DeadlineExceeded, err)
+	assert(t, time.Since(start) < time.Second, "Fire blocked for %s", time.Since(start))
+}
@@ -0,0 +1,98 @@
+package datadog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SummaryMessage - message of the entries summarizing debug entries
+	SummaryMessage = "debug summary"
+	// SummaryField - field of the summary holding the count of every message template
+	SummaryField = "debug_summary"
+	// SummaryEntriesField - field of the summary holding the count of entries
+	SummaryEntriesField = "debug_entries"
+
+	// maxSummaryMessages - distinct messages counted per summary, the others
+	// are counted under intake.BreakdownOther
+	maxSummaryMessages = 1000
+)
+
+// summary - count of the debug entries by message template, shipped periodically
+type summary struct {
+	hook     *Hook
+	interval time.Duration
+
+	m      sync.Mutex
+	counts map[string]int
+	total  int
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSummary(h *Hook, interval time.Duration) *summary {
+	s := &summary{
+		hook:     h,
+		interval: interval,
+		counts:   map[string]int{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *summary) add(entry *logrus.Entry) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	msg := Template(entry.Message)
+	if _, ok := s.counts[msg]; !ok && len(s.counts) >= maxSummaryMessages {
+		msg = intake.BreakdownOther
+	}
+	s.counts[msg]++
+	s.total++
+}
+
+func (s *summary) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-s.hook.after(s.interval):
+			s.flush()
+		case <-s.done:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush - ship the summary of the debug entries counted since the last one
+func (s *summary) flush() {
+	s.m.Lock()
+	counts, total := s.counts, s.total
+	s.counts, s.total = map[string]int{}, 0
+	s.m.Unlock()
+	if total == 0 {
+		return
+	}
+	entry := &logrus.Entry{
+		Time:    s.hook.now(),
+		Level:   logrus.InfoLevel,
+		Message: SummaryMessage,
+		Data:    logrus.Fields{SummaryField: counts, SummaryEntriesField: total},
+	}
+	if err := s.hook.ship(entry); err != nil {
+		s.hook.client.Debugf("Unable to ship debug summary, %v", err)
+	}
+}
+
+// stop - ship the last summary and stop
+func (s *summary) stop() {
+	s.once.Do(func() { close(s.done) })
+	<-s.stopped
+}
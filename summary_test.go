@@ -0,0 +1,64 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+// lockedBuffer is a tee safe to read while the hook writes to it
+type lockedBuffer struct {
+	m sync.Mutex
+	b strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.String()
+}
+
+func TestSummarizeDebug(t *testing.T) {
+	var tee lockedBuffer
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.TraceLevel, &logrus.JSONFormatter{}, Options{
+		Tee:            &tee,
+		SummarizeDebug: time.Hour,
+		Exporter:       intake.ExporterFunc(func(*intake.Batch) error { return nil }),
+	})
+	for i := 0; i < 3; i++ {
+		ok(t, hook.Fire(&logrus.Entry{Message: "cache miss", Level: logrus.DebugLevel}))
+	}
+	ok(t, hook.Fire(&logrus.Entry{Message: "cache hit", Level: logrus.TraceLevel}))
+	ok(t, hook.Fire(&logrus.Entry{Message: "served", Level: logrus.InfoLevel}))
+	// only the info entry is shipped right away
+	equals(t, 1, strings.Count(tee.String(), "\n"))
+
+	// the last summary is shipped on Close
+	ok(t, hook.Close(context.Background()))
+
+	lines := strings.Split(strings.TrimSpace(tee.String()), "\n")
+	equals(t, 2, len(lines))
+	var summary struct {
+		Msg     string         `json:"msg"`
+		Level   string         `json:"level"`
+		Counts  map[string]int `json:"debug_summary"`
+		Entries int            `json:"debug_entries"`
+	}
+	ok(t, json.Unmarshal([]byte(lines[1]), &summary))
+	equals(t, SummaryMessage, summary.Msg)
+	equals(t, "info", summary.Level)
+	equals(t, map[string]int{"cache miss": 3, "cache hit": 1}, summary.Counts)
+	equals(t, 4, summary.Entries)
+}
@@ -0,0 +1,97 @@
+package datadog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TagsField - default entry field holding Datadog tags of the entry, either
+// a []string of "key:value" tags, a comma separated string of them or a map
+// of tag keys to values
+const TagsField = "ddtags"
+
+// entryTags - remove field from the entry and merge its tags into the
+// stream. Entry tags take precedence over the stream tags of the same key,
+// the stream tags of other keys are kept.
+func entryTags(entry *logrus.Entry, stream Stream, field string) (*logrus.Entry, Stream) {
+	v, ok := entry.Data[field]
+	if !ok {
+		return entry, stream
+	}
+	stripped := *entry
+	stripped.Data = make(logrus.Fields, len(entry.Data)-1)
+	for k, v := range entry.Data {
+		if k != field {
+			stripped.Data[k] = v
+		}
+	}
+
+	tags := tagList(v)
+	if len(tags) == 0 {
+		return &stripped, stream
+	}
+	keys := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		keys[tagKey(tag)] = true
+	}
+	merged := make([]string, 0, len(stream.Tags)+len(tags))
+	for _, tag := range stream.Tags {
+		if !keys[tagKey(tag)] {
+			merged = append(merged, tag)
+		}
+	}
+	stream.Tags = append(merged, tags...)
+	return &stripped, stream
+}
+
+// tagList - the tags of a TagsField value, maps are sorted by key so entries
+// with the same tags share a batch
+func tagList(v interface{}) []string {
+	switch tags := v.(type) {
+	case []string:
+		return tags
+	case []interface{}:
+		list := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			list = append(list, fmt.Sprint(tag))
+		}
+		return list
+	case map[string]string:
+		list := make([]string, 0, len(tags))
+		for k, v := range tags {
+			list = append(list, k+":"+v)
+		}
+		sort.Strings(list)
+		return list
+	case map[string]interface{}:
+		list := make([]string, 0, len(tags))
+		for k, v := range tags {
+			list = append(list, k+":"+fmt.Sprint(v))
+		}
+		sort.Strings(list)
+		return list
+	case logrus.Fields:
+		return tagList(map[string]interface{}(tags))
+	case string:
+		var list []string
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				list = append(list, tag)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// tagKey - the key of a "key:value" tag, or the whole tag without a value
+func tagKey(tag string) string {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
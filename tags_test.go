@@ -0,0 +1,49 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEntryTags(t *testing.T) {
+	stream := Stream{Service: "api", Tags: []string{"env:prod", "team:core"}}
+
+	entry := &logrus.Entry{Message: "hello", Data: logrus.Fields{"user": "bob"}}
+	e, s := entryTags(entry, stream, TagsField)
+	assert(t, e == entry, "entry without tags should be kept as is")
+	equals(t, stream, s)
+
+	entry = &logrus.Entry{Message: "hello", Data: logrus.Fields{"user": "bob", TagsField: []string{"env:staging", "canary"}}}
+	e, s = entryTags(entry, stream, TagsField)
+	equals(t, logrus.Fields{"user": "bob"}, e.Data)
+	equals(t, []string{"team:core", "env:staging", "canary"}, s.Tags)
+	// the original entry and stream are shared and must stay untouched
+	equals(t, 2, len(entry.Data))
+	equals(t, []string{"env:prod", "team:core"}, stream.Tags)
+
+	entry = &logrus.Entry{Data: logrus.Fields{TagsField: map[string]interface{}{"version": 2, "env": "dev"}}}
+	_, s = entryTags(entry, stream, TagsField)
+	equals(t, []string{"team:core", "env:dev", "version:2"}, s.Tags)
+
+	entry = &logrus.Entry{Data: logrus.Fields{TagsField: 42}}
+	e, s = entryTags(entry, stream, TagsField)
+	equals(t, logrus.Fields{}, e.Data)
+	equals(t, stream, s)
+}
+
+func TestEntryTagsField(t *testing.T) {
+	stream := Stream{Tags: []string{"env:prod", "team:core"}}
+	entry := &logrus.Entry{Data: logrus.Fields{"tags": "team:payments,tier:db", TagsField: "env:dev"}}
+	e, s := entryTags(entry, stream, "tags")
+	equals(t, logrus.Fields{TagsField: "env:dev"}, e.Data)
+	equals(t, []string{"env:prod", "team:payments", "tier:db"}, s.Tags)
+}
+
+func TestTagList(t *testing.T) {
+	equals(t, []string{"a:1", "b"}, tagList([]interface{}{"a:1", "b"}))
+	equals(t, []string{"a:1", "b:2"}, tagList(map[string]string{"b": "2", "a": "1"}))
+	equals(t, []string{"a:1", "b:2"}, tagList(logrus.Fields{"b": 2, "a": 1}))
+	equals(t, []string{"a:1", "b"}, tagList(" a:1, b ,"))
+	equals(t, []string(nil), tagList(1.5))
+}
@@ -0,0 +1,56 @@
+package datadog
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TemplateField - field holding the message with its variable parts replaced
+	TemplateField = "message_template"
+	// FingerprintField - field holding a short hash of the message template
+	FingerprintField = "fingerprint"
+)
+
+// templatePatterns - variable parts of messages, from the most specific, in order
+var templatePatterns = []struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{16,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+(\.\d+)?`), "<num>"},
+}
+
+// Template - the message with its numbers, UUIDs, IPs and hex identifiers
+// replaced by placeholders, so messages differing only by them group together
+func Template(msg string) string {
+	for _, p := range templatePatterns {
+		msg = p.pattern.ReplaceAllString(msg, p.placeholder)
+	}
+	return msg
+}
+
+// Fingerprint - short stable identifier of a message template
+func Fingerprint(template string) string {
+	sum := sha1.Sum([]byte(template))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fingerprint - copy of the entry carrying the template and fingerprint of its message
+func fingerprint(entry *logrus.Entry) *logrus.Entry {
+	template := Template(entry.Message)
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+2)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[TemplateField] = template
+	e.Data[FingerprintField] = Fingerprint(template)
+	return &e
+}
@@ -0,0 +1,31 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTemplate(t *testing.T) {
+	equals(t, "user <num> logged in from <ip>", Template("user 42 logged in from 10.0.0.12"))
+	equals(t, "order <uuid> took <num>ms", Template("order 0f8fad5b-d9cb-469f-a165-70867728950e took 12.5ms"))
+	equals(t, "object <hex> at <hex>", Template("object 9f86d081884c7d659a2feaa0c55ad015 at 0xc000123"))
+	equals(t, "no variables here", Template("no variables here"))
+}
+
+func TestFingerprint(t *testing.T) {
+	a := fingerprint(&logrus.Entry{Message: "retry 1 of 3", Data: logrus.Fields{"job": "sync"}})
+	b := fingerprint(&logrus.Entry{Message: "retry 2 of 3"})
+	equals(t, "retry <num> of <num>", a.Data[TemplateField])
+	equals(t, a.Data[FingerprintField], b.Data[FingerprintField])
+	equals(t, 16, len(a.Data[FingerprintField].(string)))
+	equals(t, "sync", a.Data["job"])
+	assert(t, a.Data[FingerprintField] != Fingerprint("other"), "fingerprints should differ")
+}
+
+func TestSummaryTemplate(t *testing.T) {
+	s := &summary{counts: map[string]int{}}
+	s.add(&logrus.Entry{Message: "fetched 3 rows"})
+	s.add(&logrus.Entry{Message: "fetched 12 rows"})
+	equals(t, map[string]int{"fetched <num> rows": 2}, s.counts)
+}
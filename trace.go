@@ -0,0 +1,50 @@
+package datadog
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TraceIDField, SpanIDField - attributes Datadog correlates logs with
+	// APM traces on
+	TraceIDField = "dd.trace_id"
+	SpanIDField  = "dd.span_id"
+)
+
+// SpanFunc - the trace and span IDs of the span ctx carries, for instance
+// from dd-trace-go:
+//
+//	func(ctx context.Context) (uint64, uint64, bool) {
+//		span, ok := tracer.SpanFromContext(ctx)
+//		if !ok {
+//			return 0, 0, false
+//		}
+//		return span.Context().TraceID(), span.Context().SpanID(), true
+//	}
+type SpanFunc func(ctx context.Context) (traceID, spanID uint64, ok bool)
+
+// correlate - the entry with the IDs of the span of its context, as is when
+// it has no span or already carries a trace ID
+func correlate(entry *logrus.Entry, span SpanFunc) *logrus.Entry {
+	if entry.Context == nil {
+		return entry
+	}
+	if _, ok := entry.Data[TraceIDField]; ok {
+		return entry
+	}
+	traceID, spanID, ok := span(entry.Context)
+	if !ok || traceID == 0 {
+		return entry
+	}
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+2)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[TraceIDField] = strconv.FormatUint(traceID, 10)
+	e.Data[SpanIDField] = strconv.FormatUint(spanID, 10)
+	return &e
+}
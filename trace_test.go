@@ -0,0 +1,52 @@
+package datadog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+type spanKey struct{}
+
+func spanFromContext(ctx context.Context) (uint64, uint64, bool) {
+	ids, ok := ctx.Value(spanKey{}).([2]uint64)
+	return ids[0], ids[1], ok
+}
+
+func TestCorrelate(t *testing.T) {
+	ctx := context.WithValue(context.Background(), spanKey{}, [2]uint64{1234, 56})
+	entry := &logrus.Entry{Context: ctx, Data: logrus.Fields{"user": "bob"}}
+	e := correlate(entry, spanFromContext)
+	equals(t, logrus.Fields{"user": "bob", TraceIDField: "1234", SpanIDField: "56"}, e.Data)
+	// the original entry is shared and must stay untouched
+	equals(t, 1, len(entry.Data))
+
+	for _, entry := range []*logrus.Entry{
+		{Data: logrus.Fields{}},
+		{Context: context.Background(), Data: logrus.Fields{}},
+		{Context: ctx, Data: logrus.Fields{TraceIDField: "1"}},
+	} {
+		assert(t, correlate(entry, spanFromContext) == entry, "entry without span should be kept as is")
+	}
+}
+
+func TestWithTraceCorrelation(t *testing.T) {
+	var lines []string
+	hook := New("key",
+		WithTraceCorrelation(spanFromContext),
+		WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+			for _, line := range b.Lines {
+				lines = append(lines, string(line))
+			}
+			return nil
+		})),
+	)
+	ctx := context.WithValue(context.Background(), spanKey{}, [2]uint64{1234, 56})
+	ok(t, hook.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "one", Data: logrus.Fields{}, Context: ctx}))
+	ok(t, hook.Close(context.Background()))
+	equals(t, 1, len(lines))
+	assert(t, strings.Contains(lines[0], `"dd.span_id":"56","dd.trace_id":"1234"`), "line should carry the span, got %s", lines[0])
+}
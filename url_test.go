@@ -0,0 +1,31 @@
+package datadog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+)
+
+func TestDatadogURL(t *testing.T) {
+	options := Options{Source: "go", Service: "my api", Tags: []string{"team:a,b"}}
+	u, err := DatadogURL(options, DatadogUSHost, ProtocolV1HTTP)
+	ok(t, err)
+	equals(t, "https://"+DatadogUSHost+"/v1/input?ddsource=go&ddtags=team%3Aa_b&service=my+api", u)
+
+	options.AllowedSites = []string{"eu"}
+	u, err = DatadogURL(options, DatadogEUHost, ProtocolV1HTTP)
+	ok(t, err)
+	equals(t, "https://"+DatadogEUHost+"/v1/input?ddsource=go&ddtags=team%3Aa_b%2Cdatadog_site%3Aeu&service=my+api", u)
+	u, err = DatadogURL(options, DatadogEUHost, ProtocolV2HTTP)
+	ok(t, err)
+	equals(t, "https://"+DatadogEUHost+"/api/v2/logs", u)
+	equals(t, []string{"team:a,b"}, options.Tags)
+
+	u, err = DatadogURL(Options{}, "relay.example.com/datadog", ProtocolV1HTTP)
+	ok(t, err)
+	equals(t, "https://relay.example.com/datadog/v1/input", u)
+
+	_, err = DatadogURL(Options{}, DatadogUSTCPHost, ProtocolTCP)
+	assert(t, errors.Is(err, intake.ErrNoURL), "tcp has no url: %v", err)
+}
@@ -0,0 +1,197 @@
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// AnomalyEmpty - the formatter produced no output
+	AnomalyEmpty = "empty output"
+	// AnomalyNotJSON - the output is not a JSON object while the hook sends JSON
+	AnomalyNotJSON = "output is not a JSON object"
+	// AnomalyTrailingData - the JSON object is followed by more data
+	AnomalyTrailingData = "trailing data after JSON object"
+	// AnomalyDuplicateKey - the JSON object has the same key more than once
+	AnomalyDuplicateKey = "duplicate JSON key"
+
+	// anomalyInterval - an anomaly of a kind is reported once per interval
+	anomalyInterval = time.Minute
+	// maxAnomalySample - bytes of the output kept as sample
+	maxAnomalySample = 256
+)
+
+// FormatError reports formatter output which cannot be shipped as is
+type FormatError struct {
+	Anomaly string
+	// Sample - start of the output
+	Sample string
+	// Suppressed - anomalies of the same kind not reported since the last one
+	Suppressed int
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("datadog: formatter %s: %q", e.Anomaly, e.Sample)
+}
+
+// anomalies - last report and suppressed count of every kind of anomaly
+type anomalies struct {
+	m          sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+// verify - check the formatted line, reporting anomalies to OnError at
+// most once a minute by kind. False if the line must not be shipped, as a
+// line which isn't JSON would make the intake reject its whole batch.
+func (h *Hook) verify(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		h.anomaly(AnomalyEmpty, line)
+		h.invalid(AnomalyEmpty, line)
+		return false
+	}
+	if !h.json {
+		return true
+	}
+	var anomaly string
+	if h.options.QuickVerify {
+		anomaly = jsonSanity(trimmed)
+	} else {
+		anomaly = jsonAnomaly(trimmed)
+	}
+	if anomaly == "" {
+		return true
+	}
+	h.anomaly(anomaly, line)
+	if anomaly == AnomalyDuplicateKey {
+		return true
+	}
+	h.invalid(anomaly, line)
+	return false
+}
+
+// invalid - divert a line which must not be shipped to ErrorHandler,
+// instead of the batch it would poison
+func (h *Hook) invalid(kind string, line []byte) {
+	atomic.AddInt64(&h.stats.invalid, 1)
+	if h.options.ErrorHandler == nil {
+		return
+	}
+	sample := line
+	if len(sample) > maxAnomalySample {
+		sample = sample[:maxAnomalySample]
+	}
+	// the line is in a pooled buffer
+	kept := append([]byte(nil), line...)
+	h.options.ErrorHandler(&FormatError{Anomaly: kind, Sample: string(sample)}, [][]byte{kept})
+}
+
+// jsonSanity - what is obviously wrong with a line expected to be a JSON
+// object: an object with nested brackets matching and strings closed. Much
+// cheaper than jsonAnomaly, it misses invalid values and duplicate keys.
+func jsonSanity(line []byte) string {
+	if line[0] != '{' {
+		return AnomalyNotJSON
+	}
+	var open [32]byte
+	stack := open[:0]
+	inString, escaped := false, false
+	for i, c := range line {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c < 0x20:
+				return AnomalyNotJSON
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c-2 {
+				return AnomalyNotJSON
+			}
+			if stack = stack[:len(stack)-1]; len(stack) == 0 {
+				if len(bytes.TrimSpace(line[i+1:])) > 0 {
+					return AnomalyTrailingData
+				}
+				return ""
+			}
+		}
+	}
+	return AnomalyNotJSON
+}
+
+// jsonAnomaly - what is wrong with a line expected to be a JSON object
+func jsonAnomaly(line []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return AnomalyNotJSON
+	}
+	keys := map[string]bool{}
+	duplicate := false
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return AnomalyNotJSON
+		}
+		key, _ := t.(string)
+		if keys[key] {
+			duplicate = true
+		}
+		keys[key] = true
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return AnomalyNotJSON
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return AnomalyNotJSON
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return AnomalyTrailingData
+	}
+	if duplicate {
+		return AnomalyDuplicateKey
+	}
+	return ""
+}
+
+func (h *Hook) anomaly(kind string, line []byte) {
+	if h.options.OnError == nil && h.options.OnErrorContext == nil {
+		return
+	}
+	now := h.now()
+	a := &h.anomalies
+	a.m.Lock()
+	if a.last == nil {
+		a.last, a.suppressed = map[string]time.Time{}, map[string]int{}
+	}
+	if last, ok := a.last[kind]; ok && now.Sub(last) < anomalyInterval {
+		a.suppressed[kind]++
+		a.m.Unlock()
+		return
+	}
+	suppressed := a.suppressed[kind]
+	a.last[kind], a.suppressed[kind] = now, 0
+	a.m.Unlock()
+
+	if len(line) > maxAnomalySample {
+		line = line[:maxAnomalySample]
+	}
+	h.notifyError(&FormatError{Anomaly: kind, Sample: string(line), Suppressed: suppressed})
+}
@@ -0,0 +1,126 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// brokenFormatter returns the same output whatever the entry
+type brokenFormatter struct {
+	out string
+}
+
+func (f brokenFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return []byte(f.out), nil
+}
+
+func TestJSONAnomaly(t *testing.T) {
+	equals(t, "", jsonAnomaly([]byte(`{"a":1,"b":{"a":2}}`)))
+	equals(t, AnomalyDuplicateKey, jsonAnomaly([]byte(`{"a":1,"a":2}`)))
+	equals(t, AnomalyTrailingData, jsonAnomaly([]byte(`{"a":1} garbage`)))
+	equals(t, AnomalyNotJSON, jsonAnomaly([]byte(`level=info msg=hello`)))
+	equals(t, AnomalyNotJSON, jsonAnomaly([]byte(`[1,2]`)))
+	equals(t, AnomalyNotJSON, jsonAnomaly([]byte(`{"a":1`)))
+}
+
+func TestVerifyFormat(t *testing.T) {
+	var errs []error
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		VerifyFormat: true,
+		OnError:      func(err error) { errs = append(errs, err) },
+	})
+	entry := &logrus.Entry{Message: "hello", Level: logrus.InfoLevel}
+
+	hook.formatter = brokenFormatter{`{"msg":"hello"} {"msg":"again"}`}
+	for i := 0; i < 3; i++ {
+		ok(t, hook.Fire(entry))
+	}
+	hook.formatter = brokenFormatter{"\n"}
+	ok(t, hook.Fire(entry))
+	hook.formatter = brokenFormatter{`{"msg":"hello","msg":"dup"}`}
+	ok(t, hook.Fire(entry))
+
+	// repeated anomalies of a kind are reported once per interval
+	equals(t, 3, len(errs))
+	equals(t, &FormatError{Anomaly: AnomalyTrailingData, Sample: `{"msg":"hello"} {"msg":"again"}`}, errs[0])
+	equals(t, AnomalyEmpty, errs[1].(*FormatError).Anomaly)
+	equals(t, AnomalyDuplicateKey, errs[2].(*FormatError).Anomaly)
+	// lines which aren't JSON are not shipped, duplicate keys are
+	s := hook.Stats()
+	equals(t, int64(4), s.Skipped)
+	equals(t, int64(4), s.Invalid)
+	equals(t, int64(1), s.Client.Pushed)
+
+	hook.anomalies.last[AnomalyTrailingData] = time.Now().Add(-2 * anomalyInterval)
+	hook.formatter = brokenFormatter{`{"msg":"hello"} {"msg":"again"}`}
+	ok(t, hook.Fire(entry))
+	equals(t, 2, errs[3].(*FormatError).Suppressed)
+	equals(t, `datadog: formatter trailing data after JSON object: "{\"msg\":\"hello\"} {\"msg\":\"again\"}"`, errs[3].Error())
+}
+
+func TestJSONSanity(t *testing.T) {
+	for _, line := range []string{
+		`{"a":1,"b":{"a":2}}`,
+		`{"a":"}{][\"","b":[1,{"c":[]}]}`,
+		`{"a":1,"a":2}`,
+		`{"a":1} `,
+	} {
+		equals(t, "", jsonSanity([]byte(line)))
+	}
+	equals(t, AnomalyTrailingData, jsonSanity([]byte(`{"a":1} garbage`)))
+	equals(t, AnomalyTrailingData, jsonSanity([]byte(`{"a":1}]`)))
+	for _, line := range []string{
+		`level=info msg=hello`,
+		`[1,2]`,
+		`{"a":1`,
+		`{"a":"1}`,
+		`{"a":[1}`,
+		"{\"a\":\"line\nbreak\"}",
+	} {
+		equals(t, AnomalyNotJSON, jsonSanity([]byte(line)))
+	}
+}
+
+func TestVerifyDivertsInvalid(t *testing.T) {
+	var kinds []string
+	var lines []string
+	hook := NewHook(DatadogUSHost, "key", 5*time.Second, 0, logrus.InfoLevel, &logrus.JSONFormatter{}, Options{
+		QuickVerify: true,
+		ErrorHandler: func(err error, batch [][]byte) {
+			kinds = append(kinds, err.(*FormatError).Anomaly)
+			for _, line := range batch {
+				lines = append(lines, string(line))
+			}
+		},
+	})
+	entry := &logrus.Entry{Message: "hello", Level: logrus.InfoLevel}
+	ok(t, hook.Fire(entry))
+	hook.formatter = brokenFormatter{`{"msg":"hel`}
+	ok(t, hook.Fire(entry))
+	ok(t, hook.Fire(entry))
+	hook.formatter = brokenFormatter{`{"msg":"hello"} {"msg":"again"}`}
+	ok(t, hook.Fire(entry))
+
+	// every invalid line is diverted, not only the reported ones
+	equals(t, []string{AnomalyNotJSON, AnomalyNotJSON, AnomalyTrailingData}, kinds)
+	equals(t, []string{`{"msg":"hel`, `{"msg":"hel`, `{"msg":"hello"} {"msg":"again"}`}, lines)
+	s := hook.Stats()
+	equals(t, int64(3), s.Invalid)
+	equals(t, int64(1), s.Client.Pushed)
+}
+
+func BenchmarkVerify(b *testing.B) {
+	line := []byte(`{"level":"info","msg":"request served","method":"GET","path":"/api/v1/users/42","status":200,"duration":0.0042,"time":"2024-01-02T15:04:05Z"}`)
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			jsonAnomaly(line)
+		}
+	})
+	b.Run("quick", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			jsonSanity(line)
+		}
+	})
+}
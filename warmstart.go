@@ -0,0 +1,91 @@
+package datadog
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WarmStart is a logrus hook recording the last entries fired before the
+// Datadog hook can be created, e.g. while secrets load, and replaying them
+// into it once attached, so startup logs are not lost. Attached, it hands
+// every entry to the Datadog hook, so it stays added to the logger in its
+// place.
+type WarmStart struct {
+	m       sync.Mutex
+	entries []*logrus.Entry // ring of the last entries
+	next    int             // index of the oldest entry once the ring is full
+	dropped int64
+	hook    *Hook
+}
+
+// NewWarmStart - create warm start hook keeping the last n entries, older
+// ones are dropped
+func NewWarmStart(n int) *WarmStart {
+	if n < 1 {
+		n = 1
+	}
+	return &WarmStart{entries: make([]*logrus.Entry, 0, n)}
+}
+
+// Levels - implement Hook interface recording every level, the Datadog hook
+// skipping the ones above its level on replay
+func (w *WarmStart) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - implement Hook interface, record a copy of the entry until a hook
+// is attached, then fire it to the hook
+func (w *WarmStart) Fire(entry *logrus.Entry) error {
+	w.m.Lock()
+	if hook := w.hook; hook != nil {
+		w.m.Unlock()
+		return hook.Fire(entry)
+	}
+	defer w.m.Unlock()
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Buffer = nil
+	if len(w.entries) < cap(w.entries) {
+		w.entries = append(w.entries, &e)
+		return nil
+	}
+	w.entries[w.next] = &e
+	w.next = (w.next + 1) % len(w.entries)
+	w.dropped++
+	return nil
+}
+
+// Attach - replay the entries recorded into hook, oldest first, and fire the
+// next ones to it. Entries fired meanwhile wait for the replay to finish, so
+// the order is kept. The number of entries replayed, the first error of
+// hook.Fire if any, the replay going on after it. Attaching again is a no-op.
+func (w *WarmStart) Attach(hook *Hook) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.hook != nil {
+		return 0, nil
+	}
+	var first error
+	replayed := 0
+	for i := range w.entries {
+		e := w.entries[(w.next+i)%len(w.entries)]
+		if err := hook.Fire(e); err != nil && first == nil {
+			first = err
+		}
+		replayed++
+	}
+	w.hook, w.entries = hook, nil
+	return replayed, first
+}
+
+// Dropped - entries recorded then dropped to make room for newer ones
+// before a hook was attached
+func (w *WarmStart) Dropped() int64 {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.dropped
+}
@@ -0,0 +1,74 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bin3377/logrus-datadog-hook/intake"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWarmStart(t *testing.T) {
+	w := NewWarmStart(3)
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.SetLevel(logrus.DebugLevel)
+	l.Hooks.Add(w)
+	fields := logrus.Fields{"step": 0}
+	for i, msg := range []string{"zero", "one", "two", "three"} {
+		fields["step"] = i
+		l.WithFields(fields).Info(msg)
+	}
+	l.Debug("four")
+	// the oldest entries made room for the newer ones
+	equals(t, int64(2), w.Dropped())
+
+	var m sync.Mutex
+	var messages []string
+	var steps []interface{}
+	hook := New("key", WithBatchTimeout(time.Hour), WithExporter(intake.ExporterFunc(func(b *intake.Batch) error {
+		m.Lock()
+		defer m.Unlock()
+		for _, line := range b.Lines {
+			var v map[string]interface{}
+			ok(t, json.Unmarshal(line, &v))
+			messages = append(messages, v["msg"].(string))
+			steps = append(steps, v["step"])
+		}
+		return nil
+	})))
+	n, err := w.Attach(hook)
+	ok(t, err)
+	equals(t, 3, n)
+	n, err = w.Attach(hook)
+	equals(t, 0, n)
+	ok(t, err)
+
+	// attached, entries go straight to the hook
+	l.Info("five")
+	ok(t, hook.Close(context.Background()))
+	m.Lock()
+	defer m.Unlock()
+	// the debug entry is below the level of the hook
+	equals(t, []string{"two", "three", "five"}, messages)
+	equals(t, []interface{}{float64(2), float64(3), nil}, steps)
+}
+
+func TestWarmStartError(t *testing.T) {
+	w := NewWarmStart(10)
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.Hooks.Add(w)
+	l.Info("one")
+	l.Info("two")
+	hook := New("key", WithExporter(intake.ExporterFunc(func(*intake.Batch) error { return nil })))
+	ok(t, hook.Close(context.Background()))
+	n, err := w.Attach(hook)
+	equals(t, 2, n)
+	equals(t, true, errors.Is(err, intake.ErrClosed))
+}
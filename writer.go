@@ -0,0 +1,161 @@
+package datadog
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultWriterMaxLineBytes - bytes an incomplete line is held to by a
+	// Writer before it is logged
+	DefaultWriterMaxLineBytes = 64 << 10
+	// DefaultWriterFlushTimeout - how long a Writer holds a record waiting
+	// for the line after it
+	DefaultWriterFlushTimeout = time.Second
+)
+
+// WriterOptions define how the output of a Writer is framed into entries
+type WriterOptions struct {
+	// Start - lines matching Start begin a new entry, the other lines are
+	// appended to the entry before them, such as the frames of a stack trace.
+	// Every line is an entry of its own if nil.
+	Start *regexp.Regexp
+	// MaxLines - entries are cut after this many lines, no limit if 0
+	MaxLines int
+	// MaxLineBytes - an incomplete line is logged once it holds this many
+	// bytes, the rest of it following as a line of its own,
+	// DefaultWriterMaxLineBytes if 0
+	MaxLineBytes int
+	// FlushTimeout - a record framed with Start is logged this long after
+	// its first line without waiting for the line starting the next one,
+	// DefaultWriterFlushTimeout if 0, held until then or Flush if below 0
+	FlushTimeout time.Duration
+}
+
+// Writer is an io.Writer logging every line, or every multi-line record,
+// written to it as an entry with the fields of the writer. Unlike
+// logrus.Entry.WriterLevel it needs no goroutine and keeps stack traces and
+// other multi-line output in one entry.
+type Writer struct {
+	entry   *logrus.Entry
+	level   logrus.Level
+	options WriterOptions
+
+	m       sync.Mutex
+	partial []byte
+	record  []byte
+	lines   int
+	timer   *time.Timer
+	gen     int // records emitted, so a timer firing late spares the next one
+}
+
+// NewWriter - create writer logging at level with the fields of entry, typically
+// logger.WithFields set once for the output of a subprocess
+func NewWriter(entry *logrus.Entry, level logrus.Level, options WriterOptions) *Writer {
+	return &Writer{entry: entry, level: level, options: options}
+}
+
+// Write - log the complete lines of p, an incomplete last line is kept until
+// its end is written or the writer is flushed
+func (w *Writer) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.partial = append(w.partial, p...)
+	max := w.options.MaxLineBytes
+	if max <= 0 {
+		max = DefaultWriterMaxLineBytes
+	}
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			if len(w.partial) < max {
+				break
+			}
+			// too long a line is cut rather than held
+			w.line(w.partial[:max])
+			w.partial = w.partial[max:]
+			continue
+		}
+		if i > max {
+			i = max
+			w.line(w.partial[:i])
+			w.partial = w.partial[i:]
+			continue
+		}
+		w.line(bytes.TrimSuffix(w.partial[:i], []byte("\r")))
+		w.partial = w.partial[i+1:]
+	}
+	// keep the incomplete line at the start of the buffer so it does not grow forever
+	w.partial = append(w.partial[:0], w.partial...)
+	return len(p), nil
+}
+
+// Flush - log the record being framed and the incomplete last line
+func (w *Writer) Flush() {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if len(w.partial) > 0 {
+		w.line(w.partial)
+		w.partial = w.partial[:0]
+	}
+	w.emit()
+}
+
+// Close - flush the writer, it can still be written to afterwards
+func (w *Writer) Close() error {
+	w.Flush()
+	return nil
+}
+
+func (w *Writer) line(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 && len(w.record) == 0 {
+		return
+	}
+	start := w.options.Start == nil || w.options.Start.Match(line) || len(w.record) == 0
+	if start || (w.options.MaxLines > 0 && w.lines >= w.options.MaxLines) {
+		w.emit()
+		w.record = append(w.record, line...)
+	} else {
+		w.record = append(append(w.record, '\n'), line...)
+	}
+	w.lines++
+	if w.options.Start == nil {
+		w.emit()
+		return
+	}
+	if w.timer == nil && w.options.FlushTimeout >= 0 {
+		d := w.options.FlushTimeout
+		if d == 0 {
+			d = DefaultWriterFlushTimeout
+		}
+		gen := w.gen
+		w.timer = time.AfterFunc(d, func() { w.expire(gen) })
+	}
+}
+
+// expire - log the record held past the flush timeout, unless it was
+// logged already
+func (w *Writer) expire(gen int) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.gen == gen {
+		w.emit()
+	}
+}
+
+func (w *Writer) emit() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.record) > 0 {
+		w.entry.Log(w.level, string(w.record))
+	}
+	w.record = w.record[:0]
+	w.lines = 0
+	w.gen++
+}
@@ -0,0 +1,97 @@
+package datadog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func newWriterLogger() (*logrus.Logger, *test.Hook) {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	return l, test.NewLocal(l)
+}
+
+func TestWriter(t *testing.T) {
+	l, hook := newWriterLogger()
+	w := NewWriter(l.WithField("cmd", "make"), logrus.WarnLevel, WriterOptions{})
+
+	fmt.Fprint(w, "one\r\ntw")
+	equals(t, 1, len(hook.AllEntries()))
+	fmt.Fprint(w, "o\n\nthree")
+	ok(t, w.Close())
+
+	entries := hook.AllEntries()
+	equals(t, 3, len(entries))
+	for i, msg := range []string{"one", "two", "three"} {
+		equals(t, msg, entries[i].Message)
+		equals(t, logrus.WarnLevel, entries[i].Level)
+		equals(t, logrus.Fields{"cmd": "make"}, entries[i].Data)
+	}
+}
+
+func TestWriterMultiLine(t *testing.T) {
+	l, hook := newWriterLogger()
+	w := NewWriter(l.WithField("cmd", "worker"), logrus.ErrorLevel, WriterOptions{
+		Start:    regexp.MustCompile(`^\S`),
+		MaxLines: 3,
+	})
+
+	fmt.Fprint(w, "panic: boom\n\tmain.go:10\n\tmain.go:20\n")
+	fmt.Fprint(w, "exit status 2\n\textra\n\tline 3\n\tline 4\n")
+	equals(t, 2, len(hook.AllEntries()))
+	w.Flush()
+
+	entries := hook.AllEntries()
+	equals(t, 3, len(entries))
+	equals(t, "panic: boom\n\tmain.go:10\n\tmain.go:20", entries[0].Message)
+	equals(t, "exit status 2\n\textra\n\tline 3", entries[1].Message)
+	equals(t, "\tline 4", entries[2].Message)
+}
+
+func TestWriterMaxLineBytes(t *testing.T) {
+	l, hook := newWriterLogger()
+	w := NewWriter(l.WithField("cmd", "dump"), logrus.InfoLevel, WriterOptions{MaxLineBytes: 4})
+
+	fmt.Fprint(w, "abcdefghij")
+	fmt.Fprint(w, "\nabcdef\n")
+	ok(t, w.Close())
+	var messages []string
+	for _, e := range hook.AllEntries() {
+		messages = append(messages, e.Message)
+	}
+	equals(t, []string{"abcd", "efgh", "ij", "abcd", "ef"}, messages)
+}
+
+func TestWriterFlushTimeout(t *testing.T) {
+	l, hook := newWriterLogger()
+	w := NewWriter(l.WithField("cmd", "worker"), logrus.ErrorLevel, WriterOptions{
+		Start:        regexp.MustCompile(`^\S`),
+		FlushTimeout: 10 * time.Millisecond,
+	})
+	defer w.Close()
+
+	// the last record is logged without waiting for the next one
+	fmt.Fprint(w, "panic: boom\n\tmain.go:10\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(hook.AllEntries()) == 0 {
+		assert(t, time.Now().Before(deadline), "record not flushed")
+		time.Sleep(time.Millisecond)
+	}
+	equals(t, "panic: boom\n\tmain.go:10", hook.LastEntry().Message)
+
+	// or on Close when held
+	w = NewWriter(l.WithField("cmd", "worker"), logrus.ErrorLevel, WriterOptions{
+		Start:        regexp.MustCompile(`^\S`),
+		FlushTimeout: -1,
+	})
+	fmt.Fprint(w, "exit status 2\n")
+	equals(t, 1, len(hook.AllEntries()))
+	ok(t, w.Close())
+	equals(t, "exit status 2", hook.LastEntry().Message)
+}